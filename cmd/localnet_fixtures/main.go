@@ -0,0 +1,65 @@
+// Command localnet_fixtures clones the PumpSwap and pump.fun programs and the accounts trading the given
+// mints needs from a live cluster, for localnet.Start to load into solana-test-validator. Without
+// arguments the mints are read from mints.txt in the fixture directory.
+//
+//	RPC_ENDPOINT=https://api.mainnet-beta.solana.com go run ./cmd/localnet_fixtures [<mint>...]
+//
+// The programs are cloned as deployed, so the fixtures must be refreshed whenever the IDLs under idl/ are.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"solana-pumpswap-demo/internal/localnet"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+func main() {
+	dir := flag.String("dir", localnet.DefaultFixtureDir(), "fixture directory to write")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [--dir DIR] [<mint>...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var mints []solana.PublicKey
+	for _, arg := range flag.Args() {
+		mint, err := solana.PublicKeyFromBase58(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid mint %q: %v\n", arg, err)
+			os.Exit(2)
+		}
+		mints = append(mints, mint)
+	}
+	if len(mints) == 0 {
+		var err error
+		if mints, err = localnet.ReadMints(filepath.Join(*dir, "mints.txt")); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+	if len(mints) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	rpcEndpoint := os.Getenv("RPC_ENDPOINT")
+	if rpcEndpoint == "" {
+		rpcEndpoint = rpc.MainNetBeta_RPC
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	written, err := localnet.DumpFixtures(ctx, rpc.New(rpcEndpoint), *dir, mints...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to dump fixtures: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %d programs and %d accounts to %s\n", len(localnet.Programs), len(written), *dir)
+}
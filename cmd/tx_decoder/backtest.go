@@ -0,0 +1,399 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"solana-pumpswap-demo/idl/pumpfun/amm/idl/generated/amm"
+	"solana-pumpswap-demo/internal/backtest"
+	"solana-pumpswap-demo/internal/decoder"
+	"solana-pumpswap-demo/internal/exits"
+	"solana-pumpswap-demo/internal/store"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+)
+
+// defaultFeeSol is a base fee with no priority fee
+const defaultFeeSol = "0.000005"
+
+// runBacktest replays recorded trades through the copy strategy and prints the fills and the result
+func runBacktest(ctx context.Context, o *options, args []string) error {
+	cfg, err := o.backtestConfig()
+	if err != nil {
+		return err
+	}
+
+	var trades []backtest.Trade
+	switch {
+	case o.StorePath != "" && len(args) == 0:
+		trades, err = storeTrades(o.StorePath)
+	case o.StorePath == "" && len(args) == 1:
+		trades, err = recordedTrades(args[0])
+	default:
+		return errors.New("backtest takes one <events.ndjson|-> argument or --store")
+	}
+	if err != nil {
+		return err
+	}
+	if len(trades) == 0 {
+		return errors.New("no PumpSwap trades to replay")
+	}
+
+	report, err := backtest.Run(trades, cfg)
+	if err != nil {
+		return err
+	}
+	if outputFormat != outputTable {
+		enc := json.NewEncoder(recordOut)
+		if outputFormat == outputJSON {
+			enc.SetIndent("", "  ")
+		}
+		return enc.Encode(newBacktestRecord(report))
+	}
+	printBacktest(report)
+	return nil
+}
+
+// backtestConfig parses the strategy flags. The risk limits come from the environment like
+// for live copies, without the kill switch.
+func (o *options) backtestConfig() (backtest.Config, error) {
+	cfg := backtest.Config{FollowSells: o.FollowSells}
+	var err error
+	if o.Leader != "" {
+		if cfg.Leader, err = solana.PublicKeyFromBase58(o.Leader); err != nil {
+			return cfg, fmt.Errorf("invalid --leader: %w", err)
+		}
+	}
+	if o.Ratio != "" {
+		if cfg.Ratio, err = decimal.NewFromString(o.Ratio); err != nil || cfg.Ratio.IsNegative() {
+			return cfg, fmt.Errorf("invalid --ratio %q", o.Ratio)
+		}
+	}
+	if cfg.FixedLamports, err = solOpt("fixed-sol", o.FixedSol); err != nil {
+		return cfg, err
+	}
+	if cfg.FeeLamports, err = solOpt("fee-sol", o.FeeSol); err != nil {
+		return cfg, err
+	}
+	if cfg.Latency, err = durationOpt("latency", o.Latency); err != nil {
+		return cfg, err
+	}
+	if o.SlippageBps < 0 || o.SlippageBps >= 10000 {
+		return cfg, fmt.Errorf("invalid --slippage-bps %d", o.SlippageBps)
+	}
+	cfg.SlippageBps = uint32(o.SlippageBps)
+
+	if cfg.Exits, err = o.exitRules(); err != nil {
+		return cfg, err
+	}
+
+	if cfg.Limits, err = riskLimitsFromEnv(); err != nil {
+		return cfg, err
+	}
+	cfg.Limits.KillSwitch, cfg.Limits.KillSwitchFile = false, ""
+	return cfg, nil
+}
+
+// exitRules parses the exit rule flags
+func (o *options) exitRules() (exits.Rules, error) {
+	var rules exits.Rules
+	var err error
+	for _, level := range o.TakeProfits {
+		multiple, fraction, ok := strings.Cut(level, ":")
+		tp := exits.TakeProfit{}
+		if ok {
+			tp.Multiple, err = decimal.NewFromString(multiple)
+			if err == nil {
+				tp.Fraction, err = decimal.NewFromString(fraction)
+			}
+		}
+		if !ok || err != nil || !tp.Multiple.GreaterThan(decimal.NewFromInt(1)) || !tp.Fraction.IsPositive() || tp.Fraction.GreaterThan(decimal.NewFromInt(1)) {
+			return rules, fmt.Errorf("invalid --take-profit %q, want <multiple>:<fraction> such as 2:0.5", level)
+		}
+		rules.TakeProfits = append(rules.TakeProfits, tp)
+	}
+	if rules.StopLoss, err = fractionOpt("stop-loss", o.StopLoss); err != nil {
+		return rules, err
+	}
+	if rules.TrailingStop, err = fractionOpt("trailing-stop", o.TrailStop); err != nil {
+		return rules, err
+	}
+	if rules.MaxHold, err = durationOpt("max-hold", o.MaxHold); err != nil {
+		return rules, err
+	}
+	return rules, nil
+}
+
+// solOpt parses an optional SOL amount flag into lamports
+func solOpt(name, value string) (uint64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	sol, err := decimal.NewFromString(value)
+	if err != nil || sol.IsNegative() {
+		return 0, fmt.Errorf("invalid --%s %q, want an amount of SOL", name, value)
+	}
+	return uint64(sol.Shift(9).IntPart()), nil
+}
+
+// fractionOpt parses an optional fraction flag such as 0.3
+func fractionOpt(name, value string) (decimal.Decimal, error) {
+	if value == "" {
+		return decimal.Zero, nil
+	}
+	f, err := decimal.NewFromString(value)
+	if err != nil || !f.IsPositive() || f.GreaterThanOrEqual(decimal.NewFromInt(1)) {
+		return decimal.Zero, fmt.Errorf("invalid --%s %q, want a fraction between 0 and 1", name, value)
+	}
+	return f, nil
+}
+
+// durationOpt parses an optional duration flag
+func durationOpt(name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid --%s %q, want a duration such as 400ms", name, value)
+	}
+	return d, nil
+}
+
+// recordedTrades reads the PumpSwap trades from the JSON or NDJSON output of decode or monitor,
+// at path or on stdin for "-"
+func recordedTrades(path string) ([]backtest.Trade, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var trades []backtest.Trade
+	dec := json.NewDecoder(r)
+	dec.UseNumber() // Reserves and amounts can be past what a float64 holds exactly
+	for n := 1; ; n++ {
+		var record TransactionRecord
+		err := dec.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			return trades, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read transaction %d of %s: %w", n, path, err)
+		}
+		if !record.Success {
+			continue
+		}
+		recorded, err := tradesOf(record)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %s: %w", record.Signature, err)
+		}
+		trades = append(trades, recorded...)
+	}
+}
+
+// tradesOf rebuilds the PumpSwap trades of a transaction record from its events. The base mint
+// comes from the swap instruction that emitted the event.
+func tradesOf(record TransactionRecord) ([]backtest.Trade, error) {
+	var trades []backtest.Trade
+	for _, e := range record.Events {
+		if e.Venue != decoder.VenuePumpSwap {
+			continue
+		}
+		var data decoder.EventData
+		switch e.Name {
+		case "BuyEvent":
+			data = new(amm.BuyEventEventData)
+		case "SellEvent":
+			data = new(amm.SellEventEventData)
+		default:
+			continue
+		}
+		if err := eventFields(e.Data, data); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", e.Name, err)
+		}
+		trade := decoder.TradeFromEvent(data)
+		trade.Instruction = e.Instruction
+		trade.BaseMint = baseMintOf(record.Instructions, e)
+		trades = append(trades, backtest.Trade{Signature: record.Signature, Slot: record.Slot, Trade: *trade})
+	}
+	return trades, nil
+}
+
+// eventFields decodes an event's snake_case fields into the generated event struct. Without the
+// underscores the keys match its field names, which encoding/json compares case-insensitively.
+func eventFields(fields map[string]interface{}, dst interface{}) error {
+	keyed := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		keyed[strings.ReplaceAll(k, "_", "")] = v
+	}
+	raw, err := json.Marshal(keyed)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, dst)
+}
+
+// baseMintOf returns the base mint account of the PumpSwap swap instruction that emitted the event
+func baseMintOf(instructions []InstructionRecord, e EventRecord) solana.PublicKey {
+	for _, ix := range instructions {
+		if ix.Venue != decoder.VenuePumpSwap || ix.Instruction != e.Instruction || (ix.Name != "Buy" && ix.Name != "Sell") {
+			continue
+		}
+		for _, a := range ix.Accounts {
+			if a.Role == "base_mint" {
+				if mint, err := solana.PublicKeyFromBase58(a.Address); err == nil {
+					return mint
+				}
+			}
+		}
+	}
+	return solana.PublicKey{}
+}
+
+// storeTrades reads the successful PumpSwap swaps the monitor recorded with their pool reserves
+func storeTrades(path string) ([]backtest.Trade, error) {
+	st, err := store.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state store: %w", err)
+	}
+	defer st.Close()
+	records, err := st.Transactions()
+	if err != nil {
+		return nil, err
+	}
+
+	var trades []backtest.Trade
+	for _, tx := range records {
+		if !tx.Success || tx.PoolBaseReserve == 0 || tx.PoolQuoteReserve == 0 {
+			continue
+		}
+		trade := decoder.Trade{
+			Venue:            decoder.VenuePumpSwap,
+			Timestamp:        tx.BlockTime,
+			BaseAmount:       tx.BaseAmount,
+			QuoteAmount:      tx.QuoteAmount,
+			LpFeeBps:         tx.LpFeeBps,
+			ProtocolFeeBps:   tx.ProtocolFeeBps,
+			PoolBaseReserve:  tx.PoolBaseReserve,
+			PoolQuoteReserve: tx.PoolQuoteReserve,
+		}
+		switch tx.Direction {
+		case "Buy":
+			trade.Side = decoder.SideBuy
+		case "Sell":
+			trade.Side = decoder.SideSell
+		default:
+			continue
+		}
+		if trade.Pool, err = solana.PublicKeyFromBase58(tx.Pool); err != nil {
+			continue
+		}
+		trade.User, _ = solana.PublicKeyFromBase58(tx.User)
+		trade.BaseMint, _ = solana.PublicKeyFromBase58(tx.BaseMint)
+		trades = append(trades, backtest.Trade{Signature: tx.Signature, Slot: tx.Slot, Trade: trade})
+	}
+	return trades, nil
+}
+
+// BacktestRecord is the JSON schema of a backtest report, amounts in lamports
+type BacktestRecord struct {
+	Trades        int          `json:"trades"`
+	Fills         []FillRecord `json:"fills"`
+	RealizedPnL   int64        `json:"realized_pnl"`
+	UnrealizedPnL int64        `json:"unrealized_pnl"`
+	FeesPaid      uint64       `json:"fees_paid"`
+	MaxDrawdown   int64        `json:"max_drawdown"`
+	Wins          int          `json:"wins"`
+	Losses        int          `json:"losses"`
+	BreakEven     int          `json:"break_even"`
+	HitRate       string       `json:"hit_rate"`
+	OpenPositions int          `json:"open_positions"`
+}
+
+// FillRecord is an order the backtested strategy sent
+type FillRecord struct {
+	Time        int64  `json:"time"` // Unix seconds
+	Source      string `json:"source,omitempty"`
+	Pool        string `json:"pool"`
+	Mint        string `json:"mint,omitempty"`
+	Side        string `json:"side"`
+	Reason      string `json:"reason"`
+	BaseAmount  uint64 `json:"base_amount"`
+	QuoteAmount uint64 `json:"quote_amount"`
+	Price       string `json:"price"` // Lamports per raw base unit
+	PnL         int64  `json:"pnl"`
+	Error       string `json:"error,omitempty"`
+}
+
+func newBacktestRecord(r *backtest.Report) BacktestRecord {
+	record := BacktestRecord{
+		Trades:        r.Trades,
+		Fills:         []FillRecord{},
+		RealizedPnL:   r.RealizedPnL,
+		UnrealizedPnL: r.UnrealizedPnL,
+		FeesPaid:      r.FeesPaid,
+		MaxDrawdown:   r.MaxDrawdown,
+		Wins:          r.Wins,
+		Losses:        r.Losses,
+		BreakEven:     r.BreakEven,
+		HitRate:       r.HitRate().StringFixed(4),
+		OpenPositions: r.OpenPositions,
+	}
+	for _, f := range r.Fills {
+		fr := FillRecord{
+			Time:        f.Time.Unix(),
+			Source:      f.Source,
+			Pool:        f.Pool.String(),
+			Side:        f.Side,
+			Reason:      f.Reason,
+			BaseAmount:  f.BaseAmount,
+			QuoteAmount: f.QuoteAmount,
+			Price:       f.Price.String(),
+			PnL:         f.PnL,
+			Error:       f.Error,
+		}
+		if !f.Mint.IsZero() {
+			fr.Mint = f.Mint.String()
+		}
+		record.Fills = append(record.Fills, fr)
+	}
+	return record
+}
+
+// printBacktest prints the fills and the result in SOL
+func printBacktest(r *backtest.Report) {
+	sol := func(lamports int64) string { return decimal.New(lamports, -9).StringFixed(6) }
+
+	fmt.Printf("%-20s %-4s %-14s %-44s %16s %14s %14s  %s\n", "Time", "Side", "Reason", "Mint or pool", "Tokens", "SOL", "PnL SOL", "Error")
+	for _, f := range r.Fills {
+		name := f.Mint
+		if name.IsZero() {
+			name = f.Pool
+		}
+		pnl := ""
+		if f.Side == decoder.SideSell || f.Error != "" {
+			pnl = sol(f.PnL)
+		}
+		fmt.Printf("%-20s %-4s %-14s %-44s %16d %14s %14s  %s\n", f.Time.Format("2006-01-02 15:04:05"), f.Side, f.Reason,
+			name, f.BaseAmount, sol(int64(f.QuoteAmount)), pnl, f.Error)
+	}
+
+	fmt.Printf("\nReplayed %d trades, sent %d orders\n", r.Trades, len(r.Fills))
+	fmt.Printf("Realized PnL:   %s SOL\n", sol(r.RealizedPnL))
+	fmt.Printf("Unrealized PnL: %s SOL (%d open positions)\n", sol(r.UnrealizedPnL), r.OpenPositions)
+	fmt.Printf("Fees Paid:      %s SOL\n", sol(int64(r.FeesPaid)))
+	fmt.Printf("Max Drawdown:   %s SOL\n", sol(r.MaxDrawdown))
+	fmt.Printf("Hit Rate:       %s%% (%d won, %d lost, %d break-even)\n", r.HitRate().Shift(2).StringFixed(1), r.Wins, r.Losses, r.BreakEven)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"solana-pumpswap-demo/internal/decoder"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+)
+
+// TestRecordedTrades tests that the trades rebuilt from decode's NDJSON output match the decoder's
+func TestRecordedTrades(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "..", defaultRecordDir, "*.json"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no corpus transactions: %v", err)
+	}
+	var ndjson bytes.Buffer
+	var want []decoder.Trade
+	for _, file := range files {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx := new(rpc.GetTransactionResult)
+		if err := json.Unmarshal(raw, tx); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.NewEncoder(&ndjson).Encode(newTransactionRecord(tx, filepath.Base(file), nil)); err != nil {
+			t.Fatal(err)
+		}
+		if tx.Meta.Err != nil {
+			continue
+		}
+		trades, err := decoder.DecodeTrades(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, trade := range trades {
+			if trade.Venue == decoder.VenuePumpSwap {
+				want = append(want, trade)
+			}
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "trades.ndjson")
+	if err := os.WriteFile(path, ndjson.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := recordedTrades(path)
+	if err != nil {
+		t.Fatalf("recordedTrades() error = %v", err)
+	}
+	if len(got) != len(want) || len(want) == 0 {
+		t.Fatalf("recordedTrades() = %d trades, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Trade != want[i] {
+			t.Errorf("trade %d (%s) = %+v, want %+v", i, got[i].Signature, got[i].Trade, want[i])
+		}
+	}
+}
+
+// TestParseBacktest tests the backtest flags and their conversion into the strategy
+func TestParseBacktest(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(config, []byte(`{"take_profits": ["3:1"], "stop_loss": "0.4", "follow_sells": true}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	o, args, err := parseCommand(commandNamed(t, "backtest"), []string{
+		"--config", config, "trades.ndjson", "--leader", defaultAccount, "--latency", "800ms",
+		"--take-profit", "2:0.5", "--take-profit", "4:0.25", "--max-hold", "1h",
+	}, io.Discard)
+	if err != nil {
+		t.Fatalf("parseCommand() error = %v", err)
+	}
+	if len(args) != 1 || args[0] != "trades.ndjson" {
+		t.Errorf("positional args = %v", args)
+	}
+	cfg, err := o.backtestConfig()
+	if err != nil {
+		t.Fatalf("backtestConfig() error = %v", err)
+	}
+	if cfg.Leader.String() != defaultAccount || !cfg.Ratio.Equal(decimal.NewFromInt(1)) || cfg.Latency != 800*time.Millisecond ||
+		cfg.SlippageBps != copySlippageBps || cfg.FeeLamports != 5000 || !cfg.FollowSells {
+		t.Errorf("config = %+v", cfg)
+	}
+	rules := cfg.Exits
+	if len(rules.TakeProfits) != 2 || !rules.TakeProfits[1].Fraction.Equal(decimal.RequireFromString("0.25")) ||
+		!rules.StopLoss.Equal(decimal.RequireFromString("0.4")) || rules.MaxHold != time.Hour || !rules.TrailingStop.IsZero() {
+		t.Errorf("exit rules = %+v", rules)
+	}
+
+	for _, bad := range []options{
+		{Ratio: "1", TakeProfits: stringList{"2"}},
+		{Ratio: "1", TakeProfits: stringList{"0.5:1"}},
+		{Ratio: "1", StopLoss: "1.5"},
+		{Ratio: "1", Latency: "soon"},
+		{Ratio: "-1"},
+		{Ratio: "1", FixedSol: "lots"},
+		{Ratio: "1", Leader: "not-a-wallet"},
+	} {
+		if _, err := bad.backtestConfig(); err == nil {
+			t.Errorf("backtestConfig(%+v) succeeded", bad)
+		}
+	}
+}
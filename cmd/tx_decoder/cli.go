@@ -0,0 +1,510 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"solana-pumpswap-demo/internal/migration"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// defaultAccount is decoded and monitored when no address is given
+const defaultAccount = "Csd779Qwsrf1FH1eeLQnNDcxknyCvJteJtVW2MLFr4y3"
+
+// defaultWSEndpoint is used when neither --ws, the config file nor WS_ENDPOINT set one
+const defaultWSEndpoint = "wss://api.mainnet-beta.solana.com"
+
+// options are the settings shared by the subcommands. Flags override the config file,
+// which overrides the environment and the defaults.
+type options struct {
+	RPCEndpoint string     `json:"rpc"`
+	WSEndpoint  string     `json:"ws"`
+	Commitment  string     `json:"commitment"`
+	Limit       int        `json:"limit"`
+	Before      string     `json:"before"`
+	Until       string     `json:"until"`
+	Start       string     `json:"start"`
+	End         string     `json:"end"`
+	Success     bool       `json:"success"`
+	Side        string     `json:"side"`
+	Mint        string     `json:"mint"`
+	MinSol      string     `json:"min_sol"`
+	Workers     int        `json:"workers"`
+	BuySol      string     `json:"buy_sol"`
+	Window      string     `json:"window"`
+	Creators    stringList `json:"creators"`
+	NotCreators stringList `json:"exclude_creators"`
+	Names       stringList `json:"names"`
+	NotNames    stringList `json:"exclude_names"`
+	SlippageBps int        `json:"slippage_bps"`
+	DryRun      bool       `json:"dry_run"`
+	Index       int        `json:"index"`
+	QuoteMint   string     `json:"quote_mint"`
+	Output      string     `json:"output"`
+	RecordDir   string     `json:"record_dir"`
+	StorePath   string     `json:"store"`
+	Leader      string     `json:"leader"`
+	Ratio       string     `json:"ratio"`
+	FixedSol    string     `json:"fixed_sol"`
+	Latency     string     `json:"latency"`
+	FeeSol      string     `json:"fee_sol"`
+	FollowSells bool       `json:"follow_sells"`
+	TakeProfits stringList `json:"take_profits"`
+	StopLoss    string     `json:"stop_loss"`
+	TrailStop   string     `json:"trailing_stop"`
+	MaxHold     string     `json:"max_hold"`
+	ExitEvery   string     `json:"exit_interval"`
+	Config      string     `json:"-"`
+}
+
+// command is a tx_decoder subcommand
+type command struct {
+	name    string
+	args    string // Positional arguments shown in the usage line
+	summary string
+	flags   func(fs *flag.FlagSet, o *options) // Registers the flags only this command takes
+	run     func(ctx context.Context, o *options, args []string) error
+}
+
+var commands = []command{
+	{
+		name:    "decode",
+		args:    "[account_address]",
+		summary: "Analyze the recent transactions of a PumpSwap pool or wallet (default " + defaultAccount + ")",
+		flags: func(fs *flag.FlagSet, o *options) {
+			fs.IntVar(&o.Limit, "limit", 10, "most transactions to scan, 0 for no limit when --start or --until bounds the range")
+			fs.StringVar(&o.Before, "before", "", "start from transactions older than this signature")
+			fs.StringVar(&o.Until, "until", "", "stop at this signature, exclusive")
+			fs.StringVar(&o.Start, "start", "", "skip transactions older than this time, RFC 3339 or Unix seconds")
+			fs.StringVar(&o.End, "end", "", "skip transactions newer than this time, RFC 3339 or Unix seconds")
+			fs.BoolVar(&o.Success, "success", false, "only decode successful transactions")
+			fs.StringVar(&o.Side, "side", "", "only decode PumpSwap buys or sells: buy or sell")
+			fs.StringVar(&o.Mint, "mint", "", "only decode PumpSwap swaps of this base mint")
+			fs.StringVar(&o.MinSol, "min-sol", "", "only decode PumpSwap swaps of at least this many SOL")
+			fs.IntVar(&o.Workers, "workers", 4, "transactions fetched concurrently")
+		},
+		run: runDecode,
+	},
+	{
+		name:    "decode-tx",
+		args:    "<tx_signature>",
+		summary: "Decode a single transaction by signature",
+		run:     runDecodeTx,
+	},
+	{
+		name:    "monitor",
+		args:    "[account_address]",
+		summary: "Monitor an account's transactions in real time over WebSocket (default " + defaultAccount + ")",
+		flags: func(fs *flag.FlagSet, o *options) {
+			fs.StringVar(&o.WSEndpoint, "ws", envOr("WS_ENDPOINT", defaultWSEndpoint), "Solana WebSocket endpoint")
+			exitFlags(fs, o)
+			fs.StringVar(&o.ExitEvery, "exit-interval", "5s", "how often the open positions are priced against the exit rules")
+		},
+		run: runMonitor,
+	},
+	{
+		name:    "migrations",
+		summary: "Watch pump.fun bonding curves complete and migrate into PumpSwap pools",
+		flags: func(fs *flag.FlagSet, o *options) {
+			fs.StringVar(&o.WSEndpoint, "ws", envOr("WS_ENDPOINT", defaultWSEndpoint), "Solana WebSocket endpoint")
+			fs.StringVar(&o.BuySol, "buy-sol", "", "buy this many SOL into each migrated pool with $PRIVATE_KEY, subject to the risk limits")
+			fs.StringVar(&o.Window, "window", migration.DefaultWindow.String(), "how long a completion or pool creation waits for the other")
+		},
+		run: runMigrations,
+	},
+	{
+		name:    "launches",
+		summary: "Stream new pump.fun tokens with their creator, metadata and initial curve",
+		flags: func(fs *flag.FlagSet, o *options) {
+			fs.StringVar(&o.WSEndpoint, "ws", envOr("WS_ENDPOINT", defaultWSEndpoint), "Solana WebSocket endpoint")
+			fs.Var(&o.Creators, "creator", "only launches by this creator, repeatable")
+			fs.Var(&o.NotCreators, "exclude-creator", "skip launches by this creator, repeatable")
+			fs.Var(&o.Names, "name", "only launches whose name or symbol matches this regular expression, repeatable")
+			fs.Var(&o.NotNames, "exclude-name", "skip launches whose name or symbol matches this regular expression, repeatable")
+		},
+		run: runLaunches,
+	},
+	{
+		name:    "liquidity",
+		args:    "add|remove <mint> <amount>",
+		summary: "Add SOL and matching tokens to a mint's PumpSwap pool, or remove LP tokens (raw units or all)",
+		flags: func(fs *flag.FlagSet, o *options) {
+			fs.IntVar(&o.SlippageBps, "slippage-bps", copySlippageBps, "how far the pool may move before the deposit or withdrawal fails")
+			fs.BoolVar(&o.DryRun, "dry-run", false, "print the plan without sending it")
+		},
+		run: runLiquidity,
+	},
+	{
+		name:    "create-pool",
+		args:    "<base_mint> <base> <quote>",
+		summary: "Create and seed a PumpSwap pool: raw base units and SOL, or raw quote units with --quote-mint",
+		flags: func(fs *flag.FlagSet, o *options) {
+			fs.IntVar(&o.Index, "index", 0, "pool index, pools of the same creator and mints differ by it")
+			fs.StringVar(&o.QuoteMint, "quote-mint", solana.WrappedSol.String(), "quote mint, WSOL is wrapped from SOL")
+			fs.BoolVar(&o.DryRun, "dry-run", false, "print the derived accounts without sending")
+		},
+		run: runCreatePool,
+	},
+	{
+		name:    "record",
+		args:    "<name> <tx_signature>",
+		summary: "Save a transaction's raw getTransaction result to the decoder's golden-file corpus",
+		flags: func(fs *flag.FlagSet, o *options) {
+			fs.StringVar(&o.RecordDir, "dir", defaultRecordDir, "corpus directory, the default is relative to the repository root")
+		},
+		run: runRecord,
+	},
+	{
+		name:    "backtest",
+		args:    "<events.ndjson|->",
+		summary: "Replay recorded PumpSwap trades through the copy strategy on the pool simulator and report PnL",
+		flags: func(fs *flag.FlagSet, o *options) {
+			fs.StringVar(&o.StorePath, "store", "", "replay the swaps recorded in this state database instead of decode output")
+			fs.StringVar(&o.Leader, "leader", "", "wallet whose trades are copied, every trade in the stream if empty")
+			fs.StringVar(&o.Ratio, "ratio", "1", "copy buys spend this fraction of the leader's SOL")
+			fs.StringVar(&o.FixedSol, "fixed-sol", "", "spend this many SOL on every copy buy instead")
+			fs.StringVar(&o.Latency, "latency", "0s", "delay between the leader's trade and ours landing")
+			fs.IntVar(&o.SlippageBps, "slippage-bps", copySlippageBps, "slippage allowed on our orders")
+			fs.StringVar(&o.FeeSol, "fee-sol", defaultFeeSol, "network and priority fees of each transaction we send, in SOL")
+			fs.BoolVar(&o.FollowSells, "follow-sells", false, "sell the same fraction of the position when the leader sells")
+			exitFlags(fs, o)
+		},
+		run: runBacktest,
+	},
+}
+
+// exitFlags registers the exit rules shared by monitor and backtest
+func exitFlags(fs *flag.FlagSet, o *options) {
+	fs.Var(&o.TakeProfits, "take-profit", "sell a fraction of the position at a price multiple, e.g. 2:0.5, repeatable")
+	fs.StringVar(&o.StopLoss, "stop-loss", "", "sell everything after this drop from the entry price, e.g. 0.3")
+	fs.StringVar(&o.TrailStop, "trailing-stop", "", "sell everything after this drop from the highest price, e.g. 0.2")
+	fs.StringVar(&o.MaxHold, "max-hold", "", "sell everything after holding this long")
+}
+
+// stringList is a flag that may be given several times
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// errUsage reports a command line error whose message was already printed with the usage
+var errUsage = errors.New("invalid usage")
+
+// runCLI runs the subcommand named by args[0] until it finishes or ctx is cancelled
+func runCLI(ctx context.Context, args []string, stderr io.Writer) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		printUsage(stderr)
+		if len(args) == 0 {
+			return errUsage
+		}
+		return nil
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		o, positional, err := parseCommand(cmd, args[1:], stderr)
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := setOutputFormat(o.Output); err != nil {
+			return err
+		}
+		return cmd.run(ctx, o, positional)
+	}
+
+	fmt.Fprintf(stderr, "Unknown command: %s\n", args[0])
+	printUsage(stderr)
+	return errUsage
+}
+
+// parseCommand parses a subcommand's flags, which may come before or after its positional arguments,
+// and applies the config file to every option not set on the command line
+func parseCommand(cmd command, args []string, stderr io.Writer) (*options, []string, error) {
+	o := &options{}
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&o.RPCEndpoint, "rpc", envOr("RPC_ENDPOINT", fallbackRPCEndpoints[0]), "Solana RPC endpoint, public fallbacks are tried if it fails")
+	fs.StringVar(&o.Commitment, "commitment", string(rpc.CommitmentConfirmed), "commitment for reads: confirmed or finalized")
+	fs.StringVar(&o.Output, "output", outputTable, "output format: table, json or ndjson")
+	fs.StringVar(&o.Output, "o", outputTable, "shorthand for --output")
+	fs.StringVar(&o.Config, "config", os.Getenv("TX_DECODER_CONFIG"), "JSON file with default values for these flags")
+	if cmd.flags != nil {
+		cmd.flags(fs, o)
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: tx_decoder %s [flags] %s\n\n%s\n\nFlags:\n", cmd.name, cmd.args, cmd.summary)
+		fs.PrintDefaults()
+	}
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			// The flag package has already printed the error and the usage
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, nil, err
+			}
+			return nil, nil, errUsage
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	if o.Config != "" {
+		set := map[string]bool{}
+		fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		if err := applyConfig(o, o.Config, set); err != nil {
+			return nil, nil, err
+		}
+	}
+	return o, positional, nil
+}
+
+// applyConfig fills the options not set by a flag from the JSON config file at path
+func applyConfig(o *options, path string, set map[string]bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	var file options
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	apply := func(name string, dst *string, v string) {
+		if !set[name] && v != "" {
+			*dst = v
+		}
+	}
+	apply("rpc", &o.RPCEndpoint, file.RPCEndpoint)
+	apply("ws", &o.WSEndpoint, file.WSEndpoint)
+	apply("commitment", &o.Commitment, file.Commitment)
+	apply("before", &o.Before, file.Before)
+	apply("until", &o.Until, file.Until)
+	apply("start", &o.Start, file.Start)
+	apply("end", &o.End, file.End)
+	apply("side", &o.Side, file.Side)
+	apply("mint", &o.Mint, file.Mint)
+	apply("min-sol", &o.MinSol, file.MinSol)
+	apply("buy-sol", &o.BuySol, file.BuySol)
+	apply("window", &o.Window, file.Window)
+	apply("quote-mint", &o.QuoteMint, file.QuoteMint)
+	apply("dir", &o.RecordDir, file.RecordDir)
+	apply("store", &o.StorePath, file.StorePath)
+	apply("leader", &o.Leader, file.Leader)
+	apply("ratio", &o.Ratio, file.Ratio)
+	apply("fixed-sol", &o.FixedSol, file.FixedSol)
+	apply("latency", &o.Latency, file.Latency)
+	apply("fee-sol", &o.FeeSol, file.FeeSol)
+	apply("stop-loss", &o.StopLoss, file.StopLoss)
+	apply("trailing-stop", &o.TrailStop, file.TrailStop)
+	apply("max-hold", &o.MaxHold, file.MaxHold)
+	apply("exit-interval", &o.ExitEvery, file.ExitEvery)
+	applyList := func(name string, dst *stringList, v stringList) {
+		if !set[name] && len(v) > 0 {
+			*dst = v
+		}
+	}
+	applyList("creator", &o.Creators, file.Creators)
+	applyList("exclude-creator", &o.NotCreators, file.NotCreators)
+	applyList("name", &o.Names, file.Names)
+	applyList("exclude-name", &o.NotNames, file.NotNames)
+	applyList("take-profit", &o.TakeProfits, file.TakeProfits)
+	if !set["success"] && file.Success {
+		o.Success = true
+	}
+	if !set["slippage-bps"] && file.SlippageBps > 0 {
+		o.SlippageBps = file.SlippageBps
+	}
+	if !set["index"] && file.Index > 0 {
+		o.Index = file.Index
+	}
+	if !set["dry-run"] && file.DryRun {
+		o.DryRun = true
+	}
+	if !set["follow-sells"] && file.FollowSells {
+		o.FollowSells = true
+	}
+	if !set["workers"] && file.Workers > 0 {
+		o.Workers = file.Workers
+	}
+	if !set["output"] && !set["o"] && file.Output != "" {
+		o.Output = file.Output
+	}
+	if !set["limit"] && file.Limit > 0 {
+		o.Limit = file.Limit
+	}
+	return nil
+}
+
+// commitment returns the commitment to read transactions with. getTransaction does not support processed.
+func (o *options) commitment() (rpc.CommitmentType, error) {
+	switch c := rpc.CommitmentType(o.Commitment); c {
+	case rpc.CommitmentConfirmed, rpc.CommitmentFinalized:
+		return c, nil
+	default:
+		return "", fmt.Errorf("unsupported commitment %q, want confirmed or finalized", o.Commitment)
+	}
+}
+
+// signatureOpt parses an optional signature flag
+func signatureOpt(name, value string) (solana.Signature, error) {
+	if value == "" {
+		return solana.Signature{}, nil
+	}
+	sig, err := solana.SignatureFromBase58(value)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("invalid --%s signature: %w", name, err)
+	}
+	return sig, nil
+}
+
+// timeOpt parses an optional time flag given in RFC 3339 or as Unix seconds
+func timeOpt(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --%s time %q, want RFC 3339 or Unix seconds", name, value)
+	}
+	return t, nil
+}
+
+// accountArg returns the single optional account argument, or the default account
+func accountArg(args []string) (string, error) {
+	switch len(args) {
+	case 0:
+		return defaultAccount, nil
+	case 1:
+		return args[0], nil
+	default:
+		return "", fmt.Errorf("expected at most one account address, got %d arguments", len(args))
+	}
+}
+
+// withFallback runs fn against the primary endpoint and then each public fallback until one succeeds.
+// Each attempt gets its own timeout unless it is zero, and the shared context stops the attempts early.
+func withFallback(ctx context.Context, primary string, timeout time.Duration, fn func(ctx context.Context, endpoint string) error) error {
+	attempt := func(endpoint string) error {
+		if timeout == 0 {
+			return fn(ctx, endpoint)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return fn(ctx, endpoint)
+	}
+
+	err := attempt(primary)
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+	logf("Error with primary RPC endpoint: %v\n", err)
+	logln("Trying fallback RPC endpoints...")
+
+	for i, endpoint := range fallbackRPCEndpoints {
+		if endpoint == primary {
+			continue // Skip the one we already tried
+		}
+		logf("Trying fallback endpoint #%d: %s\n", i+1, endpoint)
+		if err = attempt(endpoint); err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		logf("Fallback endpoint failed: %v\n", err)
+	}
+	return fmt.Errorf("all RPC endpoints failed, try again later or pass a custom endpoint with --rpc: %w", err)
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// printUsage displays the program's usage information
+func printUsage(w io.Writer) {
+	fmt.Fprint(w, `
+PumpFun AMM Transaction Decoder
+
+Usage:
+  tx_decoder <command> [flags] [arguments]
+
+Commands:
+`)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-11s %-26s %s\n", cmd.name, cmd.args, cmd.summary)
+	}
+	fmt.Fprint(w, `
+Run "tx_decoder <command> -h" for the flags of a command. Every command takes:
+  --rpc <url>                 Solana RPC endpoint (default: $RPC_ENDPOINT or https://api.mainnet-beta.solana.com)
+                              If it fails, several fallback public endpoints are tried automatically.
+  --commitment <level>        Commitment for reads: confirmed (default) or finalized
+  -o, --output <format>       table (default), json (one indented object per transaction) or ndjson
+                              (one per line). In the JSON formats progress messages go to stderr.
+  --config <path>             JSON file with defaults for the flags, e.g. {"rpc": "...", "limit": 50}
+                              (default: $TX_DECODER_CONFIG)
+
+Environment Variables:
+  RPC_ENDPOINT                Default for --rpc
+  WS_ENDPOINT                 Default for monitor, migrations and launches --ws (default: wss://api.mainnet-beta.solana.com)
+  PRIVATE_KEY                 Wallet monitor copies leader buys and sells positions with, also buys with migrations --buy-sol, provides liquidity and creates pools
+  TX_DECODER_CONFIG           Default for --config
+  STORE_PATH                  State database used by monitor and migrations (default: tx_decoder.db)
+
+Risk Limits (unset disables the limit, SOL amounts in SOL, a value that does not parse stops monitor and backtest):
+  MAX_SOL_PER_TRADE           Largest copy buy
+  MAX_TOKEN_EXPOSURE_SOL      Largest position in a single token
+  MAX_OPEN_POSITIONS          Most tokens held at once
+  DAILY_LOSS_LIMIT_SOL        Stop buying after this realized loss since UTC midnight
+  MAX_PRICE_IMPACT_BPS        Reject copy buys that move the pool price more than this
+  MIN_POOL_LIQUIDITY_SOL      Smallest SOL reserve a pool must hold
+  KILL_SWITCH                 Set to 1 or true to refuse every buy
+  KILL_SWITCH_FILE            Refuse every buy while this file exists
+
+Examples:
+  tx_decoder decode --limit 50 Csd779Qwsrf1FH1eeLQnNDcxknyCvJteJtVW2MLFr4y3
+  tx_decoder decode --limit 0 --start 2025-04-01T00:00:00Z --success --side buy --min-sol 0.5 -o ndjson <wallet>
+  tx_decoder decode-tx 5SHT9PwxFE7BNmSQwU4KjAW16LQ5aEZmUvWKqSCamXKkWQBs1DcYkEv7ujWgASRUUKqYy6VsM7iTgJkgAygCVPZB
+  tx_decoder monitor --ws wss://my-node.example Csd779Qwsrf1FH1eeLQnNDcxknyCvJteJtVW2MLFr4y3
+  tx_decoder monitor --take-profit 2:0.5 --stop-loss 0.3 --trailing-stop 0.2 <leader_wallet>
+  tx_decoder decode -o ndjson Csd779Qwsrf1FH1eeLQnNDcxknyCvJteJtVW2MLFr4y3 | jq .token_deltas
+  tx_decoder migrations -o ndjson --buy-sol 0.1
+  tx_decoder liquidity add --dry-run <mint> 0.5
+  tx_decoder liquidity remove <mint> all
+  tx_decoder create-pool --rpc http://127.0.0.1:8899 --index 1 <mint> 1000000000000 2
+  tx_decoder launches -o ndjson --name '(?i)cat' --exclude-creator <wallet>
+  tx_decoder record pumpswap_sell_token2022 <tx_signature>
+  tx_decoder decode --limit 0 --start 2025-06-01T00:00:00Z -o ndjson <pool> > trades.ndjson
+  tx_decoder backtest --leader <wallet> --latency 800ms --follow-sells --take-profit 2:0.5 --stop-loss 0.3 trades.ndjson
+`)
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+func commandNamed(t *testing.T, name string) command {
+	t.Helper()
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	t.Fatalf("no %s command", name)
+	return command{}
+}
+
+// TestParseCommand tests flags before and after the positional arguments and the config file precedence
+func TestParseCommand(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(config, []byte(`{"rpc": "http://config", "limit": 50, "output": "ndjson", "commitment": "finalized"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	o, args, err := parseCommand(commandNamed(t, "decode"), []string{
+		"--config", config, "pool", "--limit", "5", "-o", "json",
+	}, io.Discard)
+	if err != nil {
+		t.Fatalf("parseCommand() error = %v", err)
+	}
+	if len(args) != 1 || args[0] != "pool" {
+		t.Errorf("positional args = %v, want [pool]", args)
+	}
+	// Flags win over the config file, the config file fills in the rest
+	if o.Limit != 5 || o.Output != outputJSON || o.RPCEndpoint != "http://config" || o.Commitment != "finalized" {
+		t.Errorf("options = %+v", o)
+	}
+
+	if _, _, err := parseCommand(commandNamed(t, "decode-tx"), []string{"--ws", "wss://x"}, io.Discard); err == nil {
+		t.Error("decode-tx accepted the monitor-only --ws flag")
+	}
+}
+
+// TestParseMigrations tests the migrations flags and their config file keys
+func TestParseMigrations(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(config, []byte(`{"buy_sol": "0.5", "window": "2m"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	o, _, err := parseCommand(commandNamed(t, "migrations"), []string{"--config", config, "--window", "30s"}, io.Discard)
+	if err != nil {
+		t.Fatalf("parseCommand() error = %v", err)
+	}
+	if o.BuySol != "0.5" || o.Window != "30s" {
+		t.Errorf("options = %+v", o)
+	}
+
+	o, _, err = parseCommand(commandNamed(t, "migrations"), nil, io.Discard)
+	if err != nil {
+		t.Fatalf("parseCommand() error = %v", err)
+	}
+	if o.BuySol != "" || o.Window != "10m0s" {
+		t.Errorf("defaults = %+v, want no buy and a 10m window", o)
+	}
+}
+
+// TestParseLaunches tests the repeatable launches filters and their config file keys
+func TestParseLaunches(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(config, []byte(`{"names": ["cat"], "exclude_creators": ["`+defaultAccount+`"]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	o, _, err := parseCommand(commandNamed(t, "launches"), []string{"--config", config, "--name", "(?i)dog", "--name", "^MOON"}, io.Discard)
+	if err != nil {
+		t.Fatalf("parseCommand() error = %v", err)
+	}
+	filter, err := o.launchFilter()
+	if err != nil {
+		t.Fatalf("launchFilter() error = %v", err)
+	}
+	if len(filter.Names) != 2 || filter.Names[1].String() != "^MOON" || len(filter.ExcludeCreators) != 1 || len(filter.Creators) != 0 {
+		t.Errorf("filter = %+v", filter)
+	}
+
+	o, _, err = parseCommand(commandNamed(t, "launches"), []string{"--exclude-name", "("}, io.Discard)
+	if err != nil {
+		t.Fatalf("parseCommand() error = %v", err)
+	}
+	if _, err := o.launchFilter(); err == nil {
+		t.Error("launchFilter() accepted an invalid pattern")
+	}
+}
+
+// TestParseLiquidity tests the liquidity flags and that bad arguments fail before any key or RPC is used
+func TestParseLiquidity(t *testing.T) {
+	o, args, err := parseCommand(commandNamed(t, "liquidity"), []string{"add", defaultAccount, "0.5", "--dry-run"}, io.Discard)
+	if err != nil {
+		t.Fatalf("parseCommand() error = %v", err)
+	}
+	if !o.DryRun || o.SlippageBps != copySlippageBps || len(args) != 3 {
+		t.Errorf("options = %+v, args %v", o, args)
+	}
+
+	for _, bad := range [][]string{
+		{"add", defaultAccount},
+		{"swap", defaultAccount, "1"},
+		{"add", "not-a-mint", "1"},
+	} {
+		if err := runLiquidity(context.Background(), o, bad); err == nil {
+			t.Errorf("runLiquidity(%v) accepted", bad)
+		}
+	}
+}
+
+// TestParseCreatePool tests the create-pool flags and the quote amount units
+func TestParseCreatePool(t *testing.T) {
+	o, args, err := parseCommand(commandNamed(t, "create-pool"), []string{"--index", "3", defaultAccount, "1000", "2.5"}, io.Discard)
+	if err != nil {
+		t.Fatalf("parseCommand() error = %v", err)
+	}
+	if o.Index != 3 || o.QuoteMint != solana.WrappedSol.String() || len(args) != 3 {
+		t.Errorf("options = %+v, args %v", o, args)
+	}
+
+	if got, err := quoteAmountArg("2.5", solana.WrappedSol); err != nil || got != 2_500_000_000 {
+		t.Errorf("quoteAmountArg(2.5 SOL) = %d, %v", got, err)
+	}
+	usdc := solana.MustPublicKeyFromBase58("EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v")
+	if got, err := quoteAmountArg("2500000", usdc); err != nil || got != 2_500_000 {
+		t.Errorf("quoteAmountArg(raw) = %d, %v", got, err)
+	}
+	if _, err := quoteAmountArg("2.5", usdc); err == nil {
+		t.Error("quoteAmountArg() accepted a fractional raw amount")
+	}
+
+	o.Index = 70000
+	if err := runCreatePool(context.Background(), o, args); err == nil {
+		t.Error("runCreatePool() accepted an index past uint16")
+	}
+}
+
+// TestCommitment tests that only the commitments getTransaction supports are accepted
+func TestCommitment(t *testing.T) {
+	for commitment, ok := range map[string]bool{"confirmed": true, "finalized": true, "processed": false, "": false} {
+		o := &options{Commitment: commitment}
+		if _, err := o.commitment(); (err == nil) != ok {
+			t.Errorf("commitment(%q) error = %v", commitment, err)
+		}
+	}
+}
+
+// TestNewHistoryQuery tests converting the decode flags into the history query and filter
+func TestNewHistoryQuery(t *testing.T) {
+	o := &options{Commitment: "confirmed", Limit: 0, Start: "1750000000", End: "2025-06-20T00:00:00Z",
+		Side: "sell", MinSol: "0.25", Success: true, Workers: 8}
+	q, err := newHistoryQuery(defaultAccount, o)
+	if err != nil {
+		t.Fatalf("newHistoryQuery() error = %v", err)
+	}
+	if q.query.Start.Unix() != 1_750_000_000 || q.query.End.Unix() != 1_750_377_600 || q.workers != 8 {
+		t.Errorf("query = %+v, workers %d", q.query, q.workers)
+	}
+	if !q.filter.SuccessOnly || q.filter.Side != "sell" || q.filter.MinQuote != 250_000_000 {
+		t.Errorf("filter = %+v", q.filter)
+	}
+
+	for _, bad := range []options{
+		{Commitment: "confirmed", Limit: 0, Workers: 1},                // Unbounded
+		{Commitment: "confirmed", Limit: 10, Workers: 1, Side: "hold"}, // Unknown side
+		{Commitment: "confirmed", Limit: 10, Workers: 0},               // No workers
+		{Commitment: "confirmed", Limit: 10, Workers: 1, Start: "yesterday"},
+	} {
+		if _, err := newHistoryQuery(defaultAccount, &bad); err == nil {
+			t.Errorf("newHistoryQuery(%+v) succeeded", bad)
+		}
+	}
+}
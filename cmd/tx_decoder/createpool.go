@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"solana-pumpswap-demo/idl/pumpfun/amm/idl/generated/amm"
+	"solana-pumpswap-demo/internal/router"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+)
+
+// poolVerifyTimeout bounds how long a sent create_pool is waited for before its pool is reported missing
+const poolVerifyTimeout = 60 * time.Second
+
+// PoolCreationRecord is the stable JSON schema of a PumpSwap pool we created
+type PoolCreationRecord struct {
+	Pool                  string `json:"pool"`
+	Index                 uint16 `json:"index"`
+	Creator               string `json:"creator"`
+	BaseMint              string `json:"base_mint"`
+	QuoteMint             string `json:"quote_mint"`
+	LpMint                string `json:"lp_mint"`
+	PoolBaseTokenAccount  string `json:"pool_base_token_account"`
+	PoolQuoteTokenAccount string `json:"pool_quote_token_account"`
+	BaseAmountIn          uint64 `json:"base_amount_in"`
+	QuoteAmountIn         uint64 `json:"quote_amount_in"`
+	LpSupply              uint64 `json:"lp_supply"`           // From the verified pool account, 0 with --dry-run
+	Signature             string `json:"signature,omitempty"` // Empty with --dry-run
+}
+
+func newPoolCreationRecord(c *router.PoolCreation) PoolCreationRecord {
+	return PoolCreationRecord{
+		Pool:                  c.Addresses.Pool.String(),
+		Index:                 c.Param.Index,
+		Creator:               c.Param.Creator.String(),
+		BaseMint:              c.Param.BaseMint.String(),
+		QuoteMint:             c.Param.QuoteMint.String(),
+		LpMint:                c.Addresses.LpMint.String(),
+		PoolBaseTokenAccount:  c.Addresses.PoolBaseTokenAccount.String(),
+		PoolQuoteTokenAccount: c.Addresses.PoolQuoteTokenAccount.String(),
+		BaseAmountIn:          c.Param.BaseAmountIn,
+		QuoteAmountIn:         c.Param.QuoteAmountIn,
+	}
+}
+
+// runCreatePool seeds a new PumpSwap pool from the PRIVATE_KEY wallet and checks the pool account it creates
+func runCreatePool(ctx context.Context, o *options, args []string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("create-pool takes <base_mint> <base_amount> <quote_amount>, got %d arguments", len(args))
+	}
+	baseMint, err := solana.PublicKeyFromBase58(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid base mint: %w", err)
+	}
+	quoteMint, err := solana.PublicKeyFromBase58(o.QuoteMint)
+	if err != nil {
+		return fmt.Errorf("invalid --quote-mint: %w", err)
+	}
+	if o.Index < 0 || o.Index > math.MaxUint16 {
+		return fmt.Errorf("invalid --index %d", o.Index)
+	}
+	baseAmount, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil || baseAmount == 0 {
+		return fmt.Errorf("invalid base amount %q, want raw token units", args[1])
+	}
+	quoteAmount, err := quoteAmountArg(args[2], quoteMint)
+	if err != nil {
+		return err
+	}
+	privateKey, err := solana.PrivateKeyFromBase58(os.Getenv("PRIVATE_KEY"))
+	if err != nil {
+		return fmt.Errorf("create-pool needs PRIVATE_KEY: %w", err)
+	}
+
+	r := router.New(rpc.New(o.RPCEndpoint), privateKey, copySlippageBps)
+	c, err := r.PlanCreatePool(ctx, uint16(o.Index), baseMint, quoteMint, baseAmount, quoteAmount)
+	if err != nil {
+		return fmt.Errorf("failed to plan pool creation: %w", err)
+	}
+	printPoolCreation(c)
+	record := newPoolCreationRecord(c)
+	if o.DryRun {
+		emitRecord(record, "pool "+record.Pool)
+		return nil
+	}
+
+	sig, err := r.SendCreatePool(ctx, c)
+	if err != nil {
+		return err
+	}
+	logln("Transaction sent:", sig)
+	record.Signature = sig.String()
+	pool, err := waitForPool(ctx, r, c)
+	if err != nil {
+		return fmt.Errorf("pool creation %s not verified: %w", sig, err)
+	}
+	logf("Pool verified, LP supply %d\n", pool.LpSupply)
+	record.LpSupply = pool.LpSupply
+	emitRecord(record, "pool "+record.Pool)
+	return nil
+}
+
+// quoteAmountArg parses the quote amount, in SOL for a WSOL quote and in raw units otherwise
+func quoteAmountArg(arg string, quoteMint solana.PublicKey) (uint64, error) {
+	if quoteMint.Equals(solana.WrappedSol) {
+		sol, err := decimal.NewFromString(arg)
+		if err != nil || sol.Sign() <= 0 {
+			return 0, fmt.Errorf("invalid SOL amount %q", arg)
+		}
+		return uint64(sol.Shift(9).IntPart()), nil
+	}
+	amount, err := strconv.ParseUint(arg, 10, 64)
+	if err != nil || amount == 0 {
+		return 0, fmt.Errorf("invalid quote amount %q, want raw token units", arg)
+	}
+	return amount, nil
+}
+
+// waitForPool polls the new pool until it can be read and verified
+func waitForPool(ctx context.Context, r *router.Router, c *router.PoolCreation) (*amm.PoolAccount, error) {
+	ctx, cancel := context.WithTimeout(ctx, poolVerifyTimeout)
+	defer cancel()
+	for {
+		pool, err := r.VerifyPoolCreation(ctx, c)
+		if !errors.Is(err, router.ErrPoolNotCreated) {
+			return pool, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// printPoolCreation prints a pool creation for the table format, which goes to stderr in the JSON formats
+func printPoolCreation(c *router.PoolCreation) {
+	logf("\nPool: %s (index %d, creator %s)\n", c.Addresses.Pool, c.Param.Index, c.Param.Creator)
+	logf("  Mints: %s / %s\n", c.Param.BaseMint, c.Param.QuoteMint)
+	logf("  LP mint: %s\n", c.Addresses.LpMint)
+	logf("  Pool token accounts: %s / %s\n", c.Addresses.PoolBaseTokenAccount, c.Addresses.PoolQuoteTokenAccount)
+	logf("  Seeded with: %d base / %d quote\n", c.Param.BaseAmountIn, c.Param.QuoteAmountIn)
+}
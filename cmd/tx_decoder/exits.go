@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"solana-pumpswap-demo/internal/decoder"
+	"solana-pumpswap-demo/internal/exits"
+	"solana-pumpswap-demo/internal/orders"
+	"solana-pumpswap-demo/internal/router"
+	"solana-pumpswap-demo/internal/store"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+)
+
+// exitManager sells the monitor's positions when their exit rules fire, nil when no rule is set
+var exitManager *exits.Manager
+
+// startExits creates the exit manager from the exit flags and tracks the open positions in the store.
+// The positions are priced through the router, on the bonding curve or the pool, and sold through
+// the order manager. The store must be open.
+func startExits(o *options, client *rpc.Client) error {
+	rules, err := o.exitRules()
+	if err != nil {
+		return err
+	}
+	interval, err := durationOpt("exit-interval", o.ExitEvery)
+	if err != nil {
+		return err
+	}
+	if !rules.Enabled() {
+		return nil
+	}
+	if interval <= 0 {
+		return fmt.Errorf("invalid --exit-interval %q", o.ExitEvery)
+	}
+	privateKey, err := solana.PrivateKeyFromBase58(os.Getenv("PRIVATE_KEY"))
+	if err != nil {
+		return fmt.Errorf("exit rules need PRIVATE_KEY to sell with: %w", err)
+	}
+
+	r := router.New(client, privateKey, copySlippageBps)
+	exitManager = exits.NewManager(rules, interval, routerReserves(r), routerSeller(r, privateKey.PublicKey()))
+
+	positions, err := stateStore.Positions()
+	if err != nil {
+		return fmt.Errorf("failed to load positions: %w", err)
+	}
+	for _, p := range positions {
+		trackPosition(p)
+	}
+	logf("Watching %d open positions for exits\n", len(exitManager.Positions()))
+	return nil
+}
+
+// routerReserves prices a position against the reserves the router would sell it into
+func routerReserves(r *router.Router) exits.ReserveFetcher {
+	return func(ctx context.Context, p exits.Position) (uint64, uint64, error) {
+		plan, err := r.Plan(ctx, p.Mint, decoder.SideSell, p.Amount)
+		if err != nil {
+			return 0, 0, err
+		}
+		return plan.Quote.BaseReserve, plan.Quote.QuoteReserve, nil
+	}
+}
+
+// routerSeller sells the exit's raw token amount through the order manager. The order is keyed by
+// the position, the rule and the take-profit level, so an exit is sent once and retried only if it
+// failed or expired.
+func routerSeller(r *router.Router, wallet solana.PublicKey) exits.Seller {
+	return func(ctx context.Context, p exits.Position, exit exits.Exit) (string, error) {
+		plan, err := r.Plan(ctx, p.Mint, decoder.SideSell, exit.Amount)
+		if err != nil {
+			return "", fmt.Errorf("failed to route sell: %w", err)
+		}
+		intent := orders.Intent{
+			SourceSignature: fmt.Sprintf("exit:%s:%s:%d", p.ID, exit.Reason, p.TakenProfits),
+			Wallet:          wallet.String(),
+			Mint:            p.Mint.String(),
+			Side:            "sell",
+			Reason:          string(exit.Reason),
+			AmountIn:        exit.Amount,
+			MinAmountOut:    plan.Quote.MinAmountOut,
+		}
+		order, err := copyOrders.Submit(ctx, intent, func(ctx context.Context) (string, error) {
+			sig, err := r.Send(ctx, plan)
+			if err != nil {
+				return "", err
+			}
+			return sig.String(), nil
+		})
+		return order.Signature, err
+	}
+}
+
+// trackPosition hands a stored position to the exit manager, or stops tracking it once closed
+func trackPosition(p store.Position) {
+	if exitManager == nil {
+		return
+	}
+	if !p.Open() {
+		exitManager.Close(p.ID)
+		return
+	}
+	mint, err := solana.PublicKeyFromBase58(p.Mint)
+	if err != nil {
+		logf("Not watching position %s, invalid mint %q\n", p.ID, p.Mint)
+		return
+	}
+	entry, err := decimal.NewFromString(p.EntryPrice)
+	if err != nil {
+		logf("Not watching position %s, invalid entry price %q\n", p.ID, p.EntryPrice)
+		return
+	}
+	exitManager.Open(exits.Position{
+		ID:            p.ID,
+		Mint:          mint,
+		EntryPrice:    entry,
+		InitialAmount: p.InitialAmount,
+		Amount:        p.Amount,
+		OpenedAt:      p.OpenedAt,
+		TakenProfits:  p.TakenProfits,
+	})
+}
+
+// trackMint refreshes the open positions in mint from the store, after a sell of it failed or
+// expired and the exit manager already counted it as sold
+func trackMint(mint string) {
+	if exitManager == nil {
+		return
+	}
+	positions, err := stateStore.Positions()
+	if err != nil {
+		logf("Failed to load positions: %v\n", err)
+		return
+	}
+	for _, p := range positions {
+		if p.Mint == mint && p.Open() {
+			trackPosition(p)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"solana-pumpswap-demo/internal/store"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// TestStartExits tests that monitor's exit flags start the manager on the open positions in the store
+// and that fills keep it in step
+func TestStartExits(t *testing.T) {
+	t.Setenv("STORE_PATH", filepath.Join(t.TempDir(), "state.db"))
+	t.Setenv("PRIVATE_KEY", solana.NewWallet().PrivateKey.String())
+	st, err := openStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		st.Close()
+		stateStore, copyOrders, exitManager = nil, nil, nil
+	})
+
+	mint := solana.NewWallet().PublicKey()
+	opened := time.Unix(1750000000, 0)
+	for _, p := range []store.Position{
+		{ID: "open", Mint: mint.String(), EntryPrice: "0.00003", InitialAmount: 1000, Amount: 500, CostLamports: 30, OpenedAt: opened, TakenProfits: 1},
+		{ID: "closed", Mint: mint.String(), EntryPrice: "0.00003", InitialAmount: 1000, CostLamports: 30, ClosedAt: opened},
+	} {
+		if err := st.PutPosition(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	o, _, err := parseCommand(commandNamed(t, "monitor"), []string{"--stop-loss", "0.3", "--take-profit", "2:0.5", "--exit-interval", "1s"}, io.Discard)
+	if err != nil {
+		t.Fatalf("parseCommand() error = %v", err)
+	}
+	if err := startExits(o, rpc.New("http://127.0.0.1:0")); err != nil {
+		t.Fatalf("startExits() error = %v", err)
+	}
+	if exitManager == nil {
+		t.Fatal("exit rules did not start the exit manager")
+	}
+	positions := exitManager.Positions()
+	if len(positions) != 1 {
+		t.Fatalf("watching %+v, want the open position", positions)
+	}
+	p := positions[0]
+	if p.ID != "open" || p.Mint != mint || p.Amount != 500 || p.TakenProfits != 1 || p.EntryPrice.String() != "0.00003" || !p.OpenedAt.Equal(opened) {
+		t.Errorf("watched position = %+v", p)
+	}
+
+	// A sell fill that closes the position stops watching it
+	closed, err := st.GetPosition("open")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Amount, closed.ClosedAt = 0, opened.Add(time.Hour)
+	trackPosition(closed)
+	if positions := exitManager.Positions(); len(positions) != 0 {
+		t.Errorf("still watching %+v", positions)
+	}
+
+	// Without exit rules monitor only copies
+	exitManager = nil
+	o, _, err = parseCommand(commandNamed(t, "monitor"), nil, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := startExits(o, rpc.New("http://127.0.0.1:0")); err != nil || exitManager != nil {
+		t.Errorf("startExits() without rules = %v, manager %v", err, exitManager)
+	}
+}
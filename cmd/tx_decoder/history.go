@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"solana-pumpswap-demo/internal/decoder"
+	"solana-pumpswap-demo/internal/history"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+)
+
+// historyQuery selects the account transactions decode walks through and which of them it decodes
+type historyQuery struct {
+	query   history.Query
+	filter  history.Filter
+	workers int
+}
+
+// newHistoryQuery validates the decode options before any RPC call is made
+func newHistoryQuery(accountAddress string, o *options) (historyQuery, error) {
+	q := historyQuery{workers: o.Workers}
+	var err error
+	if q.query.Account, err = solana.PublicKeyFromBase58(accountAddress); err != nil {
+		return q, fmt.Errorf("invalid account address: %w", err)
+	}
+	if q.query.Commitment, err = o.commitment(); err != nil {
+		return q, err
+	}
+	if q.query.Before, err = signatureOpt("before", o.Before); err != nil {
+		return q, err
+	}
+	if q.query.Until, err = signatureOpt("until", o.Until); err != nil {
+		return q, err
+	}
+	if q.query.Start, err = timeOpt("start", o.Start); err != nil {
+		return q, err
+	}
+	if q.query.End, err = timeOpt("end", o.End); err != nil {
+		return q, err
+	}
+	switch {
+	case o.Limit < 0:
+		return q, fmt.Errorf("--limit must not be negative, got %d", o.Limit)
+	case o.Limit == 0 && q.query.Start.IsZero() && q.query.Until.IsZero():
+		return q, fmt.Errorf("--limit 0 needs --start or --until to bound the range")
+	}
+	q.query.Limit = o.Limit
+	if q.workers <= 0 {
+		return q, fmt.Errorf("--workers must be positive, got %d", o.Workers)
+	}
+
+	q.filter.SuccessOnly = o.Success
+	switch o.Side {
+	case "", decoder.SideBuy, decoder.SideSell:
+		q.filter.Side = o.Side
+	default:
+		return q, fmt.Errorf("invalid --side %q, want %s or %s", o.Side, decoder.SideBuy, decoder.SideSell)
+	}
+	if o.Mint != "" {
+		if q.filter.Mint, err = solana.PublicKeyFromBase58(o.Mint); err != nil {
+			return q, fmt.Errorf("invalid --mint: %w", err)
+		}
+	}
+	if o.MinSol != "" {
+		sol, err := decimal.NewFromString(o.MinSol)
+		if err != nil || sol.Sign() < 0 {
+			return q, fmt.Errorf("invalid --min-sol %q", o.MinSol)
+		}
+		q.filter.MinQuote = uint64(sol.Shift(9).IntPart())
+	}
+	return q, nil
+}
+
+// retryLister retries each page of signatures the way fetchTransaction retries transactions
+type retryLister struct {
+	client *rpc.Client
+}
+
+func (l retryLister) GetSignaturesForAddressWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error) {
+	var signatures []*rpc.TransactionSignature
+	var err error
+	for retryCount := 0; retryCount < maxRetries; retryCount++ {
+		signatures, err = l.client.GetSignaturesForAddressWithOpts(ctx, account, opts)
+		if err == nil || ctx.Err() != nil {
+			return signatures, err
+		}
+
+		if retryCount < maxRetries-1 {
+			logf("Failed to get signatures (attempt %d/%d): %v\nRetrying...\n",
+				retryCount+1, maxRetries, err)
+			time.Sleep(time.Duration(retryCount+1) * time.Second) // Exponential backoff
+		}
+	}
+	return nil, fmt.Errorf("failed to get signatures after %d attempts: %w", maxRetries, err)
+}
+
+// getHistoricalTransactions pages back through an account's transactions, fetches them with a
+// bounded number of concurrent requests and decodes the ones that match the filters, newest first.
+// Only a failure to list the signatures is returned, so a fallback endpoint never repeats output.
+// It only reads, historical buys are never copied.
+func getHistoricalTransactions(ctx context.Context, rpcEndpoint string, q historyQuery) error {
+	client := rpc.New(rpcEndpoint)
+
+	signatures, err := history.Signatures(ctx, retryLister{client}, q.query)
+	if err != nil {
+		return err
+	}
+
+	// Failed transactions can be dropped before they are fetched
+	var candidates []*rpc.TransactionSignature
+	for _, sig := range signatures {
+		if q.filter.MatchSignature(sig) {
+			candidates = append(candidates, sig)
+		}
+	}
+	logf("Found %d historical transactions (fetching %d)\n", len(signatures), len(candidates))
+
+	fetch := func(ctx context.Context, signature solana.Signature) (*rpc.GetTransactionResult, error) {
+		return fetchTransaction(ctx, client, signature, q.query.Commitment)
+	}
+	decoded := 0
+	err = history.Fetch(ctx, candidates, q.workers, fetch, func(sig *rpc.TransactionSignature, tx *rpc.GetTransactionResult, err error) error {
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			logf("Error getting transaction %s: %v\n", sig.Signature, err)
+			return nil
+		}
+		match, err := q.filter.Match(tx)
+		if err != nil {
+			logf("Error filtering transaction %s: %v\n", sig.Signature, err)
+			return nil
+		}
+		if !match {
+			return nil
+		}
+
+		decoded++
+		logf("\nTransaction %d: %s\n", decoded, sig.Signature)
+		analyzeTransactionWithRPC(tx, sig.Signature.String(), rpcEndpoint)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logf("\nDecoded %d of %d transactions\n", decoded, len(signatures))
+	return nil
+}
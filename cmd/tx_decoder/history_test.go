@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"solana-pumpswap-demo/internal/rpctest"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// TestDecodeHistoryReadOnly tests that paging through a wallet's buys emits every record and never
+// trades, even with a wallet configured
+func TestDecodeHistoryReadOnly(t *testing.T) {
+	s := rpctest.NewServer()
+	defer s.Close()
+	var listed []*rpc.TransactionSignature
+	for i, name := range []string{"pumpswap_buy", "pumpfun_buy", "routed_buy"} {
+		raw, err := os.ReadFile(filepath.Join("..", "..", defaultRecordDir, name+".json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig := solana.Signature{byte(i + 1)}
+		s.AddTransactionJSON(sig, raw)
+		listed = append(listed, &rpc.TransactionSignature{Signature: sig, Slot: uint64(100 - i)})
+	}
+	s.Handle("getSignaturesForAddress", func(json.RawMessage) (interface{}, error) {
+		page := listed
+		listed = nil
+		return page, nil
+	})
+	t.Setenv("PRIVATE_KEY", solana.NewWallet().PrivateKey.String())
+
+	var records bytes.Buffer
+	recordOut, logOut = &records, io.Discard
+	t.Cleanup(func() { recordOut, logOut, outputFormat = os.Stdout, os.Stdout, outputTable })
+
+	err := runCLI(context.Background(), []string{"decode", "--rpc", s.URL, "--limit", "10", "--workers", "2", "-o", "ndjson", defaultAccount}, io.Discard)
+	if err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+	dec := json.NewDecoder(&records)
+	decoded := 0
+	for dec.More() {
+		var record TransactionRecord
+		if err := dec.Decode(&record); err != nil {
+			t.Fatal(err)
+		}
+		decoded++
+	}
+	if decoded != 3 {
+		t.Errorf("decoded %d transactions, want 3", decoded)
+	}
+	if sends := s.Calls("sendTransaction"); sends != 0 {
+		t.Errorf("decoding history sent %d transactions", sends)
+	}
+}
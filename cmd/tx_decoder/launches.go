@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	pumpfun "solana-pumpswap-demo/idl/pumpfun/pump"
+	"solana-pumpswap-demo/internal/launches"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+)
+
+// LaunchRecord is the stable JSON schema of a new pump.fun token
+type LaunchRecord struct {
+	Signature            string `json:"signature"`
+	Slot                 uint64 `json:"slot"`
+	BlockTime            *int64 `json:"block_time"` // Unix seconds, null when the launch was read from the logs
+	Mint                 string `json:"mint"`
+	BondingCurve         string `json:"bonding_curve"`
+	Creator              string `json:"creator"`
+	Name                 string `json:"name"`
+	Symbol               string `json:"symbol"`
+	URI                  string `json:"uri"`
+	VirtualTokenReserves uint64 `json:"virtual_token_reserves"`
+	VirtualSolReserves   uint64 `json:"virtual_sol_reserves"`
+	RealTokenReserves    uint64 `json:"real_token_reserves"`
+	RealSolReserves      uint64 `json:"real_sol_reserves"`
+	TokenTotalSupply     uint64 `json:"token_total_supply"`
+	DevBuySol            uint64 `json:"dev_buy_sol"` // Lamports
+	DevBuyTokens         uint64 `json:"dev_buy_tokens"`
+}
+
+func newLaunchRecord(l launches.Launch) LaunchRecord {
+	record := LaunchRecord{
+		Signature:            l.Signature.String(),
+		Slot:                 l.Slot,
+		Mint:                 l.Mint.String(),
+		BondingCurve:         l.BondingCurve.String(),
+		Creator:              l.Creator.String(),
+		Name:                 l.Name,
+		Symbol:               l.Symbol,
+		URI:                  l.URI,
+		VirtualTokenReserves: l.Curve.VirtualTokenReserves,
+		VirtualSolReserves:   l.Curve.VirtualSolReserves,
+		RealTokenReserves:    l.Curve.RealTokenReserves,
+		RealSolReserves:      l.Curve.RealSolReserves,
+		TokenTotalSupply:     l.Curve.TokenTotalSupply,
+		DevBuySol:            l.DevBuySol,
+		DevBuyTokens:         l.DevBuyTokens,
+	}
+	if !l.BlockTime.IsZero() {
+		at := l.BlockTime.Unix()
+		record.BlockTime = &at
+	}
+	return record
+}
+
+// runLaunches streams new pump.fun tokens that pass the creator and name filters
+func runLaunches(ctx context.Context, o *options, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("launches takes no arguments, got %d", len(args))
+	}
+	commitment, err := o.commitment()
+	if err != nil {
+		return err
+	}
+	filter, err := o.launchFilter()
+	if err != nil {
+		return err
+	}
+
+	client := rpc.New(o.RPCEndpoint)
+	feed := launches.NewFeed(client, commitment, filter)
+	if global, err := pumpfun.FetchGlobal(ctx, client); err != nil {
+		logf("Using the default initial curve, failed to read pump.fun's Global account: %v\n", err)
+	} else {
+		feed.Initial = launches.InitialCurve(global)
+	}
+	feed.OnError = func(signature solana.Signature, err error) {
+		logf("Error handling %s: %v\n", signature, err)
+	}
+
+	wsClient, err := connectWS(ctx, o.WSEndpoint)
+	if err != nil {
+		return err
+	}
+	defer wsClient.Close()
+
+	logln("Watching pump.fun for new tokens...")
+	return feed.Run(ctx, wsClient, func(l launches.Launch) {
+		printLaunch(l)
+		record := newLaunchRecord(l)
+		emitRecord(record, "launch of "+record.Mint)
+	})
+}
+
+// launchFilter parses the creator and name flags
+func (o *options) launchFilter() (launches.Filter, error) {
+	var filter launches.Filter
+	var err error
+	if filter.Creators, err = publicKeys("creator", o.Creators); err != nil {
+		return filter, err
+	}
+	if filter.ExcludeCreators, err = publicKeys("exclude-creator", o.NotCreators); err != nil {
+		return filter, err
+	}
+	if filter.Names, err = patterns("name", o.Names); err != nil {
+		return filter, err
+	}
+	if filter.ExcludeNames, err = patterns("exclude-name", o.NotNames); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+func publicKeys(name string, values []string) ([]solana.PublicKey, error) {
+	var keys []solana.PublicKey
+	for _, v := range values {
+		key, err := solana.PublicKeyFromBase58(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s %q: %w", name, v, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func patterns(name string, values []string) ([]*regexp.Regexp, error) {
+	var out []*regexp.Regexp
+	for _, v := range values {
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s pattern: %w", name, err)
+		}
+		out = append(out, re)
+	}
+	return out, nil
+}
+
+// printLaunch prints a launch for the table format, which goes to stderr in the JSON formats
+func printLaunch(l launches.Launch) {
+	logf("\nLaunch: %s (%s) %s\n", l.Name, l.Symbol, l.Mint)
+	logf("  Creator: %s\n", l.Creator)
+	logf("  Signature: %s (slot %d", l.Signature, l.Slot)
+	if !l.BlockTime.IsZero() {
+		logf(", %s", l.BlockTime.UTC().Format(time.RFC3339))
+	}
+	logln(")")
+	logf("  URI: %s\n", l.URI)
+	logf("  Bonding curve: %s\n", l.BondingCurve)
+	logf("  Virtual reserves: %d tokens / %s SOL\n", l.Curve.VirtualTokenReserves, decimal.New(int64(l.Curve.VirtualSolReserves), -9))
+	logf("  Real reserves: %d tokens / %s SOL\n", l.Curve.RealTokenReserves, decimal.New(int64(l.Curve.RealSolReserves), -9))
+	if l.DevBuySol > 0 {
+		logf("  Creator bought: %d tokens for %s SOL\n", l.DevBuyTokens, decimal.New(int64(l.DevBuySol), -9))
+	}
+}
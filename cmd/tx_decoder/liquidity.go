@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"solana-pumpswap-demo/internal/router"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+)
+
+// LiquidityRecord is the stable JSON schema of a deposit into or a withdrawal from a PumpSwap pool
+type LiquidityRecord struct {
+	Side        string `json:"side"` // deposit or withdraw
+	Mint        string `json:"mint"`
+	Pool        string `json:"pool"`
+	LpMint      string `json:"lp_mint"`
+	LpAmount    uint64 `json:"lp_amount"`
+	LpSupply    uint64 `json:"lp_supply"`
+	PoolShare   string `json:"pool_share"` // Fraction of the pool the LP tokens stand for
+	BaseAmount  uint64 `json:"base_amount"`
+	QuoteAmount uint64 `json:"quote_amount"` // Lamports
+	BaseLimit   uint64 `json:"base_limit"`   // Most a deposit takes, fewest a withdrawal returns
+	QuoteLimit  uint64 `json:"quote_limit"`
+	Signature   string `json:"signature,omitempty"` // Empty with --dry-run
+}
+
+func newLiquidityRecord(plan *router.LiquidityPlan) LiquidityRecord {
+	return LiquidityRecord{
+		Side:        plan.Side,
+		Mint:        plan.Mint.String(),
+		Pool:        plan.Pool.String(),
+		LpMint:      plan.LpMint.String(),
+		LpAmount:    plan.LpAmount,
+		LpSupply:    plan.LpSupply,
+		PoolShare:   plan.Share().String(),
+		BaseAmount:  plan.BaseAmount,
+		QuoteAmount: plan.QuoteAmount,
+		BaseLimit:   plan.BaseLimit,
+		QuoteLimit:  plan.QuoteLimit,
+	}
+}
+
+// runLiquidity adds SOL and the matching tokens to a mint's PumpSwap pool, or removes LP tokens from it
+func runLiquidity(ctx context.Context, o *options, args []string) error {
+	if len(args) != 3 || (args[0] != "add" && args[0] != "remove") {
+		return fmt.Errorf("liquidity takes add <mint> <sol> or remove <mint> <lp_amount|all>")
+	}
+	mint, err := solana.PublicKeyFromBase58(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid mint: %w", err)
+	}
+	if o.SlippageBps < 0 || o.SlippageBps >= 10000 {
+		return fmt.Errorf("invalid --slippage-bps %d", o.SlippageBps)
+	}
+	privateKey, err := solana.PrivateKeyFromBase58(os.Getenv("PRIVATE_KEY"))
+	if err != nil {
+		return fmt.Errorf("liquidity needs PRIVATE_KEY: %w", err)
+	}
+	r := router.New(rpc.New(o.RPCEndpoint), privateKey, uint32(o.SlippageBps))
+
+	var plan *router.LiquidityPlan
+	if args[0] == "add" {
+		sol, err := decimal.NewFromString(args[2])
+		if err != nil || sol.Sign() <= 0 {
+			return fmt.Errorf("invalid SOL amount %q", args[2])
+		}
+		plan, err = r.PlanDeposit(ctx, mint, uint64(sol.Shift(9).IntPart()))
+		if err != nil {
+			return fmt.Errorf("failed to plan deposit: %w", err)
+		}
+	} else {
+		var lpAmount uint64 // Zero withdraws the whole balance
+		if args[2] != "all" {
+			if lpAmount, err = strconv.ParseUint(args[2], 10, 64); err != nil || lpAmount == 0 {
+				return fmt.Errorf("invalid LP token amount %q, want raw units or all", args[2])
+			}
+		}
+		if plan, err = r.PlanWithdraw(ctx, mint, lpAmount); err != nil {
+			return fmt.Errorf("failed to plan withdrawal: %w", err)
+		}
+	}
+
+	printLiquidity(plan)
+	record := newLiquidityRecord(plan)
+	if !o.DryRun {
+		sig, err := r.SendLiquidity(ctx, plan)
+		if err != nil {
+			return err
+		}
+		logln("Transaction sent:", sig)
+		record.Signature = sig.String()
+	}
+	emitRecord(record, record.Side+" of "+record.Mint)
+	return nil
+}
+
+// printLiquidity prints a liquidity plan for the table format, which goes to stderr in the JSON formats
+func printLiquidity(plan *router.LiquidityPlan) {
+	sol := func(lamports uint64) decimal.Decimal { return decimal.New(int64(lamports), -9) }
+	share := plan.Share().Mul(decimal.NewFromInt(100)).StringFixed(4)
+	logf("\nPool: %s (LP mint %s)\n", plan.Pool, plan.LpMint)
+	logf("  Reserves: %d tokens / %s SOL, LP supply %d\n", plan.BaseReserve, sol(plan.QuoteReserve), plan.LpSupply)
+	if plan.Side == router.SideDeposit {
+		logf("  Deposit: %d tokens (at most %d) and %s SOL (at most %s)\n", plan.BaseAmount, plan.BaseLimit, sol(plan.QuoteAmount), sol(plan.QuoteLimit))
+		logf("  LP tokens minted: %d, %s%% of the pool\n", plan.LpAmount, share)
+	} else {
+		logf("  LP tokens burned: %d, %s%% of the pool\n", plan.LpAmount, share)
+		logf("  Withdraw: %d tokens (at least %d) and %s SOL (at least %s)\n", plan.BaseAmount, plan.BaseLimit, sol(plan.QuoteAmount), sol(plan.QuoteLimit))
+	}
+}
@@ -1,19 +1,19 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"math"
 	"net/http"
 	"os"
 	"os/signal"
-	"solana-pumpswap-demo/internal/swapper"
-	"strconv"
+	"solana-pumpswap-demo/idl/pumpfun/amm/idl/generated/amm"
+	"solana-pumpswap-demo/idl/pumpfun/pump/idl/generated/pump"
+	"solana-pumpswap-demo/internal/decoder"
 	"strings"
 	"syscall"
 	"time"
@@ -23,7 +23,6 @@ import (
 	token "github.com/gagliardetto/solana-go/programs/token"
 	"github.com/gagliardetto/solana-go/rpc"
 	"github.com/gagliardetto/solana-go/rpc/ws"
-	"github.com/mr-tron/base58"
 )
 
 // pumpSwapProgramID is the program ID for PumpSwap AMM
@@ -44,25 +43,16 @@ var fallbackRPCEndpoints = []string{
 // Maximum number of retry attempts for RPC requests
 const maxRetries = 3
 
-// Instruction discriminators for PumpSwap AMM (8-byte identifiers)
-var (
-	// These are the 8-byte discriminators for PumpSwap instructions
-	// From the IDL file: Instruction_Buy = ag_binary.TypeID([8]byte{102, 6, 61, 18, 1, 218, 235, 234})
-	BuyDiscriminator        = []byte{102, 6, 61, 18, 1, 218, 235, 234}
-	SellDiscriminator       = []byte{143, 244, 89, 80, 224, 16, 16, 88}  // Example value - replace with correct one
-	CreatePoolDiscriminator = []byte{175, 175, 109, 31, 13, 152, 155, 9} // Example value - replace with correct one
-)
-
 // TokenInfo represents detailed information about a token
 type TokenInfo struct {
-	Symbol      string
-	Name        string
-	Decimals    uint8
-	Description string
-	Image       string
-	Website     string
-	Twitter     string
-	Telegram    string
+	Symbol      string `json:"symbol"`
+	Name        string `json:"name"`
+	Decimals    uint8  `json:"decimals"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+	Website     string `json:"website,omitempty"`
+	Twitter     string `json:"twitter,omitempty"`
+	Telegram    string `json:"telegram,omitempty"`
 }
 
 type PumpSwapPoolInfo struct {
@@ -109,174 +99,106 @@ type TokenUriData struct {
 // TokenCache to avoid redundant lookups during a session
 var tokenCache = make(map[string]*TokenInfo)
 
-func main() {
-	// Display usage information if requested
-	if len(os.Args) > 1 && (os.Args[1] == "-h" || os.Args[1] == "--help") {
-		printUsage()
-		return
-	}
-
-	// Process commands
-	if len(os.Args) > 1 {
-		switch os.Args[1] {
-		case "decode":
-			// If the decode command is provided, shift arguments to pass to decodeTxCmd
-			os.Args = os.Args[1:]
-			decodeTxCmd()
-		case "decode-tx":
-			// Decode a specific transaction by signature
-			if len(os.Args) < 3 {
-				fmt.Println("Error: Transaction signature required")
-				printUsage()
-				os.Exit(1)
-			}
-
-			// Get the transaction signature
-			txSignature := os.Args[2]
-
-			//output txSingautre
-			fmt.Println("tx signature is:", txSignature)
-
-			// Get RPC endpoint from environment or use default
-			rpcEndpoint := os.Getenv("RPC_ENDPOINT")
-			if rpcEndpoint == "" {
-				rpcEndpoint = fallbackRPCEndpoints[0]
-			}
-
-			// Create context and decode transaction
-			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-			defer cancel()
-
-			err := decodeSpecificTransaction(ctx, rpcEndpoint, txSignature)
-			if err != nil {
-				fmt.Printf("Error with primary RPC endpoint: %v\n", err)
-				fmt.Println("Trying fallback RPC endpoints...")
-
-				// Try fallback endpoints
-				var success bool
-				for i, endpoint := range fallbackRPCEndpoints {
-					if endpoint == rpcEndpoint {
-						continue // Skip the one we already tried
-					}
-
-					fmt.Printf("Trying fallback endpoint #%d: %s\n", i+1, endpoint)
-					ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-					err := decodeSpecificTransaction(ctx, endpoint, txSignature)
-					cancel()
-
-					if err == nil {
-						success = true
-						break
-					}
-					fmt.Printf("Fallback endpoint failed: %v\n", err)
-				}
+// TransactionSummary tracks the important data of an analyzed transaction
+type TransactionSummary struct {
+	Operation    string
+	Direction    string
+	BaseMint     string
+	AmountIn     uint64
+	AmountOut    uint64
+	BaseMintName string
+	TokenInfo    *TokenInfo
+	Venue        string         // decoder.VenuePumpSwap or decoder.VenuePumpFun, empty if no swap was found
+	Trade        *decoder.Trade // Executed amounts, fees and reserves from the swap event
+	Via          string         // Program that invoked the swap through CPI, empty when called directly
+}
 
-				if !success {
-					fmt.Println("\nAll RPC endpoints failed. Please try again later or use a custom RPC endpoint:")
-					fmt.Println("export RPC_ENDPOINT=\"your-custom-endpoint\"")
-					os.Exit(1)
-				}
-			}
-		case "monitor":
-			// New command to monitor transactions in real-time using WebSocket
-			monitorAccountCmd()
-		default:
-			// If this is a pool address for decoding, pass it along
-			if len(os.Args[1]) > 30 {
-				decodeTxCmd()
-			} else {
-				fmt.Printf("Unknown command: %s\n", os.Args[1])
-				printUsage()
-				os.Exit(1)
-			}
+func main() {
+	// Every command shares one context that is cancelled on Ctrl+C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Ctrl+C is a normal way to stop monitor
+	if err := runCLI(ctx, os.Args[1:], os.Stderr); err != nil && !errors.Is(err, context.Canceled) {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		}
-	} else {
-		// Run the default transaction decoder if no arguments
-		decodeTxCmd()
+		stop()
+		os.Exit(1)
 	}
 }
 
-// monitorAccountCmd monitors transactions for an account in real-time using WebSocket
-func monitorAccountCmd() {
-	// Get the account address to monitor from args or use default
-	accountAddress := "Csd779Qwsrf1FH1eeLQnNDcxknyCvJteJtVW2MLFr4y3" // Default smart money account
-	if len(os.Args) > 2 {
-		accountAddress = os.Args[2]
-	}
-
-	fmt.Printf("Starting real-time monitoring for account: %s\n", accountAddress)
-	fmt.Println("Press Ctrl+C to exit")
-
-	// Get RPC endpoint from environment or use default
-	rpcEndpoint := os.Getenv("RPC_ENDPOINT")
-	if rpcEndpoint == "" {
-		rpcEndpoint = fallbackRPCEndpoints[0]
+// runMonitor monitors transactions for an account in real-time using WebSocket
+func runMonitor(ctx context.Context, o *options, args []string) error {
+	accountAddress, err := accountArg(args)
+	if err != nil {
+		return err
 	}
-
-	// Get WebSocket endpoint from environment or derive from RPC endpoint
-	wsEndpoint := os.Getenv("WS_ENDPOINT")
-	if wsEndpoint == "" {
-		wsEndpoint = "wss://api.mainnet-beta.solana.com"
+	commitment, err := o.commitment()
+	if err != nil {
+		return err
 	}
+	rpcEndpoint, wsEndpoint := o.RPCEndpoint, o.WSEndpoint
 
 	// Convert account address to PublicKey
 	accountPubkey, err := solana.PublicKeyFromBase58(accountAddress)
 	if err != nil {
-		log.Fatalf("Invalid account address: %v", err)
+		return fmt.Errorf("invalid account address: %w", err)
+	}
+	// Copy trades read the limits again, a value that does not parse stops monitor before it starts
+	if _, err := riskLimitsFromEnv(); err != nil {
+		return err
 	}
 
-	// Create context with cancellation for proper shutdown
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// Handle Ctrl+C to gracefully exit
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-	go func() {
-		<-c
-		fmt.Println("\nShutting down WebSocket connection...")
-		cancel()
-		time.Sleep(1 * time.Second) // Give time for cleanup
-		os.Exit(0)
-	}()
+	logf("Starting real-time monitoring for account: %s\n", accountAddress)
+	logln("Press Ctrl+C to exit")
 
 	// Create regular RPC client for transaction details
 	rpcClient := rpc.New(rpcEndpoint)
 
-	// Connect to WebSocket endpoint with retry logic
-	var wsClient *ws.Client
-	for retryCount := 0; retryCount < maxRetries; retryCount++ {
-		fmt.Printf("Connecting to WebSocket endpoint: %s (attempt %d/%d)\n", wsEndpoint, retryCount+1, maxRetries)
-		wsClient, err = ws.Connect(ctx, wsEndpoint)
-		if err == nil {
-			break
-		}
-		fmt.Printf("Failed to connect to WebSocket: %v\n", err)
-		if retryCount < maxRetries-1 {
-			time.Sleep(time.Duration(retryCount+1) * time.Second)
-		}
+	// Open the state store so a restart neither loses nor repeats transactions
+	st, err := openStore()
+	if err != nil {
+		return fmt.Errorf("failed to open state store: %w", err)
 	}
+	defer st.Close()
 
+	// Positions are watched for exits from their entry, including the ones opened before a restart
+	if err := startExits(o, rpcClient); err != nil {
+		return err
+	}
+
+	// Orders sent before a restart are settled before anything new is copied
+	reconcileOrders(ctx, rpcClient)
+	if err := backfillSinceCursor(ctx, rpcClient, st, accountPubkey, rpcEndpoint, commitment); err != nil {
+		logf("Backfill failed: %v\n", err)
+	}
+	reconcileOrders(ctx, rpcClient)
+	if exitManager != nil {
+		go exitManager.Run(ctx)
+	}
+
+	wsClient, err := connectWS(ctx, wsEndpoint)
 	if err != nil {
-		log.Fatalf("Failed to connect to WebSocket after %d attempts: %v", maxRetries, err)
+		return err
 	}
 	defer wsClient.Close()
 
-	fmt.Println("Successfully connected to WebSocket")
+	logln("Successfully connected to WebSocket")
 
 	// Subscribe to logs that mention the account
-	// Using CommitmentConfirmed for better balance between speed and reliability
+	// Processed commitment gives the earliest notice, the transaction is fetched with --commitment
 	sub, err := wsClient.LogsSubscribeMentions(
 		accountPubkey,
 		rpc.CommitmentProcessed,
 	)
 	if err != nil {
-		log.Fatalf("Failed to subscribe to logs: %v", err)
+		return fmt.Errorf("failed to subscribe to logs: %w", err)
 	}
 	defer sub.Unsubscribe()
 
-	fmt.Printf("Successfully subscribed to logs for account: %s\n", accountAddress)
-	fmt.Println("Waiting for transactions...")
+	logf("Successfully subscribed to logs for account: %s\n", accountAddress)
+	logln("Waiting for transactions...")
 
 	// Transaction counter
 	txCount := 0
@@ -293,7 +215,7 @@ func monitorAccountCmd() {
 					// Context was canceled, exit gracefully
 					return
 				}
-				fmt.Printf("Error receiving log: %v\n", err)
+				logf("Error receiving log: %v\n", err)
 				// Brief pause before retrying
 				time.Sleep(100 * time.Millisecond)
 				continue
@@ -304,201 +226,122 @@ func monitorAccountCmd() {
 		}
 	}()
 
+	// Copy orders are checked between transactions until they land, fail or expire
+	reconcile := time.NewTicker(reconcileInterval)
+	defer reconcile.Stop()
+
 	// Process incoming transactions from the channel
 	for {
 		select {
 		case <-ctx.Done():
-			return
+			logln("\nShutting down WebSocket connection...")
+			return ctx.Err()
+		case <-reconcile.C:
+			reconcileOrders(ctx, rpcClient)
 		case logResult := <-transactionChan:
 			// A transaction involving the account was detected
-			txCount++
 			txSignature := logResult.Value.Signature.String()
-			fmt.Printf("\n[%d] Transaction detected: %s\n", txCount, txSignature)
+			if processed, err := st.IsProcessed(txSignature); err == nil && processed {
+				logf("\nSkipping already processed transaction: %s\n", txSignature)
+				continue
+			}
+			txCount++
+			logf("\n[%d] Transaction detected: %s\n", txCount, txSignature)
 
 			// Print transaction logs if available
 			if len(logResult.Value.Logs) > 0 {
-				fmt.Println("Transaction logs:")
+				logln("Transaction logs:")
 				for i, log := range logResult.Value.Logs {
 					if i < 5 { // Only show first 5 logs to avoid flooding the console
-						fmt.Printf("  %s\n", log)
+						logf("  %s\n", log)
 					} else {
-						fmt.Printf("  ...and %d more log entries\n", len(logResult.Value.Logs)-5)
+						logf("  ...and %d more log entries\n", len(logResult.Value.Logs)-5)
 						break
 					}
 				}
 			}
 
-			// Get transaction details with retry logic
-			var tx *rpc.GetTransactionResult
-			for retryCount := 0; retryCount < maxRetries; retryCount++ {
-				tx, err = rpcClient.GetTransaction(ctx, logResult.Value.Signature, &rpc.GetTransactionOpts{
-					Encoding:   solana.EncodingBase64,
-					Commitment: rpc.CommitmentConfirmed,
-				})
-
-				if err == nil {
-					break
-				}
-
-				if retryCount < maxRetries-1 {
-					fmt.Printf("Failed to get transaction details (attempt %d/%d): %v\nRetrying...\n",
-						retryCount+1, maxRetries, err)
-					time.Sleep(time.Duration(retryCount+1) * time.Second)
-				}
-			}
-
+			tx, err := fetchTransaction(ctx, rpcClient, logResult.Value.Signature, commitment)
 			if err != nil {
-				fmt.Printf("Error getting transaction details after %d attempts: %v\n", maxRetries, err)
-				fmt.Printf("You can view this transaction on Solana Explorer: https://explorer.solana.com/tx/%s\n",
+				logf("Error getting transaction details: %v\n", err)
+				logf("You can view this transaction on Solana Explorer: https://explorer.solana.com/tx/%s\n",
 					txSignature)
 				continue
 			}
 
 			// Process the transaction
-			fmt.Println("Analyzing transaction...")
-			analyzeTransactionWithRPC(tx, txSignature, rpcEndpoint)
+			logln("Analyzing transaction...")
+			summary := analyzeTransactionWithRPC(tx, txSignature, rpcEndpoint)
+			if err := recordTransaction(st, accountAddress, txSignature, tx, summary); err != nil {
+				logf("Failed to record transaction: %v\n", err)
+			}
+			// Only live notifications are copied, decoding never trades
+			copyLeaderBuy(rpcEndpoint, txSignature, tx, summary)
 
 			// Give a visual separator for the next transaction
-			fmt.Println("\nWaiting for next transaction...")
+			logln("\nWaiting for next transaction...")
 		}
 	}
 }
 
-// decodeTxCmd decodes transactions for a given PumpFun AMM pool
-func decodeTxCmd() {
-	// Get RPC endpoint from environment or use default
-	rpcEndpoint := os.Getenv("RPC_ENDPOINT")
-	if rpcEndpoint == "" {
-		rpcEndpoint = fallbackRPCEndpoints[0]
-	}
-
-	// Get the account address to monitor from args or use default
-	accountAddress := "Csd779Qwsrf1FH1eeLQnNDcxknyCvJteJtVW2MLFr4y3" // Default pool address
-	if len(os.Args) > 1 {
-		accountAddress = os.Args[1]
-	}
-
-	fmt.Printf("Analyzing transactions for AccountAddress: %s\n", accountAddress)
-
-	// Create context with cancellation and timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
-
-	// Handle Ctrl+C to gracefully exit
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-	go func() {
-		<-c
-		fmt.Println("\nShutting down...")
-		cancel()
-		os.Exit(0)
-	}()
-
-	// Fetch and decode historical transactions
-	limit := 10 // Default limit
-	err := getHistoricalTransactions(ctx, rpcEndpoint, accountAddress, limit)
-	if err != nil {
-		fmt.Printf("Error with primary RPC endpoint: %v\n", err)
-		fmt.Println("Trying fallback RPC endpoints...")
-
-		// Try fallback endpoints
-		var success bool
-		for i, endpoint := range fallbackRPCEndpoints {
-			if endpoint == rpcEndpoint {
-				continue // Skip the one we already tried
-			}
-
-			fmt.Printf("Trying fallback endpoint #%d: %s\n", i+1, endpoint)
-			ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-			err := getHistoricalTransactions(ctx, endpoint, accountAddress, limit)
-			cancel()
-
-			if err == nil {
-				success = true
-				break
-			}
-			fmt.Printf("Fallback endpoint failed: %v\n", err)
+// connectWS connects to the WebSocket endpoint, retrying up to maxRetries times
+func connectWS(ctx context.Context, wsEndpoint string) (*ws.Client, error) {
+	var wsClient *ws.Client
+	var err error
+	for retryCount := 0; retryCount < maxRetries; retryCount++ {
+		logf("Connecting to WebSocket endpoint: %s (attempt %d/%d)\n", wsEndpoint, retryCount+1, maxRetries)
+		wsClient, err = ws.Connect(ctx, wsEndpoint)
+		if err == nil {
+			return wsClient, nil
 		}
-
-		if !success {
-			log.Fatalf("All RPC endpoints failed. Please try again later or use a custom RPC endpoint")
+		logf("Failed to connect to WebSocket: %v\n", err)
+		if retryCount < maxRetries-1 {
+			time.Sleep(time.Duration(retryCount+1) * time.Second)
 		}
 	}
+	return nil, fmt.Errorf("failed to connect to WebSocket after %d attempts: %w", maxRetries, err)
 }
 
-// getHistoricalTransactions fetches and processes historical transactions for an account
-func getHistoricalTransactions(ctx context.Context, rpcEndpoint, accountAddress string, limit int) error {
-	// Convert account address to PublicKey
-	accountPubkey, err := solana.PublicKeyFromBase58(accountAddress)
+// runDecode decodes the recent transactions of a PumpFun AMM pool or wallet
+func runDecode(ctx context.Context, o *options, args []string) error {
+	accountAddress, err := accountArg(args)
 	if err != nil {
-		return fmt.Errorf("invalid account address: %w", err)
+		return err
+	}
+	query, err := newHistoryQuery(accountAddress, o)
+	if err != nil {
+		return err
 	}
 
-	// Create RPC client
-	client := rpc.New(rpcEndpoint)
+	logf("Analyzing transactions for AccountAddress: %s\n", accountAddress)
 
-	// Get signatures for the account with retry logic
-	var signatures []*rpc.TransactionSignature
-	for retryCount := 0; retryCount < maxRetries; retryCount++ {
-		signatures, err = client.GetSignaturesForAddress(ctx, accountPubkey)
-		if err == nil {
-			break
-		}
-
-		//output  the length of signatures
-		fmt.Println("len of signatures:", len(signatures))
+	// Fetch and decode historical transactions
+	// Walking a long history takes as long as it takes, Ctrl+C stops it
+	return withFallback(ctx, o.RPCEndpoint, 0, func(ctx context.Context, endpoint string) error {
+		return getHistoricalTransactions(ctx, endpoint, query)
+	})
+}
 
-		if retryCount < maxRetries-1 {
-			fmt.Printf("Failed to get signatures (attempt %d/%d): %v\nRetrying...\n",
-				retryCount+1, maxRetries, err)
-			time.Sleep(time.Duration(retryCount+1) * time.Second) // Exponential backoff
-		}
+// runDecodeTx decodes a specific transaction by signature
+func runDecodeTx(ctx context.Context, o *options, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("decode-tx takes exactly one transaction signature, got %d arguments", len(args))
 	}
-
+	txSignature := args[0]
+	signature, err := solana.SignatureFromBase58(txSignature)
 	if err != nil {
-		return fmt.Errorf("failed to get signatures after %d attempts: %w", maxRetries, err)
+		return fmt.Errorf("invalid signature: %w", err)
 	}
-
-	// Limit the number of signatures to process
-	if len(signatures) > limit {
-		signatures = signatures[:limit]
+	commitment, err := o.commitment()
+	if err != nil {
+		return err
 	}
 
-	fmt.Printf("Found %d historical transactions (processing %d)\n", len(signatures), limit)
-
-	// Process each transaction
-	for i, sig := range signatures {
-		fmt.Printf("\nTransaction %d/%d: %s\n", i+1, len(signatures), sig.Signature.String())
-
-		// Get transaction details with retry logic
-		var tx *rpc.GetTransactionResult
-		for retryCount := 0; retryCount < maxRetries; retryCount++ {
-			tx, err = client.GetTransaction(ctx, sig.Signature, &rpc.GetTransactionOpts{
-				Encoding:   solana.EncodingBase64, // Use Base64 encoding for binary data
-				Commitment: rpc.CommitmentConfirmed,
-			})
-
-			if err == nil {
-				break
-			}
-
-			if retryCount < maxRetries-1 {
-				fmt.Printf("Failed to get transaction (attempt %d/%d): %v\nRetrying...\n",
-					retryCount+1, maxRetries, err)
-				time.Sleep(time.Duration(retryCount+1) * time.Second) // Exponential backoff
-			}
-		}
+	logln("tx signature is:", txSignature)
 
-		if err != nil {
-			fmt.Printf("Error getting transaction after %d attempts: %v\n", maxRetries, err)
-			continue
-		}
-
-		// Process the transaction
-		analyzeTransactionWithRPC(tx, sig.Signature.String(), rpcEndpoint)
-	}
-
-	return nil
+	return withFallback(ctx, o.RPCEndpoint, 30*time.Second, func(ctx context.Context, endpoint string) error {
+		return decodeSpecificTransaction(ctx, endpoint, signature, commitment)
+	})
 }
 
 // analyzeTransaction analyzes a transaction to identify PumpFun AMM operations
@@ -512,26 +355,30 @@ func analyzeTransaction(tx *rpc.GetTransactionResult, signature string) {
 	analyzeTransactionWithRPC(tx, signature, rpcEndpoint)
 }
 
-// analyzeTransactionWithRPC analyzes a transaction with a specific RPC endpoint
-func analyzeTransactionWithRPC(tx *rpc.GetTransactionResult, signature string, rpcEndpoint string) {
+// analyzeTransactionWithRPC analyzes a transaction with a specific RPC endpoint and emits its record.
+// It only reads, copying is up to the caller. It returns the transaction summary, or nil if the
+// transaction has no metadata.
+func analyzeTransactionWithRPC(tx *rpc.GetTransactionResult, signature string, rpcEndpoint string) *TransactionSummary {
 	if tx == nil {
-		fmt.Println("Transaction data is nil")
-		return
+		logln("Transaction data is nil")
+		return nil
 	}
 
-	fmt.Printf("Transaction signature: %s\n", signature)
+	var result *TransactionSummary
+
+	logf("Transaction signature: %s\n", signature)
 
 	// Display transaction metadata if available
 	if tx.Meta != nil {
 		// Check for errors
 		if tx.Meta.Err != nil {
-			fmt.Printf("Transaction failed: %v\n", tx.Meta.Err)
+			logf("Transaction failed: %v\n", tx.Meta.Err)
 		} else {
-			fmt.Println("Transaction successful")
+			logln("Transaction successful")
 		}
 
 		// Display fee information
-		fmt.Printf("Transaction fee: %d lamports\n", tx.Meta.Fee)
+		logf("Transaction fee: %d lamports\n", tx.Meta.Fee)
 
 		// Initialize detection variables
 		isPumpSwap := false
@@ -539,291 +386,211 @@ func analyzeTransactionWithRPC(tx *rpc.GetTransactionResult, signature string, r
 		isBuy := false
 		isSell := false
 
-		// Track important transaction data for optimized output
-		type TransactionSummary struct {
-			Operation    string
-			Direction    string
-			BaseMint     string
-			AmountIn     uint64
-			AmountOut    uint64
-			BaseMintName string
-			TokenInfo    *TokenInfo
-		}
-
 		summary := TransactionSummary{
 			Operation: "Unknown",
 			Direction: "Unknown",
 			BaseMint:  "Unknown",
 		}
 
+		// Instruction parameters are only limits, the events hold what actually executed
+		trades, err := decoder.DecodeTrades(tx)
+		if err != nil {
+			logf("  Error decoding PumpSwap events: %v\n", err)
+		}
+
 		//outptut tx.Transaciton is nil or not
-		fmt.Printf("  Transaction data: %v\n", tx.Transaction == nil)
-		fmt.Println("isPumpSwap: ", isPumpSwap)
-
-		if tx.Transaction != nil && !isPumpSwap {
-			fmt.Println("\nDecoding transaction data for detailed analysis:")
-
-			// 1. Get the transaction data
-			data := tx.Transaction.GetBinary()
-			if data != nil {
-				// 2. Create a transaction object to decode into
-				var decodedTx solana.Transaction
-
-				// 3. Decode the transaction data
-				err := decodedTx.UnmarshalWithDecoder(bin.NewBinDecoder(data))
-				if err != nil {
-					fmt.Printf("  Error decoding transaction: %v\n", err)
-				} else {
-					fmt.Printf("  Successfully decoded transaction with %d instructions\n",
-						len(decodedTx.Message.Instructions))
-
-					// 4. Analyze each instruction in the transaction
-					pumpSwapProgID := solana.MustPublicKeyFromBase58(pumpSwapProgramID)
-
-					for i, inst := range decodedTx.Message.Instructions {
-						// Get the program ID for this instruction
-						programIdIndex := inst.ProgramIDIndex
-						if int(programIdIndex) < len(decodedTx.Message.AccountKeys) {
-							programID := decodedTx.Message.AccountKeys[programIdIndex]
-
-							// Check if this instruction is for the PumpSwap program
-							if programID.Equals(pumpSwapProgID) {
-								isPumpSwap = true
-								fmt.Printf("  Instruction %d uses PumpSwap program\n", i)
-
-								// The instruction data can be analyzed to determine operation type
-								var instDataBytes []byte
-								var currentDiscriminator []byte // Store the discriminator for later use
-
-								// Check if inst.Data is already a byte array or needs conversion from base58
-								if len(inst.Data) > 0 {
-									// If inst.Data looks like a base58 string (Solana sometimes returns it this way)
-									// Try to convert it from base58
-									dataStr := string(inst.Data)
-									var err error
-									instDataBytes, err = base58.Decode(dataStr)
-									if err != nil {
-										// If not a valid base58 string, assume it's already in byte format
-										fmt.Printf("  Data is not base58, using raw bytes\n")
-										instDataBytes = inst.Data
-									} else {
-										fmt.Printf("  Converted data from base58 to bytes\n")
-									}
-								}
-
-								//lenght of instDataByts
-								fmt.Printf("  Length of instDataBytes: %d\n", len(instDataBytes))
-
-								if len(instDataBytes) >= 8 {
-									// In Anchor programs, the first 8 bytes are the instruction discriminator
-									// This is a SHA256 hash of the instruction name
-									currentDiscriminator = instDataBytes[:8]
-									fmt.Printf("  Instruction discriminator: %v\n", currentDiscriminator)
-
-									// Check if it matches known discriminators
-									if bytes.Equal(currentDiscriminator, BuyDiscriminator) {
-										isSwapInstruction = true
-										isBuy = true
-										summary.Operation = "Swap"
-										summary.Direction = "Buy (SOL → Token)"
-
-										// If there's enough data, try to decode the parameters
-										// In Anchor format, parameters follow the 8-byte discriminator
-										if len(instDataBytes) >= 24 { // 8 + 8 + 8 (discriminator + two uint64 params typically)
-											// Extract parameters: In Buy instruction we typically have BaseAmountOut and MaxQuoteAmountIn
-											baseAmountOut := binary.LittleEndian.Uint64(instDataBytes[8:16])
-											maxQuoteAmountIn := binary.LittleEndian.Uint64(instDataBytes[16:24])
-
-											summary.AmountOut = baseAmountOut
-											summary.AmountIn = maxQuoteAmountIn
-
-											fmt.Printf("  Buy parameters:\n")
-											fmt.Printf("    Base Amount Out: %d (tokens received)\n", baseAmountOut)
-											fmt.Printf("    Max Quote Amount In: %d (max SOL to spend)\n", maxQuoteAmountIn)
-										}
-
-										privateKeyStr := os.Getenv("PRIVATE_KEY")
-										WrappedSOL := "So11111111111111111111111111111111111111112" // Wrapped SOL address
-										// Valid protocol fee recipients - must use one of these
-										var validProtocolFeeRecipients = []string{
-											"62qc2CNXwrYqQScmEdiZFFAnJR262PxWEuNQtxfafNgV",
-											"7VtfL8fvgNfhz17qKRMjzQEXgbdpnHHHQRh54R9jP2RJ",
-											"7hTckgnGnLQR6sdH7YkqFTAA7VwTfYFaZ6EhEsU3saCX",
-											"9rPYyANsfQZw3DnDmKE3YCQF5E8oD89UXoHn9JFEhJUz",
-											"AVmoTthdrX6tKt4nDjco2D775W2YK3sDhxPcMmzUAmTY",
-											"FWsW1xNtWscwNmKv6wVsU1iTzRN6wmmk3MjxRP5tT7hz",
-											"G5UZAVbAf46s7cKWoyKu8kYTip9DGTpbLZ2qa9Aq69dP",
-										}
-										poolInfo := swapper.PumpSwapPoolInfo{
-											PoolAddress:                      "H9d3XHfvMGfoohydEpqh4w3mopnvjCRzE9VqaiHKdqs7",
-											BaseMint:                         "4TBi66vi32S7J8X1A6eWfaLHYmUXu7CStcEmsJQdpump", // PUMP token
-											QuoteMint:                        WrappedSOL,                                     // Wrapped SOL
-											PoolBaseTokenAccount:             "4vDmqnKLN2jdPGR2DMf5L6C93AG4XbHdfRAXJuironK8", // Example
-											PoolQuoteTokenAccount:            "5mDDjsgR9HQGFjHGy1cZ7fNYMzqkZ9hBeAJbjkcTZgCt", // Example
-											ProtocolFeeRecipient:             validProtocolFeeRecipients[1],                  // Using the first valid recipient
-											ProtocolFeeRecipientTokenAccount: "7NXr6RhzBFo4Ki9pUEVyD3fULvTw7PzGiwzxNk3gboYh", // Example
-										}
-										amountIn := strconv.FormatUint(summary.AmountIn, 10)
-										slippage := uint64(100)
-										signature, err := swapper.ExecutePumpSwap(
-											context.Background(),
-											rpcEndpoint,
-											privateKeyStr,
-											poolInfo,
-											amountIn,
-											slippage,
-											isBuy,
-										)
-										if err != nil {
-											fmt.Println("err execution pump swap:", err)
-										}
-
-										fmt.Println("the signature is:", signature)
-
-										//TODO: exit the program
-										os.Exit(1)
-
-									} else if bytes.Equal(currentDiscriminator, SellDiscriminator) {
-										isSwapInstruction = true
-										isSell = true
-										summary.Operation = "Swap"
-										summary.Direction = "Sell (Token → SOL)"
-
-										// Similar parameter decoding for Sell
-										if len(instDataBytes) >= 24 {
-											// Extract parameters: In Sell instruction we typically have BaseAmountIn and MinQuoteAmountOut
-											baseAmountIn := binary.LittleEndian.Uint64(instDataBytes[8:16])
-											minQuoteAmountOut := binary.LittleEndian.Uint64(instDataBytes[16:24])
-
-											summary.AmountIn = baseAmountIn
-											summary.AmountOut = minQuoteAmountOut
-
-											fmt.Printf("  Sell parameters:\n")
-											fmt.Printf("    Base Amount In: %d (tokens to sell)\n", baseAmountIn)
-											fmt.Printf("    Min Quote Amount Out: %d (min SOL to receive)\n", minQuoteAmountOut)
-										}
-
-									} else if bytes.Equal(currentDiscriminator, CreatePoolDiscriminator) {
-										summary.Operation = "CreatePool"
-										fmt.Printf("  Detected CreatePool instruction (matched discriminator)\n")
-										// CreatePool parameters would follow a similar pattern if needed
-									} else {
-										fmt.Printf("  Unknown instruction type with discriminator: %v\n", currentDiscriminator)
-										// For debugging, print more of the data
-										maxPrint := len(instDataBytes)
-										if maxPrint > 32 {
-											maxPrint = 32
-										}
-										fmt.Printf("  Instruction data (first %d bytes): %v\n", maxPrint, instDataBytes[:maxPrint])
-									}
-								} else {
-									fmt.Printf("  Instruction data too short to contain discriminator (len: %d)\n", len(instDataBytes))
-								}
-
-								// Print the accounts involved in this instruction
-								fmt.Printf("  Accounts involved in this instruction:\n")
-
-								// Define account role names based on the IDL
-								var accountRoles = []string{
-									"Pool",
-									"User",
-									"Global Config",
-									"Base Mint (Token)",
-									"Quote Mint (SOL)",
-									"User Base Token Account",
-									"User Quote Token Account",
-									"Pool Base Token Account",
-									"Pool Quote Token Account",
-									"Protocol Fee Recipient",
-									"Protocol Fee Recipient Token Account",
-									"Base Token Program",
-									"Quote Token Program",
-									"System Program",
-									"Associated Token Program",
-									"Event Authority",
-									"Program",
-								}
-
-								// Check if it's a Buy or Sell instruction to provide better context
-								instructionType := "Unknown"
-								if bytes.Equal(currentDiscriminator, BuyDiscriminator) {
-									instructionType = "Buy"
-								} else if bytes.Equal(currentDiscriminator, SellDiscriminator) {
-									instructionType = "Sell"
-								} else if bytes.Equal(currentDiscriminator, CreatePoolDiscriminator) {
-									instructionType = "CreatePool"
-								}
-
-								fmt.Printf("  Instruction type: %s\n", instructionType)
-
-								// Create a context for token info retrieval
-								ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-								defer cancel()
-
-								// Display accounts with their roles
-								for j, accIdx := range inst.Accounts {
-									if int(accIdx) < len(decodedTx.Message.AccountKeys) {
-										account := decodedTx.Message.AccountKeys[accIdx]
-
-										// Add role name if available (within the known roles array bounds)
-										if j < len(accountRoles) {
-											fmt.Printf("    Account %d [%s]: %s\n", j, accountRoles[j], account.String())
-
-											// Capture the Base Mint for the summary
-											if accountRoles[j] == "Base Mint (Token)" {
-												tokenAddress := account.String()
-												summary.BaseMint = tokenAddress
-
-												// Get token info if we haven't already
-												if summary.TokenInfo == nil {
-													// Try to get token info from RPC endpoint
-													tokenInfo, err := getTokenInfo(ctx, rpcEndpoint, tokenAddress)
-													if err != nil {
-														fmt.Printf("  Error getting token info: %v\n", err)
-													} else if tokenInfo != nil {
-														summary.TokenInfo = tokenInfo
-														summary.BaseMintName = tokenInfo.Name
-														if summary.BaseMintName == "" {
-															summary.BaseMintName = tokenInfo.Symbol
-														}
-													}
-												}
-											}
-										} else {
-											fmt.Printf("    Account %d: %s\n", j, account.String())
-										}
-									}
-								}
-							}
-						}
+		logf("  Transaction data: %v\n", tx.Transaction == nil)
+		logln("isPumpSwap: ", isPumpSwap)
+
+		if tx.Transaction != nil {
+			logln("\nDecoding transaction data for detailed analysis:")
+
+			instructions, err := decoder.DecodeInstructions(tx)
+			if err != nil {
+				logf("  Error decoding transaction: %v\n", err)
+			}
+
+			// Create a context for token info retrieval
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			for _, ix := range instructions {
+				// Instructions invoked through CPI are handled with the routed swaps below
+				if ix.Inner >= 0 {
+					continue
+				}
+				i := ix.Instruction
+				isPumpSwap = true
+				logf("  Instruction %d uses %s program\n", i, venueName(ix.Venue))
+				logf("  Instruction type: %s\n", ix.Name)
+
+				if mint := ix.BaseMint(); !mint.IsZero() {
+					summary.BaseMint = mint.String()
+					if summary.TokenInfo == nil {
+						fillTokenInfo(ctx, &summary, rpcEndpoint)
 					}
 				}
+
+				switch impl := ix.Impl.(type) {
+				case *amm.Buy:
+					isSwapInstruction = true
+					isBuy = true
+					summary.Operation = "Swap"
+					summary.Venue = ix.Venue
+					summary.Direction = "Buy (SOL → Token)"
+					summary.AmountOut = *impl.BaseAmountOut
+					summary.AmountIn = *impl.MaxQuoteAmountIn
+
+					logf("  Buy parameters:\n")
+					logf("    Base Amount Out: %d (tokens received)\n", *impl.BaseAmountOut)
+					logf("    Max Quote Amount In: %d (max SOL to spend)\n", *impl.MaxQuoteAmountIn)
+					if trade := tradeForInstruction(trades, i); trade != nil {
+						applyTrade(&summary, trade)
+					}
+
+				case *amm.Sell:
+					isSwapInstruction = true
+					isSell = true
+					summary.Operation = "Swap"
+					summary.Venue = ix.Venue
+					summary.Direction = "Sell (Token → SOL)"
+					summary.AmountIn = *impl.BaseAmountIn
+					summary.AmountOut = *impl.MinQuoteAmountOut
+
+					logf("  Sell parameters:\n")
+					logf("    Base Amount In: %d (tokens to sell)\n", *impl.BaseAmountIn)
+					logf("    Min Quote Amount Out: %d (min SOL to receive)\n", *impl.MinQuoteAmountOut)
+					if trade := tradeForInstruction(trades, i); trade != nil {
+						applyTrade(&summary, trade)
+					}
+
+				case *pump.Buy:
+					isSwapInstruction = true
+					isBuy = true
+					summary.Operation = "Swap"
+					summary.Venue = ix.Venue
+					summary.Direction = "Buy (SOL → Token)"
+					summary.AmountOut = *impl.Amount
+					summary.AmountIn = *impl.MaxSolCost
+
+					logf("  Bonding curve buy parameters:\n")
+					logf("    Amount: %d (tokens received)\n", *impl.Amount)
+					logf("    Max SOL Cost: %d (max SOL to spend)\n", *impl.MaxSolCost)
+					if trade := tradeForInstruction(trades, i); trade != nil {
+						applyTrade(&summary, trade)
+					}
+
+				case *pump.Sell:
+					isSwapInstruction = true
+					isSell = true
+					summary.Operation = "Swap"
+					summary.Venue = ix.Venue
+					summary.Direction = "Sell (Token → SOL)"
+					summary.AmountIn = *impl.Amount
+					summary.AmountOut = *impl.MinSolOutput
+
+					logf("  Bonding curve sell parameters:\n")
+					logf("    Amount: %d (tokens to sell)\n", *impl.Amount)
+					logf("    Min SOL Output: %d (min SOL to receive)\n", *impl.MinSolOutput)
+					if trade := tradeForInstruction(trades, i); trade != nil {
+						applyTrade(&summary, trade)
+					}
+
+				case *pump.Create:
+					summary.Operation = "Create"
+					summary.Venue = ix.Venue
+					logf("  Create parameters:\n")
+					logf("    Name: %s\n", *impl.Name)
+					logf("    Symbol: %s\n", *impl.Symbol)
+					logf("    URI: %s\n", *impl.Uri)
+
+				case *amm.CreatePool:
+					summary.Operation = "CreatePool"
+					logf("  CreatePool parameters:\n")
+					logf("    Index: %d\n", *impl.Index)
+					logf("    Base Amount In: %d\n", *impl.BaseAmountIn)
+					logf("    Quote Amount In: %d\n", *impl.QuoteAmountIn)
+
+				default:
+					if summary.Operation == "Unknown" {
+						summary.Operation = ix.Name
+					}
+				}
+
+				// Print the accounts involved in this instruction
+				logf("  Accounts involved in this instruction:\n")
+				for j, account := range ix.Accounts {
+					logf("    Account %d [%s]: %s\n", j, account.Role, account.PublicKey)
+				}
+			}
+		} else {
+			logln("  No binary transaction data available")
+		}
+
+		// Swaps routed through aggregators and bots only show up among the inner instructions
+		swaps, err := decoder.DecodeSwaps(tx)
+		if err != nil {
+			logf("  Error decoding inner PumpSwap instructions: %v\n", err)
+		}
+		for _, swap := range swaps {
+			if !swap.Routed() {
+				continue
+			}
+			isPumpSwap, isSwapInstruction = true, true
+			logf("  Instruction %d calls %s %s through program %s (inner instruction %d)\n",
+				swap.Instruction, venueName(swap.Venue), swap.Side, swap.Program, swap.Inner)
+
+			// The summary describes the first swap found
+			if summary.Operation != "Unknown" {
+				continue
+			}
+			summary.Operation = "Swap"
+			summary.Venue = swap.Venue
+			summary.Via = swap.Program.String()
+			summary.BaseMint = swap.BaseMint.String()
+			if swap.Side == decoder.SideBuy {
+				isBuy = true
+				summary.Direction = "Buy (SOL → Token)"
+				summary.AmountIn, summary.AmountOut = swap.QuoteLimit, swap.BaseAmount
 			} else {
-				fmt.Println("  No binary transaction data available")
+				isSell = true
+				summary.Direction = "Sell (Token → SOL)"
+				summary.AmountIn, summary.AmountOut = swap.BaseAmount, swap.QuoteLimit
+			}
+			if swap.Trade != nil {
+				applyTrade(&summary, swap.Trade)
 			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			fillTokenInfo(ctx, &summary, rpcEndpoint)
+			cancel()
 		}
 
 		// Summarize what we found from both approaches
 		if isPumpSwap {
-			fmt.Println("\nSummary: This is a PumpSwap transaction")
+			venue := decoder.VenuePumpSwap
+			if summary.Venue != "" {
+				venue = summary.Venue
+			}
+			logf("\nSummary: This is a %s transaction\n", venueName(venue))
 			if isSwapInstruction {
-				fmt.Println("Operation: Swap")
+				logln("Operation: Swap")
 				if isBuy {
-					fmt.Println("Direction: Buy (SOL → Token)")
+					logln("Direction: Buy (SOL → Token)")
 				} else if isSell {
-					fmt.Println("Direction: Sell (Token → SOL)")
+					logln("Direction: Sell (Token → SOL)")
 				} else {
-					fmt.Println("Direction: Unknown")
+					logln("Direction: Unknown")
 				}
 			} else {
-				fmt.Println("Operation: Other PumpSwap operation (not a swap)")
+				logln("Operation: Other PumpSwap operation (not a swap)")
 			}
 		} else {
-			fmt.Println("Summary: Not a PumpSwap transaction or could not detect PumpSwap operations")
+			logln("Summary: Not a PumpSwap transaction or could not detect PumpSwap operations")
 		}
 
 		// Display optimized transaction summary in a clear, formatted box
-		fmt.Println("\n┌────────────────── TRANSACTION SUMMARY ──────────────────┐")
+		logln("\n┌────────────────── TRANSACTION SUMMARY ──────────────────┐")
 
 		// Display token details if available
 		tokenName := "Unknown Token"
@@ -835,21 +602,27 @@ func analyzeTransactionWithRPC(tx *rpc.GetTransactionResult, signature string, r
 			}
 		}
 
-		fmt.Printf("│ Operation:  %-47s │\n", summary.Operation)
-		fmt.Printf("│ Direction:  %-47s │\n", summary.Direction)
-		fmt.Printf("│ Base Mint:  %-47s │\n", summary.BaseMint)
-		fmt.Printf("│ Token Name: %-47s │\n", tokenName)
+		logf("│ Operation:  %-47s │\n", summary.Operation)
+		logf("│ Direction:  %-47s │\n", summary.Direction)
+		if summary.Venue != "" {
+			logf("│ Venue:      %-47s │\n", venueName(summary.Venue))
+		}
+		logf("│ Base Mint:  %-47s │\n", summary.BaseMint)
+		if summary.Via != "" {
+			logf("│ Routed Via: %-47s │\n", summary.Via)
+		}
+		logf("│ Token Name: %-47s │\n", tokenName)
 
 		// Show token symbol if available
 		if summary.TokenInfo != nil && summary.TokenInfo.Symbol != "" {
-			fmt.Printf("│ Symbol:     %-47s │\n", summary.TokenInfo.Symbol)
+			logf("│ Symbol:     %-47s │\n", summary.TokenInfo.Symbol)
 		}
 
 		// Format amounts differently based on whether it's Buy or Sell
 		if isBuy {
 			// For Buy, SOL amount in (lamports) and token amount out
 			solAmount := float64(summary.AmountIn) / 1_000_000_000 // Convert lamports to SOL
-			fmt.Printf("│ Amount In:  %-12.9f SOL %-30s │\n", solAmount, "")
+			logf("│ Amount In:  %-12.9f SOL %-30s │\n", solAmount, "")
 
 			// Use token decimals if available, otherwise default to 6
 			tokenDecimals := 6
@@ -861,7 +634,7 @@ func analyzeTransactionWithRPC(tx *rpc.GetTransactionResult, signature string, r
 			if summary.TokenInfo != nil && summary.TokenInfo.Symbol != "" {
 				symbolStr = strings.TrimSpace(summary.TokenInfo.Symbol)
 			}
-			fmt.Printf("│ Amount Out: %-12.*f %s %-26s │\n", tokenDecimals, tokenAmount, symbolStr, "")
+			logf("│ Amount Out: %-12.*f %s %-26s │\n", tokenDecimals, tokenAmount, symbolStr, "")
 		} else if isSell {
 			// For Sell, token amount in and SOL amount out (lamports)
 			tokenDecimals := 6
@@ -873,14 +646,22 @@ func analyzeTransactionWithRPC(tx *rpc.GetTransactionResult, signature string, r
 			if summary.TokenInfo != nil && summary.TokenInfo.Symbol != "" {
 				symbolStr = strings.TrimSpace(summary.TokenInfo.Symbol)
 			}
-			fmt.Printf("│ Amount In:  %-12.*f %s %-26s │\n", tokenDecimals, tokenAmount, symbolStr, "")
+			logf("│ Amount In:  %-12.*f %s %-26s │\n", tokenDecimals, tokenAmount, symbolStr, "")
 
 			solAmount := float64(summary.AmountOut) / 1_000_000_000
-			fmt.Printf("│ Amount Out: %-12.9f SOL %-30s │\n", solAmount, "")
+			logf("│ Amount Out: %-12.9f SOL %-30s │\n", solAmount, "")
 		} else {
 			// Unknown or other operation type
-			fmt.Printf("│ Amount In:  %-47d │\n", summary.AmountIn)
-			fmt.Printf("│ Amount Out: %-47d │\n", summary.AmountOut)
+			logf("│ Amount In:  %-47d │\n", summary.AmountIn)
+			logf("│ Amount Out: %-47d │\n", summary.AmountOut)
+		}
+
+		if summary.Trade != nil && summary.Trade.Venue == decoder.VenuePumpSwap {
+			logf("│ LP Fee:     %-12.9f SOL %-30s │\n", float64(summary.Trade.LpFee)/1_000_000_000, "")
+			logf("│ Proto Fee:  %-12.9f SOL %-30s │\n", float64(summary.Trade.ProtocolFee)/1_000_000_000, "")
+			logf("│ Pool After: %-47s │\n", fmt.Sprintf("%d base / %d quote", summary.Trade.PoolBaseReserve, summary.Trade.PoolQuoteReserve))
+		} else if summary.Trade != nil {
+			logf("│ Curve:      %-47s │\n", fmt.Sprintf("%d tokens / %d lamports (virtual)", summary.Trade.PoolBaseReserve, summary.Trade.PoolQuoteReserve))
 		}
 
 		// Add separator for token information section if we have any social info
@@ -892,7 +673,7 @@ func analyzeTransactionWithRPC(tx *rpc.GetTransactionResult, signature string, r
 				summary.TokenInfo.Image != "")
 
 		if hasTokenInfo {
-			fmt.Println("├──────────────── TOKEN SOCIAL INFORMATION ───────────────┤")
+			logln("├──────────────── TOKEN SOCIAL INFORMATION ───────────────┤")
 
 			// Add token description if available
 			if summary.TokenInfo.Description != "" {
@@ -900,19 +681,19 @@ func analyzeTransactionWithRPC(tx *rpc.GetTransactionResult, signature string, r
 				// Handle multi-line description by truncating and adding ellipsis
 				if len(desc) > 47 {
 					// Print first line with ellipsis
-					fmt.Printf("│ Description: %-46s │\n", desc[:44]+"...")
+					logf("│ Description: %-46s │\n", desc[:44]+"...")
 
 					// Print additional lines if really long
 					if len(desc) > 90 {
-						fmt.Printf("│             %-47s │\n", desc[44:90]+"...")
+						logf("│             %-47s │\n", desc[44:90]+"...")
 					} else if len(desc) > 44 {
-						fmt.Printf("│             %-47s │\n", desc[44:])
+						logf("│             %-47s │\n", desc[44:])
 					}
 				} else {
-					fmt.Printf("│ Description: %-46s │\n", desc)
+					logf("│ Description: %-46s │\n", desc)
 				}
 			} else {
-				fmt.Printf("│ Description: %-46s │\n", "Not available")
+				logf("│ Description: %-46s │\n", "Not available")
 			}
 
 			// Add image if available
@@ -922,7 +703,7 @@ func analyzeTransactionWithRPC(tx *rpc.GetTransactionResult, signature string, r
 				if len(imgUrl) > 47 {
 					imgUrl = imgUrl[:44] + "..."
 				}
-				fmt.Printf("│ Image:       %-46s │\n", imgUrl)
+				logf("│ Image:       %-46s │\n", imgUrl)
 			}
 
 			// Add website if available
@@ -932,9 +713,9 @@ func analyzeTransactionWithRPC(tx *rpc.GetTransactionResult, signature string, r
 				if len(website) > 47 {
 					website = website[:44] + "..."
 				}
-				fmt.Printf("│ Website:     %-46s │\n", website)
+				logf("│ Website:     %-46s │\n", website)
 			} else {
-				fmt.Printf("│ Website:     %-46s │\n", "Not available")
+				logf("│ Website:     %-46s │\n", "Not available")
 			}
 
 			// Add social links with clear formatting
@@ -942,108 +723,52 @@ func analyzeTransactionWithRPC(tx *rpc.GetTransactionResult, signature string, r
 			if summary.TokenInfo.Twitter != "" {
 				twitterInfo = summary.TokenInfo.Twitter
 			}
-			fmt.Printf("│ Twitter:     %-46s │\n", twitterInfo)
+			logf("│ Twitter:     %-46s │\n", twitterInfo)
 
 			telegramInfo := "Not available"
 			if summary.TokenInfo.Telegram != "" {
 				telegramInfo = summary.TokenInfo.Telegram
 			}
-			fmt.Printf("│ Telegram:    %-46s │\n", telegramInfo)
+			logf("│ Telegram:    %-46s │\n", telegramInfo)
 		}
 
-		fmt.Println("└──────────────────────────────────────────────────────────┘")
+		logln("└──────────────────────────────────────────────────────────┘")
 
 		// If we have token info but didn't show it in the summary (maybe there was a lot),
 		// display additional details here
 		if summary.TokenInfo != nil && summary.TokenInfo.Description != "" && len(summary.TokenInfo.Description) > 90 {
-			fmt.Println("\nFull Token Description:")
-			fmt.Println(summary.TokenInfo.Description)
+			logln("\nFull Token Description:")
+			logln(summary.TokenInfo.Description)
 		}
 
 		if summary.TokenInfo != nil && summary.TokenInfo.Image != "" {
-			fmt.Println("\nToken Image URL:")
-			fmt.Println(summary.TokenInfo.Image)
+			logln("\nToken Image URL:")
+			logln(summary.TokenInfo.Image)
 		}
+
+		result = &summary
 	} else {
-		fmt.Println("No transaction metadata available")
+		logln("No transaction metadata available")
 	}
 
-	fmt.Printf("\nView transaction: https://solscan.io/tx/%s\n", signature)
-	fmt.Println("--------------------------------------------------")
+	logf("\nView transaction: https://solscan.io/tx/%s\n", signature)
+	logln("--------------------------------------------------")
+	emitTransaction(tx, signature, result)
+	return result
 }
 
 // decodeSpecificTransaction decodes a specific transaction by signature
-func decodeSpecificTransaction(ctx context.Context, rpcEndpoint, signatureStr string) error {
-	// Parse signature string to Signature type
-	signature, err := solana.SignatureFromBase58(signatureStr)
-	if err != nil {
-		return fmt.Errorf("invalid signature: %w", err)
-	}
-
-	client := rpc.New(rpcEndpoint)
-
-	// Get transaction with retry logic
-	var tx *rpc.GetTransactionResult
-	for retryCount := 0; retryCount < maxRetries; retryCount++ {
-		tx, err = client.GetTransaction(ctx, signature, &rpc.GetTransactionOpts{
-			Encoding:   solana.EncodingBase64,
-			Commitment: rpc.CommitmentConfirmed,
-		})
-
-		if err == nil {
-			break
-		}
-
-		if retryCount < maxRetries-1 {
-			fmt.Printf("Failed to get transaction (attempt %d/%d): %v\nRetrying...\n",
-				retryCount+1, maxRetries, err)
-			time.Sleep(time.Duration(retryCount+1) * time.Second) // Exponential backoff
-		}
-	}
-
+func decodeSpecificTransaction(ctx context.Context, rpcEndpoint string, signature solana.Signature, commitment rpc.CommitmentType) error {
+	tx, err := fetchTransaction(ctx, rpc.New(rpcEndpoint), signature, commitment)
 	if err != nil {
-		return fmt.Errorf("failed to get transaction after %d attempts: %w", maxRetries, err)
+		return err
 	}
 
-	fmt.Printf("Decoding transaction: %s\n", signatureStr)
-	analyzeTransactionWithRPC(tx, signatureStr, rpcEndpoint)
+	logf("Decoding transaction: %s\n", signature)
+	analyzeTransactionWithRPC(tx, signature.String(), rpcEndpoint)
 	return nil
 }
 
-// printUsage displays the program's usage information
-func printUsage() {
-	fmt.Println(`
-PumpFun AMM Transaction Decoder
-
-Usage:
-  tx_decoder [command] [options]
-
-Commands:
-  decode [pool_address]       Analyze transactions for a PumpFun AMM pool
-                              Default pool: Csd779Qwsrf1FH1eeLQnNDcxknyCvJteJtVW2MLFr4y3
-  
-  decode-tx <tx_signature>    Decode a specific transaction by signature
-
-  monitor [account_address]   Monitor transactions for an account in real-time using WebSocket
-                              Default account: Csd779Qwsrf1FH1eeLQnNDcxknyCvJteJtVW2MLFr4y3
-
-Options:
-  -h, --help                  Show this help message
-
-Environment Variables:
-  RPC_ENDPOINT                Solana RPC endpoint (default: https://api.mainnet-beta.solana.com)
-                              If the default endpoint fails, the program will try several
-                              fallback public endpoints automatically.
-  
-  WS_ENDPOINT                 Solana WebSocket endpoint (default: wss://api.mainnet-beta.solana.com)
-
-Examples:
-  tx_decoder decode Csd779Qwsrf1FH1eeLQnNDcxknyCvJteJtVW2MLFr4y3
-  tx_decoder decode-tx 5SHT9PwxFE7BNmSQwU4KjAW16LQ5aEZmUvWKqSCamXKkWQBs1DcYkEv7ujWgASRUUKqYy6VsM7iTgJkgAygCVPZB
-  tx_decoder monitor Csd779Qwsrf1FH1eeLQnNDcxknyCvJteJtVW2MLFr4y3
-`)
-}
-
 // getTokenInfo retrieves detailed token information by mint address
 func getTokenInfo(ctx context.Context, rpcEndpoint string, mintAddress string) (*TokenInfo, error) {
 	// Check cache first
@@ -1109,7 +834,7 @@ func getTokenInfo(ctx context.Context, rpcEndpoint string, mintAddress string) (
 			// Try to fetch extended metadata from URI if available
 			if metadata.Data.Uri != "" {
 				extendedInfo, err := fetchTokenUriData(metadata.Data.Uri)
-				fmt.Printf("extendedInfo: %v\n", extendedInfo)
+				logf("extendedInfo: %v\n", extendedInfo)
 				if err == nil && extendedInfo != nil {
 					// Update with extended info
 					if tokenInfo.Name == "" {
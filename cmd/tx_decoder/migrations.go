@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"solana-pumpswap-demo/internal/decoder"
+	"solana-pumpswap-demo/internal/migration"
+	"solana-pumpswap-demo/internal/orders"
+	"solana-pumpswap-demo/internal/risk"
+	"solana-pumpswap-demo/internal/router"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+)
+
+// MigrationRecord is the stable JSON schema of a bonding curve that migrated to a PumpSwap pool
+type MigrationRecord struct {
+	Mint                  string `json:"mint"`
+	BondingCurve          string `json:"bonding_curve"`
+	CompleteSignature     string `json:"complete_signature,omitempty"` // Empty when completion was read from the curve account
+	CompletedAt           *int64 `json:"completed_at"`                 // Unix seconds, null when completion was read from the curve account
+	PoolSignature         string `json:"pool_signature"`
+	PoolCreatedAt         int64  `json:"pool_created_at"`
+	Pool                  string `json:"pool"`
+	Creator               string `json:"creator"`
+	BaseMint              string `json:"base_mint"`
+	QuoteMint             string `json:"quote_mint"`
+	LpMint                string `json:"lp_mint"`
+	PoolBaseTokenAccount  string `json:"pool_base_token_account"`
+	PoolQuoteTokenAccount string `json:"pool_quote_token_account"`
+	BaseReserve           uint64 `json:"base_reserve"`
+	QuoteReserve          uint64 `json:"quote_reserve"`
+	BuySignature          string `json:"buy_signature,omitempty"` // Our buy into the pool, with --buy-sol
+}
+
+func newMigrationRecord(m migration.Migration) MigrationRecord {
+	record := MigrationRecord{
+		Mint:                  m.Mint.String(),
+		BondingCurve:          m.BondingCurve.String(),
+		PoolSignature:         m.PoolSignature.String(),
+		PoolCreatedAt:         m.PoolCreatedAt.Unix(),
+		Pool:                  m.Pool.Address.String(),
+		Creator:               m.Creator.String(),
+		BaseMint:              m.Pool.BaseMint.String(),
+		QuoteMint:             m.Pool.QuoteMint.String(),
+		LpMint:                m.LpMint.String(),
+		PoolBaseTokenAccount:  m.Pool.PoolBaseTokenAccount.String(),
+		PoolQuoteTokenAccount: m.Pool.PoolQuoteTokenAccount.String(),
+		BaseReserve:           m.Pool.BaseReserve,
+		QuoteReserve:          m.Pool.QuoteReserve,
+	}
+	if !m.CompleteSignature.IsZero() {
+		record.CompleteSignature = m.CompleteSignature.String()
+	}
+	if !m.CompletedAt.IsZero() {
+		at := m.CompletedAt.Unix()
+		record.CompletedAt = &at
+	}
+	return record
+}
+
+// runMigrations watches bonding curves complete and their liquidity move into PumpSwap pools,
+// buying --buy-sol into each new pool when it is set
+func runMigrations(ctx context.Context, o *options, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("migrations takes no arguments, got %d", len(args))
+	}
+	commitment, err := o.commitment()
+	if err != nil {
+		return err
+	}
+	window, err := time.ParseDuration(o.Window)
+	if err != nil {
+		return fmt.Errorf("invalid --window: %w", err)
+	}
+	var buyLamports uint64
+	if o.BuySol != "" {
+		sol, err := decimal.NewFromString(o.BuySol)
+		if err != nil || sol.Sign() < 0 {
+			return fmt.Errorf("invalid --buy-sol %q", o.BuySol)
+		}
+		buyLamports = uint64(sol.Shift(9).IntPart())
+	}
+
+	var buyer *router.Router
+	var wallet solana.PublicKey
+	if buyLamports > 0 {
+		privateKey, err := solana.PrivateKeyFromBase58(os.Getenv("PRIVATE_KEY"))
+		if err != nil {
+			return fmt.Errorf("--buy-sol needs PRIVATE_KEY: %w", err)
+		}
+		st, err := openStore()
+		if err != nil {
+			return fmt.Errorf("failed to open state store: %w", err)
+		}
+		defer st.Close()
+		buyer, wallet = router.New(rpc.New(o.RPCEndpoint), privateKey, copySlippageBps), privateKey.PublicKey()
+		logf("Buying %s SOL into each migrated pool with %s\n", decimal.New(int64(buyLamports), -9), wallet)
+	}
+
+	wsClient, err := connectWS(ctx, o.WSEndpoint)
+	if err != nil {
+		return err
+	}
+	defer wsClient.Close()
+
+	watcher := migration.NewWatcher(rpc.New(o.RPCEndpoint), commitment, window)
+	watcher.OnError = func(signature solana.Signature, err error) {
+		logf("Error handling %s: %v\n", signature, err)
+	}
+	logln("Watching pump.fun and PumpSwap for migrations...")
+	return watcher.Run(ctx, wsClient, func(m migration.Migration) {
+		record := newMigrationRecord(m)
+		printMigration(m)
+		if buyer != nil {
+			record.BuySignature = buyMigration(ctx, buyer, wallet, m, buyLamports)
+		}
+		emitRecord(record, "migration of "+record.Mint)
+	})
+}
+
+// buyMigration buys into the migrated pool with the accounts its CreatePoolEvent gave,
+// through the risk checks and the order manager. It returns the buy's signature, empty if none was sent.
+func buyMigration(ctx context.Context, r *router.Router, wallet solana.PublicKey, m migration.Migration, lamports uint64) string {
+	plan, err := r.PlanPool(ctx, m.Pool, decoder.SideBuy, lamports)
+	if err != nil {
+		logf("Failed to plan buy into %s: %v\n", m.Pool.Address, err)
+		return ""
+	}
+	sig, err := submitRouted(ctx, r, wallet, plan, m.PoolSignature.String(), lamports)
+	var rejection *risk.Rejection
+	switch {
+	case errors.Is(err, orders.ErrDuplicate):
+		logln("Migration already bought, skipping:", err)
+	case errors.As(err, &rejection):
+		logf("Buy rejected by %s limit: %s\n", rejection.Rule, rejection.Reason)
+	case err != nil:
+		logf("Failed to buy into %s: %v\n", m.Pool.Address, err)
+	default:
+		logln("Bought into migrated pool:", sig)
+	}
+	return sig
+}
+
+// printMigration prints a migration for the table format, which goes to stderr in the JSON formats
+func printMigration(m migration.Migration) {
+	logf("\nMigration: %s\n", m.Mint)
+	logf("  Bonding curve: %s\n", m.BondingCurve)
+	if m.CompleteSignature.IsZero() {
+		logln("  Completed: read from the curve account")
+	} else {
+		logf("  Completed: %s\n", m.CompleteSignature)
+	}
+	logf("  Pool: %s (created %s in %s)\n", m.Pool.Address, m.PoolCreatedAt.UTC().Format(time.RFC3339), m.PoolSignature)
+	logf("  Pool token accounts: %s / %s\n", m.Pool.PoolBaseTokenAccount, m.Pool.PoolQuoteTokenAccount)
+	logf("  LP mint: %s\n", m.LpMint)
+	logf("  Reserves: %d tokens / %s SOL\n", m.Pool.BaseReserve, decimal.New(int64(m.Pool.QuoteReserve), -9))
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"solana-pumpswap-demo/internal/decoder"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Output formats for decoded transactions
+const (
+	outputTable  = "table"
+	outputJSON   = "json"
+	outputNDJSON = "ndjson"
+)
+
+// outputFormat is how decoded transactions are written, set once by setOutputFormat
+var outputFormat = outputTable
+
+// recordOut receives the JSON records and logOut the human-readable progress output, which goes
+// to stderr in the JSON formats so stdout can be piped straight into jq
+var (
+	recordOut io.Writer = os.Stdout
+	logOut    io.Writer = os.Stdout
+)
+
+// logf and logln write progress output
+func logf(format string, a ...interface{}) {
+	fmt.Fprintf(logOut, format, a...)
+}
+
+func logln(a ...interface{}) {
+	fmt.Fprintln(logOut, a...)
+}
+
+// setOutputFormat selects the output format
+func setOutputFormat(format string) error {
+	switch format {
+	case outputTable:
+		logOut = os.Stdout
+	case outputJSON, outputNDJSON:
+		logOut = os.Stderr
+	default:
+		return fmt.Errorf("unknown output format %q, want %s, %s or %s", format, outputTable, outputJSON, outputNDJSON)
+	}
+	outputFormat = format
+	return nil
+}
+
+// TransactionRecord is the stable JSON schema of a decoded transaction
+type TransactionRecord struct {
+	Signature    string                `json:"signature"`
+	Slot         uint64                `json:"slot"`
+	BlockTime    *int64                `json:"block_time"` // Unix seconds, null if the node did not report it
+	Success      bool                  `json:"success"`
+	Error        interface{}           `json:"error,omitempty"`
+	Fee          uint64                `json:"fee"`
+	Instructions []InstructionRecord   `json:"instructions"`
+	Events       []EventRecord         `json:"events"`
+	TokenDeltas  []TokenDeltaRecord    `json:"token_deltas"`
+	Tokens       map[string]*TokenInfo `json:"tokens"` // Metadata of the mints we looked up, by mint address
+}
+
+// InstructionRecord is a decoded PumpSwap or pump.fun instruction
+type InstructionRecord struct {
+	Venue       string                 `json:"venue"`
+	Name        string                 `json:"name"`
+	Instruction int                    `json:"instruction"`
+	Inner       int                    `json:"inner"` // -1 when called directly
+	Program     string                 `json:"program"`
+	Args        map[string]interface{} `json:"args"`
+	Accounts    []AccountRecord        `json:"accounts"`
+}
+
+// AccountRecord is an instruction account with its IDL role
+type AccountRecord struct {
+	Role     string `json:"role"`
+	Address  string `json:"address"`
+	Writable bool   `json:"writable"`
+	Signer   bool   `json:"signer"`
+}
+
+// EventRecord is a decoded PumpSwap or pump.fun event
+type EventRecord struct {
+	Venue       string                 `json:"venue"`
+	Name        string                 `json:"name"`
+	Instruction int                    `json:"instruction"`
+	Inner       int                    `json:"inner"`
+	Data        map[string]interface{} `json:"data"`
+}
+
+// TokenDeltaRecord is the change of a token account's balance, in raw units
+type TokenDeltaRecord struct {
+	Account  string      `json:"account"`
+	Owner    string      `json:"owner,omitempty"`
+	Mint     string      `json:"mint"`
+	Decimals uint8       `json:"decimals"`
+	Pre      uint64      `json:"pre"`
+	Post     uint64      `json:"post"`
+	Change   json.Number `json:"change"` // Post minus pre, negative when tokens left the account
+}
+
+// newTransactionRecord builds the JSON record of a transaction. Decoding errors leave the affected lists empty.
+func newTransactionRecord(tx *rpc.GetTransactionResult, signature string, summary *TransactionSummary) TransactionRecord {
+	record := TransactionRecord{
+		Signature:    signature,
+		Slot:         tx.Slot,
+		Instructions: []InstructionRecord{},
+		Events:       []EventRecord{},
+		TokenDeltas:  []TokenDeltaRecord{},
+		Tokens:       map[string]*TokenInfo{},
+	}
+	if tx.BlockTime != nil {
+		blockTime := int64(*tx.BlockTime)
+		record.BlockTime = &blockTime
+	}
+	if tx.Meta != nil {
+		record.Success = tx.Meta.Err == nil
+		record.Error = tx.Meta.Err
+		record.Fee = tx.Meta.Fee
+	}
+
+	instructions, _ := decoder.DecodeInstructions(tx)
+	for _, ix := range instructions {
+		ir := InstructionRecord{
+			Venue:       ix.Venue,
+			Name:        ix.Name,
+			Instruction: ix.Instruction,
+			Inner:       ix.Inner,
+			Program:     ix.Program.String(),
+			Args:        fieldsOf(ix.Impl),
+			Accounts:    []AccountRecord{},
+		}
+		for _, a := range ix.Accounts {
+			ir.Accounts = append(ir.Accounts, AccountRecord{
+				Role:     a.Role,
+				Address:  a.PublicKey.String(),
+				Writable: a.Writable,
+				Signer:   a.Signer,
+			})
+		}
+		record.Instructions = append(record.Instructions, ir)
+	}
+
+	events, _ := decoder.DecodeEvents(tx)
+	for _, e := range events {
+		record.Events = append(record.Events, EventRecord{
+			Venue:       e.Venue,
+			Name:        e.Name,
+			Instruction: e.Instruction,
+			Inner:       e.Inner,
+			Data:        fieldsOf(e.Data),
+		})
+	}
+
+	deltas, _ := decoder.TokenDeltas(tx)
+	for _, d := range deltas {
+		dr := TokenDeltaRecord{
+			Account:  d.Account.String(),
+			Mint:     d.Mint.String(),
+			Decimals: d.Decimals,
+			Pre:      d.Pre,
+			Post:     d.Post,
+			Change:   json.Number(d.Change().String()),
+		}
+		if !d.Owner.IsZero() {
+			dr.Owner = d.Owner.String()
+		}
+		record.TokenDeltas = append(record.TokenDeltas, dr)
+
+		if info, ok := tokenCache[dr.Mint]; ok {
+			record.Tokens[dr.Mint] = info
+		}
+	}
+	if summary != nil && summary.TokenInfo != nil {
+		record.Tokens[summary.BaseMint] = summary.TokenInfo
+	}
+	return record
+}
+
+// emitTransaction writes the transaction's record in the JSON formats, the table format prints as it decodes
+func emitTransaction(tx *rpc.GetTransactionResult, signature string, summary *TransactionSummary) {
+	if outputFormat == outputTable || tx == nil {
+		return
+	}
+	emitRecord(newTransactionRecord(tx, signature, summary), "transaction "+signature)
+}
+
+// emitRecord writes a record to recordOut in the JSON formats, key names it in the error if writing fails
+func emitRecord(v interface{}, key string) {
+	if outputFormat == outputTable {
+		return
+	}
+	enc := json.NewEncoder(recordOut)
+	if outputFormat == outputJSON {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write %s: %v\n", key, err)
+	}
+}
+
+// accountMetaSliceType is embedded in every generated instruction and holds its accounts, not its arguments
+var accountMetaSliceType = reflect.TypeOf(solana.AccountMetaSlice{})
+
+// fieldsOf returns the exported fields of a generated instruction or event keyed by their snake_case
+// name, with optional arguments dereferenced. Public keys marshal as base58 strings.
+func fieldsOf(v interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return fields
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fields
+	}
+
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+		if !field.IsExported() || field.Type == accountMetaSliceType {
+			continue
+		}
+		value := rv.Field(i)
+		if value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				fields[snakeCase(field.Name)] = nil
+				continue
+			}
+			value = value.Elem()
+		}
+		fields[snakeCase(field.Name)] = value.Interface()
+	}
+	return fields
+}
+
+// snakeCase converts a Go field name such as BaseAmountOut to base_amount_out
+func snakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// Start a new word unless inside an acronym such as "LP" in "LPFee"
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
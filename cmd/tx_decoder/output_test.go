@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"solana-pumpswap-demo/idl/pumpfun/amm/idl/generated/amm"
+	"solana-pumpswap-demo/internal/rpctest"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// TestFieldsOf tests flattening generated instructions and events into snake_case JSON fields
+func TestFieldsOf(t *testing.T) {
+	baseAmountOut := uint64(1_000)
+	buy := amm.NewBuyInstructionBuilder().SetBaseAmountOut(baseAmountOut)
+	fields := fieldsOf(buy)
+	if len(fields) != 2 || fields["base_amount_out"] != baseAmountOut || fields["max_quote_amount_in"] != nil {
+		t.Errorf("fieldsOf(buy) = %v", fields)
+	}
+
+	pool := solana.NewWallet().PublicKey()
+	raw, err := json.Marshal(fieldsOf(&amm.SellEventEventData{LpFeeBasisPoints: 20, Pool: pool}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded["lp_fee_basis_points"] != float64(20) || decoded["pool"] != pool.String() {
+		t.Errorf("sell event JSON = %s", raw)
+	}
+}
+
+// TestDecodeTxNDJSON tests that decoding a buy writes its record to stdout and never trades,
+// even with a wallet configured
+func TestDecodeTxNDJSON(t *testing.T) {
+	corpus, err := os.ReadFile(filepath.Join("..", "..", defaultRecordDir, "pumpswap_buy.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := rpctest.NewServer()
+	defer s.Close()
+	sig := solana.Signature{7}
+	s.AddTransactionJSON(sig, corpus)
+	t.Setenv("PRIVATE_KEY", solana.NewWallet().PrivateKey.String())
+
+	var records bytes.Buffer
+	recordOut, logOut = &records, io.Discard
+	t.Cleanup(func() { recordOut, logOut, outputFormat = os.Stdout, os.Stdout, outputTable })
+
+	if err := runCLI(context.Background(), []string{"decode-tx", "--rpc", s.URL, "-o", "ndjson", sig.String()}, io.Discard); err != nil {
+		t.Fatalf("decode-tx error = %v", err)
+	}
+	var record TransactionRecord
+	if err := json.Unmarshal(records.Bytes(), &record); err != nil {
+		t.Fatalf("decode-tx wrote %q: %v", records.String(), err)
+	}
+	if record.Signature != sig.String() || len(record.Events) != 1 || record.Events[0].Name != "BuyEvent" {
+		t.Errorf("record = %+v", record)
+	}
+	if logOut != os.Stderr {
+		t.Error("progress output is not on stderr in ndjson mode")
+	}
+	if sends := s.Calls("sendTransaction"); sends != 0 {
+		t.Errorf("decode-tx sent %d transactions", sends)
+	}
+}
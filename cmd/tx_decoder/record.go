@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// defaultRecordDir is the decoder's golden-file corpus, relative to the repository root
+const defaultRecordDir = "internal/decoder/testdata/transactions"
+
+// sourcesFile lists where each corpus transaction came from, one "<name> <signature>" per line,
+// or "<name> synthesized" for the ones built offline rather than captured from a node
+const sourcesFile = "sources.txt"
+
+// recordName is what a corpus file may be called, it names the golden test case
+var recordName = regexp.MustCompile(`^[a-z0-9][a-z0-9_]*$`)
+
+// runRecord saves a transaction's raw getTransaction result to the decoder corpus, so the golden
+// tests can decode it offline. The result is kept exactly as the node returned it.
+func runRecord(ctx context.Context, o *options, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("record takes <name> <tx_signature>, got %d arguments", len(args))
+	}
+	name := args[0]
+	if !recordName.MatchString(name) {
+		return fmt.Errorf("invalid name %q, want lowercase letters, digits and underscores", name)
+	}
+	signature, err := solana.SignatureFromBase58(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	commitment, err := o.commitment()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(o.RecordDir, name+".json")
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists, remove it to record it again", path)
+	}
+
+	var raw json.RawMessage
+	err = withFallback(ctx, o.RPCEndpoint, 30*time.Second, func(ctx context.Context, endpoint string) error {
+		raw, err = fetchRawTransaction(ctx, rpc.New(endpoint), signature, commitment)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeRecord(path, raw); err != nil {
+		return err
+	}
+	if err := recordSource(o.RecordDir, name, signature); err != nil {
+		return fmt.Errorf("recorded %s but failed to note its source: %w", path, err)
+	}
+	fmt.Fprintf(os.Stderr, "Recorded %s to %s, run go test ./internal/decoder -run TestGolden -update to create its golden file\n", signature, path)
+	return nil
+}
+
+// fetchRawTransaction fetches the raw getTransaction result of signature, base64 encoded and with
+// versioned transactions allowed, the way the decoder is fed
+func fetchRawTransaction(ctx context.Context, client *rpc.Client, signature solana.Signature, commitment rpc.CommitmentType) (json.RawMessage, error) {
+	var raw json.RawMessage
+	err := client.RPCCallForInto(ctx, &raw, "getTransaction", []interface{}{
+		signature.String(),
+		map[string]interface{}{
+			"encoding":                       solana.EncodingBase64,
+			"commitment":                     commitment,
+			"maxSupportedTransactionVersion": 0,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction %s: %w", signature, err)
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, fmt.Errorf("transaction %s not found at %s commitment", signature, commitment)
+	}
+	return raw, nil
+}
+
+// writeRecord writes the result indented, so corpus changes review as readable diffs
+func writeRecord(path string, raw json.RawMessage) error {
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, raw, "", "  "); err != nil {
+		return fmt.Errorf("failed to encode transaction: %w", err)
+	}
+	indented.WriteByte('\n')
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s already exists, remove it to record it again", path)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(indented.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// recordSource notes the signature a corpus file was recorded from in the corpus' sources file,
+// replacing the line of an earlier, possibly synthesized, transaction of the same name
+func recordSource(dir, name string, signature solana.Signature) error {
+	path := filepath.Join(dir, sourcesFile)
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	entry := name + " " + signature.String()
+	var lines []string
+	replaced := false
+	if len(data) > 0 {
+		for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+			if fields := strings.Fields(line); len(fields) == 2 && fields[0] == name {
+				line, replaced = entry, true
+			}
+			lines = append(lines, line)
+		}
+	}
+	if !replaced {
+		lines = append(lines, entry)
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"solana-pumpswap-demo/internal/decoder"
+	"solana-pumpswap-demo/internal/rpctest"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// TestRecord tests that a recorded transaction is written as the node returned it and decodes again
+func TestRecord(t *testing.T) {
+	corpus, err := os.ReadFile(filepath.Join("..", "..", defaultRecordDir, "pumpswap_buy.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := rpctest.NewServer()
+	defer s.Close()
+	sig := solana.Signature{7}
+	s.AddTransactionJSON(sig, corpus)
+	ctx := context.Background()
+
+	raw, err := fetchRawTransaction(ctx, rpc.New(s.URL), sig, rpc.CommitmentConfirmed)
+	if err != nil {
+		t.Fatalf("fetchRawTransaction() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "corpus", "buy.json")
+	if err := writeRecord(path, raw); err != nil {
+		t.Fatalf("writeRecord() error = %v", err)
+	}
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(written, corpus) {
+		t.Errorf("recorded file differs from what the node returned:\n%s", written)
+	}
+	txResult := new(rpc.GetTransactionResult)
+	if err := json.Unmarshal(written, txResult); err != nil {
+		t.Fatal(err)
+	}
+	if swaps, err := decoder.DecodeSwaps(txResult); err != nil || len(swaps) != 1 {
+		t.Errorf("DecodeSwaps() = %v, %v", swaps, err)
+	}
+
+	if err := writeRecord(path, raw); err == nil {
+		t.Error("writeRecord() overwrote an existing recording")
+	}
+	if _, err := fetchRawTransaction(ctx, rpc.New(s.URL), solana.Signature{8}, rpc.CommitmentConfirmed); err == nil {
+		t.Error("fetchRawTransaction() of an unknown signature succeeded")
+	}
+}
+
+// TestRecordSource tests that recording replaces a synthesized transaction's source and appends new ones
+func TestRecordSource(t *testing.T) {
+	dir := t.TempDir()
+	sources := filepath.Join(dir, sourcesFile)
+	if err := os.WriteFile(sources, []byte("# header\nbuy synthesized\nsell synthesized\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	buy, deposit := solana.Signature{1}, solana.Signature{2}
+	if err := recordSource(dir, "buy", buy); err != nil {
+		t.Fatalf("recordSource() error = %v", err)
+	}
+	if err := recordSource(dir, "deposit", deposit); err != nil {
+		t.Fatalf("recordSource() error = %v", err)
+	}
+	got, err := os.ReadFile(sources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# header\nbuy " + buy.String() + "\nsell synthesized\ndeposit " + deposit.String() + "\n"
+	if string(got) != want {
+		t.Errorf("sources = %q, want %q", got, want)
+	}
+}
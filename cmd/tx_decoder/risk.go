@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"solana-pumpswap-demo/internal/risk"
+	"solana-pumpswap-demo/internal/router"
+
+	"github.com/shopspring/decimal"
+)
+
+// riskLimitsFromEnv reads the pre-trade limits. SOL amounts are converted to lamports and unset
+// values leave the limit disabled. A value that does not parse is an error rather than a disabled
+// limit, so a typo cannot turn a safety control off.
+func riskLimitsFromEnv() (risk.Limits, error) {
+	var limits risk.Limits
+	var maxOpen uint64
+	var err error
+	for _, env := range []struct {
+		name  string
+		parse func(string) (uint64, error)
+		out   *uint64
+	}{
+		{"MAX_SOL_PER_TRADE", parseSol, &limits.MaxSolPerTrade},
+		{"MAX_TOKEN_EXPOSURE_SOL", parseSol, &limits.MaxTokenExposure},
+		{"MAX_OPEN_POSITIONS", parseUint, &maxOpen},
+		{"DAILY_LOSS_LIMIT_SOL", parseSol, &limits.DailyLossLimit},
+		{"MAX_PRICE_IMPACT_BPS", parseUint, &limits.MaxPriceImpactBps},
+		{"MIN_POOL_LIQUIDITY_SOL", parseSol, &limits.MinPoolLiquidity},
+	} {
+		value := os.Getenv(env.name)
+		if value == "" {
+			continue
+		}
+		if *env.out, err = env.parse(value); err != nil {
+			return risk.Limits{}, fmt.Errorf("invalid %s %q: %w", env.name, value, err)
+		}
+	}
+	limits.MaxOpenPositions = int(maxOpen)
+
+	if value := os.Getenv("KILL_SWITCH"); value != "" {
+		if limits.KillSwitch, err = strconv.ParseBool(value); err != nil {
+			return risk.Limits{}, fmt.Errorf("invalid KILL_SWITCH %q, want true or false", value)
+		}
+	}
+	limits.KillSwitchFile = os.Getenv("KILL_SWITCH_FILE")
+	return limits, nil
+}
+
+// parseSol parses a non-negative SOL amount into lamports
+func parseSol(value string) (uint64, error) {
+	sol, err := decimal.NewFromString(value)
+	if err != nil {
+		return 0, fmt.Errorf("want a SOL amount such as 0.5")
+	}
+	if sol.Sign() < 0 {
+		return 0, fmt.Errorf("want a non-negative SOL amount")
+	}
+	return uint64(sol.Shift(9).IntPart()), nil
+}
+
+// parseUint parses a non-negative integer
+func parseUint(value string) (uint64, error) {
+	v, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("want a non-negative integer")
+	}
+	return v, nil
+}
+
+// copySlippageBps is the slippage allowed on copy trades
+const copySlippageBps = 100
+
+// checkCopyRisk runs the risk engine on a routed buy using the quote it was planned with
+// and the exposure recorded in the state store
+func checkCopyRisk(plan *router.Plan, amountIn uint64) error {
+	quote := plan.Quote
+	logf("Quote on %s: %d tokens for %s SOL, price %s -> %s, impact %d bps\n",
+		venueName(plan.Venue), quote.AmountOut, decimal.New(int64(amountIn), -9), quote.SpotPriceBefore, quote.SpotPriceAfter, quote.PriceImpactBps)
+
+	book, err := risk.BookFromStore(stateStore, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to load positions: %w", err)
+	}
+
+	limits, err := riskLimitsFromEnv()
+	if err != nil {
+		return err
+	}
+	return risk.NewEngine(limits).Check(risk.Order{
+		Mint:             plan.Mint.String(),
+		Side:             risk.SideBuy,
+		AmountIn:         amountIn,
+		PriceImpactBps:   quote.PriceImpactBps,
+		PoolQuoteReserve: quote.QuoteReserve,
+	}, book)
+}
@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+// TestRiskLimitsFromEnv tests that limits parse into lamports and that a typo is an error, not a disabled limit
+func TestRiskLimitsFromEnv(t *testing.T) {
+	t.Setenv("MAX_SOL_PER_TRADE", "0.5")
+	t.Setenv("MAX_OPEN_POSITIONS", "3")
+	t.Setenv("KILL_SWITCH", "false")
+	limits, err := riskLimitsFromEnv()
+	if err != nil {
+		t.Fatalf("riskLimitsFromEnv() error = %v", err)
+	}
+	if limits.MaxSolPerTrade != 500_000_000 || limits.MaxOpenPositions != 3 || limits.KillSwitch || limits.DailyLossLimit != 0 {
+		t.Errorf("riskLimitsFromEnv() = %+v", limits)
+	}
+
+	for name, value := range map[string]string{
+		"MAX_SOL_PER_TRADE":    "0.5sol",
+		"DAILY_LOSS_LIMIT_SOL": "-1",
+		"MAX_PRICE_IMPACT_BPS": "1.5",
+		"KILL_SWITCH":          "on",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			if _, err := riskLimitsFromEnv(); err == nil {
+				t.Errorf("riskLimitsFromEnv() accepted %s=%s", name, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"solana-pumpswap-demo/internal/decoder"
+	"solana-pumpswap-demo/internal/orders"
+	"solana-pumpswap-demo/internal/risk"
+	"solana-pumpswap-demo/internal/router"
+	"solana-pumpswap-demo/internal/store"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// defaultStorePath is where the monitor keeps its state between runs
+const defaultStorePath = "tx_decoder.db"
+
+const (
+	// orderMaxAge is how long an order may stay pending or unconfirmed before it is expired,
+	// well past the lifetime of the blockhash it was signed with
+	orderMaxAge = 2 * time.Minute
+	// reconcileInterval is how often monitor checks the status of the orders it sent
+	reconcileInterval = 15 * time.Second
+)
+
+// stateStore and copyOrders are opened once per process by openStore
+var (
+	stateStore *store.Store
+	copyOrders *orders.Manager
+)
+
+// openStore opens the state database from STORE_PATH or the default path
+func openStore() (*store.Store, error) {
+	if stateStore != nil {
+		return stateStore, nil
+	}
+
+	path := os.Getenv("STORE_PATH")
+	if path == "" {
+		path = defaultStorePath
+	}
+	st, err := store.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	stateStore = st
+	copyOrders = orders.NewManager(st)
+	return st, nil
+}
+
+// recordTransaction saves an analyzed transaction, marks it processed and advances the account's cursor
+func recordTransaction(st *store.Store, account, signature string, tx *rpc.GetTransactionResult, summary *TransactionSummary) error {
+	record := store.Transaction{
+		Signature: signature,
+		Slot:      tx.Slot,
+	}
+	if tx.BlockTime != nil {
+		record.BlockTime = int64(*tx.BlockTime)
+	}
+	if tx.Meta != nil {
+		record.Success = tx.Meta.Err == nil
+		record.Fee = tx.Meta.Fee
+	}
+	if summary != nil {
+		record.Operation = summary.Operation
+		record.BaseMint = summary.BaseMint
+		switch {
+		case strings.HasPrefix(summary.Direction, "Buy"):
+			record.Direction = "Buy"
+			record.QuoteAmount, record.BaseAmount = summary.AmountIn, summary.AmountOut
+		case strings.HasPrefix(summary.Direction, "Sell"):
+			record.Direction = "Sell"
+			record.BaseAmount, record.QuoteAmount = summary.AmountIn, summary.AmountOut
+		}
+	}
+	// The swap's event has what executed rather than the instruction's limits, and the pool
+	// state the backtester replays it against
+	trades, _ := decoder.DecodeTrades(tx)
+	for _, trade := range trades {
+		if trade.Venue != decoder.VenuePumpSwap {
+			continue
+		}
+		record.Pool, record.User = trade.Pool.String(), trade.User.String()
+		if !trade.BaseMint.IsZero() {
+			record.BaseMint = trade.BaseMint.String()
+		}
+		record.Direction = "Buy"
+		if trade.Side == decoder.SideSell {
+			record.Direction = "Sell"
+		}
+		record.BaseAmount, record.QuoteAmount = trade.BaseAmount, trade.QuoteAmount
+		record.PoolBaseReserve, record.PoolQuoteReserve = trade.PoolBaseReserve, trade.PoolQuoteReserve
+		record.LpFeeBps, record.ProtocolFeeBps = trade.LpFeeBps, trade.ProtocolFeeBps
+		break
+	}
+
+	if err := st.PutTransaction(record); err != nil {
+		return err
+	}
+	if err := st.MarkProcessed(signature); err != nil {
+		return err
+	}
+	return st.SetCursor(account, signature)
+}
+
+// fetchTransaction gets a transaction with retry logic
+func fetchTransaction(ctx context.Context, client *rpc.Client, signature solana.Signature, commitment rpc.CommitmentType) (*rpc.GetTransactionResult, error) {
+	var tx *rpc.GetTransactionResult
+	var err error
+	for retryCount := 0; retryCount < maxRetries; retryCount++ {
+		tx, err = client.GetTransaction(ctx, signature, &rpc.GetTransactionOpts{
+			Encoding:   solana.EncodingBase64,
+			Commitment: commitment,
+		})
+		if err == nil {
+			return tx, nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		if retryCount < maxRetries-1 {
+			logf("Failed to get transaction (attempt %d/%d): %v\nRetrying...\n",
+				retryCount+1, maxRetries, err)
+			time.Sleep(time.Duration(retryCount+1) * time.Second)
+		}
+	}
+	return nil, fmt.Errorf("failed to get transaction after %d attempts: %w", maxRetries, err)
+}
+
+// backfillSinceCursor processes the account's transactions that landed after the saved cursor,
+// so a restart picks up where the previous run stopped without handling anything twice
+func backfillSinceCursor(ctx context.Context, client *rpc.Client, st *store.Store, account solana.PublicKey, rpcEndpoint string, commitment rpc.CommitmentType) error {
+	cursor, err := st.Cursor(account.String())
+	if err != nil || cursor == "" {
+		return err
+	}
+	until, err := solana.SignatureFromBase58(cursor)
+	if err != nil {
+		return fmt.Errorf("invalid saved cursor %s: %w", cursor, err)
+	}
+
+	signatures, err := client.GetSignaturesForAddressWithOpts(ctx, account, &rpc.GetSignaturesForAddressOpts{
+		Until:      until,
+		Commitment: commitment,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get signatures since cursor: %w", err)
+	}
+
+	logf("Resuming after %s: %d missed transactions\n", cursor, len(signatures))
+
+	// Signatures come newest first, process them in chronological order
+	for i := len(signatures) - 1; i >= 0; i-- {
+		sig := signatures[i].Signature.String()
+		if processed, err := st.IsProcessed(sig); err != nil || processed {
+			continue
+		}
+
+		tx, err := fetchTransaction(ctx, client, signatures[i].Signature, commitment)
+		if err != nil {
+			logf("Error backfilling %s: %v\n", sig, err)
+			continue
+		}
+		summary := analyzeTransactionWithRPC(tx, sig, rpcEndpoint)
+		if err := recordTransaction(st, account.String(), sig, tx, summary); err != nil {
+			return fmt.Errorf("failed to record %s: %w", sig, err)
+		}
+	}
+	return nil
+}
+
+// reconcileOrders brings the stored orders up to date with their signatures on chain, including
+// the ones a previous run sent or claimed before it stopped, and applies what landed to the positions
+func reconcileOrders(ctx context.Context, client *rpc.Client) {
+	changed, err := copyOrders.Reconcile(ctx, client, orderMaxAge)
+	for _, order := range changed {
+		if order.Side == "sell" && order.Status != string(orders.StatusLanded) {
+			// The exit manager counted the sell when it was sent, the tokens are still held
+			trackMint(order.Mint)
+		}
+		if order.Error != "" {
+			logf("Order %s is %s: %s\n", order.ID, order.Status, order.Error)
+			continue
+		}
+		logf("Order %s is %s\n", order.ID, order.Status)
+	}
+	if err != nil {
+		logf("Failed to reconcile orders: %v\n", err)
+	}
+
+	unfilled, err := copyOrders.Unfilled()
+	if err != nil {
+		logf("Failed to list landed orders: %v\n", err)
+		return
+	}
+	for _, order := range unfilled {
+		if err := applyFill(ctx, client, order); err != nil {
+			logf("Failed to apply the fill of %s: %v\n", order.ID, err)
+		}
+	}
+}
+
+// applyFill reads what a landed order executed from its transaction and updates the mint's position
+func applyFill(ctx context.Context, client *rpc.Client, order store.Order) error {
+	sig, err := solana.SignatureFromBase58(order.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature %q: %w", order.Signature, err)
+	}
+	tx, err := fetchTransaction(ctx, client, sig, rpc.CommitmentConfirmed)
+	if err != nil {
+		return err
+	}
+	fill, err := orders.FillFromTransaction(tx, order)
+	if err != nil {
+		return err
+	}
+	position, err := copyOrders.ApplyFill(order.ID, fill)
+	if err != nil {
+		return err
+	}
+	trackPosition(position)
+	if position.Open() {
+		logf("Position %s holds %d of %s, %d lamports in\n", position.ID, position.Amount, position.Mint, position.CostLamports)
+	} else {
+		logf("Position %s in %s closed, realized %d lamports\n", position.ID, position.Mint, position.RealizedPnL())
+	}
+	return nil
+}
+
+// copyLeaderTrade sends a copy of the leader's buy of mint through the order manager,
+// so the same leader transaction is never copied twice by our wallet. The router picks the
+// bonding curve or the PumpSwap pool, and the trade must pass the risk checks before anything is signed.
+func copyLeaderTrade(rpcEndpoint, leaderSignature, privateKeyStr string, mint solana.PublicKey, amountIn uint64) (string, error) {
+	privateKey, err := solana.PrivateKeyFromBase58(privateKeyStr)
+	if err != nil {
+		return "", fmt.Errorf("invalid private key: %w", err)
+	}
+	if _, err := openStore(); err != nil {
+		return "", fmt.Errorf("failed to open state store: %w", err)
+	}
+	r := router.New(rpc.New(rpcEndpoint), privateKey, copySlippageBps)
+	plan, err := r.Plan(context.Background(), mint, decoder.SideBuy, amountIn)
+	if err != nil {
+		return "", fmt.Errorf("failed to route copy: %w", err)
+	}
+	return submitRouted(context.Background(), r, privateKey.PublicKey(), plan, leaderSignature, amountIn)
+}
+
+// submitRouted checks a routed buy against the risk limits and sends it through the order manager,
+// keyed by the transaction that triggered it so it is never sent twice. The store must be open.
+func submitRouted(ctx context.Context, r *router.Router, wallet solana.PublicKey, plan *router.Plan, sourceSignature string, amountIn uint64) (string, error) {
+	if err := checkCopyRisk(plan, amountIn); err != nil {
+		return "", err
+	}
+
+	intent := orders.Intent{
+		SourceSignature: sourceSignature,
+		Wallet:          wallet.String(),
+		Mint:            plan.Mint.String(),
+		Side:            "buy",
+		AmountIn:        amountIn,
+	}
+	order, err := copyOrders.Submit(ctx, intent, func(ctx context.Context) (string, error) {
+		sig, err := r.Send(ctx, plan)
+		if err != nil {
+			return "", err
+		}
+		return sig.String(), nil
+	})
+	return order.Signature, err
+}
+
+// copyLeaderBuy copies the leader's successful buy in a monitored transaction, on the bonding curve
+// while it is active and on the mint's PumpSwap pool once it has migrated. Anything else is ignored.
+func copyLeaderBuy(rpcEndpoint, signature string, tx *rpc.GetTransactionResult, summary *TransactionSummary) {
+	if summary == nil || !strings.HasPrefix(summary.Direction, "Buy") || tx.Meta == nil || tx.Meta.Err != nil {
+		return
+	}
+	mint, err := solana.PublicKeyFromBase58(summary.BaseMint)
+	if err != nil {
+		logf("Not copying %s, base mint unknown\n", signature)
+		return
+	}
+
+	privateKeyStr := os.Getenv("PRIVATE_KEY")
+	copySignature, err := copyLeaderTrade(rpcEndpoint, signature, privateKeyStr, mint, summary.AmountIn)
+	var rejection *risk.Rejection
+	switch {
+	case errors.Is(err, orders.ErrDuplicate):
+		logln("Leader trade already copied, skipping:", err)
+	case errors.As(err, &rejection):
+		logf("Copy rejected by %s limit: %s\n", rejection.Rule, rejection.Reason)
+	case err != nil:
+		logln("Failed to copy leader trade:", err)
+	default:
+		logln("Copied leader trade:", copySignature)
+	}
+}
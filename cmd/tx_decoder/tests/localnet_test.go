@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"solana-pumpswap-demo/internal/decoder"
+	"solana-pumpswap-demo/internal/localnet"
+	"solana-pumpswap-demo/internal/orders"
+	"solana-pumpswap-demo/internal/router"
+	"solana-pumpswap-demo/internal/store"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// TestLocalnetSwapDecodeCopy buys a fixture mint on a local validator, decodes the buy and copies it
+// from a second wallet through the order manager. It needs solana-test-validator and the fixtures
+// cmd/localnet_fixtures writes, and is skipped without them.
+func TestLocalnetSwapDecodeCopy(t *testing.T) {
+	v := localnet.Run(t)
+	t.Logf("Using %s", v)
+	mints, err := v.Fixtures.PoolMints()
+	if err != nil {
+		t.Fatalf("PoolMints() error = %v", err)
+	}
+	if len(mints) == 0 {
+		t.Skip("no PumpSwap pool in the fixtures")
+	}
+	mint := mints[0]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	leader, follower := solana.NewWallet().PrivateKey, solana.NewWallet().PrivateKey
+	for _, key := range []solana.PrivateKey{leader, follower} {
+		if err := v.Fund(ctx, key.PublicKey(), 10*solana.LAMPORTS_PER_SOL); err != nil {
+			t.Fatalf("Fund() error = %v", err)
+		}
+	}
+
+	// The leader buys
+	sig, plan, err := router.New(v.RPC, leader, 500).Trade(ctx, mint, decoder.SideBuy, solana.LAMPORTS_PER_SOL/10)
+	if err != nil {
+		t.Fatalf("Trade() error = %v", err)
+	}
+	if plan.Venue != decoder.VenuePumpSwap {
+		t.Errorf("routed to %s, want the pool", plan.Venue)
+	}
+	if err := v.Confirm(ctx, sig); err != nil {
+		t.Fatal(err)
+	}
+
+	// The buy decodes as a PumpSwap swap with its event
+	maxVersion := uint64(0)
+	txResult, err := v.RPC.GetTransaction(ctx, sig, &rpc.GetTransactionOpts{
+		Encoding:                       solana.EncodingBase64,
+		Commitment:                     rpc.CommitmentConfirmed,
+		MaxSupportedTransactionVersion: &maxVersion,
+	})
+	if err != nil {
+		t.Fatalf("GetTransaction() error = %v", err)
+	}
+	swaps, err := decoder.DecodeSwaps(txResult)
+	if err != nil {
+		t.Fatalf("DecodeSwaps() error = %v", err)
+	}
+	if len(swaps) != 1 {
+		t.Fatalf("decoded %d swaps, want 1", len(swaps))
+	}
+	swap := swaps[0]
+	if swap.Venue != decoder.VenuePumpSwap || swap.Side != decoder.SideBuy || !swap.BaseMint.Equals(mint) ||
+		!swap.User.Equals(leader.PublicKey()) || swap.Trade == nil || swap.Trade.BaseAmount == 0 {
+		t.Fatalf("swap = %+v", swap)
+	}
+
+	// The follower copies it once
+	st, err := store.Open(filepath.Join(t.TempDir(), "orders.db"))
+	if err != nil {
+		t.Fatalf("store.Open() error = %v", err)
+	}
+	defer st.Close()
+	m := orders.NewManager(st)
+	copier := router.New(v.RPC, follower, 500)
+	intent := orders.Intent{
+		SourceSignature: sig.String(),
+		Wallet:          follower.PublicKey().String(),
+		Mint:            mint.String(),
+		Side:            decoder.SideBuy,
+		AmountIn:        swap.Trade.QuoteAmount,
+	}
+	send := func(ctx context.Context) (string, error) {
+		sig, _, err := copier.Trade(ctx, mint, intent.Side, intent.AmountIn)
+		return sig.String(), err
+	}
+	order, err := m.Submit(ctx, intent, send)
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	copySig, err := solana.SignatureFromBase58(order.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Confirm(ctx, copySig); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Submit(ctx, intent, send); !errors.Is(err, orders.ErrDuplicate) {
+		t.Errorf("second Submit() error = %v, want ErrDuplicate", err)
+	}
+}
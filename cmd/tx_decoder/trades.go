@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+
+	"solana-pumpswap-demo/internal/decoder"
+)
+
+// tradeForInstruction returns the executed trade of a top-level instruction, or nil if it emitted none
+func tradeForInstruction(trades []decoder.Trade, index int) *decoder.Trade {
+	for i := range trades {
+		if trades[i].Instruction == index {
+			return &trades[i]
+		}
+	}
+	return nil
+}
+
+// applyTrade replaces the instruction limits in the summary with what the swap actually did
+func applyTrade(summary *TransactionSummary, trade *decoder.Trade) {
+	summary.Trade = trade
+	if trade.Side == decoder.SideBuy {
+		summary.AmountIn, summary.AmountOut = trade.QuoteAmount, trade.BaseAmount
+	} else {
+		summary.AmountIn, summary.AmountOut = trade.BaseAmount, trade.QuoteAmount
+	}
+
+	logf("  Executed (from %s %s event):\n", venueName(trade.Venue), trade.Side)
+	logf("    Base Amount: %d\n", trade.BaseAmount)
+	if trade.Venue == decoder.VenuePumpFun {
+		// The bonding curve reports the SOL amount before its fee and no fee breakdown
+		logf("    Quote Amount: %d lamports (before the bonding curve fee)\n", trade.QuoteAmount)
+		logf("    Curve Reserves After: %d tokens / %d lamports (virtual)\n", trade.PoolBaseReserve, trade.PoolQuoteReserve)
+		return
+	}
+	logf("    Quote Amount: %d lamports (fees included)\n", trade.QuoteAmount)
+	logf("    LP Fee: %d lamports (%d bps)\n", trade.LpFee, trade.LpFeeBps)
+	logf("    Protocol Fee: %d lamports (%d bps)\n", trade.ProtocolFee, trade.ProtocolFeeBps)
+	logf("    Pool Reserves After: %d base / %d quote\n", trade.PoolBaseReserve, trade.PoolQuoteReserve)
+}
+
+// venueName returns a venue's display name
+func venueName(venue string) string {
+	switch venue {
+	case decoder.VenuePumpSwap:
+		return "PumpSwap"
+	case decoder.VenuePumpFun:
+		return "pump.fun bonding curve"
+	default:
+		return venue
+	}
+}
+
+// fillTokenInfo looks up the summary's base mint and records its name and metadata
+func fillTokenInfo(ctx context.Context, summary *TransactionSummary, rpcEndpoint string) {
+	tokenInfo, err := getTokenInfo(ctx, rpcEndpoint, summary.BaseMint)
+	if err != nil {
+		logf("  Error getting token info: %v\n", err)
+		return
+	}
+	if tokenInfo == nil {
+		return
+	}
+	summary.TokenInfo = tokenInfo
+	summary.BaseMintName = tokenInfo.Name
+	if summary.BaseMintName == "" {
+		summary.BaseMintName = tokenInfo.Symbol
+	}
+}
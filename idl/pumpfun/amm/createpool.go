@@ -0,0 +1,160 @@
+package amm
+
+import (
+	"fmt"
+	"solana-pumpswap-demo/idl/pumpfun/amm/idl/generated/amm"
+
+	ag_solanago "github.com/gagliardetto/solana-go"
+)
+
+type CreatePoolParam struct {
+	// Parameters:
+	Index         uint16
+	BaseAmountIn  uint64
+	QuoteAmountIn uint64
+	// Accounts:
+	Creator           ag_solanago.PublicKey
+	BaseMint          ag_solanago.PublicKey
+	QuoteMint         ag_solanago.PublicKey
+	BaseTokenProgram  ag_solanago.PublicKey // TokenProgramID if zero
+	QuoteTokenProgram ag_solanago.PublicKey // TokenProgramID if zero
+}
+
+// PoolAddresses are the accounts create_pool creates or funds, derived from the pool's index, creator and mints
+type PoolAddresses struct {
+	Pool                  ag_solanago.PublicKey
+	LpMint                ag_solanago.PublicKey
+	PoolBaseTokenAccount  ag_solanago.PublicKey
+	PoolQuoteTokenAccount ag_solanago.PublicKey
+	UserBaseTokenAccount  ag_solanago.PublicKey
+	UserQuoteTokenAccount ag_solanago.PublicKey
+	UserPoolTokenAccount  ag_solanago.PublicKey
+}
+
+// FindLpMint derives the LP mint PDA of a pool
+func FindLpMint(pool ag_solanago.PublicKey) (ag_solanago.PublicKey, error) {
+	lpMint, _, err := ag_solanago.FindProgramAddress([][]byte{[]byte("pool_lp_mint"), pool.Bytes()}, amm.ProgramID)
+	if err != nil {
+		return ag_solanago.PublicKey{}, fmt.Errorf("failed to derive LP mint: %w", err)
+	}
+	return lpMint, nil
+}
+
+// FindAssociatedTokenAccount derives an associated token account under any token program.
+// solana.FindAssociatedTokenAddress only knows the legacy token program.
+func FindAssociatedTokenAccount(owner, tokenProgram, mint ag_solanago.PublicKey) (ag_solanago.PublicKey, error) {
+	account, _, err := ag_solanago.FindProgramAddress([][]byte{
+		owner.Bytes(),
+		tokenProgram.Bytes(),
+		mint.Bytes(),
+	}, ag_solanago.SPLAssociatedTokenAccountProgramID)
+	if err != nil {
+		return ag_solanago.PublicKey{}, fmt.Errorf("failed to derive associated token account: %w", err)
+	}
+	return account, nil
+}
+
+// FindPoolAddresses derives the pool, its LP mint and vaults, and the creator's token accounts
+func FindPoolAddresses(para *CreatePoolParam) (*PoolAddresses, error) {
+	baseProgram, quoteProgram := para.tokenPrograms()
+	pool, err := FindPoolAddress(para.Index, para.Creator, para.BaseMint, para.QuoteMint)
+	if err != nil {
+		return nil, err
+	}
+	lpMint, err := FindLpMint(pool)
+	if err != nil {
+		return nil, err
+	}
+	addrs := &PoolAddresses{Pool: pool, LpMint: lpMint}
+	for _, ata := range []struct {
+		dst                  *ag_solanago.PublicKey
+		owner, program, mint ag_solanago.PublicKey
+	}{
+		{&addrs.PoolBaseTokenAccount, pool, baseProgram, para.BaseMint},
+		{&addrs.PoolQuoteTokenAccount, pool, quoteProgram, para.QuoteMint},
+		{&addrs.UserBaseTokenAccount, para.Creator, baseProgram, para.BaseMint},
+		{&addrs.UserQuoteTokenAccount, para.Creator, quoteProgram, para.QuoteMint},
+		{&addrs.UserPoolTokenAccount, para.Creator, LpTokenProgram, lpMint},
+	} {
+		if *ata.dst, err = FindAssociatedTokenAccount(ata.owner, ata.program, ata.mint); err != nil {
+			return nil, err
+		}
+	}
+	return addrs, nil
+}
+
+// NewCreatePoolInstruction builds create_pool with every derived account filled in
+func NewCreatePoolInstruction(para *CreatePoolParam) (ag_solanago.Instruction, *PoolAddresses, error) {
+	if para.BaseAmountIn == 0 || para.QuoteAmountIn == 0 {
+		return nil, nil, fmt.Errorf("a pool needs initial base and quote amounts")
+	}
+	addrs, err := FindPoolAddresses(para)
+	if err != nil {
+		return nil, nil, err
+	}
+	baseProgram, quoteProgram := para.tokenPrograms()
+	createPool := amm.NewCreatePoolInstruction(
+		para.Index,
+		para.BaseAmountIn,
+		para.QuoteAmountIn,
+		addrs.Pool,
+		PumpAmmGlobalConfigAddress,
+		para.Creator,
+		para.BaseMint,
+		para.QuoteMint,
+		addrs.LpMint,
+		addrs.UserBaseTokenAccount,
+		addrs.UserQuoteTokenAccount,
+		addrs.UserPoolTokenAccount,
+		addrs.PoolBaseTokenAccount,
+		addrs.PoolQuoteTokenAccount,
+		ag_solanago.SystemProgramID,
+		LpTokenProgram,
+		baseProgram,
+		quoteProgram,
+		ag_solanago.SPLAssociatedTokenAccountProgramID,
+		PumpAmmEventAuthorityAddress,
+		amm.ProgramID,
+	)
+	ix, err := createPool.ValidateAndBuild()
+	if err != nil {
+		return nil, nil, err
+	}
+	return ix, addrs, nil
+}
+
+// VerifyPool decodes a created pool account and checks it holds the accounts create_pool was given
+func VerifyPool(data []byte, para *CreatePoolParam, addrs *PoolAddresses) (*amm.PoolAccount, error) {
+	pool, err := DecodePool(data)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case pool.Index != para.Index:
+		return pool, fmt.Errorf("pool index is %d, want %d", pool.Index, para.Index)
+	case !pool.Creator.Equals(para.Creator):
+		return pool, fmt.Errorf("pool creator is %s, want %s", pool.Creator, para.Creator)
+	case !pool.BaseMint.Equals(para.BaseMint) || !pool.QuoteMint.Equals(para.QuoteMint):
+		return pool, fmt.Errorf("pool mints are %s/%s, want %s/%s", pool.BaseMint, pool.QuoteMint, para.BaseMint, para.QuoteMint)
+	case !pool.LpMint.Equals(addrs.LpMint):
+		return pool, fmt.Errorf("pool LP mint is %s, want %s", pool.LpMint, addrs.LpMint)
+	case !pool.PoolBaseTokenAccount.Equals(addrs.PoolBaseTokenAccount) || !pool.PoolQuoteTokenAccount.Equals(addrs.PoolQuoteTokenAccount):
+		return pool, fmt.Errorf("pool token accounts are %s/%s, want %s/%s", pool.PoolBaseTokenAccount, pool.PoolQuoteTokenAccount,
+			addrs.PoolBaseTokenAccount, addrs.PoolQuoteTokenAccount)
+	case pool.LpSupply == 0:
+		return pool, fmt.Errorf("pool %s has no LP supply", addrs.Pool)
+	}
+	return pool, nil
+}
+
+// tokenPrograms returns the base and quote token programs, the legacy token program where unset
+func (para *CreatePoolParam) tokenPrograms() (base, quote ag_solanago.PublicKey) {
+	base, quote = para.BaseTokenProgram, para.QuoteTokenProgram
+	if base.IsZero() {
+		base = ag_solanago.TokenProgramID
+	}
+	if quote.IsZero() {
+		quote = ag_solanago.TokenProgramID
+	}
+	return base, quote
+}
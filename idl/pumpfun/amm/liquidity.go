@@ -0,0 +1,166 @@
+package amm
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"solana-pumpswap-demo/idl/pumpfun/amm/idl/generated/amm"
+
+	ag_solanago "github.com/gagliardetto/solana-go"
+)
+
+// LpTokenProgram is the token program of PumpSwap's LP mints, which are Token-2022 mints
+var LpTokenProgram = ag_solanago.Token2022ProgramID
+
+// ErrEmptyPool is returned when a pool has no LP supply or reserves to price liquidity against
+var ErrEmptyPool = errors.New("pool has no liquidity")
+
+type LiquidityDirection int
+
+const (
+	DepositDirection LiquidityDirection = iota
+	WithdrawDirection
+)
+
+type LiquidityParam struct {
+	// Direction
+	Direction LiquidityDirection
+	// Parameters:
+	LpTokenAmount uint64 // LpTokenAmountOut(Deposit) Or LpTokenAmountIn(Withdraw)
+	BaseAmount    uint64 // MaxBaseAmountIn(Deposit) Or MinBaseAmountOut(Withdraw)
+	QuoteAmount   uint64 // MaxQuoteAmountIn(Deposit) Or MinQuoteAmountOut(Withdraw)
+	// Accounts:
+	Pool                  ag_solanago.PublicKey
+	User                  ag_solanago.PublicKey
+	BaseMint              ag_solanago.PublicKey
+	QuoteMint             ag_solanago.PublicKey
+	LpMint                ag_solanago.PublicKey
+	UserBaseTokenAccount  ag_solanago.PublicKey
+	UserQuoteTokenAccount ag_solanago.PublicKey
+	UserPoolTokenAccount  ag_solanago.PublicKey
+	PoolBaseTokenAccount  ag_solanago.PublicKey
+	PoolQuoteTokenAccount ag_solanago.PublicKey
+}
+
+// NewLiquidityInstruction builds a deposit or withdraw
+func NewLiquidityInstruction(para *LiquidityParam) (ag_solanago.Instruction, error) {
+	switch para.Direction {
+	case DepositDirection:
+		deposit := amm.NewDepositInstruction(
+			para.LpTokenAmount, // LpTokenAmountOut
+			para.BaseAmount,    // MaxBaseAmountIn
+			para.QuoteAmount,   // MaxQuoteAmountIn
+			para.Pool,
+			PumpAmmGlobalConfigAddress,
+			para.User,
+			para.BaseMint,
+			para.QuoteMint,
+			para.LpMint,
+			para.UserBaseTokenAccount,
+			para.UserQuoteTokenAccount,
+			para.UserPoolTokenAccount,
+			para.PoolBaseTokenAccount,
+			para.PoolQuoteTokenAccount,
+			ag_solanago.TokenProgramID,
+			LpTokenProgram,
+			PumpAmmEventAuthorityAddress,
+			amm.ProgramID,
+		)
+		return deposit.ValidateAndBuild()
+	case WithdrawDirection:
+		withdraw := amm.NewWithdrawInstruction(
+			para.LpTokenAmount, // LpTokenAmountIn
+			para.BaseAmount,    // MinBaseAmountOut
+			para.QuoteAmount,   // MinQuoteAmountOut
+			para.Pool,
+			PumpAmmGlobalConfigAddress,
+			para.User,
+			para.BaseMint,
+			para.QuoteMint,
+			para.LpMint,
+			para.UserBaseTokenAccount,
+			para.UserQuoteTokenAccount,
+			para.UserPoolTokenAccount,
+			para.PoolBaseTokenAccount,
+			para.PoolQuoteTokenAccount,
+			ag_solanago.TokenProgramID,
+			LpTokenProgram,
+			PumpAmmEventAuthorityAddress,
+			amm.ProgramID,
+		)
+		return withdraw.ValidateAndBuild()
+	default:
+		return nil, fmt.Errorf("unknown liquidity direction: %d", para.Direction)
+	}
+}
+
+// FindUserPoolTokenAccount derives the user's associated token account for an LP mint under Token-2022
+func FindUserPoolTokenAccount(user, lpMint ag_solanago.PublicKey) (ag_solanago.PublicKey, error) {
+	return FindAssociatedTokenAccount(user, LpTokenProgram, lpMint)
+}
+
+// NewCreateUserPoolTokenAccountInstruction creates the user's LP token account if it does not exist.
+// The associated token account builder in solana-go only knows the legacy token program.
+func NewCreateUserPoolTokenAccountInstruction(payer, user, lpMint ag_solanago.PublicKey) (ag_solanago.Instruction, error) {
+	account, err := FindUserPoolTokenAccount(user, lpMint)
+	if err != nil {
+		return nil, err
+	}
+	return ag_solanago.NewInstruction(ag_solanago.SPLAssociatedTokenAccountProgramID, ag_solanago.AccountMetaSlice{
+		ag_solanago.Meta(payer).WRITE().SIGNER(),
+		ag_solanago.Meta(account).WRITE(),
+		ag_solanago.Meta(user),
+		ag_solanago.Meta(lpMint),
+		ag_solanago.Meta(ag_solanago.SystemProgramID),
+		ag_solanago.Meta(LpTokenProgram),
+	}, []byte{1}), nil // CreateIdempotent
+}
+
+// LpTokensForQuote returns the most LP tokens a deposit of quoteAmountIn can mint, rounded down
+func LpTokensForQuote(quoteAmountIn, lpSupply, quoteReserve uint64) (uint64, error) {
+	if lpSupply == 0 || quoteReserve == 0 {
+		return 0, ErrEmptyPool
+	}
+	return mulDiv(quoteAmountIn, lpSupply, quoteReserve, false), nil
+}
+
+// LpTokensForBase returns the most LP tokens a deposit of baseAmountIn can mint, rounded down
+func LpTokensForBase(baseAmountIn, lpSupply, baseReserve uint64) (uint64, error) {
+	if lpSupply == 0 || baseReserve == 0 {
+		return 0, ErrEmptyPool
+	}
+	return mulDiv(baseAmountIn, lpSupply, baseReserve, false), nil
+}
+
+// DepositAmounts returns the base and quote a deposit minting lpTokenAmountOut takes, rounded up as the program does
+func DepositAmounts(lpTokenAmountOut, lpSupply, baseReserve, quoteReserve uint64) (baseIn, quoteIn uint64, err error) {
+	if lpSupply == 0 {
+		return 0, 0, ErrEmptyPool
+	}
+	return mulDiv(lpTokenAmountOut, baseReserve, lpSupply, true), mulDiv(lpTokenAmountOut, quoteReserve, lpSupply, true), nil
+}
+
+// WithdrawAmounts returns the base and quote burning lpTokenAmountIn gives back, rounded down as the program does
+func WithdrawAmounts(lpTokenAmountIn, lpSupply, baseReserve, quoteReserve uint64) (baseOut, quoteOut uint64, err error) {
+	if lpSupply == 0 {
+		return 0, 0, ErrEmptyPool
+	}
+	if lpTokenAmountIn > lpSupply {
+		return 0, 0, fmt.Errorf("withdrawing %d LP tokens of a supply of %d", lpTokenAmountIn, lpSupply)
+	}
+	return mulDiv(lpTokenAmountIn, baseReserve, lpSupply, false), mulDiv(lpTokenAmountIn, quoteReserve, lpSupply, false), nil
+}
+
+// mulDiv returns a*b/c without overflowing, rounded up or down. Results past uint64 saturate.
+func mulDiv(a, b, c uint64, roundUp bool) uint64 {
+	n := new(big.Int).Mul(new(big.Int).SetUint64(a), new(big.Int).SetUint64(b))
+	d := new(big.Int).SetUint64(c)
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	if roundUp && r.Sign() > 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if !q.IsUint64() {
+		return ^uint64(0)
+	}
+	return q.Uint64()
+}
@@ -0,0 +1,55 @@
+package amm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"solana-pumpswap-demo/idl/pumpfun/amm/idl/generated/amm"
+	"solana-pumpswap-demo/idl/pumpfun/pump/idl/generated/pump"
+
+	ag_binary "github.com/gagliardetto/binary"
+	ag_solanago "github.com/gagliardetto/solana-go"
+)
+
+// FindPoolAddress derives the pool PDA from its index, creator and mints
+func FindPoolAddress(index uint16, creator, baseMint, quoteMint ag_solanago.PublicKey) (ag_solanago.PublicKey, error) {
+	indexBytes := make([]byte, 2)
+	binary.LittleEndian.PutUint16(indexBytes, index)
+	pool, _, err := ag_solanago.FindProgramAddress([][]byte{
+		[]byte("pool"),
+		indexBytes,
+		creator.Bytes(),
+		baseMint.Bytes(),
+		quoteMint.Bytes(),
+	}, amm.ProgramID)
+	if err != nil {
+		return ag_solanago.PublicKey{}, fmt.Errorf("failed to derive pool address: %w", err)
+	}
+	return pool, nil
+}
+
+// FindPoolAuthority derives the pump.fun PDA that creates the pool a completed bonding curve migrates into
+func FindPoolAuthority(mint ag_solanago.PublicKey) (ag_solanago.PublicKey, error) {
+	authority, _, err := ag_solanago.FindProgramAddress([][]byte{[]byte("pool-authority"), mint.Bytes()}, pump.ProgramID)
+	if err != nil {
+		return ag_solanago.PublicKey{}, fmt.Errorf("failed to derive pool authority: %w", err)
+	}
+	return authority, nil
+}
+
+// FindCanonicalPool derives the mint/WSOL pool pump.fun migrates a completed bonding curve into
+func FindCanonicalPool(mint ag_solanago.PublicKey) (ag_solanago.PublicKey, error) {
+	authority, err := FindPoolAuthority(mint)
+	if err != nil {
+		return ag_solanago.PublicKey{}, err
+	}
+	return FindPoolAddress(0, authority, mint, ag_solanago.WrappedSol)
+}
+
+// DecodePool decodes a pool account's data, checking its discriminator
+func DecodePool(data []byte) (*amm.PoolAccount, error) {
+	pool := new(amm.PoolAccount)
+	if err := pool.UnmarshalWithDecoder(ag_binary.NewBorshDecoder(data)); err != nil {
+		return nil, fmt.Errorf("failed to decode pool account: %w", err)
+	}
+	return pool, nil
+}
@@ -2,46 +2,61 @@ package pumpfun
 
 import (
 	"context"
-	"encoding/binary"
+	"errors"
 	"fmt"
 	"math/big"
 
+	"solana-pumpswap-demo/idl/pumpfun/pump/idl/generated/pump"
+
+	bin "github.com/gagliardetto/binary"
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 )
 
+// ErrCurveComplete is returned when trading on a bonding curve that has completed.
+// Its token has migrated to PumpSwap and trades there instead.
+var ErrCurveComplete = errors.New("bonding curve is complete")
+
 // BondingCurveData holds the relevant information decoded from the on-chain data.
 type BondingCurveData struct {
-	RealTokenReserves    *big.Int
 	VirtualTokenReserves *big.Int
 	VirtualSolReserves   *big.Int
+	RealTokenReserves    *big.Int
+	RealSolReserves      *big.Int
+	TokenTotalSupply     *big.Int
+	Complete             bool // The curve sold out, the token migrated to PumpSwap
 }
 
 func (b *BondingCurveData) String() string {
-	return fmt.Sprintf("RealTokenReserves=%s, VirtualTokenReserves=%s, VirtualSolReserves=%s", b.RealTokenReserves, b.VirtualTokenReserves, b.VirtualSolReserves)
+	return fmt.Sprintf("VirtualTokenReserves=%s, VirtualSolReserves=%s, RealTokenReserves=%s, RealSolReserves=%s, TokenTotalSupply=%s, Complete=%t",
+		b.VirtualTokenReserves, b.VirtualSolReserves, b.RealTokenReserves, b.RealSolReserves, b.TokenTotalSupply, b.Complete)
 }
 
-// FetchBondingCurve fetches the bonding curve data from the blockchain and decodes it.
-func FetchBondingCurve(rpcClient *rpc.Client, bondingCurvePubKey solana.PublicKey) (*BondingCurveData, error) {
-	accountInfo, err := rpcClient.GetAccountInfoWithOpts(context.TODO(), bondingCurvePubKey, &rpc.GetAccountInfoOpts{Encoding: solana.EncodingBase64, Commitment: rpc.CommitmentProcessed})
-	if err != nil || accountInfo.Value == nil {
+// FetchBondingCurve fetches the bonding curve account from the blockchain and decodes it.
+func FetchBondingCurve(ctx context.Context, rpcClient *rpc.Client, bondingCurvePubKey solana.PublicKey) (*BondingCurveData, error) {
+	accountInfo, err := rpcClient.GetAccountInfoWithOpts(ctx, bondingCurvePubKey, &rpc.GetAccountInfoOpts{Encoding: solana.EncodingBase64, Commitment: rpc.CommitmentProcessed})
+	if err != nil {
 		return nil, fmt.Errorf("FBCD: failed to get account info: %w", err)
 	}
-
-	data := accountInfo.Value.Data.GetBinary()
-	if len(data) < 24 {
-		return nil, fmt.Errorf("FBCD: insufficient data length")
+	if accountInfo == nil || accountInfo.Value == nil {
+		return nil, fmt.Errorf("FBCD: bonding curve %s not found", bondingCurvePubKey)
 	}
+	return DecodeBondingCurve(accountInfo.Value.Data.GetBinary())
+}
 
-	// Decode the bonding curve data assuming it follows little-endian format
-	realTokenReserves := big.NewInt(0).SetUint64(binary.LittleEndian.Uint64(data[0:8]))
-	virtualTokenReserves := big.NewInt(0).SetUint64(binary.LittleEndian.Uint64(data[8:16]))
-	virtualSolReserves := big.NewInt(0).SetUint64(binary.LittleEndian.Uint64(data[16:24]))
-
+// DecodeBondingCurve decodes bonding curve account data, checking its discriminator
+func DecodeBondingCurve(data []byte) (*BondingCurveData, error) {
+	var curve pump.BondingCurve
+	if err := curve.UnmarshalWithDecoder(bin.NewBorshDecoder(data)); err != nil {
+		return nil, fmt.Errorf("FBCD: failed to decode bonding curve: %w", err)
+	}
 	return &BondingCurveData{
-		RealTokenReserves:    realTokenReserves,
-		VirtualTokenReserves: virtualTokenReserves,
-		VirtualSolReserves:   virtualSolReserves,
+		VirtualTokenReserves: new(big.Int).SetUint64(curve.VirtualTokenReserves),
+		VirtualSolReserves:   new(big.Int).SetUint64(curve.VirtualSolReserves),
+		RealTokenReserves:    new(big.Int).SetUint64(curve.RealTokenReserves),
+		RealSolReserves:      new(big.Int).SetUint64(curve.RealSolReserves),
+		TokenTotalSupply:     new(big.Int).SetUint64(curve.TokenTotalSupply),
+		Complete:             curve.Complete,
 	}, nil
 }
 
@@ -1,11 +1,12 @@
 package pumpfun
 
 import (
+	"context"
 	"fmt"
 	"math/big"
 
-	"github.com/dexs-k/dexs-backend/pkg/pumpfun/pump/idl/generated/pump"
-	"github.com/dexs-k/dexs-backend/pkg/trade"
+	"solana-pumpswap-demo/idl/pumpfun/pump/idl/generated/pump"
+
 	aSDK "github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/programs/system"
 	"github.com/gagliardetto/solana-go/programs/token"
@@ -44,36 +45,61 @@ func CalculateBuyQuote(solAmount uint64, bondingCurve *BondingCurveData, percent
 	return finalTokensBig.Uint64()
 }
 
-func BuildBuyInstruction(user aSDK.PublicKey, tokenMint aSDK.PublicKey,
+// BuildBuyInstruction builds a buy spending solAmountUint64 lamports. With a price the minimum tokens out
+// come from it, otherwise the bonding curve is fetched and ErrCurveComplete returned if it has migrated.
+// feeBasisPoints is the program's fee, see FetchFee.
+func BuildBuyInstruction(ctx context.Context, user aSDK.PublicKey, tokenMint aSDK.PublicKey,
 	solAmountUint64 uint64, slippageBasisPoint uint32, rpcClient *rpc.Client,
-	price float64, inDecimal, outDecimal uint8) (aSDK.Instruction, error) {
+	price float64, inDecimal, outDecimal uint8, feeBasisPoints uint64) (aSDK.Instruction, error) {
 
 	/////////Going to build pumpfun buy instrustions /////
 	bondingCurveData, err := GetBondingCurveAndAssociatedBondingCurve(tokenMint)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get bonding curve data: %w", err)
 	}
-	var minAmountOut uint64
 	// 如果价格不为空 那么按照价格走而不是恒乘积走
 	if price != 0 {
-		minAmountOut, _, err = trade.CalcMinAmountOutByPrice(slippageBasisPoint, solAmountUint64, true, price, inDecimal, outDecimal, trade.PumpFee)
+		minAmountOut, _, err := CalcMinAmountOutByPrice(slippageBasisPoint, solAmountUint64, true, price, inDecimal, outDecimal, feeBasisPoints)
 		if err != nil {
 			return nil, err
 		}
-	} else {
-		bondingCurve, err := FetchBondingCurve(rpcClient, bondingCurveData.BondingCurve)
-		if err != nil {
-			return nil, fmt.Errorf("can't fetch bonding curve: %w", err)
-		}
+		return newBuyInstruction(user, tokenMint, bondingCurveData, minAmountOut, solAmountUint64)
+	}
 
-		slippage := big.NewFloat(float64(1))
-		slippage = slippage.Quo(big.NewFloat(float64(slippageBasisPoint)), big.NewFloat(float64(1e4)))
+	bondingCurve, err := FetchBondingCurve(ctx, rpcClient, bondingCurveData.BondingCurve)
+	if err != nil {
+		return nil, fmt.Errorf("can't fetch bonding curve: %w", err)
+	}
+	buyInstr, _, _, err := BuyFromCurve(user, tokenMint, solAmountUint64, slippageBasisPoint, bondingCurve, feeBasisPoints)
+	return buyInstr, err
+}
 
-		slippageF64, _ := slippage.Float64()
-		percentage := float64(1.0 - slippageF64)
-		minAmountOut = CalculateBuyQuote(solAmountUint64, bondingCurve, percentage)
+// BuyFromCurve builds a buy spending solAmount lamports, quoted against a bonding curve the caller already fetched.
+// It returns the instruction, the minimum tokens out it carries and the tokens expected before slippage,
+// or ErrCurveComplete if the curve has migrated. feeBasisPoints is the program's fee.
+func BuyFromCurve(user, tokenMint aSDK.PublicKey, solAmount uint64, slippageBasisPoint uint32,
+	bondingCurve *BondingCurveData, feeBasisPoints uint64) (buyInstr aSDK.Instruction, minAmountOut, amountOut uint64, err error) {
+	if bondingCurve.Complete {
+		return nil, 0, 0, ErrCurveComplete
 	}
+	bondingCurveData, err := GetBondingCurveAndAssociatedBondingCurve(tokenMint)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to get bonding curve data: %w", err)
+	}
+
+	// The fee is paid on top of the curve price, so only part of the SOL buys tokens
+	solIn := solAfterBuyFee(solAmount, feeBasisPoints)
+	minAmountOut = CalculateBuyQuote(solIn, bondingCurve, slippagePercentage(slippageBasisPoint))
+	buyInstr, err = newBuyInstruction(user, tokenMint, bondingCurveData, minAmountOut, solAmount)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return buyInstr, minAmountOut, CalculateBuyQuote(solIn, bondingCurve, 1), nil
+}
 
+// newBuyInstruction builds the buy instruction into the user's associated token account
+func newBuyInstruction(user, tokenMint aSDK.PublicKey, bondingCurveData *BondingCurvePublicKeys,
+	minAmountOut, solAmount uint64) (aSDK.Instruction, error) {
 	ata, _, err := aSDK.FindAssociatedTokenAddress(
 		user,
 		tokenMint,
@@ -84,7 +110,7 @@ func BuildBuyInstruction(user aSDK.PublicKey, tokenMint aSDK.PublicKey,
 
 	buyInstr := pump.NewBuyInstruction(
 		minAmountOut,
-		solAmountUint64,
+		solAmount,
 		GlobalPumpFunAddress,
 		PumpFunFeeRecipient,
 		tokenMint,
@@ -101,3 +127,12 @@ func BuildBuyInstruction(user aSDK.PublicKey, tokenMint aSDK.PublicKey,
 
 	return buyInstr.Build(), nil
 }
+
+// slippagePercentage turns slippage in basis points into the multiplier the curve quotes take, 0.98 for 200
+func slippagePercentage(slippageBasisPoint uint32) float64 {
+	slippage := big.NewFloat(float64(1))
+	slippage = slippage.Quo(big.NewFloat(float64(slippageBasisPoint)), big.NewFloat(float64(1e4)))
+
+	slippageF64, _ := slippage.Float64()
+	return float64(1.0 - slippageF64)
+}
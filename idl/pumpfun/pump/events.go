@@ -0,0 +1,442 @@
+// Hand-written, not generated: the pump package under idl/generated was produced by an anchor-go
+// version without event support, so unlike the amm package it has no events.go. The types below
+// follow the events and types sections of idl/idl.json, in the layout anchor-go gives the PumpSwap
+// events, and must be updated by hand with the IDL. internal/decoder holds the one discriminator
+// table for decoding them.
+
+package pumpfun
+
+import (
+	"fmt"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+)
+
+// CreateEventEventData is emitted when a token is launched on a bonding curve
+type CreateEventEventData struct {
+	Name         string
+	Symbol       string
+	Uri          string
+	Mint         solana.PublicKey
+	BondingCurve solana.PublicKey
+	User         solana.PublicKey
+}
+
+var CreateEventEventDataDiscriminator = [8]byte{27, 114, 169, 77, 222, 235, 99, 118}
+
+func (obj CreateEventEventData) MarshalWithEncoder(encoder *bin.Encoder) (err error) {
+	// Write account discriminator:
+	err = encoder.WriteBytes(CreateEventEventDataDiscriminator[:], false)
+	if err != nil {
+		return err
+	}
+	// Serialize `Name` param:
+	err = encoder.Encode(obj.Name)
+	if err != nil {
+		return err
+	}
+	// Serialize `Symbol` param:
+	err = encoder.Encode(obj.Symbol)
+	if err != nil {
+		return err
+	}
+	// Serialize `Uri` param:
+	err = encoder.Encode(obj.Uri)
+	if err != nil {
+		return err
+	}
+	// Serialize `Mint` param:
+	err = encoder.Encode(obj.Mint)
+	if err != nil {
+		return err
+	}
+	// Serialize `BondingCurve` param:
+	err = encoder.Encode(obj.BondingCurve)
+	if err != nil {
+		return err
+	}
+	// Serialize `User` param:
+	err = encoder.Encode(obj.User)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (obj *CreateEventEventData) UnmarshalWithDecoder(decoder *bin.Decoder) (err error) {
+	// Read and check account discriminator:
+	{
+		discriminator, err := decoder.ReadTypeID()
+		if err != nil {
+			return err
+		}
+		if !discriminator.Equal(CreateEventEventDataDiscriminator[:]) {
+			return fmt.Errorf(
+				"wrong discriminator: wanted %s, got %s",
+				"[27 114 169 77 222 235 99 118]",
+				fmt.Sprint(discriminator[:]))
+		}
+	}
+	// Deserialize `Name`:
+	err = decoder.Decode(&obj.Name)
+	if err != nil {
+		return err
+	}
+	// Deserialize `Symbol`:
+	err = decoder.Decode(&obj.Symbol)
+	if err != nil {
+		return err
+	}
+	// Deserialize `Uri`:
+	err = decoder.Decode(&obj.Uri)
+	if err != nil {
+		return err
+	}
+	// Deserialize `Mint`:
+	err = decoder.Decode(&obj.Mint)
+	if err != nil {
+		return err
+	}
+	// Deserialize `BondingCurve`:
+	err = decoder.Decode(&obj.BondingCurve)
+	if err != nil {
+		return err
+	}
+	// Deserialize `User`:
+	err = decoder.Decode(&obj.User)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (*CreateEventEventData) isEventData() {}
+
+// TradeEventEventData is emitted by every buy and sell on a bonding curve
+type TradeEventEventData struct {
+	Mint                 solana.PublicKey
+	SolAmount            uint64
+	TokenAmount          uint64
+	IsBuy                bool
+	User                 solana.PublicKey
+	Timestamp            int64
+	VirtualSolReserves   uint64
+	VirtualTokenReserves uint64
+	RealSolReserves      uint64
+	RealTokenReserves    uint64
+}
+
+var TradeEventEventDataDiscriminator = [8]byte{189, 219, 127, 211, 78, 230, 97, 238}
+
+func (obj TradeEventEventData) MarshalWithEncoder(encoder *bin.Encoder) (err error) {
+	// Write account discriminator:
+	err = encoder.WriteBytes(TradeEventEventDataDiscriminator[:], false)
+	if err != nil {
+		return err
+	}
+	// Serialize `Mint` param:
+	err = encoder.Encode(obj.Mint)
+	if err != nil {
+		return err
+	}
+	// Serialize `SolAmount` param:
+	err = encoder.Encode(obj.SolAmount)
+	if err != nil {
+		return err
+	}
+	// Serialize `TokenAmount` param:
+	err = encoder.Encode(obj.TokenAmount)
+	if err != nil {
+		return err
+	}
+	// Serialize `IsBuy` param:
+	err = encoder.Encode(obj.IsBuy)
+	if err != nil {
+		return err
+	}
+	// Serialize `User` param:
+	err = encoder.Encode(obj.User)
+	if err != nil {
+		return err
+	}
+	// Serialize `Timestamp` param:
+	err = encoder.Encode(obj.Timestamp)
+	if err != nil {
+		return err
+	}
+	// Serialize `VirtualSolReserves` param:
+	err = encoder.Encode(obj.VirtualSolReserves)
+	if err != nil {
+		return err
+	}
+	// Serialize `VirtualTokenReserves` param:
+	err = encoder.Encode(obj.VirtualTokenReserves)
+	if err != nil {
+		return err
+	}
+	// Serialize `RealSolReserves` param:
+	err = encoder.Encode(obj.RealSolReserves)
+	if err != nil {
+		return err
+	}
+	// Serialize `RealTokenReserves` param:
+	err = encoder.Encode(obj.RealTokenReserves)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (obj *TradeEventEventData) UnmarshalWithDecoder(decoder *bin.Decoder) (err error) {
+	// Read and check account discriminator:
+	{
+		discriminator, err := decoder.ReadTypeID()
+		if err != nil {
+			return err
+		}
+		if !discriminator.Equal(TradeEventEventDataDiscriminator[:]) {
+			return fmt.Errorf(
+				"wrong discriminator: wanted %s, got %s",
+				"[189 219 127 211 78 230 97 238]",
+				fmt.Sprint(discriminator[:]))
+		}
+	}
+	// Deserialize `Mint`:
+	err = decoder.Decode(&obj.Mint)
+	if err != nil {
+		return err
+	}
+	// Deserialize `SolAmount`:
+	err = decoder.Decode(&obj.SolAmount)
+	if err != nil {
+		return err
+	}
+	// Deserialize `TokenAmount`:
+	err = decoder.Decode(&obj.TokenAmount)
+	if err != nil {
+		return err
+	}
+	// Deserialize `IsBuy`:
+	err = decoder.Decode(&obj.IsBuy)
+	if err != nil {
+		return err
+	}
+	// Deserialize `User`:
+	err = decoder.Decode(&obj.User)
+	if err != nil {
+		return err
+	}
+	// Deserialize `Timestamp`:
+	err = decoder.Decode(&obj.Timestamp)
+	if err != nil {
+		return err
+	}
+	// Deserialize `VirtualSolReserves`:
+	err = decoder.Decode(&obj.VirtualSolReserves)
+	if err != nil {
+		return err
+	}
+	// Deserialize `VirtualTokenReserves`:
+	err = decoder.Decode(&obj.VirtualTokenReserves)
+	if err != nil {
+		return err
+	}
+	// Deserialize `RealSolReserves`:
+	err = decoder.Decode(&obj.RealSolReserves)
+	if err != nil {
+		return err
+	}
+	// Deserialize `RealTokenReserves`:
+	err = decoder.Decode(&obj.RealTokenReserves)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (*TradeEventEventData) isEventData() {}
+
+// CompleteEventEventData is emitted when a bonding curve sells its last token and can migrate
+type CompleteEventEventData struct {
+	User         solana.PublicKey
+	Mint         solana.PublicKey
+	BondingCurve solana.PublicKey
+	Timestamp    int64
+}
+
+var CompleteEventEventDataDiscriminator = [8]byte{95, 114, 97, 156, 212, 46, 152, 8}
+
+func (obj CompleteEventEventData) MarshalWithEncoder(encoder *bin.Encoder) (err error) {
+	// Write account discriminator:
+	err = encoder.WriteBytes(CompleteEventEventDataDiscriminator[:], false)
+	if err != nil {
+		return err
+	}
+	// Serialize `User` param:
+	err = encoder.Encode(obj.User)
+	if err != nil {
+		return err
+	}
+	// Serialize `Mint` param:
+	err = encoder.Encode(obj.Mint)
+	if err != nil {
+		return err
+	}
+	// Serialize `BondingCurve` param:
+	err = encoder.Encode(obj.BondingCurve)
+	if err != nil {
+		return err
+	}
+	// Serialize `Timestamp` param:
+	err = encoder.Encode(obj.Timestamp)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (obj *CompleteEventEventData) UnmarshalWithDecoder(decoder *bin.Decoder) (err error) {
+	// Read and check account discriminator:
+	{
+		discriminator, err := decoder.ReadTypeID()
+		if err != nil {
+			return err
+		}
+		if !discriminator.Equal(CompleteEventEventDataDiscriminator[:]) {
+			return fmt.Errorf(
+				"wrong discriminator: wanted %s, got %s",
+				"[95 114 97 156 212 46 152 8]",
+				fmt.Sprint(discriminator[:]))
+		}
+	}
+	// Deserialize `User`:
+	err = decoder.Decode(&obj.User)
+	if err != nil {
+		return err
+	}
+	// Deserialize `Mint`:
+	err = decoder.Decode(&obj.Mint)
+	if err != nil {
+		return err
+	}
+	// Deserialize `BondingCurve`:
+	err = decoder.Decode(&obj.BondingCurve)
+	if err != nil {
+		return err
+	}
+	// Deserialize `Timestamp`:
+	err = decoder.Decode(&obj.Timestamp)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (*CompleteEventEventData) isEventData() {}
+
+// SetParamsEventEventData is emitted when the program's global parameters change
+type SetParamsEventEventData struct {
+	FeeRecipient                solana.PublicKey
+	InitialVirtualTokenReserves uint64
+	InitialVirtualSolReserves   uint64
+	InitialRealTokenReserves    uint64
+	TokenTotalSupply            uint64
+	FeeBasisPoints              uint64
+}
+
+var SetParamsEventEventDataDiscriminator = [8]byte{223, 195, 159, 246, 62, 48, 143, 131}
+
+func (obj SetParamsEventEventData) MarshalWithEncoder(encoder *bin.Encoder) (err error) {
+	// Write account discriminator:
+	err = encoder.WriteBytes(SetParamsEventEventDataDiscriminator[:], false)
+	if err != nil {
+		return err
+	}
+	// Serialize `FeeRecipient` param:
+	err = encoder.Encode(obj.FeeRecipient)
+	if err != nil {
+		return err
+	}
+	// Serialize `InitialVirtualTokenReserves` param:
+	err = encoder.Encode(obj.InitialVirtualTokenReserves)
+	if err != nil {
+		return err
+	}
+	// Serialize `InitialVirtualSolReserves` param:
+	err = encoder.Encode(obj.InitialVirtualSolReserves)
+	if err != nil {
+		return err
+	}
+	// Serialize `InitialRealTokenReserves` param:
+	err = encoder.Encode(obj.InitialRealTokenReserves)
+	if err != nil {
+		return err
+	}
+	// Serialize `TokenTotalSupply` param:
+	err = encoder.Encode(obj.TokenTotalSupply)
+	if err != nil {
+		return err
+	}
+	// Serialize `FeeBasisPoints` param:
+	err = encoder.Encode(obj.FeeBasisPoints)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (obj *SetParamsEventEventData) UnmarshalWithDecoder(decoder *bin.Decoder) (err error) {
+	// Read and check account discriminator:
+	{
+		discriminator, err := decoder.ReadTypeID()
+		if err != nil {
+			return err
+		}
+		if !discriminator.Equal(SetParamsEventEventDataDiscriminator[:]) {
+			return fmt.Errorf(
+				"wrong discriminator: wanted %s, got %s",
+				"[223 195 159 246 62 48 143 131]",
+				fmt.Sprint(discriminator[:]))
+		}
+	}
+	// Deserialize `FeeRecipient`:
+	err = decoder.Decode(&obj.FeeRecipient)
+	if err != nil {
+		return err
+	}
+	// Deserialize `InitialVirtualTokenReserves`:
+	err = decoder.Decode(&obj.InitialVirtualTokenReserves)
+	if err != nil {
+		return err
+	}
+	// Deserialize `InitialVirtualSolReserves`:
+	err = decoder.Decode(&obj.InitialVirtualSolReserves)
+	if err != nil {
+		return err
+	}
+	// Deserialize `InitialRealTokenReserves`:
+	err = decoder.Decode(&obj.InitialRealTokenReserves)
+	if err != nil {
+		return err
+	}
+	// Deserialize `TokenTotalSupply`:
+	err = decoder.Decode(&obj.TokenTotalSupply)
+	if err != nil {
+		return err
+	}
+	// Deserialize `FeeBasisPoints`:
+	err = decoder.Decode(&obj.FeeBasisPoints)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (*SetParamsEventEventData) isEventData() {}
+
+// EventData is implemented by the event types
+type EventData interface {
+	UnmarshalWithDecoder(decoder *bin.Decoder) error
+	isEventData()
+}
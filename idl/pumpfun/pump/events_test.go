@@ -0,0 +1,52 @@
+package pumpfun
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+)
+
+type encodable interface {
+	MarshalWithEncoder(*bin.Encoder) error
+}
+
+func encodeEvent(t *testing.T, event encodable) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	if err := event.MarshalWithEncoder(bin.NewBorshEncoder(buf)); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestEventRoundTrip tests that each event decodes to what it was encoded from, behind its discriminator
+func TestEventRoundTrip(t *testing.T) {
+	user, mint, curve := solana.PublicKey{1}, solana.PublicKey{2}, solana.PublicKey{3}
+	tests := []struct {
+		event         EventData
+		discriminator [8]byte
+	}{
+		{&CreateEventEventData{Name: "Test", Symbol: "TST", Uri: "https://example.com", Mint: mint, BondingCurve: curve, User: user}, CreateEventEventDataDiscriminator},
+		{&TradeEventEventData{Mint: mint, SolAmount: 1_000_000, TokenAmount: 35_000_000, IsBuy: true, User: user, Timestamp: 1745000000,
+			VirtualSolReserves: 30_000_000_000, VirtualTokenReserves: 1_073_000_000_000_000}, TradeEventEventDataDiscriminator},
+		{&CompleteEventEventData{User: user, Mint: mint, BondingCurve: curve, Timestamp: 1745000000}, CompleteEventEventDataDiscriminator},
+		{&SetParamsEventEventData{FeeRecipient: user, InitialVirtualTokenReserves: 1, InitialVirtualSolReserves: 2, InitialRealTokenReserves: 3, TokenTotalSupply: 4, FeeBasisPoints: 100},
+			SetParamsEventEventDataDiscriminator},
+	}
+	for _, tt := range tests {
+		data := encodeEvent(t, tt.event.(encodable))
+		if !bytes.Equal(data[:8], tt.discriminator[:]) {
+			t.Errorf("%T encoded with discriminator %v", tt.event, data[:8])
+		}
+		got := reflect.New(reflect.TypeOf(tt.event).Elem()).Interface().(EventData)
+		if err := got.UnmarshalWithDecoder(bin.NewBorshDecoder(data)); err != nil {
+			t.Fatalf("%T UnmarshalWithDecoder() error = %v", tt.event, err)
+		}
+		if !reflect.DeepEqual(got, tt.event) {
+			t.Errorf("decoded %+v, want %+v", got, tt.event)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package pumpfun
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"solana-pumpswap-demo/idl/pumpfun/pump/idl/generated/pump"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// DefaultFeeBasisPoints is the bonding curve's trading fee, 1%
+const DefaultFeeBasisPoints uint64 = 100
+
+// FetchGlobal fetches and decodes pump.fun's Global account
+func FetchGlobal(ctx context.Context, rpcClient *rpc.Client) (*pump.Global, error) {
+	accountInfo, err := rpcClient.GetAccountInfoWithOpts(ctx, GlobalPumpFunAddress, &rpc.GetAccountInfoOpts{Encoding: solana.EncodingBase64, Commitment: rpc.CommitmentConfirmed})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get global account: %w", err)
+	}
+	if accountInfo == nil || accountInfo.Value == nil {
+		return nil, fmt.Errorf("global account %s not found", GlobalPumpFunAddress)
+	}
+	return DecodeGlobal(accountInfo.Value.Data.GetBinary())
+}
+
+// DecodeGlobal decodes the Global account's data, checking its discriminator
+func DecodeGlobal(data []byte) (*pump.Global, error) {
+	global := new(pump.Global)
+	if err := global.UnmarshalWithDecoder(bin.NewBorshDecoder(data)); err != nil {
+		return nil, fmt.Errorf("failed to decode global account: %w", err)
+	}
+	return global, nil
+}
+
+// FetchFee returns the trading fee in basis points from the Global account, for the builders' feeBasisPoints
+func FetchFee(ctx context.Context, rpcClient *rpc.Client) (uint64, error) {
+	global, err := FetchGlobal(ctx, rpcClient)
+	if err != nil {
+		return 0, err
+	}
+	return global.FeeBasisPoints, nil
+}
+
+// solAfterBuyFee returns the SOL that reaches the curve when a buy spends solAmount.
+// The fee is charged on top of the curve's price, so solAmount covers price plus fee.
+func solAfterBuyFee(solAmount, feeBasisPoints uint64) uint64 {
+	return mulDiv(solAmount, 10000, 10000+feeBasisPoints)
+}
+
+// solAfterSellFee returns what a seller receives when the curve pays out solAmount
+func solAfterSellFee(solAmount, feeBasisPoints uint64) uint64 {
+	return solAmount - mulDiv(solAmount, feeBasisPoints, 10000)
+}
+
+// mulDiv returns floor(a*b/c) with the product held in a big.Int, so large amounts don't wrap.
+// Callers keep b <= c, so the result always fits in a uint64.
+func mulDiv(a, b, c uint64) uint64 {
+	n := new(big.Int).Mul(new(big.Int).SetUint64(a), new(big.Int).SetUint64(b))
+	return n.Quo(n, new(big.Int).SetUint64(c)).Uint64()
+}
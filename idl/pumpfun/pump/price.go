@@ -0,0 +1,42 @@
+package pumpfun
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// CalcMinAmountOutByPrice quotes a trade at a known price instead of against the curve's reserves,
+// for when the price is already tracked and fetching the bonding curve would cost a round trip.
+// price is SOL per token in UI units. amountIn is raw lamports for a buy and raw token units for a sell,
+// inDecimal and outDecimal are the decimals of the input and output. The fee is charged on the SOL side.
+// It returns the output after slippage, used as the instruction limit, and the expected output.
+func CalcMinAmountOutByPrice(slippageBasisPoint uint32, amountIn uint64, isBuy bool, price float64,
+	inDecimal, outDecimal uint8, feeBasisPoints uint64) (minAmountOut uint64, amountOut uint64, err error) {
+	if slippageBasisPoint >= 10000 {
+		return 0, 0, fmt.Errorf("slippage %d bps must be below 10000", slippageBasisPoint)
+	}
+	if price <= 0 {
+		return 0, 0, fmt.Errorf("price %v must be positive", price)
+	}
+
+	priceDec := decimal.NewFromFloat(price)
+	var out decimal.Decimal
+	if isBuy {
+		in := decimal.NewFromUint64(solAfterBuyFee(amountIn, feeBasisPoints)).Shift(-int32(inDecimal))
+		out = in.Div(priceDec).Shift(int32(outDecimal)).Floor()
+	} else {
+		in := decimal.NewFromUint64(amountIn).Shift(-int32(inDecimal))
+		lamports := in.Mul(priceDec).Shift(int32(outDecimal)).Floor()
+		out = decimal.NewFromUint64(solAfterSellFee(uint64(lamports.IntPart()), feeBasisPoints))
+	}
+	if out.LessThanOrEqual(decimal.Zero) {
+		return 0, 0, fmt.Errorf("calculated amount out is zero or negative")
+	}
+
+	minOut := out.Mul(decimal.NewFromUint64(10000 - uint64(slippageBasisPoint))).Div(decimal.NewFromUint64(10000)).Floor()
+	if minOut.LessThanOrEqual(decimal.Zero) {
+		return 0, 0, fmt.Errorf("calculated amount out is zero or negative")
+	}
+	return uint64(minOut.IntPart()), uint64(out.IntPart()), nil
+}
@@ -3,7 +3,8 @@ package pumpfun
 import (
 	"fmt"
 
-	"github.com/dexs-k/dexs-backend/pkg/pumpfun/pump/idl/generated/pump"
+	"solana-pumpswap-demo/idl/pumpfun/pump/idl/generated/pump"
+
 	"github.com/gagliardetto/solana-go"
 )
 
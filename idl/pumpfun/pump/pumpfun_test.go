@@ -0,0 +1,202 @@
+package pumpfun
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"solana-pumpswap-demo/idl/pumpfun/pump/idl/generated/pump"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+)
+
+// TestCalcMinAmountOutByPrice tests price-based quotes at 0.0000001 SOL per token with the 1% fee
+func TestCalcMinAmountOutByPrice(t *testing.T) {
+	tests := []struct {
+		name       string
+		slippage   uint32
+		amountIn   uint64
+		isBuy      bool
+		price      float64
+		wantMinOut uint64
+		wantOut    uint64
+		wantErr    bool
+	}{
+		{
+			name:       "buy",
+			slippage:   100,
+			amountIn:   1_000_000_000, // 1 SOL, of which 1/1.01 reaches the curve
+			isBuy:      true,
+			price:      0.0000001,
+			wantMinOut: 9_801_980_189_100,
+			wantOut:    9_900_990_090_000,
+		},
+		{
+			name:       "sell",
+			slippage:   50,
+			amountIn:   1_000_000_000_000, // 1M tokens worth 0.1 SOL before the fee
+			price:      0.0000001,
+			wantMinOut: 98_505_000,
+			wantOut:    99_000_000,
+		},
+		{name: "no price", slippage: 50, amountIn: 1_000, isBuy: true, wantErr: true},
+		{name: "full slippage", slippage: 10000, amountIn: 1_000, isBuy: true, price: 1, wantErr: true},
+		{name: "dust", slippage: 50, amountIn: 1, price: 0.0000001, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inDecimal, outDecimal := uint8(6), uint8(9)
+			if tt.isBuy {
+				inDecimal, outDecimal = 9, 6
+			}
+			minOut, out, err := CalcMinAmountOutByPrice(tt.slippage, tt.amountIn, tt.isBuy, tt.price, inDecimal, outDecimal, DefaultFeeBasisPoints)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CalcMinAmountOutByPrice() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if minOut != tt.wantMinOut || out != tt.wantOut {
+				t.Errorf("CalcMinAmountOutByPrice() = %d, %d; want %d, %d", minOut, out, tt.wantMinOut, tt.wantOut)
+			}
+		})
+	}
+}
+
+// TestCurveQuotes tests that selling what a buy returned gives back the SOL spent, less rounding
+func TestCurveQuotes(t *testing.T) {
+	curve := &BondingCurveData{
+		RealTokenReserves:    big.NewInt(793_100_000_000_000),
+		VirtualTokenReserves: big.NewInt(1_073_000_000_000_000),
+		VirtualSolReserves:   big.NewInt(30_000_000_000),
+	}
+	const sol = 1_000_000_000
+
+	tokens := CalculateBuyQuote(sol, curve, 1)
+	if tokens != 34_612_903_225_807 {
+		t.Fatalf("CalculateBuyQuote() = %d, want 34612903225807", tokens)
+	}
+	if withSlippage := CalculateBuyQuote(sol, curve, 0.99); withSlippage > tokens*99/100 || withSlippage < tokens*99/100-1 {
+		t.Errorf("CalculateBuyQuote() with 1%% slippage = %d, want about %d", withSlippage, tokens*99/100)
+	}
+
+	after := &BondingCurveData{
+		RealTokenReserves:    new(big.Int).Sub(curve.RealTokenReserves, big.NewInt(int64(tokens))),
+		VirtualTokenReserves: new(big.Int).Sub(curve.VirtualTokenReserves, big.NewInt(int64(tokens))),
+		VirtualSolReserves:   new(big.Int).Add(curve.VirtualSolReserves, big.NewInt(sol)),
+	}
+	_, out := calculateSellQuote(tokens, after, 1)
+	if out < sol-1 || out > sol+1 {
+		t.Errorf("calculateSellQuote() = %d, want %d within rounding", out, sol)
+	}
+}
+
+// TestFees tests the fee on each side of a trade
+func TestFees(t *testing.T) {
+	if got := solAfterBuyFee(1_010_000_000, 100); got != 1_000_000_000 {
+		t.Errorf("solAfterBuyFee() = %d, want 1000000000", got)
+	}
+	if got := solAfterSellFee(1_000_000_000, 100); got != 990_000_000 {
+		t.Errorf("solAfterSellFee() = %d, want 990000000", got)
+	}
+	// amount*10000 no longer fits in a uint64 past ~1.8e15 lamports
+	const large = 10_000_000_000_000_000
+	if got := solAfterBuyFee(large+large/100, 100); got != large {
+		t.Errorf("solAfterBuyFee() = %d, want %d", got, uint64(large))
+	}
+	if got := solAfterSellFee(large, 100); got != large-large/100 {
+		t.Errorf("solAfterSellFee() = %d, want %d", got, uint64(large-large/100))
+	}
+}
+
+// TestDecodeGlobal tests reading the fee from Global account data and rejecting other accounts
+func TestDecodeGlobal(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := (pump.Global{Initialized: true, FeeBasisPoints: 95}).MarshalWithEncoder(bin.NewBorshEncoder(buf)); err != nil {
+		t.Fatal(err)
+	}
+	global, err := DecodeGlobal(buf.Bytes())
+	if err != nil {
+		t.Fatalf("DecodeGlobal() error = %v", err)
+	}
+	if global.FeeBasisPoints != 95 {
+		t.Errorf("FeeBasisPoints = %d, want 95", global.FeeBasisPoints)
+	}
+
+	buf.Reset()
+	if err := (pump.BondingCurve{}).MarshalWithEncoder(bin.NewBorshEncoder(buf)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeGlobal(buf.Bytes()); err == nil {
+		t.Error("DecodeGlobal() accepted bonding curve data")
+	}
+}
+
+// TestBuildBuyInstructionByPrice tests building a buy from a known price, which needs no RPC calls
+func TestBuildBuyInstructionByPrice(t *testing.T) {
+	user := solana.NewWallet().PublicKey()
+	mint := solana.NewWallet().PublicKey()
+
+	ix, err := BuildBuyInstruction(context.Background(), user, mint, 1_000_000_000, 100, nil, 0.0000001, 9, 6, DefaultFeeBasisPoints)
+	if err != nil {
+		t.Fatalf("BuildBuyInstruction() error = %v", err)
+	}
+	if !ix.ProgramID().Equals(pump.ProgramID) {
+		t.Errorf("program = %s, want %s", ix.ProgramID(), pump.ProgramID)
+	}
+
+	keys, err := GetBondingCurveAndAssociatedBondingCurve(mint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accounts := ix.Accounts()
+	if !accounts[2].PublicKey.Equals(mint) || !accounts[3].PublicKey.Equals(keys.BondingCurve) ||
+		!accounts[4].PublicKey.Equals(keys.AssociatedBondingCurve) || !accounts[6].PublicKey.Equals(user) || !accounts[6].IsSigner {
+		t.Errorf("accounts = %v", accounts)
+	}
+
+	data, err := ix.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := pump.DecodeInstruction(accounts, data)
+	if err != nil {
+		t.Fatalf("DecodeInstruction() error = %v", err)
+	}
+	buy, ok := decoded.Impl.(*pump.Buy)
+	if !ok || *buy.Amount != 9_801_980_189_100 || *buy.MaxSolCost != 1_000_000_000 {
+		t.Errorf("buy = %+v", decoded.Impl)
+	}
+}
+
+// TestDecodeBondingCurve tests decoding every bonding curve field after the discriminator
+func TestDecodeBondingCurve(t *testing.T) {
+	want := pump.BondingCurve{
+		VirtualTokenReserves: 1_073_000_000_000_000,
+		VirtualSolReserves:   30_000_000_000,
+		RealTokenReserves:    793_100_000_000_000,
+		RealSolReserves:      85_000_000_000,
+		TokenTotalSupply:     1_000_000_000_000_000,
+		Complete:             true,
+	}
+	buf := new(bytes.Buffer)
+	if err := want.MarshalWithEncoder(bin.NewBorshEncoder(buf)); err != nil {
+		t.Fatal(err)
+	}
+	curve, err := DecodeBondingCurve(buf.Bytes())
+	if err != nil {
+		t.Fatalf("DecodeBondingCurve() error = %v", err)
+	}
+	if curve.VirtualTokenReserves.Uint64() != want.VirtualTokenReserves || curve.VirtualSolReserves.Uint64() != want.VirtualSolReserves ||
+		curve.RealTokenReserves.Uint64() != want.RealTokenReserves || curve.RealSolReserves.Uint64() != want.RealSolReserves ||
+		curve.TokenTotalSupply.Uint64() != want.TokenTotalSupply || !curve.Complete {
+		t.Errorf("DecodeBondingCurve() = %s", curve)
+	}
+
+	buf.Reset()
+	if err := (pump.Global{}).MarshalWithEncoder(bin.NewBorshEncoder(buf)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeBondingCurve(buf.Bytes()); err == nil {
+		t.Error("DecodeBondingCurve() accepted global account data")
+	}
+}
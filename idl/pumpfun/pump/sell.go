@@ -7,8 +7,8 @@ import (
 	"math/big"
 	"strconv"
 
-	"github.com/dexs-k/dexs-backend/pkg/pumpfun/pump/idl/generated/pump"
-	"github.com/dexs-k/dexs-backend/pkg/trade"
+	"solana-pumpswap-demo/idl/pumpfun/pump/idl/generated/pump"
+
 	"github.com/gagliardetto/solana-go"
 	associatedtokenaccount "github.com/gagliardetto/solana-go/programs/associated-token-account"
 	"github.com/gagliardetto/solana-go/programs/system"
@@ -16,11 +16,13 @@ import (
 	"github.com/gagliardetto/solana-go/rpc"
 )
 
-// BuildSellInstruction is a function that returns the pump.fun instructions to sell the token
-func BuildSellInstruction(ata, user, mint solana.PublicKey, sellTokenAmount uint64, slippageBasisPoint uint32,
-	all bool, rpcClient *rpc.Client, price float64, inDecimal, outDecimal uint8) (*pump.Instruction, uint64, error) {
+// BuildSellInstruction is a function that returns the pump.fun instructions to sell the token.
+// Without a price the bonding curve is fetched and ErrCurveComplete returned if it has migrated.
+// feeBasisPoints is the program's fee, see FetchFee.
+func BuildSellInstruction(ctx context.Context, ata, user, mint solana.PublicKey, sellTokenAmount uint64, slippageBasisPoint uint32,
+	all bool, rpcClient *rpc.Client, price float64, inDecimal, outDecimal uint8, feeBasisPoints uint64) (*pump.Instruction, uint64, error) {
 	if all {
-		tokenAccounts, err := rpcClient.GetTokenAccountBalance(context.TODO(), ata, rpc.CommitmentConfirmed)
+		tokenAccounts, err := rpcClient.GetTokenAccountBalance(ctx, ata, rpc.CommitmentConfirmed)
 		if err != nil {
 			return nil, 0, fmt.Errorf("can't get amount of token in balance: %w", err)
 		}
@@ -35,33 +37,57 @@ func BuildSellInstruction(ata, user, mint solana.PublicKey, sellTokenAmount uint
 		return nil, 0, fmt.Errorf("can't get bonding curve data: %w", err)
 	}
 
-	var minSolOutputUint64, solOutput uint64
 	// 如果价格不为空 那么按照价格走而不是恒乘积走
 	if price != 0 {
-		minSolOutputUint64, solOutput, err = trade.CalcMinAmountOutByPrice(slippageBasisPoint, sellTokenAmount, false, price, inDecimal, outDecimal, trade.PumpFee)
+		minSolOutputUint64, solOutput, err := CalcMinAmountOutByPrice(slippageBasisPoint, sellTokenAmount, false, price, inDecimal, outDecimal, feeBasisPoints)
 		if err != nil {
 			return nil, 0, err
 		}
-	} else {
-		bondingCurve, err := FetchBondingCurve(rpcClient, bondingCurveData.BondingCurve)
+		sell, err := newSellInstruction(ata, user, mint, bondingCurveData, sellTokenAmount, minSolOutputUint64)
 		if err != nil {
-			return nil, 0, fmt.Errorf("can't fetch bonding curve: %w", err)
+			return nil, 0, err
 		}
+		return sell, solOutput, nil
+	}
 
-		//percentage := float64(1.0 - (slippageBasisPoint / 10e3))
+	bondingCurve, err := FetchBondingCurve(ctx, rpcClient, bondingCurveData.BondingCurve)
+	if err != nil {
+		return nil, 0, fmt.Errorf("can't fetch bonding curve: %w", err)
+	}
+	sell, _, solOutput, err := SellFromCurve(ata, user, mint, sellTokenAmount, slippageBasisPoint, bondingCurve, feeBasisPoints)
+	return sell, solOutput, err
+}
 
-		slippage := big.NewFloat(float64(1))
-		slippage = slippage.Quo(big.NewFloat(float64(slippageBasisPoint)), big.NewFloat(float64(1e4)))
+// SellFromCurve builds a sell of sellTokenAmount from ata, quoted against a bonding curve the caller already fetched.
+// It returns the instruction, the minimum SOL out it carries and the SOL expected after the fee,
+// or ErrCurveComplete if the curve has migrated. feeBasisPoints is the program's fee.
+func SellFromCurve(ata, user, mint solana.PublicKey, sellTokenAmount uint64, slippageBasisPoint uint32,
+	bondingCurve *BondingCurveData, feeBasisPoints uint64) (*pump.Instruction, uint64, uint64, error) {
+	if bondingCurve.Complete {
+		return nil, 0, 0, ErrCurveComplete
+	}
+	bondingCurveData, err := GetBondingCurveAndAssociatedBondingCurve(mint)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("can't get bonding curve data: %w", err)
+	}
 
-		slippageF64, _ := slippage.Float64()
-		percentage := float64(1.0 - slippageF64)
+	minSolOutputUint64, solOutput := calculateSellQuote(sellTokenAmount, bondingCurve, slippagePercentage(slippageBasisPoint))
+	// The fee comes out of what the curve pays
+	minSolOutputUint64, solOutput = solAfterSellFee(minSolOutputUint64, feeBasisPoints), solAfterSellFee(solOutput, feeBasisPoints)
 
-		minSolOutputUint64, solOutput = calculateSellQuote(sellTokenAmount, bondingCurve, percentage)
+	sell, err := newSellInstruction(ata, user, mint, bondingCurveData, sellTokenAmount, minSolOutputUint64)
+	if err != nil {
+		return nil, 0, 0, err
 	}
+	return sell, minSolOutputUint64, solOutput, nil
+}
 
+// newSellInstruction builds and validates the sell instruction from ata
+func newSellInstruction(ata, user, mint solana.PublicKey, bondingCurveData *BondingCurvePublicKeys,
+	sellTokenAmount, minSolOutput uint64) (*pump.Instruction, error) {
 	sellInstr := pump.NewSellInstruction(
 		sellTokenAmount,
-		minSolOutputUint64,
+		minSolOutput,
 		GlobalPumpFunAddress,
 		PumpFunFeeRecipient,
 		mint,
@@ -77,9 +103,9 @@ func BuildSellInstruction(ata, user, mint solana.PublicKey, sellTokenAmount uint
 	)
 	sell, err := sellInstr.ValidateAndBuild()
 	if err != nil {
-		return nil, 0, fmt.Errorf("can't validate and build sell instruction: %w", err)
+		return nil, fmt.Errorf("can't validate and build sell instruction: %w", err)
 	}
-	return sell, solOutput, nil
+	return sell, nil
 }
 
 // calculateSellQuote calculates how many SOL should be received for selling a specific amount of tokens, given a specific amount of token, bonding curve data, and percentage.
@@ -0,0 +1,459 @@
+// Package backtest replays recorded PumpSwap trades in time order against the offline pool simulator
+// with the copy strategy: buys copied from a leader wallet, sized, delayed, checked against the risk
+// limits and exited by the exit rules, to estimate what copying the wallet would have returned.
+//
+// Each recorded trade resets its pool to the reserves the trade left behind, so our own fills move
+// the price only until the next recorded trade in that pool.
+package backtest
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"solana-pumpswap-demo/internal/decoder"
+	"solana-pumpswap-demo/internal/exits"
+	"solana-pumpswap-demo/internal/risk"
+	"solana-pumpswap-demo/internal/simulator"
+	"solana-pumpswap-demo/internal/swapper"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+)
+
+// Reasons of the fills that are not exit rules
+const (
+	ReasonCopy       = "copy"
+	ReasonLeaderSell = "leader-sell"
+)
+
+// Trade is a recorded PumpSwap swap of the replayed stream
+type Trade struct {
+	Signature string
+	Slot      uint64
+	decoder.Trade
+}
+
+// Time is when the trade landed
+func (t Trade) Time() time.Time {
+	return time.Unix(t.Timestamp, 0).UTC()
+}
+
+// Config is the copy strategy under test
+type Config struct {
+	Leader        solana.PublicKey // Wallet whose trades are copied, zero copies every trade in the stream
+	Ratio         decimal.Decimal  // Copy buys spend this fraction of the leader's SOL, e.g. 1 to match it
+	FixedLamports uint64           // Spend this on every copy buy instead of a fraction of the leader's
+	Latency       time.Duration    // Between the leader's trade and our order landing
+	SlippageBps   uint32           // Slippage allowed when the order is built, as the router does
+	FeeLamports   uint64           // Network and priority fees of each transaction we send
+	FollowSells   bool             // Sell the same fraction of our position when the leader sells
+	Exits         exits.Rules
+	Limits        risk.Limits
+}
+
+// Fill is an order the strategy sent. Orders that did not execute have Error set and no amounts.
+type Fill struct {
+	Time        time.Time
+	Source      string // Leader trade that triggered the order, empty for exits
+	Pool        solana.PublicKey
+	Mint        solana.PublicKey // Zero if the stream did not name it
+	Side        string
+	Reason      string          // ReasonCopy, ReasonLeaderSell or the exit rule
+	BaseAmount  uint64          // Tokens bought or sold, raw units
+	QuoteAmount uint64          // Lamports spent by a buy or received by a sell, fees excluded
+	Price       decimal.Decimal // Lamports per raw base unit paid or received
+	PnL         int64           // Realized lamports of a sell against the average cost, fees included
+	Error       string          // Risk rejection or on-chain failure such as ExceededSlippage
+}
+
+// Report is the outcome of a backtest, amounts in lamports
+type Report struct {
+	Fills         []Fill
+	Trades        int   // Recorded trades replayed
+	RealizedPnL   int64 // Fees of failed orders included
+	UnrealizedPnL int64 // Open positions valued as a sell into their pool at the end
+	FeesPaid      uint64
+	MaxDrawdown   int64 // Largest fall of realized plus unrealized PnL from its running peak
+	Wins, Losses  int   // Positions closed with a profit or a loss
+	BreakEven     int   // Positions closed with a PnL of exactly zero, in neither Wins nor Losses
+	OpenPositions int
+}
+
+// HitRate is the share of closed positions that made money, zero when none closed.
+// Break-even positions are left out, so they neither raise nor lower it.
+func (r Report) HitRate() decimal.Decimal {
+	if r.Wins+r.Losses == 0 {
+		return decimal.Zero
+	}
+	return decimal.NewFromInt(int64(r.Wins)).Div(decimal.NewFromInt(int64(r.Wins + r.Losses)))
+}
+
+// position is a holding in one pool with its average cost
+type position struct {
+	exits.Position
+	mint        solana.PublicKey
+	cost        uint64 // Lamports, fees included, of the tokens still held
+	pnl         int64  // Realized by the sells so far
+	pendingSell bool   // An exit is in flight, the rules are not evaluated again until it lands
+}
+
+// order is a decided trade waiting out the latency
+type order struct {
+	due    time.Time
+	source string
+	pool   solana.PublicKey
+	mint   solana.PublicKey
+	side   string
+	reason string
+	amount uint64 // MaxQuoteAmountIn of a buy, BaseAmountIn of a sell
+	limit  uint64 // BaseAmountOut of a buy, MinQuoteAmountOut of a sell
+}
+
+// run is the state of one backtest
+type run struct {
+	cfg       Config
+	engine    *risk.Engine
+	pools     map[solana.PublicKey]*simulator.Pool
+	positions map[solana.PublicKey]*position
+	leader    map[solana.PublicKey]uint64 // Leader's token holdings per pool, as seen in the stream
+	pending   []order
+	nextID    int
+	peak      int64
+	report    Report
+}
+
+// Run replays the trades, sorted by time, through the strategy
+func Run(trades []Trade, cfg Config) (*Report, error) {
+	if cfg.FixedLamports == 0 && !cfg.Ratio.IsPositive() {
+		return nil, errors.New("copy buys need a positive ratio or a fixed size")
+	}
+	if cfg.SlippageBps >= 10000 {
+		return nil, fmt.Errorf("slippage %d bps must be below 10000", cfg.SlippageBps)
+	}
+	sorted := make([]Trade, len(trades))
+	copy(sorted, trades)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Timestamp != b.Timestamp {
+			return a.Timestamp < b.Timestamp
+		}
+		if a.Slot != b.Slot {
+			return a.Slot < b.Slot
+		}
+		return a.Instruction < b.Instruction
+	})
+
+	r := &run{
+		cfg:       cfg,
+		engine:    risk.NewEngine(cfg.Limits),
+		pools:     map[solana.PublicKey]*simulator.Pool{},
+		positions: map[solana.PublicKey]*position{},
+		leader:    map[solana.PublicKey]uint64{},
+	}
+	for _, t := range sorted {
+		if t.Venue != decoder.VenuePumpSwap || t.PoolQuoteReserve == 0 || t.PoolBaseReserve == 0 {
+			continue
+		}
+		r.fillDue(func(o order) bool { return o.due.Before(t.Time()) })
+		r.replay(t)
+		r.mark()
+	}
+	r.fillDue(func(order) bool { return true })
+
+	r.report.UnrealizedPnL = r.unrealized()
+	for _, p := range r.positions {
+		if p.Amount > 0 {
+			r.report.OpenPositions++
+		}
+	}
+	return &r.report, nil
+}
+
+// replay applies a recorded trade to its pool and lets the strategy react to it
+func (r *run) replay(t Trade) {
+	r.report.Trades++
+	pool, ok := r.pools[t.Pool]
+	if !ok {
+		pool = &simulator.Pool{Address: t.Pool}
+		r.pools[t.Pool] = pool
+	}
+	pool.BaseReserve, pool.QuoteReserve = t.PoolBaseReserve, t.PoolQuoteReserve
+	pool.LpFeeBps, pool.ProtocolFeeBps = t.LpFeeBps, t.ProtocolFeeBps
+	pool.Timestamp = t.Timestamp
+
+	if r.cfg.Leader.IsZero() || t.User.Equals(r.cfg.Leader) {
+		held := r.leader[t.Pool]
+		switch t.Side {
+		case decoder.SideBuy:
+			r.leader[t.Pool] = held + t.BaseAmount
+			r.copyBuy(t, pool)
+		case decoder.SideSell:
+			if t.BaseAmount >= held {
+				r.leader[t.Pool] = 0
+			} else {
+				r.leader[t.Pool] = held - t.BaseAmount
+			}
+			if r.cfg.FollowSells {
+				r.followSell(t, pool, held)
+			}
+		}
+	}
+	r.checkExits(t, pool)
+}
+
+// copyBuy decides a copy of the leader's buy, quoted against the pool the leader left behind
+func (r *run) copyBuy(t Trade, pool *simulator.Pool) {
+	amount := r.cfg.FixedLamports
+	if amount == 0 {
+		amount = uint64(r.cfg.Ratio.Mul(decimal.NewFromUint64(t.QuoteAmount)).IntPart())
+	}
+	if amount == 0 {
+		return
+	}
+	fill := Fill{Time: t.Time(), Source: t.Signature, Pool: t.Pool, Mint: t.BaseMint, Side: decoder.SideBuy, Reason: ReasonCopy}
+	quote, err := swapper.QuoteSwap(r.cfg.SlippageBps, amount, true, pool.BaseReserve, pool.QuoteReserve, swapper.PumpSwapFeeRate)
+	if err != nil {
+		fill.Error = err.Error()
+		r.report.Fills = append(r.report.Fills, fill)
+		return
+	}
+	err = r.engine.Check(risk.Order{
+		Mint:             mintKey(t.Pool, t.BaseMint),
+		Side:             risk.SideBuy,
+		AmountIn:         amount,
+		PriceImpactBps:   quote.PriceImpactBps,
+		PoolQuoteReserve: pool.QuoteReserve,
+	}, r.book(t.Time()))
+	if err != nil {
+		fill.Error = err.Error()
+		r.report.Fills = append(r.report.Fills, fill)
+		return
+	}
+	r.submit(order{source: t.Signature, pool: t.Pool, mint: t.BaseMint, side: decoder.SideBuy, reason: ReasonCopy, amount: amount, limit: quote.MinAmountOut}, t.Time())
+}
+
+// followSell sells the fraction of our position the leader sold of the holding seen in the stream,
+// all of it when the leader sells tokens bought before the stream started
+func (r *run) followSell(t Trade, pool *simulator.Pool, leaderHeld uint64) {
+	p, ok := r.positions[t.Pool]
+	if !ok || p.Amount == 0 {
+		return
+	}
+	amount := p.Amount
+	if t.BaseAmount < leaderHeld {
+		amount = uint64(decimal.NewFromUint64(p.Amount).Mul(decimal.NewFromUint64(t.BaseAmount)).Div(decimal.NewFromUint64(leaderHeld)).IntPart())
+	}
+	r.sell(t.Time(), t.Signature, pool, p, amount, ReasonLeaderSell)
+}
+
+// checkExits evaluates the exit rules of our position in the pool at its new spot price
+func (r *run) checkExits(t Trade, pool *simulator.Pool) {
+	p, ok := r.positions[t.Pool]
+	if !ok || p.Amount == 0 || p.pendingSell {
+		return
+	}
+	price := exits.SpotPrice(pool.BaseReserve, pool.QuoteReserve)
+	if price.GreaterThan(p.PeakPrice) {
+		p.PeakPrice = price
+	}
+	exit := r.cfg.Exits.Evaluate(&p.Position, price, t.Time())
+	if exit == nil {
+		return
+	}
+	r.sell(t.Time(), "", pool, p, exit.Amount, string(exit.Reason))
+}
+
+// sell decides a sell of amount quoted against the pool as it is now
+func (r *run) sell(now time.Time, source string, pool *simulator.Pool, p *position, amount uint64, reason string) {
+	if amount == 0 {
+		return
+	}
+	quote, err := swapper.QuoteSwap(r.cfg.SlippageBps, amount, false, pool.BaseReserve, pool.QuoteReserve, swapper.PumpSwapFeeRate)
+	if err != nil {
+		r.report.Fills = append(r.report.Fills, Fill{Time: now, Source: source, Pool: pool.Address, Mint: p.mint, Side: decoder.SideSell, Reason: reason, Error: err.Error()})
+		return
+	}
+	p.pendingSell = true
+	r.submit(order{source: source, pool: pool.Address, mint: p.mint, side: decoder.SideSell, reason: reason, amount: amount, limit: quote.MinAmountOut}, now)
+}
+
+// submit queues an order to land after the latency
+func (r *run) submit(o order, now time.Time) {
+	o.due = now.Add(r.cfg.Latency)
+	r.pending = append(r.pending, o)
+}
+
+// fillDue executes the pending orders that have landed, in the order they land
+func (r *run) fillDue(landed func(order) bool) {
+	sort.SliceStable(r.pending, func(i, j int) bool { return r.pending[i].due.Before(r.pending[j].due) })
+	remaining := r.pending[:0]
+	var due []order
+	for _, o := range r.pending {
+		if landed(o) {
+			due = append(due, o)
+		} else {
+			remaining = append(remaining, o)
+		}
+	}
+	r.pending = remaining
+	for _, o := range due {
+		r.fill(o)
+		r.mark()
+	}
+}
+
+// fill executes an order on the simulated pool as the program would
+func (r *run) fill(o order) {
+	pool := r.pools[o.pool]
+	fill := Fill{Time: o.due, Source: o.source, Pool: o.pool, Mint: o.mint, Side: o.side, Reason: o.reason}
+	switch o.side {
+	case decoder.SideBuy:
+		r.report.FeesPaid += r.cfg.FeeLamports
+		event, err := pool.Buy(solana.PublicKey{}, o.limit, o.amount)
+		if err != nil {
+			r.failed(fill, err)
+			return
+		}
+		fill.BaseAmount, fill.QuoteAmount = event.BaseAmountOut, event.UserQuoteAmountIn
+		r.opened(fill)
+	case decoder.SideSell:
+		// An earlier sell may have emptied the position while this one waited
+		p, ok := r.positions[o.pool]
+		if !ok || p.Amount == 0 {
+			return
+		}
+		p.pendingSell = false
+		amount := o.amount
+		if amount > p.Amount {
+			amount = p.Amount
+		}
+		r.report.FeesPaid += r.cfg.FeeLamports
+		event, err := pool.Sell(solana.PublicKey{}, amount, o.limit)
+		if err != nil {
+			r.failed(fill, err)
+			return
+		}
+		fill.BaseAmount, fill.QuoteAmount = amount, event.UserQuoteAmountOut
+		r.closed(&fill, p)
+	}
+	fill.Price = decimal.NewFromUint64(fill.QuoteAmount).Div(decimal.NewFromUint64(fill.BaseAmount))
+	r.report.Fills = append(r.report.Fills, fill)
+}
+
+// failed records an order that landed and failed, its fee is lost
+func (r *run) failed(fill Fill, err error) {
+	fill.Error = err.Error()
+	fill.PnL = -int64(r.cfg.FeeLamports)
+	r.report.RealizedPnL -= int64(r.cfg.FeeLamports)
+	r.report.Fills = append(r.report.Fills, fill)
+}
+
+// opened adds a buy to the pool's position at its average cost
+func (r *run) opened(fill Fill) {
+	p, ok := r.positions[fill.Pool]
+	if !ok || p.Amount == 0 {
+		r.nextID++
+		p = &position{mint: fill.Mint}
+		p.ID = fmt.Sprintf("%s-%d", fill.Pool, r.nextID)
+		p.OpenedAt = fill.Time
+		r.positions[fill.Pool] = p
+	}
+	p.Amount += fill.BaseAmount
+	p.InitialAmount += fill.BaseAmount
+	p.cost += fill.QuoteAmount + r.cfg.FeeLamports
+	p.EntryPrice = decimal.NewFromUint64(p.cost).Div(decimal.NewFromUint64(p.Amount))
+	if p.PeakPrice.LessThan(p.EntryPrice) {
+		p.PeakPrice = p.EntryPrice
+	}
+}
+
+// closed takes a sell off the position, realizing its share of the cost
+func (r *run) closed(fill *Fill, p *position) {
+	cost := uint64(decimal.NewFromUint64(p.cost).Mul(decimal.NewFromUint64(fill.BaseAmount)).Div(decimal.NewFromUint64(p.Amount)).IntPart())
+	fill.PnL = int64(fill.QuoteAmount) - int64(r.cfg.FeeLamports) - int64(cost)
+	p.cost -= cost
+	p.Amount -= fill.BaseAmount
+	p.pnl += fill.PnL
+	r.report.RealizedPnL += fill.PnL
+	if fill.Reason == string(exits.ReasonTakeProfit) {
+		p.TakenProfits++
+	}
+	if p.Amount > 0 {
+		return
+	}
+	switch {
+	case p.pnl > 0:
+		r.report.Wins++
+	case p.pnl < 0:
+		r.report.Losses++
+	default:
+		r.report.BreakEven++
+	}
+	delete(r.positions, fill.Pool)
+}
+
+// book is the exposure the risk limits see: open positions at cost and today's realized PnL
+func (r *run) book(now time.Time) risk.Book {
+	b := risk.Book{Exposure: map[string]uint64{}}
+	for pool, p := range r.positions {
+		if p.Amount > 0 {
+			b.OpenPositions++
+			b.Exposure[mintKey(pool, p.mint)] += p.cost
+		}
+	}
+	for _, o := range r.pending {
+		if o.side != decoder.SideBuy {
+			continue
+		}
+		key := mintKey(o.pool, o.mint)
+		if b.Exposure[key] == 0 {
+			b.OpenPositions++
+		}
+		b.Exposure[key] += o.amount
+	}
+	dayStart := now.UTC().Truncate(24 * time.Hour)
+	for _, f := range r.report.Fills {
+		if !f.Time.Before(dayStart) {
+			b.DailyPnL += f.PnL
+		}
+	}
+	return b
+}
+
+// unrealized values the open positions as sells into their pools, less what they cost
+func (r *run) unrealized() int64 {
+	var total int64
+	for pool, p := range r.positions {
+		if p.Amount == 0 {
+			continue
+		}
+		event, err := r.pools[pool].QuoteSell(solana.PublicKey{}, p.Amount, 0)
+		value := int64(0)
+		if err == nil {
+			value = int64(event.UserQuoteAmountOut) - int64(r.cfg.FeeLamports)
+		}
+		total += value - int64(p.cost)
+	}
+	return total
+}
+
+// mark records the equity after an event for the drawdown
+func (r *run) mark() {
+	equity := r.report.RealizedPnL + r.unrealized()
+	if equity > r.peak {
+		r.peak = equity
+	}
+	if dd := r.peak - equity; dd > r.report.MaxDrawdown {
+		r.report.MaxDrawdown = dd
+	}
+}
+
+// mintKey names a position for the risk limits, by mint when the stream named it
+func mintKey(pool, mint solana.PublicKey) string {
+	if mint.IsZero() {
+		return pool.String()
+	}
+	return mint.String()
+}
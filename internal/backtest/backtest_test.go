@@ -0,0 +1,202 @@
+package backtest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"solana-pumpswap-demo/internal/decoder"
+	"solana-pumpswap-demo/internal/exits"
+	"solana-pumpswap-demo/internal/risk"
+	"solana-pumpswap-demo/internal/simulator"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	leader = solana.PublicKey{1}
+	others = solana.PublicKey{2}
+)
+
+// market records the trades made on a simulated pool one second apart, the way the decoder reports them
+type market struct {
+	t      *testing.T
+	pool   *simulator.Pool
+	mint   solana.PublicKey
+	trades []Trade
+}
+
+// newMarket starts a pool holding 1B tokens (6 decimals) and 100 SOL
+func newMarket(t *testing.T) *market {
+	pool := simulator.NewPool(solana.PublicKey{9}, 1_000_000_000_000_000, 100_000_000_000)
+	pool.Timestamp = 1750000000
+	return &market{t: t, pool: pool, mint: solana.PublicKey{8}}
+}
+
+func (m *market) record(event decoder.EventData) Trade {
+	m.t.Helper()
+	trade := decoder.TradeFromEvent(event)
+	trade.BaseMint = m.mint
+	recorded := Trade{Signature: string(rune('a' + len(m.trades))), Slot: uint64(len(m.trades)), Trade: *trade}
+	m.trades = append(m.trades, recorded)
+	m.pool.Timestamp++
+	return recorded
+}
+
+func (m *market) buy(user solana.PublicKey, lamports uint64) Trade {
+	m.t.Helper()
+	event, err := m.pool.BuyWithQuote(user, lamports)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	return m.record(event)
+}
+
+func (m *market) sell(user solana.PublicKey, base uint64) Trade {
+	m.t.Helper()
+	event, err := m.pool.Sell(user, base, 0)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	return m.record(event)
+}
+
+func baseConfig() Config {
+	return Config{Leader: leader, Ratio: decimal.NewFromInt(1), SlippageBps: 100, FeeLamports: 5000}
+}
+
+// TestCopyAndFollowSell tests a profitable round trip copied from the leader's buy and sell
+func TestCopyAndFollowSell(t *testing.T) {
+	m := newMarket(t)
+	bought := m.buy(leader, 1_000_000_000).BaseAmount
+	m.buy(others, 20_000_000_000) // Pushes the price up
+	m.sell(leader, bought/2)
+	m.sell(leader, bought/2)
+
+	cfg := baseConfig()
+	cfg.FollowSells = true
+	report, err := Run(m.trades, cfg)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(report.Fills) != 3 {
+		t.Fatalf("fills = %+v, want a buy and two sells", report.Fills)
+	}
+	buy, first, second := report.Fills[0], report.Fills[1], report.Fills[2]
+	if buy.Side != decoder.SideBuy || buy.Reason != ReasonCopy || buy.Error != "" || buy.QuoteAmount > 1_000_000_000 || buy.Source != "a" {
+		t.Errorf("copy buy = %+v", buy)
+	}
+	// The first sell follows half the leader's holding, the second the rest
+	if first.Reason != ReasonLeaderSell || first.BaseAmount != buy.BaseAmount/2 || second.BaseAmount != buy.BaseAmount-first.BaseAmount {
+		t.Errorf("follow sells = %+v, %+v", first, second)
+	}
+	if report.Wins != 1 || report.Losses != 0 || !report.HitRate().Equal(decimal.NewFromInt(1)) || report.OpenPositions != 0 {
+		t.Errorf("report = %+v", report)
+	}
+	if report.RealizedPnL != first.PnL+second.PnL || report.RealizedPnL <= 0 || report.UnrealizedPnL != 0 {
+		t.Errorf("realized %d from sells %d and %d, unrealized %d", report.RealizedPnL, first.PnL, second.PnL, report.UnrealizedPnL)
+	}
+	if report.FeesPaid != 3*5000 || report.Trades != 4 {
+		t.Errorf("fees %d over %d trades", report.FeesPaid, report.Trades)
+	}
+}
+
+// TestLatencySlippage tests that a copy landing after someone else moved the pool fails like on chain
+// and still pays its fee, while a copy landing first fills
+func TestLatencySlippage(t *testing.T) {
+	m := newMarket(t)
+	m.buy(leader, 1_000_000_000)
+	m.buy(others, 10_000_000_000) // One second after the leader
+
+	cfg := baseConfig()
+	report, err := Run(m.trades, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Fills) != 1 || report.Fills[0].Error != "" || report.OpenPositions != 1 {
+		t.Fatalf("without latency fills = %+v", report.Fills)
+	}
+
+	cfg.Latency = 2 * time.Second
+	report, err = Run(m.trades, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Fills) != 1 || !strings.Contains(report.Fills[0].Error, "ExceededSlippage") {
+		t.Fatalf("with latency fills = %+v", report.Fills)
+	}
+	if report.RealizedPnL != -5000 || report.OpenPositions != 0 || report.FeesPaid != 5000 {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+// TestStopLoss tests that the exit rules sell into a falling pool and the loss shows in the drawdown
+func TestStopLoss(t *testing.T) {
+	m := newMarket(t)
+	m.buy(leader, 1_000_000_000)
+	m.sell(others, 50_000_000_000_000) // -10%
+	m.sell(others, 200_000_000_000_000)
+	m.sell(others, 10_000_000_000_000)
+
+	cfg := baseConfig()
+	cfg.Exits = exits.Rules{StopLoss: decimal.RequireFromString("0.3")}
+	report, err := Run(m.trades, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Fills) != 2 {
+		t.Fatalf("fills = %+v", report.Fills)
+	}
+	exit := report.Fills[1]
+	if exit.Reason != string(exits.ReasonStopLoss) || exit.Source != "" || exit.PnL >= 0 || exit.Time != m.trades[2].Time() {
+		t.Errorf("stop loss = %+v", exit)
+	}
+	if report.Losses != 1 || report.Wins != 0 || !report.HitRate().IsZero() {
+		t.Errorf("report = %+v", report)
+	}
+	if report.MaxDrawdown < -report.RealizedPnL {
+		t.Errorf("max drawdown %d smaller than the loss %d", report.MaxDrawdown, report.RealizedPnL)
+	}
+}
+
+// TestRiskAndSizing tests fixed sizing, the risk limits and unrealized PnL of positions left open
+func TestRiskAndSizing(t *testing.T) {
+	m := newMarket(t)
+	m.buy(leader, 5_000_000_000)
+	m.buy(leader, 100_000_000)
+	m.buy(others, 30_000_000_000)
+
+	cfg := baseConfig()
+	cfg.FixedLamports = 200_000_000
+	cfg.Limits = risk.Limits{MaxTokenExposure: 300_000_000}
+	report, err := Run(m.trades, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Fills) != 2 || report.Fills[0].Error != "" || !strings.Contains(report.Fills[1].Error, "max_token_exposure") {
+		t.Fatalf("fills = %+v", report.Fills)
+	}
+	if report.Fills[0].QuoteAmount > 200_000_000 || report.OpenPositions != 1 || report.UnrealizedPnL <= 0 {
+		t.Errorf("report = %+v", report)
+	}
+
+	if _, err := Run(m.trades, Config{}); err == nil {
+		t.Error("Run() without sizing succeeded")
+	}
+}
+
+// TestBreakEven tests that a position closed at exactly zero PnL is neither a win nor a loss
+func TestBreakEven(t *testing.T) {
+	pool := solana.NewWallet().PublicKey()
+	r := &run{positions: map[solana.PublicKey]*position{
+		pool: {Position: exits.Position{Amount: 1_000}, cost: 5_000},
+	}}
+	r.closed(&Fill{Pool: pool, BaseAmount: 1_000, QuoteAmount: 5_000}, r.positions[pool])
+	if r.report.BreakEven != 1 || r.report.Wins != 0 || r.report.Losses != 0 || !r.report.HitRate().IsZero() {
+		t.Errorf("report = %+v", r.report)
+	}
+	if len(r.positions) != 0 {
+		t.Errorf("position not closed: %+v", r.positions)
+	}
+}
@@ -0,0 +1,88 @@
+package decoder
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// TokenDelta is the change of one token account's balance over a transaction
+type TokenDelta struct {
+	Account  solana.PublicKey
+	Owner    solana.PublicKey // Zero if the node did not report the owner
+	Mint     solana.PublicKey
+	Decimals uint8
+	Pre      uint64 // Raw amount before the transaction, zero for accounts it created
+	Post     uint64 // Raw amount after the transaction, zero for accounts it closed
+}
+
+// Change returns the signed balance change in raw units
+func (d TokenDelta) Change() *big.Int {
+	return new(big.Int).Sub(new(big.Int).SetUint64(d.Post), new(big.Int).SetUint64(d.Pre))
+}
+
+// TokenDeltas returns the token accounts whose balance changed in the transaction, in account order
+func TokenDeltas(txResult *rpc.GetTransactionResult) ([]TokenDelta, error) {
+	if txResult == nil || txResult.Transaction == nil || txResult.Meta == nil {
+		return nil, nil
+	}
+	tx, err := txResult.Transaction.GetTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	keys := AccountKeys(tx, txResult.Meta)
+
+	byIndex := make(map[uint16]*TokenDelta)
+	var order []uint16
+	apply := func(balances []rpc.TokenBalance, post bool) error {
+		for _, b := range balances {
+			if int(b.AccountIndex) >= len(keys) {
+				return fmt.Errorf("token balance account index %d out of range", b.AccountIndex)
+			}
+			var amount uint64
+			var decimals uint8
+			if b.UiTokenAmount != nil {
+				parsed, err := strconv.ParseUint(b.UiTokenAmount.Amount, 10, 64)
+				if err != nil {
+					return fmt.Errorf("invalid token amount %q: %w", b.UiTokenAmount.Amount, err)
+				}
+				amount, decimals = parsed, b.UiTokenAmount.Decimals
+			}
+
+			delta, ok := byIndex[b.AccountIndex]
+			if !ok {
+				delta = &TokenDelta{Account: keys[b.AccountIndex], Mint: b.Mint, Decimals: decimals}
+				if b.Owner != nil {
+					delta.Owner = *b.Owner
+				}
+				byIndex[b.AccountIndex] = delta
+				order = append(order, b.AccountIndex)
+			}
+			if post {
+				delta.Post = amount
+			} else {
+				delta.Pre = amount
+			}
+		}
+		return nil
+	}
+	if err := apply(txResult.Meta.PreTokenBalances, false); err != nil {
+		return nil, err
+	}
+	if err := apply(txResult.Meta.PostTokenBalances, true); err != nil {
+		return nil, err
+	}
+
+	var deltas []TokenDelta
+	slices.Sort(order)
+	for _, index := range order {
+		if d := byIndex[index]; d.Pre != d.Post {
+			deltas = append(deltas, *d)
+		}
+	}
+	return deltas, nil
+}
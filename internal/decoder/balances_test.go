@@ -0,0 +1,67 @@
+package decoder
+
+import (
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// TestTokenDeltas tests pairing pre and post balances, including accounts opened and closed by the transaction
+func TestTokenDeltas(t *testing.T) {
+	payer := solana.NewWallet().PublicKey()
+	owner := solana.NewWallet().PublicKey()
+	mint := solana.NewWallet().PublicKey()
+	opened := solana.NewWallet().PublicKey()
+	closed := solana.NewWallet().PublicKey()
+	unchanged := solana.NewWallet().PublicKey()
+
+	ix := solana.NewInstruction(solana.TokenProgramID, solana.AccountMetaSlice{
+		solana.Meta(opened).WRITE(),
+		solana.Meta(closed).WRITE(),
+		solana.Meta(unchanged).WRITE(),
+	}, []byte{3})
+	tx, err := solana.NewTransaction([]solana.Instruction{ix}, solana.Hash{}, solana.TransactionPayer(payer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := func(key solana.PublicKey) uint16 {
+		i, err := tx.Message.GetAccountIndex(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return i
+	}
+	balance := func(key solana.PublicKey, amount string) rpc.TokenBalance {
+		return rpc.TokenBalance{
+			AccountIndex:  index(key),
+			Owner:         &owner,
+			Mint:          mint,
+			UiTokenAmount: &rpc.UiTokenAmount{Amount: amount, Decimals: 6},
+		}
+	}
+
+	result := testTransaction(t, tx, nil)
+	result.Meta.PreTokenBalances = []rpc.TokenBalance{balance(closed, "700"), balance(unchanged, "5")}
+	result.Meta.PostTokenBalances = []rpc.TokenBalance{balance(unchanged, "5"), balance(opened, "1000")}
+
+	deltas, err := TokenDeltas(result)
+	if err != nil {
+		t.Fatalf("TokenDeltas() error = %v", err)
+	}
+	want := map[solana.PublicKey][2]uint64{opened: {0, 1000}, closed: {700, 0}}
+	if len(deltas) != len(want) {
+		t.Fatalf("TokenDeltas() returned %d deltas, want %d", len(deltas), len(want))
+	}
+	for i, d := range deltas {
+		if i > 0 && index(d.Account) < index(deltas[i-1].Account) {
+			t.Errorf("deltas not in account order")
+		}
+		if w := want[d.Account]; d.Pre != w[0] || d.Post != w[1] || !d.Owner.Equals(owner) || !d.Mint.Equals(mint) || d.Decimals != 6 {
+			t.Errorf("delta = %+v, want pre %d post %d", d, w[0], w[1])
+		}
+	}
+	if change := deltas[0].Change().Int64() + deltas[1].Change().Int64(); change != 300 {
+		t.Errorf("total change = %d, want 300", change)
+	}
+}
@@ -0,0 +1,375 @@
+package decoder
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"solana-pumpswap-demo/idl/pumpfun/amm/idl/generated/amm"
+	pumpfun "solana-pumpswap-demo/idl/pumpfun/pump"
+	"solana-pumpswap-demo/idl/pumpfun/pump/idl/generated/pump"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Side of a decoded trade
+const (
+	SideBuy  = "buy"
+	SideSell = "sell"
+)
+
+// Venue a trade executed on
+const (
+	VenuePumpSwap = "pumpswap" // PumpSwap AMM pool
+	VenuePumpFun  = "pumpfun"  // pump.fun bonding curve, before the token migrates to PumpSwap
+)
+
+// venueOf returns the venue of a program, or "" for programs we do not decode
+func venueOf(program solana.PublicKey) string {
+	switch {
+	case program.Equals(amm.ProgramID):
+		return VenuePumpSwap
+	case program.Equals(pump.ProgramID):
+		return VenuePumpFun
+	default:
+		return ""
+	}
+}
+
+// eventIxTag prefixes the data of the self-invocation Anchor's emit_cpi! uses to publish an event
+var eventIxTag = []byte{0xe4, 0x45, 0xa5, 0x2e, 0x51, 0xcb, 0x9a, 0x1d}
+
+// Trade is a PumpSwap or bonding-curve buy or sell as it executed on chain, read from the
+// program's event rather than from the limits in the instruction
+type Trade struct {
+	Venue       string
+	Side        string
+	Instruction int              // Top-level instruction the swap ran under
+	Pool        solana.PublicKey // PumpSwap pool or pump.fun bonding curve
+	User        solana.PublicKey
+	BaseMint    solana.PublicKey // Zero if the swap instruction could not be matched
+	Timestamp   int64
+
+	BaseAmount  uint64 // Tokens received by a buy or sold by a sell
+	QuoteAmount uint64 // Lamports paid by a buy or received by a sell, after all fees on PumpSwap and before the fee on the bonding curve
+
+	LpFee          uint64
+	LpFeeBps       uint64
+	ProtocolFee    uint64 // Not reported by the bonding curve
+	ProtocolFeeBps uint64
+
+	PoolBaseReserve  uint64 // Pool token balance after the trade, the virtual token reserve on the bonding curve
+	PoolQuoteReserve uint64 // Pool WSOL balance after the trade, the virtual SOL reserve on the bonding curve
+}
+
+// AccountKeys returns the transaction's static account keys followed by the ones loaded from address lookup tables
+func AccountKeys(tx *solana.Transaction, meta *rpc.TransactionMeta) solana.PublicKeySlice {
+	keys := append(solana.PublicKeySlice{}, tx.Message.AccountKeys...)
+	if meta != nil {
+		keys = append(keys, meta.LoadedAddresses.Writable...)
+		keys = append(keys, meta.LoadedAddresses.ReadOnly...)
+	}
+	return keys
+}
+
+// Swap is a PumpSwap or bonding-curve buy or sell instruction, called directly or through CPI by a router or bot program
+type Swap struct {
+	Venue       string
+	Side        string
+	Instruction int              // Top-level instruction the swap ran under
+	Inner       int              // Position among that instruction's inner instructions, -1 when called directly
+	Program     solana.PublicKey // Top-level program the swap is attributed to, the venue's program when called directly
+	Pool        solana.PublicKey // PumpSwap pool or pump.fun bonding curve
+	User        solana.PublicKey
+	BaseMint    solana.PublicKey
+	BaseAmount  uint64 // BaseAmountOut of a buy, BaseAmountIn of a sell; the token amount on the bonding curve
+	QuoteLimit  uint64 // MaxQuoteAmountIn of a buy, MinQuoteAmountOut of a sell; MaxSolCost or MinSolOutput on the bonding curve
+	Trade       *Trade // What actually executed, nil if the swap emitted no event
+}
+
+// Routed reports whether the swap was invoked by another program
+func (s Swap) Routed() bool {
+	return s.Inner >= 0
+}
+
+// EventData is a decoded PumpSwap or pump.fun event
+type EventData interface {
+	UnmarshalWithDecoder(decoder *bin.Decoder) error
+}
+
+// Event is a PumpSwap or pump.fun event, published through emit_cpi or, by older pump.fun deployments, in the logs
+type Event struct {
+	Venue       string
+	Name        string // Event name, e.g. "BuyEvent", "CreatePoolEvent" or "TradeEvent"
+	Instruction int    // Top-level instruction it was emitted under
+	Inner       int    // Position of the emitting self-invocation among that instruction's inner instructions, -1 when read from the logs
+	Data        EventData
+}
+
+// DecodeEvents returns every PumpSwap and pump.fun event in the transaction, in execution order
+func DecodeEvents(txResult *rpc.GetTransactionResult) ([]Event, error) {
+	d, err := walk(txResult)
+	return d.events, err
+}
+
+// DecodeSwaps returns every PumpSwap and bonding-curve buy and sell in the transaction, top-level and inner, in execution order
+func DecodeSwaps(txResult *rpc.GetTransactionResult) ([]Swap, error) {
+	d, err := walk(txResult)
+	return d.swaps, err
+}
+
+// DecodeTrades returns the PumpSwap and bonding-curve trades in the transaction, in execution order.
+// PumpSwap publishes its events through emit_cpi, so they are read from the inner instructions.
+// pump.fun events are read from emit_cpi too, or from the logs when the program wrote them there.
+func DecodeTrades(txResult *rpc.GetTransactionResult) ([]Trade, error) {
+	d, err := walk(txResult)
+	return d.trades, err
+}
+
+// decoded is everything walk found in a transaction
+type decoded struct {
+	instructions []Instruction
+	swaps        []Swap
+	trades       []Trade
+	events       []Event
+}
+
+// walk visits each top-level instruction followed by the inner instructions it invoked,
+// decoding PumpSwap and pump.fun instructions and pairing each swap with the event it emitted
+func walk(txResult *rpc.GetTransactionResult) (decoded, error) {
+	var d decoded
+	if txResult == nil || txResult.Transaction == nil || txResult.Meta == nil {
+		return d, nil
+	}
+	tx, err := txResult.Transaction.GetTransaction()
+	if err != nil {
+		return d, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	metas := accountMetas(tx, txResult.Meta)
+
+	inner := make(map[int][]solana.CompiledInstruction)
+	for _, group := range txResult.Meta.InnerInstructions {
+		inner[int(group.Index)] = append(inner[int(group.Index)], group.Instructions...)
+	}
+	logEvents := pumpLogEvents(txResult.Meta.LogMessages)
+
+	programOf := func(ix solana.CompiledInstruction) solana.PublicKey {
+		if int(ix.ProgramIDIndex) >= len(metas) {
+			return solana.PublicKey{}
+		}
+		return metas[ix.ProgramIDIndex].PublicKey
+	}
+
+	for i, ix := range tx.Message.Instructions {
+		if int(ix.ProgramIDIndex) >= len(metas) {
+			continue
+		}
+		program := metas[ix.ProgramIDIndex].PublicKey
+
+		// Swaps of each venue still waiting for their event, in execution order
+		pending := make(map[string][]int)
+		add := func(ix solana.CompiledInstruction, innerIndex int) {
+			decodedIx, err := decodeCompiled(programOf(ix), ix, metas)
+			if err != nil {
+				return
+			}
+			decodedIx.Instruction, decodedIx.Inner, decodedIx.Program = i, innerIndex, program
+			d.instructions = append(d.instructions, *decodedIx)
+			if swap := swapFromInstruction(decodedIx); swap != nil {
+				d.swaps = append(d.swaps, *swap)
+				pending[swap.Venue] = append(pending[swap.Venue], len(d.swaps)-1)
+			}
+		}
+		emitted := func(venue string, event EventData, innerIndex int) {
+			d.events = append(d.events, Event{Venue: venue, Name: eventName(event), Instruction: i, Inner: innerIndex, Data: event})
+
+			trade := TradeFromEvent(event)
+			if trade == nil {
+				return
+			}
+			trade.Instruction = i
+			if queue := pending[venue]; len(queue) > 0 {
+				swap := &d.swaps[queue[0]]
+				pending[venue] = queue[1:]
+				if trade.BaseMint.IsZero() {
+					trade.BaseMint = swap.BaseMint
+				}
+				if trade.Pool.IsZero() {
+					trade.Pool = swap.Pool
+				}
+				matched := *trade
+				swap.Trade = &matched
+			}
+			d.trades = append(d.trades, *trade)
+		}
+
+		if venueOf(program) != "" {
+			add(ix, -1)
+		}
+
+		cpiEvents := false
+		for j, innerIx := range inner[i] {
+			venue := venueOf(programOf(innerIx))
+			if venue == "" {
+				continue
+			}
+			event, err := decodeEvent(innerIx.Data)
+			if err != nil {
+				return d, err
+			}
+			if event == nil {
+				add(innerIx, j)
+				continue
+			}
+			cpiEvents = cpiEvents || venue == VenuePumpFun
+			emitted(venue, event, j)
+		}
+
+		// Older pump.fun deployments log their events instead of publishing them through emit_cpi
+		if !cpiEvents {
+			for _, payload := range logEvents[i] {
+				event, err := decodeEventPayload(payload)
+				if err != nil {
+					return d, err
+				}
+				if event != nil {
+					emitted(VenuePumpFun, event, -1)
+				}
+			}
+		}
+	}
+	return d, nil
+}
+
+// accountMetas returns the transaction's accounts, including the ones loaded from address lookup
+// tables, with the signer and writable flags from the message header
+func accountMetas(tx *solana.Transaction, meta *rpc.TransactionMeta) []*solana.AccountMeta {
+	header := tx.Message.Header
+	static := len(tx.Message.AccountKeys)
+	signers := int(header.NumRequiredSignatures)
+
+	var metas []*solana.AccountMeta
+	for i, key := range AccountKeys(tx, meta) {
+		var writable bool
+		switch {
+		case i < signers:
+			writable = i < signers-int(header.NumReadonlySignedAccounts)
+		case i < static:
+			writable = i < static-int(header.NumReadonlyUnsignedAccounts)
+		default:
+			writable = i < static+len(meta.LoadedAddresses.Writable)
+		}
+		metas = append(metas, solana.NewAccountMeta(key, writable, i < signers))
+	}
+	return metas
+}
+
+// eventTypes creates the generated event type for each PumpSwap and pump.fun event discriminator
+var eventTypes = map[[8]byte]func() EventData{
+	amm.BuyEventEventDataDiscriminator:             func() EventData { return new(amm.BuyEventEventData) },
+	amm.CreateConfigEventEventDataDiscriminator:    func() EventData { return new(amm.CreateConfigEventEventData) },
+	amm.CreatePoolEventEventDataDiscriminator:      func() EventData { return new(amm.CreatePoolEventEventData) },
+	amm.DepositEventEventDataDiscriminator:         func() EventData { return new(amm.DepositEventEventData) },
+	amm.DisableEventEventDataDiscriminator:         func() EventData { return new(amm.DisableEventEventData) },
+	amm.ExtendAccountEventEventDataDiscriminator:   func() EventData { return new(amm.ExtendAccountEventEventData) },
+	amm.SellEventEventDataDiscriminator:            func() EventData { return new(amm.SellEventEventData) },
+	amm.UpdateAdminEventEventDataDiscriminator:     func() EventData { return new(amm.UpdateAdminEventEventData) },
+	amm.UpdateFeeConfigEventEventDataDiscriminator: func() EventData { return new(amm.UpdateFeeConfigEventEventData) },
+	amm.WithdrawEventEventDataDiscriminator:        func() EventData { return new(amm.WithdrawEventEventData) },
+
+	pumpfun.CreateEventEventDataDiscriminator:    func() EventData { return new(pumpfun.CreateEventEventData) },
+	pumpfun.TradeEventEventDataDiscriminator:     func() EventData { return new(pumpfun.TradeEventEventData) },
+	pumpfun.CompleteEventEventDataDiscriminator:  func() EventData { return new(pumpfun.CompleteEventEventData) },
+	pumpfun.SetParamsEventEventDataDiscriminator: func() EventData { return new(pumpfun.SetParamsEventEventData) },
+}
+
+// decodeEvent decodes an emit_cpi instruction's data into its event.
+// Data that is not an event, or an event we do not know, returns nil.
+func decodeEvent(data []byte) (EventData, error) {
+	if !isEventData(data) {
+		return nil, nil
+	}
+	return decodeEventPayload(data[8:])
+}
+
+// decodeEventPayload decodes a discriminator-prefixed event, as carried by emit_cpi or written to the logs
+func decodeEventPayload(payload []byte) (EventData, error) {
+	if len(payload) < 8 {
+		return nil, nil
+	}
+	var discriminator [8]byte
+	copy(discriminator[:], payload)
+	newEvent, ok := eventTypes[discriminator]
+	if !ok {
+		return nil, nil
+	}
+	event := newEvent()
+
+	if err := event.UnmarshalWithDecoder(bin.NewBorshDecoder(payload)); err != nil {
+		return nil, fmt.Errorf("failed to decode event: %w", err)
+	}
+	return event, nil
+}
+
+// eventName names an event as the IDL does, e.g. "BuyEvent" for *amm.BuyEventEventData
+func eventName(event EventData) string {
+	return strings.TrimSuffix(reflect.TypeOf(event).Elem().Name(), "EventData")
+}
+
+// TradeFromEvent returns the trade an event reports, or nil for events that are not trades.
+// Events do not name the base mint or the instruction, the caller fills them in.
+func TradeFromEvent(event EventData) *Trade {
+	var trade Trade
+	switch e := event.(type) {
+	case *amm.BuyEventEventData:
+		trade = tradeFromBuy(e)
+	case *amm.SellEventEventData:
+		trade = tradeFromSell(e)
+	case *pumpfun.TradeEventEventData:
+		trade = tradeFromPump(e)
+	default:
+		return nil
+	}
+	return &trade
+}
+
+// The event reports the pool reserves before the swap. A buy adds the quote amount and
+// the LP fee to the pool, the protocol fee goes to the fee recipient.
+func tradeFromBuy(e *amm.BuyEventEventData) Trade {
+	return Trade{
+		Venue:            VenuePumpSwap,
+		Side:             SideBuy,
+		Pool:             e.Pool,
+		User:             e.User,
+		Timestamp:        e.Timestamp,
+		BaseAmount:       e.BaseAmountOut,
+		QuoteAmount:      e.UserQuoteAmountIn,
+		LpFee:            e.LpFee,
+		LpFeeBps:         e.LpFeeBasisPoints,
+		ProtocolFee:      e.ProtocolFee,
+		ProtocolFeeBps:   e.ProtocolFeeBasisPoints,
+		PoolBaseReserve:  e.PoolBaseTokenReserves - e.BaseAmountOut,
+		PoolQuoteReserve: e.PoolQuoteTokenReserves + e.QuoteAmountInWithLpFee,
+	}
+}
+
+// A sell takes the quote amount out of the pool less the LP fee, which stays behind
+func tradeFromSell(e *amm.SellEventEventData) Trade {
+	return Trade{
+		Venue:            VenuePumpSwap,
+		Side:             SideSell,
+		Pool:             e.Pool,
+		User:             e.User,
+		Timestamp:        e.Timestamp,
+		BaseAmount:       e.BaseAmountIn,
+		QuoteAmount:      e.UserQuoteAmountOut,
+		LpFee:            e.LpFee,
+		LpFeeBps:         e.LpFeeBasisPoints,
+		ProtocolFee:      e.ProtocolFee,
+		ProtocolFeeBps:   e.ProtocolFeeBasisPoints,
+		PoolBaseReserve:  e.PoolBaseTokenReserves + e.BaseAmountIn,
+		PoolQuoteReserve: e.PoolQuoteTokenReserves - e.QuoteAmountOutWithoutLpFee,
+	}
+}
@@ -0,0 +1,277 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"solana-pumpswap-demo/idl/pumpfun/amm/idl/generated/amm"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// testTransaction wraps tx and its inner instructions the way getTransaction returns them with base64 encoding
+func testTransaction(t *testing.T, tx *solana.Transaction, inner []rpc.InnerInstruction) *rpc.GetTransactionResult {
+	t.Helper()
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	envelope, err := json.Marshal([]string{base64.StdEncoding.EncodeToString(raw), "base64"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := &rpc.GetTransactionResult{
+		Transaction: &rpc.TransactionResultEnvelope{},
+		Meta:        &rpc.TransactionMeta{InnerInstructions: inner},
+	}
+	if err := result.Transaction.UnmarshalJSON(envelope); err != nil {
+		t.Fatalf("UnmarshalJSON() error = %v", err)
+	}
+	return result
+}
+
+// eventData encodes an event as the data of its emit_cpi instruction
+func eventData(t *testing.T, event interface {
+	MarshalWithEncoder(*bin.Encoder) error
+}) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	buf.Write(eventIxTag)
+	if err := event.MarshalWithEncoder(bin.NewBorshEncoder(buf)); err != nil {
+		t.Fatalf("MarshalWithEncoder() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// swapData encodes buy or sell instruction data with its two amount arguments
+func swapData(discriminator [8]byte, baseAmount, quoteLimit uint64) []byte {
+	data := append([]byte{}, discriminator[:]...)
+	data = binary.LittleEndian.AppendUint64(data, baseAmount)
+	return binary.LittleEndian.AppendUint64(data, quoteLimit)
+}
+
+// swapAccounts returns the buy/sell account list with the given pool, user and base mint
+func swapAccounts(pool, user, baseMint solana.PublicKey) solana.AccountMetaSlice {
+	metas := solana.AccountMetaSlice{
+		solana.Meta(pool).WRITE(),
+		solana.Meta(user).WRITE().SIGNER(),
+		solana.Meta(solana.NewWallet().PublicKey()),
+		solana.Meta(baseMint),
+	}
+	for len(metas) < 17 {
+		metas = append(metas, solana.Meta(solana.NewWallet().PublicKey()))
+	}
+	return metas
+}
+
+// TestDecodeTrades tests reading actual amounts, fees and reserves from buy and sell events
+func TestDecodeTrades(t *testing.T) {
+	pool := solana.NewWallet().PublicKey()
+	user := solana.NewWallet().PublicKey()
+	mint := solana.NewWallet().PublicKey()
+
+	buyIx := solana.NewInstruction(amm.ProgramID, swapAccounts(pool, user, mint), swapData(amm.Instruction_Buy, 1_000_000_000, 150_000_000))
+	sellIx := solana.NewInstruction(amm.ProgramID, swapAccounts(pool, user, mint), swapData(amm.Instruction_Sell, 1_000_000_000, 90_000_000))
+	tx, err := solana.NewTransaction([]solana.Instruction{buyIx, sellIx}, solana.Hash{}, solana.TransactionPayer(user))
+	if err != nil {
+		t.Fatal(err)
+	}
+	programIndex, err := tx.Message.GetAccountIndex(amm.ProgramID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buy := &amm.BuyEventEventData{
+		Timestamp:              1745000000,
+		BaseAmountOut:          1_000_000_000,
+		MaxQuoteAmountIn:       150_000_000,
+		PoolBaseTokenReserves:  100_000_000_000,
+		PoolQuoteTokenReserves: 10_000_000_000,
+		QuoteAmountIn:          101_010_102,
+		LpFeeBasisPoints:       20,
+		LpFee:                  202_021,
+		ProtocolFeeBasisPoints: 5,
+		ProtocolFee:            50_506,
+		QuoteAmountInWithLpFee: 101_212_123,
+		UserQuoteAmountIn:      101_262_629,
+		Pool:                   pool,
+		User:                   user,
+	}
+	sell := &amm.SellEventEventData{
+		Timestamp:                  1745000001,
+		BaseAmountIn:               1_000_000_000,
+		MinQuoteAmountOut:          90_000_000,
+		PoolBaseTokenReserves:      99_000_000_000,
+		PoolQuoteTokenReserves:     10_101_212_123,
+		QuoteAmountOut:             101_012_121,
+		LpFeeBasisPoints:           20,
+		LpFee:                      202_025,
+		ProtocolFeeBasisPoints:     5,
+		ProtocolFee:                50_507,
+		QuoteAmountOutWithoutLpFee: 100_810_096,
+		UserQuoteAmountOut:         100_759_589,
+		Pool:                       pool,
+		User:                       user,
+	}
+	inner := []rpc.InnerInstruction{
+		{Index: 0, Instructions: []solana.CompiledInstruction{
+			{ProgramIDIndex: programIndex, Data: []byte{1, 2, 3}}, // Too short to be an event
+			{ProgramIDIndex: programIndex, Data: eventData(t, buy)},
+		}},
+		{Index: 1, Instructions: []solana.CompiledInstruction{
+			{ProgramIDIndex: programIndex, Data: eventData(t, sell)},
+		}},
+	}
+
+	trades, err := DecodeTrades(testTransaction(t, tx, inner))
+	if err != nil {
+		t.Fatalf("DecodeTrades() error = %v", err)
+	}
+	want := []Trade{
+		{
+			Venue: VenuePumpSwap, Side: SideBuy, Instruction: 0, Pool: pool, User: user, BaseMint: mint, Timestamp: 1745000000,
+			BaseAmount: 1_000_000_000, QuoteAmount: 101_262_629,
+			LpFee: 202_021, LpFeeBps: 20, ProtocolFee: 50_506, ProtocolFeeBps: 5,
+			PoolBaseReserve: 99_000_000_000, PoolQuoteReserve: 10_101_212_123,
+		},
+		{
+			Venue: VenuePumpSwap, Side: SideSell, Instruction: 1, Pool: pool, User: user, BaseMint: mint, Timestamp: 1745000001,
+			BaseAmount: 1_000_000_000, QuoteAmount: 100_759_589,
+			LpFee: 202_025, LpFeeBps: 20, ProtocolFee: 50_507, ProtocolFeeBps: 5,
+			PoolBaseReserve: 100_000_000_000, PoolQuoteReserve: 10_000_402_027,
+		},
+	}
+	if len(trades) != len(want) {
+		t.Fatalf("DecodeTrades() returned %d trades, want %d", len(trades), len(want))
+	}
+	for i := range want {
+		if trades[i] != want[i] {
+			t.Errorf("trade %d = %+v\nwant %+v", i, trades[i], want[i])
+		}
+	}
+}
+
+// TestDecodeEventIgnoresOtherData tests that non-event data and unknown events are skipped
+func TestDecodeEventIgnoresOtherData(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "instruction data", data: []byte{102, 6, 61, 18, 1, 218, 235, 234, 1, 0, 0, 0, 0, 0, 0, 0}},
+		{name: "tag only", data: eventIxTag},
+		{name: "unknown event", data: append(append([]byte{}, eventIxTag...), 1, 2, 3, 4, 5, 6, 7, 8)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := decodeEvent(tt.data)
+			if event != nil || err != nil {
+				t.Errorf("decodeEvent() = %v, %v; want nil, nil", event, err)
+			}
+		})
+	}
+}
+
+// TestDecodeSwapsThroughRouter tests finding a swap invoked through CPI and attributing it to the router
+func TestDecodeSwapsThroughRouter(t *testing.T) {
+	router := solana.NewWallet().PublicKey()
+	pool := solana.NewWallet().PublicKey()
+	user := solana.NewWallet().PublicKey()
+	mint := solana.NewWallet().PublicKey()
+
+	// The router's own instruction references every account the swap needs
+	accounts := append(swapAccounts(pool, user, mint), solana.Meta(amm.ProgramID), solana.Meta(solana.TokenProgramID))
+	routerIx := solana.NewInstruction(router, accounts, []byte{9, 9, 9})
+	tx, err := solana.NewTransaction([]solana.Instruction{routerIx}, solana.Hash{}, solana.TransactionPayer(user))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index := func(key solana.PublicKey) uint16 {
+		i, err := tx.Message.GetAccountIndex(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return i
+	}
+	var swapIndexes []uint16
+	for _, meta := range accounts[:4] {
+		swapIndexes = append(swapIndexes, index(meta.PublicKey))
+	}
+
+	buy := &amm.BuyEventEventData{
+		BaseAmountOut:          500_000,
+		PoolBaseTokenReserves:  10_000_000,
+		PoolQuoteTokenReserves: 1_000_000,
+		QuoteAmountInWithLpFee: 52_734,
+		UserQuoteAmountIn:      52_760,
+		Pool:                   pool,
+		User:                   user,
+	}
+	inner := []rpc.InnerInstruction{{Index: 0, Instructions: []solana.CompiledInstruction{
+		{ProgramIDIndex: index(amm.ProgramID), Accounts: swapIndexes, Data: swapData(amm.Instruction_Buy, 500_000, 60_000)},
+		{ProgramIDIndex: index(solana.TokenProgramID), Data: []byte{3}},
+		{ProgramIDIndex: index(amm.ProgramID), Data: eventData(t, buy)},
+	}}}
+
+	swaps, err := DecodeSwaps(testTransaction(t, tx, inner))
+	if err != nil {
+		t.Fatalf("DecodeSwaps() error = %v", err)
+	}
+	if len(swaps) != 1 {
+		t.Fatalf("DecodeSwaps() returned %d swaps, want 1", len(swaps))
+	}
+
+	swap := swaps[0]
+	if !swap.Routed() || swap.Instruction != 0 || swap.Inner != 0 || !swap.Program.Equals(router) {
+		t.Errorf("swap attribution = instruction %d, inner %d, program %s", swap.Instruction, swap.Inner, swap.Program)
+	}
+	if swap.Side != SideBuy || !swap.Pool.Equals(pool) || !swap.User.Equals(user) || !swap.BaseMint.Equals(mint) ||
+		swap.BaseAmount != 500_000 || swap.QuoteLimit != 60_000 {
+		t.Errorf("swap = %+v", swap)
+	}
+	if swap.Trade == nil || swap.Trade.QuoteAmount != 52_760 || !swap.Trade.BaseMint.Equals(mint) || swap.Trade.PoolQuoteReserve != 1_052_734 {
+		t.Errorf("swap trade = %+v", swap.Trade)
+	}
+}
+
+// TestDecodeEvents tests that every PumpSwap event is returned with its name and position, not only trades
+func TestDecodeEvents(t *testing.T) {
+	user := solana.NewWallet().PublicKey()
+	ix := solana.NewInstruction(amm.ProgramID, solana.AccountMetaSlice{solana.Meta(user).WRITE().SIGNER()}, []byte{1})
+	tx, err := solana.NewTransaction([]solana.Instruction{ix}, solana.Hash{}, solana.TransactionPayer(user))
+	if err != nil {
+		t.Fatal(err)
+	}
+	programIndex, err := tx.Message.GetAccountIndex(amm.ProgramID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deposit := &amm.DepositEventEventData{LpTokenAmountOut: 42, User: user}
+	inner := []rpc.InnerInstruction{{Index: 0, Instructions: []solana.CompiledInstruction{
+		{ProgramIDIndex: programIndex, Data: []byte{1, 2, 3}},
+		{ProgramIDIndex: programIndex, Data: eventData(t, deposit)},
+	}}}
+
+	events, err := DecodeEvents(testTransaction(t, tx, inner))
+	if err != nil {
+		t.Fatalf("DecodeEvents() error = %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("DecodeEvents() returned %d events, want 1", len(events))
+	}
+	event := events[0]
+	if event.Name != "DepositEvent" || event.Instruction != 0 || event.Inner != 1 {
+		t.Errorf("event = %s at %d/%d, want DepositEvent at 0/1", event.Name, event.Instruction, event.Inner)
+	}
+	data, ok := event.Data.(*amm.DepositEventEventData)
+	if !ok || data.LpTokenAmountOut != 42 || !data.User.Equals(user) {
+		t.Errorf("event data = %+v", event.Data)
+	}
+}
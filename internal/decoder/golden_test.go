@@ -0,0 +1,227 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files from the decoder's output")
+
+// goldenInstruction is an Instruction with its decoded arguments, without the generated account list
+type goldenInstruction struct {
+	Venue       string
+	Name        string
+	Instruction int
+	Inner       int
+	Program     string
+	Accounts    []Account
+	Args        map[string]interface{}
+}
+
+// goldenEvent is an Event with its data as JSON
+type goldenEvent struct {
+	Venue       string
+	Name        string
+	Instruction int
+	Inner       int
+	Data        EventData
+}
+
+// golden is everything the decoder reports about a transaction
+type golden struct {
+	Err          interface{}
+	Instructions []goldenInstruction
+	Swaps        []Swap
+	Events       []goldenEvent
+	TokenDeltas  []TokenDelta
+}
+
+// instructionArgs returns the arguments of a generated instruction, which also carries its accounts
+func instructionArgs(t *testing.T, impl interface{}) map[string]interface{} {
+	t.Helper()
+	raw, err := json.Marshal(impl)
+	if err != nil {
+		t.Fatalf("failed to encode %T: %v", impl, err)
+	}
+	var args map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&args); err != nil {
+		t.Fatalf("failed to decode %T: %v", impl, err)
+	}
+	delete(args, "AccountMetaSlice")
+	return args
+}
+
+func decodeGolden(t *testing.T, txResult *rpc.GetTransactionResult) golden {
+	t.Helper()
+	instructions, err := DecodeInstructions(txResult)
+	if err != nil {
+		t.Fatalf("DecodeInstructions() error = %v", err)
+	}
+	swaps, err := DecodeSwaps(txResult)
+	if err != nil {
+		t.Fatalf("DecodeSwaps() error = %v", err)
+	}
+	events, err := DecodeEvents(txResult)
+	if err != nil {
+		t.Fatalf("DecodeEvents() error = %v", err)
+	}
+	deltas, err := TokenDeltas(txResult)
+	if err != nil {
+		t.Fatalf("TokenDeltas() error = %v", err)
+	}
+
+	g := golden{Err: txResult.Meta.Err, Swaps: swaps, TokenDeltas: deltas}
+	for _, ix := range instructions {
+		g.Instructions = append(g.Instructions, goldenInstruction{
+			Venue:       ix.Venue,
+			Name:        ix.Name,
+			Instruction: ix.Instruction,
+			Inner:       ix.Inner,
+			Program:     ix.Program.String(),
+			Accounts:    ix.Accounts,
+			Args:        instructionArgs(t, ix.Impl),
+		})
+	}
+	for _, e := range events {
+		g.Events = append(g.Events, goldenEvent{e.Venue, e.Name, e.Instruction, e.Inner, e.Data})
+	}
+	return g
+}
+
+// TestGolden decodes every getTransaction result in testdata/transactions and compares the decoder's
+// output with testdata/golden. Run with -update after a deliberate change to the output.
+//
+// The seed corpus was built offline from the program builders and the IDL event layouts with
+// deterministic keys, in the shape a node returns: buys and sells, a swap routed through an
+// aggregator, a v0 transaction with a lookup table, a failed buy, a pool creation, a deposit, a
+// withdrawal and a pump.fun buy. testdata/transactions/sources.txt marks those as synthesized;
+// testdata/capture.sh replaces them with transactions captured from mainnet by "tx_decoder record".
+func TestGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "transactions", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no transactions in testdata/transactions")
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		t.Run(name, func(t *testing.T) {
+			raw, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			txResult := new(rpc.GetTransactionResult)
+			if err := json.Unmarshal(raw, txResult); err != nil {
+				t.Fatalf("failed to parse %s: %v", file, err)
+			}
+			got, err := json.MarshalIndent(decodeGolden(t, txResult), "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			goldenFile := filepath.Join("testdata", "golden", name+".golden.json")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(goldenFile), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(goldenFile, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(goldenFile)
+			if err != nil {
+				t.Fatalf("%v, run go test -run TestGolden -update to create it", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("decoded output differs from %s, run go test -run TestGolden -update if the change is intended\ngot:\n%s", goldenFile, got)
+			}
+		})
+	}
+}
+
+// TestCorpusSources checks that every corpus transaction is listed in sources.txt, and that the
+// captured ones are the transactions their signatures name. With TEST_CORPUS_CAPTURED set, as in a
+// CI job that must exercise real transactions, synthesized and missing entries fail the test.
+func TestCorpusSources(t *testing.T) {
+	raw, err := os.ReadFile(filepath.Join("testdata", "transactions", "sources.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := map[string]string{}
+	for _, line := range strings.Split(string(raw), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) != 2 {
+			t.Fatalf("sources.txt line %q, want <name> <signature|synthesized|missing>", line)
+		}
+		sources[fields[0]] = fields[1]
+	}
+
+	files, err := filepath.Glob(filepath.Join("testdata", "transactions", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	synthesized := 0
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		source, ok := sources[name]
+		delete(sources, name)
+		switch {
+		case !ok:
+			t.Errorf("%s is not listed in sources.txt", name)
+		case source == "synthesized":
+			synthesized++
+		default:
+			want, err := solana.SignatureFromBase58(source)
+			if err != nil {
+				t.Errorf("%s: invalid signature %q: %v", name, source, err)
+				continue
+			}
+			raw, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			txResult := new(rpc.GetTransactionResult)
+			if err := json.Unmarshal(raw, txResult); err != nil {
+				t.Fatalf("failed to parse %s: %v", file, err)
+			}
+			tx, err := txResult.Transaction.GetTransaction()
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if len(tx.Signatures) == 0 || tx.Signatures[0] != want {
+				t.Errorf("%s holds %v, sources.txt says %s", name, tx.Signatures, want)
+			}
+		}
+	}
+	missing := 0
+	for name, source := range sources {
+		if source == "missing" {
+			missing++
+			continue
+		}
+		t.Errorf("sources.txt lists %s, which is not in the corpus", name)
+	}
+	if synthesized > 0 || missing > 0 {
+		report := t.Logf
+		if os.Getenv("TEST_CORPUS_CAPTURED") != "" {
+			report = t.Errorf
+		}
+		report("%d of %d corpus transactions are synthesized, %d more are still to be captured", synthesized, len(files), missing)
+	}
+}
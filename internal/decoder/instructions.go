@@ -0,0 +1,202 @@
+package decoder
+
+import (
+	"fmt"
+
+	"solana-pumpswap-demo/idl/pumpfun/amm/idl/generated/amm"
+	"solana-pumpswap-demo/idl/pumpfun/pump/idl/generated/pump"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// AccountRoles lists the accounts of each PumpSwap instruction in order, named as in the IDL
+var AccountRoles = map[string][]string{
+	"Buy": {
+		"pool", "user", "global_config", "base_mint", "quote_mint",
+		"user_base_token_account", "user_quote_token_account", "pool_base_token_account", "pool_quote_token_account",
+		"protocol_fee_recipient", "protocol_fee_recipient_token_account",
+		"base_token_program", "quote_token_program", "system_program", "associated_token_program",
+		"event_authority", "program",
+	},
+	"CreateConfig": {"admin", "global_config", "system_program", "event_authority", "program"},
+	"CreatePool": {
+		"pool", "global_config", "creator", "base_mint", "quote_mint", "lp_mint",
+		"user_base_token_account", "user_quote_token_account", "user_pool_token_account",
+		"pool_base_token_account", "pool_quote_token_account",
+		"system_program", "token_2022_program", "base_token_program", "quote_token_program", "associated_token_program",
+		"event_authority", "program",
+	},
+	"Deposit": {
+		"pool", "global_config", "user", "base_mint", "quote_mint", "lp_mint",
+		"user_base_token_account", "user_quote_token_account", "user_pool_token_account",
+		"pool_base_token_account", "pool_quote_token_account",
+		"token_program", "token_2022_program", "event_authority", "program",
+	},
+	"Disable":       {"admin", "global_config", "event_authority", "program"},
+	"ExtendAccount": {"account", "user", "system_program", "event_authority", "program"},
+	"Sell": {
+		"pool", "user", "global_config", "base_mint", "quote_mint",
+		"user_base_token_account", "user_quote_token_account", "pool_base_token_account", "pool_quote_token_account",
+		"protocol_fee_recipient", "protocol_fee_recipient_token_account",
+		"base_token_program", "quote_token_program", "system_program", "associated_token_program",
+		"event_authority", "program",
+	},
+	"UpdateAdmin":     {"admin", "global_config", "new_admin", "event_authority", "program"},
+	"UpdateFeeConfig": {"admin", "global_config", "event_authority", "program"},
+	"Withdraw": {
+		"pool", "global_config", "user", "base_mint", "quote_mint", "lp_mint",
+		"user_base_token_account", "user_quote_token_account", "user_pool_token_account",
+		"pool_base_token_account", "pool_quote_token_account",
+		"token_program", "token_2022_program", "event_authority", "program",
+	},
+}
+
+// remainingRole names accounts passed beyond the ones the IDL declares
+const remainingRole = "remaining"
+
+// Account is an instruction account with its role
+type Account struct {
+	Role      string
+	PublicKey solana.PublicKey
+	Writable  bool
+	Signer    bool
+}
+
+// Instruction is a PumpSwap or pump.fun instruction decoded into its generated type
+type Instruction struct {
+	Venue       string
+	Name        string           // Instruction name, e.g. "Buy" or "CreatePool"
+	Instruction int              // Top-level instruction it ran under
+	Inner       int              // Position among that instruction's inner instructions, -1 when called directly
+	Program     solana.PublicKey // Top-level program it is attributed to, the venue's program when called directly
+	Impl        interface{}      // *amm.Buy, *amm.Sell, *amm.CreatePool, *pump.Buy, *pump.Create, ...
+	Accounts    []Account
+}
+
+// Account returns the public key of the account with the given role, or the zero key if there is none
+func (i Instruction) Account(role string) solana.PublicKey {
+	for _, a := range i.Accounts {
+		if a.Role == role {
+			return a.PublicKey
+		}
+	}
+	return solana.PublicKey{}
+}
+
+// DecodeInstruction decodes PumpSwap instruction data with the generated instruction registry
+func DecodeInstruction(accounts []*solana.AccountMeta, data []byte) (*Instruction, error) {
+	decoded, err := solana.DecodeInstruction(amm.ProgramID, accounts, data)
+	if err != nil {
+		return nil, err
+	}
+	inst, ok := decoded.(*amm.Instruction)
+	if !ok {
+		return nil, fmt.Errorf("unexpected instruction type %T", decoded)
+	}
+
+	name := amm.InstructionIDToName(inst.TypeID)
+	return &Instruction{
+		Venue:    VenuePumpSwap,
+		Name:     name,
+		Inner:    -1,
+		Impl:     inst.Impl,
+		Accounts: withRoles(accounts, AccountRoles[name]),
+	}, nil
+}
+
+// withRoles names accounts by their position in the instruction
+func withRoles(accounts []*solana.AccountMeta, roles []string) []Account {
+	var out []Account
+	for j, meta := range accounts {
+		role := remainingRole
+		if j < len(roles) {
+			role = roles[j]
+		}
+		out = append(out, Account{
+			Role:      role,
+			PublicKey: meta.PublicKey,
+			Writable:  meta.IsWritable,
+			Signer:    meta.IsSigner,
+		})
+	}
+	return out
+}
+
+// DecodeInstructions returns every PumpSwap and pump.fun instruction in the transaction, top-level and inner,
+// in execution order. The self-invocations that carry events are not included.
+func DecodeInstructions(txResult *rpc.GetTransactionResult) ([]Instruction, error) {
+	d, err := walk(txResult)
+	return d.instructions, err
+}
+
+// decodeCompiled resolves a compiled instruction's accounts and decodes it with its program's decoder
+func decodeCompiled(program solana.PublicKey, ix solana.CompiledInstruction, metas []*solana.AccountMeta) (*Instruction, error) {
+	accounts := make([]*solana.AccountMeta, 0, len(ix.Accounts))
+	for _, index := range ix.Accounts {
+		if int(index) >= len(metas) {
+			return nil, fmt.Errorf("account index %d out of range", index)
+		}
+		accounts = append(accounts, metas[index])
+	}
+	if venueOf(program) == VenuePumpFun {
+		return DecodePumpInstruction(accounts, ix.Data)
+	}
+	return DecodeInstruction(accounts, ix.Data)
+}
+
+// swapFromInstruction returns the swap described by a buy or sell instruction, or nil for anything else
+func swapFromInstruction(ix *Instruction) *Swap {
+	swap := &Swap{
+		Venue:       ix.Venue,
+		Instruction: ix.Instruction,
+		Inner:       ix.Inner,
+		Program:     ix.Program,
+		User:        ix.Account("user"),
+	}
+	switch impl := ix.Impl.(type) {
+	case *amm.Buy:
+		swap.Side = SideBuy
+		swap.BaseAmount = valueOf(impl.BaseAmountOut)
+		swap.QuoteLimit = valueOf(impl.MaxQuoteAmountIn)
+	case *amm.Sell:
+		swap.Side = SideSell
+		swap.BaseAmount = valueOf(impl.BaseAmountIn)
+		swap.QuoteLimit = valueOf(impl.MinQuoteAmountOut)
+	case *pump.Buy:
+		swap.Side = SideBuy
+		swap.BaseAmount = valueOf(impl.Amount)
+		swap.QuoteLimit = valueOf(impl.MaxSolCost)
+	case *pump.Sell:
+		swap.Side = SideSell
+		swap.BaseAmount = valueOf(impl.Amount)
+		swap.QuoteLimit = valueOf(impl.MinSolOutput)
+	default:
+		return nil
+	}
+	swap.Pool, swap.BaseMint = ix.Pool(), ix.BaseMint()
+	return swap
+}
+
+// Pool returns the PumpSwap pool or pump.fun bonding curve the instruction acts on
+func (i Instruction) Pool() solana.PublicKey {
+	if i.Venue == VenuePumpFun {
+		return i.Account("bonding_curve")
+	}
+	return i.Account("pool")
+}
+
+// BaseMint returns the mint of the token the instruction trades or creates
+func (i Instruction) BaseMint() solana.PublicKey {
+	if i.Venue == VenuePumpFun {
+		return i.Account("mint")
+	}
+	return i.Account("base_mint")
+}
+
+func valueOf(v *uint64) uint64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
@@ -0,0 +1,263 @@
+package decoder
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"solana-pumpswap-demo/idl/pumpfun/amm/idl/generated/amm"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// TestAccountRolesMatchIDL tests that the role table follows the PumpSwap IDL
+func TestAccountRolesMatchIDL(t *testing.T) {
+	raw, err := os.ReadFile("../../idl/pumpfun/amm/idl/idl.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var idl struct {
+		Instructions []struct {
+			Name     string `json:"name"`
+			Accounts []struct {
+				Name string `json:"name"`
+			} `json:"accounts"`
+		} `json:"instructions"`
+	}
+	if err := json.Unmarshal(raw, &idl); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(idl.Instructions) != len(AccountRoles) {
+		t.Errorf("AccountRoles has %d instructions, IDL has %d", len(AccountRoles), len(idl.Instructions))
+	}
+	for _, ix := range idl.Instructions {
+		// buy -> Buy, update_fee_config -> UpdateFeeConfig
+		var name string
+		for _, part := range strings.Split(ix.Name, "_") {
+			name += strings.ToUpper(part[:1]) + part[1:]
+		}
+
+		roles := AccountRoles[name]
+		if len(roles) != len(ix.Accounts) {
+			t.Errorf("%s has %d roles, IDL has %d accounts", name, len(roles), len(ix.Accounts))
+			continue
+		}
+		for i, account := range ix.Accounts {
+			if roles[i] != account.Name {
+				t.Errorf("%s account %d = %s, IDL has %s", name, i, roles[i], account.Name)
+			}
+		}
+	}
+}
+
+// TestPumpAccountRolesMatchIDL tests that the pump.fun role table follows the pump.fun IDL, whose
+// names mix camelCase and snake_case where the table uses snake_case throughout
+func TestPumpAccountRolesMatchIDL(t *testing.T) {
+	raw, err := os.ReadFile("../../idl/pumpfun/pump/idl/idl.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var idl struct {
+		Instructions []struct {
+			Name     string `json:"name"`
+			Accounts []struct {
+				Name string `json:"name"`
+			} `json:"accounts"`
+		} `json:"instructions"`
+	}
+	if err := json.Unmarshal(raw, &idl); err != nil {
+		t.Fatal(err)
+	}
+
+	// bondingCurve -> bonding_curve, associated_tokenProgram -> associated_token_program
+	snake := func(s string) string {
+		var b strings.Builder
+		for _, r := range s {
+			if r >= 'A' && r <= 'Z' {
+				b.WriteByte('_')
+				r += 'a' - 'A'
+			}
+			b.WriteRune(r)
+		}
+		return b.String()
+	}
+
+	if len(idl.Instructions) != len(PumpAccountRoles) {
+		t.Errorf("PumpAccountRoles has %d instructions, IDL has %d", len(PumpAccountRoles), len(idl.Instructions))
+	}
+	for _, ix := range idl.Instructions {
+		// setParams -> SetParams
+		name := strings.ToUpper(ix.Name[:1]) + ix.Name[1:]
+
+		roles := PumpAccountRoles[name]
+		if len(roles) != len(ix.Accounts) {
+			t.Errorf("%s has %d roles, IDL has %d accounts", name, len(roles), len(ix.Accounts))
+			continue
+		}
+		for i, account := range ix.Accounts {
+			if roles[i] != snake(account.Name) {
+				t.Errorf("%s account %d = %s, IDL has %s", name, i, roles[i], account.Name)
+			}
+		}
+	}
+}
+
+// TestDecodeInstruction tests decoding each kind of PumpSwap instruction into its generated type
+func TestDecodeInstruction(t *testing.T) {
+	tests := []struct {
+		name  string
+		inst  *amm.Instruction
+		check func(t *testing.T, impl interface{})
+	}{
+		{
+			name: "Buy",
+			inst: amm.NewBuyInstructionBuilder().SetBaseAmountOut(1000).SetMaxQuoteAmountIn(2000).Build(),
+			check: func(t *testing.T, impl interface{}) {
+				buy, ok := impl.(*amm.Buy)
+				if !ok || *buy.BaseAmountOut != 1000 || *buy.MaxQuoteAmountIn != 2000 {
+					t.Errorf("Impl = %#v", impl)
+				}
+			},
+		},
+		{
+			name: "Sell",
+			inst: amm.NewSellInstructionBuilder().SetBaseAmountIn(1000).SetMinQuoteAmountOut(900).Build(),
+			check: func(t *testing.T, impl interface{}) {
+				sell, ok := impl.(*amm.Sell)
+				if !ok || *sell.BaseAmountIn != 1000 || *sell.MinQuoteAmountOut != 900 {
+					t.Errorf("Impl = %#v", impl)
+				}
+			},
+		},
+		{
+			name: "CreatePool",
+			inst: amm.NewCreatePoolInstructionBuilder().SetIndex(1).SetBaseAmountIn(5000).SetQuoteAmountIn(6000).Build(),
+			check: func(t *testing.T, impl interface{}) {
+				create, ok := impl.(*amm.CreatePool)
+				if !ok || *create.Index != 1 || *create.BaseAmountIn != 5000 || *create.QuoteAmountIn != 6000 {
+					t.Errorf("Impl = %#v", impl)
+				}
+			},
+		},
+		{
+			name: "Deposit",
+			inst: amm.NewDepositInstructionBuilder().SetLpTokenAmountOut(10).SetMaxBaseAmountIn(20).SetMaxQuoteAmountIn(30).Build(),
+			check: func(t *testing.T, impl interface{}) {
+				deposit, ok := impl.(*amm.Deposit)
+				if !ok || *deposit.LpTokenAmountOut != 10 || *deposit.MaxBaseAmountIn != 20 || *deposit.MaxQuoteAmountIn != 30 {
+					t.Errorf("Impl = %#v", impl)
+				}
+			},
+		},
+		{
+			name: "Withdraw",
+			inst: amm.NewWithdrawInstructionBuilder().SetLpTokenAmountIn(10).SetMinBaseAmountOut(20).SetMinQuoteAmountOut(30).Build(),
+			check: func(t *testing.T, impl interface{}) {
+				withdraw, ok := impl.(*amm.Withdraw)
+				if !ok || *withdraw.LpTokenAmountIn != 10 || *withdraw.MinBaseAmountOut != 20 || *withdraw.MinQuoteAmountOut != 30 {
+					t.Errorf("Impl = %#v", impl)
+				}
+			},
+		},
+		{
+			name: "Disable",
+			inst: amm.NewDisableInstructionBuilder().SetDisableCreatePool(true).SetDisableDeposit(false).
+				SetDisableWithdraw(false).SetDisableBuy(true).SetDisableSell(false).Build(),
+			check: func(t *testing.T, impl interface{}) {
+				disable, ok := impl.(*amm.Disable)
+				if !ok || !*disable.DisableCreatePool || !*disable.DisableBuy || *disable.DisableSell {
+					t.Errorf("Impl = %#v", impl)
+				}
+			},
+		},
+		{
+			name: "UpdateFeeConfig",
+			inst: amm.NewUpdateFeeConfigInstructionBuilder().SetLpFeeBasisPoints(20).SetProtocolFeeBasisPoints(5).
+				SetProtocolFeeRecipients([8]solana.PublicKey{}).Build(),
+			check: func(t *testing.T, impl interface{}) {
+				update, ok := impl.(*amm.UpdateFeeConfig)
+				if !ok || *update.LpFeeBasisPoints != 20 || *update.ProtocolFeeBasisPoints != 5 {
+					t.Errorf("Impl = %#v", impl)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.inst.Data()
+			if err != nil {
+				t.Fatalf("Data() error = %v", err)
+			}
+
+			// One account more than the IDL declares
+			roles := AccountRoles[tt.name]
+			var accounts []*solana.AccountMeta
+			for range len(roles) + 1 {
+				accounts = append(accounts, solana.NewAccountMeta(solana.NewWallet().PublicKey(), false, false))
+			}
+
+			ix, err := DecodeInstruction(accounts, data)
+			if err != nil {
+				t.Fatalf("DecodeInstruction() error = %v", err)
+			}
+			if ix.Name != tt.name {
+				t.Errorf("Name = %s, want %s", ix.Name, tt.name)
+			}
+			tt.check(t, ix.Impl)
+
+			if len(ix.Accounts) != len(accounts) {
+				t.Fatalf("got %d accounts, want %d", len(ix.Accounts), len(accounts))
+			}
+			for i, role := range roles {
+				if ix.Accounts[i].Role != role || !ix.Account(role).Equals(accounts[i].PublicKey) {
+					t.Errorf("account %d = %+v, want role %s", i, ix.Accounts[i], role)
+				}
+			}
+			if last := ix.Accounts[len(roles)]; last.Role != remainingRole {
+				t.Errorf("extra account role = %s, want %s", last.Role, remainingRole)
+			}
+		})
+	}
+}
+
+// TestDecodeInstructionRejectsUnknownData tests that data without a known discriminator is an error
+func TestDecodeInstructionRejectsUnknownData(t *testing.T) {
+	if _, err := DecodeInstruction(nil, []byte{1, 2, 3, 4, 5, 6, 7, 8}); err == nil {
+		t.Error("DecodeInstruction() with an unknown discriminator returned no error")
+	}
+	if _, err := DecodeInstruction(nil, []byte{1, 2}); err == nil {
+		t.Error("DecodeInstruction() with short data returned no error")
+	}
+}
+
+// TestEventTypesMatchIDL checks that eventTypes decodes every event of both IDLs under its name
+func TestEventTypesMatchIDL(t *testing.T) {
+	for _, path := range []string{"../../idl/pumpfun/amm/idl/idl.json", "../../idl/pumpfun/pump/idl/idl.json"} {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var idl struct {
+			Events []struct {
+				Name          string  `json:"name"`
+				Discriminator [8]byte `json:"discriminator"`
+			} `json:"events"`
+		}
+		if err := json.Unmarshal(raw, &idl); err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range idl.Events {
+			newEvent, ok := eventTypes[e.Discriminator]
+			if !ok {
+				t.Errorf("%s: no event type for %s", path, e.Name)
+				continue
+			}
+			if got := eventName(newEvent()); got != e.Name {
+				t.Errorf("%s: discriminator of %s decodes %s", path, e.Name, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+
+	pumpfun "solana-pumpswap-demo/idl/pumpfun/pump"
+	"solana-pumpswap-demo/idl/pumpfun/pump/idl/generated/pump"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// PumpAccountRoles lists the accounts of each pump.fun instruction in order, named as in the IDL
+var PumpAccountRoles = map[string][]string{
+	"Initialize": {"global", "user", "system_program"},
+	"SetParams":  {"global", "user", "system_program", "event_authority", "program"},
+	"Create": {
+		"mint", "mint_authority", "bonding_curve", "associated_bonding_curve", "global",
+		"mpl_token_metadata", "metadata", "user",
+		"system_program", "token_program", "associated_token_program", "rent",
+		"event_authority", "program",
+	},
+	"Buy": {
+		"global", "fee_recipient", "mint", "bonding_curve", "associated_bonding_curve", "associated_user", "user",
+		"system_program", "token_program", "rent",
+		"event_authority", "program",
+	},
+	"Sell": {
+		"global", "fee_recipient", "mint", "bonding_curve", "associated_bonding_curve", "associated_user", "user",
+		"system_program", "associated_token_program", "token_program",
+		"event_authority", "program",
+	},
+	"Withdraw": {
+		"global", "last_withdraw", "mint", "bonding_curve", "associated_bonding_curve", "associated_user", "user",
+		"system_program", "token_program", "rent",
+		"event_authority", "program",
+	},
+}
+
+// DecodePumpInstruction decodes pump.fun instruction data with the generated instruction registry
+func DecodePumpInstruction(accounts []*solana.AccountMeta, data []byte) (*Instruction, error) {
+	decoded, err := solana.DecodeInstruction(pump.ProgramID, accounts, data)
+	if err != nil {
+		return nil, err
+	}
+	inst, ok := decoded.(*pump.Instruction)
+	if !ok {
+		return nil, fmt.Errorf("unexpected instruction type %T", decoded)
+	}
+
+	name := pump.InstructionIDToName(inst.TypeID)
+	return &Instruction{
+		Venue:    VenuePumpFun,
+		Name:     name,
+		Inner:    -1,
+		Impl:     inst.Impl,
+		Accounts: withRoles(accounts, PumpAccountRoles[name]),
+	}, nil
+}
+
+// programDataPrefix starts the log line a program writes with sol_log_data
+const programDataPrefix = "Program data: "
+
+// pumpLogEvents returns the payloads pump.fun wrote to the logs with sol_log_data, by the top-level
+// instruction it ran under. The logs are walked as a call stack so data logged by other programs,
+// including ones pump.fun calls, is left out. Lines that are not valid base64 are skipped.
+func pumpLogEvents(logs []string) map[int][][]byte {
+	out := make(map[int][][]byte)
+	var stack []string
+	instruction := -1
+	for _, line := range logs {
+		switch {
+		case strings.HasPrefix(line, programDataPrefix):
+			if len(stack) == 0 || stack[len(stack)-1] != pump.ProgramID.String() {
+				continue
+			}
+			payload, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(line, programDataPrefix))
+			if err != nil {
+				continue
+			}
+			out[instruction] = append(out[instruction], payload)
+
+		case strings.HasPrefix(line, "Program "):
+			fields := strings.Fields(line)
+			if len(fields) < 3 {
+				continue
+			}
+			switch {
+			case fields[2] == "invoke":
+				if len(fields) == 4 && fields[3] == "[1]" {
+					instruction++
+					stack = stack[:0]
+				}
+				stack = append(stack, fields[1])
+			case fields[2] == "success" || strings.HasPrefix(fields[2], "failed"):
+				if len(stack) > 0 {
+					stack = stack[:len(stack)-1]
+				}
+			}
+		}
+	}
+	return out
+}
+
+// DecodeLogEvents returns the pump.fun events written to the logs, in order. A logs subscription
+// delivers these without the transaction, events published through emit_cpi are not among them.
+// Payloads that do not decode are skipped.
+func DecodeLogEvents(logs []string) []Event {
+	byInstruction := pumpLogEvents(logs)
+	instructions := make([]int, 0, len(byInstruction))
+	for i := range byInstruction {
+		instructions = append(instructions, i)
+	}
+	sort.Ints(instructions)
+
+	var events []Event
+	for _, i := range instructions {
+		for _, payload := range byInstruction[i] {
+			data, err := decodeEventPayload(payload)
+			if err != nil || data == nil {
+				continue
+			}
+			events = append(events, Event{Venue: VenuePumpFun, Name: eventName(data), Instruction: i, Inner: -1, Data: data})
+		}
+	}
+	return events
+}
+
+// isEventData reports whether instruction data is an emit_cpi self-invocation
+func isEventData(data []byte) bool {
+	return len(data) >= 16 && bytes.Equal(data[:8], eventIxTag)
+}
+
+// tradeFromPump converts a bonding-curve trade. The curve reports no fee breakdown and its
+// reserves are virtual, so the fee fields stay zero and the reserves are the virtual ones.
+func tradeFromPump(e *pumpfun.TradeEventEventData) Trade {
+	side := SideSell
+	if e.IsBuy {
+		side = SideBuy
+	}
+	return Trade{
+		Venue:            VenuePumpFun,
+		Side:             side,
+		User:             e.User,
+		BaseMint:         e.Mint,
+		Timestamp:        e.Timestamp,
+		BaseAmount:       e.TokenAmount,
+		QuoteAmount:      e.SolAmount,
+		PoolBaseReserve:  e.VirtualTokenReserves,
+		PoolQuoteReserve: e.VirtualSolReserves,
+	}
+}
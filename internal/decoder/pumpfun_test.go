@@ -0,0 +1,224 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"solana-pumpswap-demo/idl/pumpfun/amm/idl/generated/amm"
+	pumpfun "solana-pumpswap-demo/idl/pumpfun/pump"
+	"solana-pumpswap-demo/idl/pumpfun/pump/idl/generated/pump"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// pumpAccounts returns the pump.fun buy/sell account list with the given mint, bonding curve and user
+func pumpAccounts(mint, bondingCurve, user solana.PublicKey) solana.AccountMetaSlice {
+	metas := solana.AccountMetaSlice{
+		solana.Meta(solana.NewWallet().PublicKey()),
+		solana.Meta(solana.NewWallet().PublicKey()).WRITE(),
+		solana.Meta(mint),
+		solana.Meta(bondingCurve).WRITE(),
+		solana.Meta(solana.NewWallet().PublicKey()).WRITE(),
+		solana.Meta(solana.NewWallet().PublicKey()).WRITE(),
+		solana.Meta(user).WRITE().SIGNER(),
+	}
+	for len(metas) < 12 {
+		metas = append(metas, solana.Meta(solana.NewWallet().PublicKey()))
+	}
+	return metas
+}
+
+// logData encodes an event as the "Program data:" log line sol_log_data writes
+func logData(t *testing.T, event interface {
+	MarshalWithEncoder(*bin.Encoder) error
+}) string {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	if err := event.MarshalWithEncoder(bin.NewBorshEncoder(buf)); err != nil {
+		t.Fatalf("MarshalWithEncoder() error = %v", err)
+	}
+	return programDataPrefix + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+// TestDecodePumpTradesFromCPI tests a bonding-curve buy next to a PumpSwap sell, each matched with its own event
+func TestDecodePumpTradesFromCPI(t *testing.T) {
+	user := solana.NewWallet().PublicKey()
+	mint := solana.NewWallet().PublicKey()
+	curve := solana.NewWallet().PublicKey()
+	pool := solana.NewWallet().PublicKey()
+
+	pumpBuy := solana.NewInstruction(pump.ProgramID, pumpAccounts(mint, curve, user), swapData(pump.Instruction_Buy, 2_000_000, 70_000_000))
+	ammSell := solana.NewInstruction(amm.ProgramID, swapAccounts(pool, user, mint), swapData(amm.Instruction_Sell, 2_000_000, 50_000_000))
+	tx, err := solana.NewTransaction([]solana.Instruction{pumpBuy, ammSell}, solana.Hash{}, solana.TransactionPayer(user))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pumpIndex, err := tx.Message.GetAccountIndex(pump.ProgramID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ammIndex, err := tx.Message.GetAccountIndex(amm.ProgramID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trade := &pumpfun.TradeEventEventData{
+		Mint:                 mint,
+		SolAmount:            65_000_000,
+		TokenAmount:          2_000_000,
+		IsBuy:                true,
+		User:                 user,
+		Timestamp:            1745000000,
+		VirtualSolReserves:   30_065_000_000,
+		VirtualTokenReserves: 1_070_998_000_000_000,
+		RealSolReserves:      65_000_000,
+		RealTokenReserves:    792_898_000_000_000,
+	}
+	sell := &amm.SellEventEventData{BaseAmountIn: 2_000_000, UserQuoteAmountOut: 60_000_000, Pool: pool, User: user}
+	inner := []rpc.InnerInstruction{
+		{Index: 0, Instructions: []solana.CompiledInstruction{
+			{ProgramIDIndex: pumpIndex, Data: eventData(t, trade)},
+		}},
+		{Index: 1, Instructions: []solana.CompiledInstruction{
+			{ProgramIDIndex: ammIndex, Data: eventData(t, sell)},
+		}},
+	}
+
+	swaps, err := DecodeSwaps(testTransaction(t, tx, inner))
+	if err != nil {
+		t.Fatalf("DecodeSwaps() error = %v", err)
+	}
+	if len(swaps) != 2 {
+		t.Fatalf("DecodeSwaps() returned %d swaps, want 2", len(swaps))
+	}
+
+	bonding := swaps[0]
+	if bonding.Venue != VenuePumpFun || bonding.Side != SideBuy || !bonding.Pool.Equals(curve) || !bonding.BaseMint.Equals(mint) ||
+		!bonding.User.Equals(user) || bonding.BaseAmount != 2_000_000 || bonding.QuoteLimit != 70_000_000 {
+		t.Errorf("bonding-curve swap = %+v", bonding)
+	}
+	want := Trade{
+		Venue: VenuePumpFun, Side: SideBuy, Instruction: 0, Pool: curve, User: user, BaseMint: mint, Timestamp: 1745000000,
+		BaseAmount: 2_000_000, QuoteAmount: 65_000_000,
+		PoolBaseReserve: 1_070_998_000_000_000, PoolQuoteReserve: 30_065_000_000,
+	}
+	if bonding.Trade == nil || *bonding.Trade != want {
+		t.Errorf("bonding-curve trade = %+v\nwant %+v", bonding.Trade, want)
+	}
+
+	pumpSwap := swaps[1]
+	if pumpSwap.Venue != VenuePumpSwap || pumpSwap.Trade == nil || pumpSwap.Trade.Venue != VenuePumpSwap || pumpSwap.Trade.QuoteAmount != 60_000_000 {
+		t.Errorf("PumpSwap swap = %+v, trade %+v", pumpSwap, pumpSwap.Trade)
+	}
+}
+
+// TestDecodePumpEventsFromLogs tests reading events logged by older pump.fun deployments
+func TestDecodePumpEventsFromLogs(t *testing.T) {
+	user := solana.NewWallet().PublicKey()
+	mint := solana.NewWallet().PublicKey()
+	curve := solana.NewWallet().PublicKey()
+
+	sellIx := solana.NewInstruction(pump.ProgramID, pumpAccounts(mint, curve, user), swapData(pump.Instruction_Sell, 5_000_000, 1_000_000))
+	tx, err := solana.NewTransaction([]solana.Instruction{sellIx}, solana.Hash{}, solana.TransactionPayer(user))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trade := &pumpfun.TradeEventEventData{Mint: mint, SolAmount: 1_200_000, TokenAmount: 5_000_000, User: user, VirtualSolReserves: 85_000_000_000}
+	complete := &pumpfun.CompleteEventEventData{User: user, Mint: mint, BondingCurve: curve}
+	other := &pumpfun.TradeEventEventData{Mint: mint, SolAmount: 1}
+	program := pump.ProgramID.String()
+	result := testTransaction(t, tx, nil)
+	result.Meta.LogMessages = []string{
+		"Program " + program + " invoke [1]",
+		"Program log: Instruction: Sell",
+		"Program " + solana.TokenProgramID.String() + " invoke [2]",
+		logData(t, other), // Logged by the token program, not pump.fun
+		"Program " + solana.TokenProgramID.String() + " success",
+		logData(t, trade),
+		logData(t, complete),
+		"Program " + program + " consumed 30000 of 200000 compute units",
+		"Program " + program + " success",
+	}
+
+	events, err := DecodeEvents(result)
+	if err != nil {
+		t.Fatalf("DecodeEvents() error = %v", err)
+	}
+	if len(events) != 2 || events[0].Name != "TradeEvent" || events[1].Name != "CompleteEvent" {
+		t.Fatalf("DecodeEvents() = %+v, want TradeEvent and CompleteEvent", events)
+	}
+	if events[0].Venue != VenuePumpFun || events[0].Instruction != 0 || events[0].Inner != -1 {
+		t.Errorf("trade event = %s at %d/%d", events[0].Venue, events[0].Instruction, events[0].Inner)
+	}
+	if data, ok := events[1].Data.(*pumpfun.CompleteEventEventData); !ok || !data.BondingCurve.Equals(curve) {
+		t.Errorf("complete event data = %+v", events[1].Data)
+	}
+
+	if logged := DecodeLogEvents(result.Meta.LogMessages); len(logged) != 2 || logged[1].Name != "CompleteEvent" || logged[1].Instruction != 0 {
+		t.Errorf("DecodeLogEvents() = %+v", logged)
+	}
+
+	trades, err := DecodeTrades(result)
+	if err != nil {
+		t.Fatalf("DecodeTrades() error = %v", err)
+	}
+	if len(trades) != 1 || trades[0].Side != SideSell || trades[0].QuoteAmount != 1_200_000 || !trades[0].Pool.Equals(curve) {
+		t.Errorf("DecodeTrades() = %+v", trades)
+	}
+}
+
+// TestDecodePumpCreate tests decoding a token launch with its accounts and event
+func TestDecodePumpCreate(t *testing.T) {
+	user := solana.NewWallet().PublicKey()
+	mint := solana.NewWallet().PublicKey()
+	curve := solana.NewWallet().PublicKey()
+
+	create := pump.NewCreateInstructionBuilder().SetName("Test").SetSymbol("TST").SetUri("https://example.com/test.json")
+	data, err := (&pump.Instruction{BaseVariant: bin.BaseVariant{TypeID: pump.Instruction_Create, Impl: create}}).Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	accounts := solana.AccountMetaSlice{solana.Meta(mint).WRITE().SIGNER(), solana.Meta(solana.NewWallet().PublicKey()), solana.Meta(curve).WRITE()}
+	for len(accounts) < 7 {
+		accounts = append(accounts, solana.Meta(solana.NewWallet().PublicKey()))
+	}
+	accounts = append(accounts, solana.Meta(user).WRITE().SIGNER())
+	tx, err := solana.NewTransaction([]solana.Instruction{solana.NewInstruction(pump.ProgramID, accounts, data)}, solana.Hash{}, solana.TransactionPayer(user))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := testTransaction(t, tx, nil)
+	result.Meta.LogMessages = []string{
+		"Program " + pump.ProgramID.String() + " invoke [1]",
+		logData(t, &pumpfun.CreateEventEventData{Name: "Test", Symbol: "TST", Uri: "https://example.com/test.json", Mint: mint, BondingCurve: curve, User: user}),
+		"Program " + pump.ProgramID.String() + " success",
+	}
+
+	instructions, err := DecodeInstructions(result)
+	if err != nil {
+		t.Fatalf("DecodeInstructions() error = %v", err)
+	}
+	if len(instructions) != 1 || instructions[0].Name != "Create" || instructions[0].Venue != VenuePumpFun ||
+		!instructions[0].BaseMint().Equals(mint) || !instructions[0].Pool().Equals(curve) || !instructions[0].Account("user").Equals(user) {
+		t.Fatalf("DecodeInstructions() = %+v", instructions)
+	}
+	if impl, ok := instructions[0].Impl.(*pump.Create); !ok || *impl.Symbol != "TST" {
+		t.Errorf("instruction impl = %+v", instructions[0].Impl)
+	}
+
+	events, err := DecodeEvents(result)
+	if err != nil {
+		t.Fatalf("DecodeEvents() error = %v", err)
+	}
+	if len(events) != 1 || events[0].Name != "CreateEvent" {
+		t.Fatalf("DecodeEvents() = %+v", events)
+	}
+	if e, ok := events[0].Data.(*pumpfun.CreateEventEventData); !ok || e.Symbol != "TST" || !e.Mint.Equals(mint) {
+		t.Errorf("create event = %+v", events[0].Data)
+	}
+}
@@ -0,0 +1,33 @@
+#!/bin/bash
+# Replace transactions of the decoder corpus with mainnet captures and regenerate the golden files.
+# Pass each corpus name with the signature of a mainnet transaction of the same kind, a failed buy
+# for failed_buy, a v0 transaction with a lookup table for v0_lookup_table_buy, a bonding curve
+# completing into a PumpSwap pool for migration and so on:
+#
+#   internal/decoder/testdata/capture.sh pumpswap_buy=<signature> pumpswap_sell=<signature>
+#
+# Run it from the repository root. RPC_ENDPOINT selects the node. sources.txt records each signature,
+# and the tests that replay the corpus are run again, as captured amounts differ from the seed ones.
+set -euo pipefail
+
+dir=internal/decoder/testdata/transactions
+if [ $# -eq 0 ]; then
+    echo "usage: $0 <name>=<signature>..." >&2
+    echo "synthesized or missing transactions:" >&2
+    grep -E ' (synthesized|missing)$' "$dir/sources.txt" | cut -d' ' -f1 >&2
+    exit 2
+fi
+
+for arg in "$@"; do
+    name=${arg%%=*}
+    signature=${arg#*=}
+    if [ "$name" = "$arg" ] || [ -z "$signature" ]; then
+        echo "invalid argument $arg, want <name>=<signature>" >&2
+        exit 2
+    fi
+    rm -f "$dir/$name.json"
+    go run ./cmd/tx_decoder record "$name" "$signature"
+done
+
+go test ./internal/decoder -run TestGolden -update
+go test ./internal/decoder ./internal/simulator ./cmd/tx_decoder
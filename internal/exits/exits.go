@@ -0,0 +1,304 @@
+package exits
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"solana-pumpswap-demo/internal/swapper"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+)
+
+// Reason describes why an exit fired
+type Reason string
+
+const (
+	ReasonTakeProfit   Reason = "take-profit"
+	ReasonStopLoss     Reason = "stop-loss"
+	ReasonTrailingStop Reason = "trailing-stop"
+	ReasonMaxHold      Reason = "max-hold"
+)
+
+// TakeProfit sells Fraction of the initial position once the price reaches Multiple times the entry price
+type TakeProfit struct {
+	Multiple decimal.Decimal // e.g. 2 for 2x
+	Fraction decimal.Decimal // e.g. 0.5 to sell half of the initial size
+}
+
+// Rules configures when a position is exited. Zero values disable a rule.
+type Rules struct {
+	TakeProfits  []TakeProfit    // Partial exits, evaluated in ascending Multiple order
+	StopLoss     decimal.Decimal // Drop from entry price, e.g. 0.3 for -30%
+	TrailingStop decimal.Decimal // Drop from the highest observed price, e.g. 0.2 for -20%
+	MaxHold      time.Duration   // Sell everything after holding this long
+}
+
+// Enabled reports whether any rule is set
+func (r Rules) Enabled() bool {
+	return len(r.TakeProfits) > 0 || r.StopLoss.IsPositive() || r.TrailingStop.IsPositive() || r.MaxHold > 0
+}
+
+// Position is an open copy position on a bonding curve or in a PumpSwap pool
+type Position struct {
+	ID            string
+	Mint          solana.PublicKey
+	Pool          swapper.PumpSwapPoolInfo
+	EntryPrice    decimal.Decimal // Quote lamports per raw base unit
+	InitialAmount uint64          // Raw base amount bought
+	Amount        uint64          // Raw base amount still held
+	OpenedAt      time.Time
+	PeakPrice     decimal.Decimal // Highest price seen since entry
+	TakenProfits  int             // Number of take-profit levels already sold
+}
+
+// Exit is a sell the manager decided to make
+type Exit struct {
+	PositionID string
+	Amount     uint64
+	Price      decimal.Decimal
+	Reason     Reason
+}
+
+// SpotPrice returns the pool price in quote lamports per raw base unit from the constant product reserves,
+// or zero when a reserve is empty. Evaluate treats a zero price as no signal.
+func SpotPrice(baseReserve, quoteReserve uint64) decimal.Decimal {
+	if baseReserve == 0 || quoteReserve == 0 {
+		return decimal.Zero
+	}
+	return decimal.NewFromUint64(quoteReserve).Div(decimal.NewFromUint64(baseReserve))
+}
+
+// Evaluate returns the exit a position needs at the given price, or nil if it should be held.
+// Full exits (stop loss, trailing stop, max hold) take precedence over partial take profits.
+// A price that is not positive means the price is unknown and never fires a rule.
+func (r Rules) Evaluate(p *Position, price decimal.Decimal, now time.Time) *Exit {
+	if p.Amount == 0 || !p.EntryPrice.IsPositive() || !price.IsPositive() {
+		return nil
+	}
+
+	full := func(reason Reason) *Exit {
+		return &Exit{PositionID: p.ID, Amount: p.Amount, Price: price, Reason: reason}
+	}
+
+	one := decimal.NewFromInt(1)
+	if r.StopLoss.IsPositive() && price.LessThanOrEqual(p.EntryPrice.Mul(one.Sub(r.StopLoss))) {
+		return full(ReasonStopLoss)
+	}
+	if r.TrailingStop.IsPositive() && p.PeakPrice.IsPositive() &&
+		price.LessThanOrEqual(p.PeakPrice.Mul(one.Sub(r.TrailingStop))) {
+		return full(ReasonTrailingStop)
+	}
+	if r.MaxHold > 0 && now.Sub(p.OpenedAt) >= r.MaxHold {
+		return full(ReasonMaxHold)
+	}
+
+	levels := r.sortedTakeProfits()
+	if p.TakenProfits >= len(levels) {
+		return nil
+	}
+	level := levels[p.TakenProfits]
+	if price.LessThan(p.EntryPrice.Mul(level.Multiple)) {
+		return nil
+	}
+
+	amount := uint64(decimal.NewFromUint64(p.InitialAmount).Mul(level.Fraction).IntPart())
+	if amount > p.Amount || p.TakenProfits == len(levels)-1 && level.Fraction.GreaterThanOrEqual(one) {
+		amount = p.Amount
+	}
+	if amount == 0 {
+		return nil
+	}
+	return &Exit{PositionID: p.ID, Amount: amount, Price: price, Reason: ReasonTakeProfit}
+}
+
+func (r Rules) sortedTakeProfits() []TakeProfit {
+	levels := make([]TakeProfit, len(r.TakeProfits))
+	copy(levels, r.TakeProfits)
+	sort.SliceStable(levels, func(i, j int) bool {
+		return levels[i].Multiple.LessThan(levels[j].Multiple)
+	})
+	return levels
+}
+
+// ReserveFetcher returns the base and quote reserves the position is priced against
+type ReserveFetcher func(ctx context.Context, p Position) (base, quote uint64, err error)
+
+// Seller sells exit.Amount raw base units of the position and returns the transaction signature
+type Seller func(ctx context.Context, p Position, exit Exit) (string, error)
+
+// RPCReserveFetcher reads the reserves from the vault token accounts of the position's pool
+func RPCReserveFetcher(client *rpc.Client) ReserveFetcher {
+	return func(ctx context.Context, p Position) (uint64, uint64, error) {
+		pool := p.Pool
+		baseVault, err := solana.PublicKeyFromBase58(pool.PoolBaseTokenAccount)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid pool base token account: %w", err)
+		}
+		quoteVault, err := solana.PublicKeyFromBase58(pool.PoolQuoteTokenAccount)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid pool quote token account: %w", err)
+		}
+
+		reserves, err := swapper.GetMultipleTokenBalances(ctx, client, baseVault, quoteVault)
+		if err != nil {
+			return 0, 0, err
+		}
+		if len(reserves) < 2 {
+			return 0, 0, fmt.Errorf("failed to get both pool reserves")
+		}
+		return reserves[0], reserves[1], nil
+	}
+}
+
+// Manager watches open positions and sells them when their exit rules fire
+type Manager struct {
+	rules    Rules
+	interval time.Duration
+	fetch    ReserveFetcher
+	sell     Seller
+
+	mu        sync.Mutex
+	positions map[string]*Position
+	now       func() time.Time
+}
+
+// NewManager creates a manager that checks every position once per interval
+func NewManager(rules Rules, interval time.Duration, fetch ReserveFetcher, sell Seller) *Manager {
+	return &Manager{
+		rules:     rules,
+		interval:  interval,
+		fetch:     fetch,
+		sell:      sell,
+		positions: make(map[string]*Position),
+		now:       time.Now,
+	}
+}
+
+// Open starts tracking a position. Opening one already tracked replaces it and keeps the highest
+// price seen, so positions can be refreshed from their fills.
+func (m *Manager) Open(p Position) {
+	if p.OpenedAt.IsZero() {
+		p.OpenedAt = m.now()
+	}
+	if p.Amount == 0 {
+		p.Amount = p.InitialAmount
+	}
+	if p.PeakPrice.LessThan(p.EntryPrice) {
+		p.PeakPrice = p.EntryPrice
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if old, ok := m.positions[p.ID]; ok && old.PeakPrice.GreaterThan(p.PeakPrice) {
+		p.PeakPrice = old.PeakPrice
+	}
+	m.positions[p.ID] = &p
+}
+
+// Close stops tracking a position without selling it
+func (m *Manager) Close(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.positions, id)
+}
+
+// Positions returns a snapshot of the open positions
+func (m *Manager) Positions() []Position {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]Position, 0, len(m.positions))
+	for _, p := range m.positions {
+		out = append(out, *p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].OpenedAt.Before(out[j].OpenedAt) })
+	return out
+}
+
+// Run checks the positions every interval until the context is cancelled
+func (m *Manager) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			m.Check(ctx)
+		}
+	}
+}
+
+// Check prices every open position once and executes the exits that fire
+func (m *Manager) Check(ctx context.Context) []Exit {
+	var exits []Exit
+	for _, p := range m.Positions() {
+		base, quote, err := m.fetch(ctx, p)
+		if err != nil {
+			log.Printf("exits: failed to fetch reserves for %s: %v", p.ID, err)
+			continue
+		}
+		price := SpotPrice(base, quote)
+		if !price.IsPositive() {
+			log.Printf("exits: no price for %s, reserves %d/%d", p.ID, base, quote)
+			continue
+		}
+
+		exit := m.observe(p.ID, price)
+		if exit == nil {
+			continue
+		}
+
+		sig, err := m.sell(ctx, p, *exit)
+		if err != nil {
+			log.Printf("exits: %s sell of %d for %s failed: %v", exit.Reason, exit.Amount, p.ID, err)
+			continue
+		}
+		log.Printf("exits: %s sold %d of %s at %s (tx %s)", exit.Reason, exit.Amount, p.ID, price, sig)
+
+		m.applyExit(*exit)
+		exits = append(exits, *exit)
+	}
+	return exits
+}
+
+// observe records the latest price and evaluates the rules under the lock
+func (m *Manager) observe(id string, price decimal.Decimal) *Exit {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.positions[id]
+	if !ok {
+		return nil
+	}
+	if price.GreaterThan(p.PeakPrice) {
+		p.PeakPrice = price
+	}
+	return m.rules.Evaluate(p, price, m.now())
+}
+
+// applyExit reduces the position after a successful sell and drops it once empty
+func (m *Manager) applyExit(exit Exit) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.positions[exit.PositionID]
+	if !ok {
+		return
+	}
+	if exit.Amount >= p.Amount {
+		delete(m.positions, exit.PositionID)
+		return
+	}
+	p.Amount -= exit.Amount
+	if exit.Reason == ReasonTakeProfit {
+		p.TakenProfits++
+	}
+}
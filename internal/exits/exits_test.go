@@ -0,0 +1,183 @@
+package exits
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func d(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+// TestEvaluate tests the exit rules against a single position
+func TestEvaluate(t *testing.T) {
+	opened := time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC)
+	rules := Rules{
+		TakeProfits: []TakeProfit{
+			{Multiple: d("3"), Fraction: d("0.5")},
+			{Multiple: d("2"), Fraction: d("0.5")},
+		},
+		StopLoss:     d("0.3"),
+		TrailingStop: d("0.25"),
+		MaxHold:      time.Hour,
+	}
+
+	tests := []struct {
+		name       string
+		position   Position
+		price      string
+		now        time.Time
+		wantReason Reason
+		wantAmount uint64
+	}{
+		{
+			name:     "hold below first take profit",
+			position: Position{ID: "a", EntryPrice: d("1"), PeakPrice: d("1.5"), InitialAmount: 1000, Amount: 1000, OpenedAt: opened},
+			price:    "1.5",
+			now:      opened.Add(time.Minute),
+		},
+		{
+			name:       "first take profit sells half at 2x",
+			position:   Position{ID: "a", EntryPrice: d("1"), PeakPrice: d("2"), InitialAmount: 1000, Amount: 1000, OpenedAt: opened},
+			price:      "2",
+			now:        opened.Add(time.Minute),
+			wantReason: ReasonTakeProfit,
+			wantAmount: 500,
+		},
+		{
+			name:     "second take profit not reached",
+			position: Position{ID: "a", EntryPrice: d("1"), PeakPrice: d("2.5"), InitialAmount: 1000, Amount: 500, OpenedAt: opened, TakenProfits: 1},
+			price:    "2.5",
+			now:      opened.Add(time.Minute),
+		},
+		{
+			name:       "second take profit capped at remaining amount",
+			position:   Position{ID: "a", EntryPrice: d("1"), PeakPrice: d("3"), InitialAmount: 1000, Amount: 400, OpenedAt: opened, TakenProfits: 1},
+			price:      "3",
+			now:        opened.Add(time.Minute),
+			wantReason: ReasonTakeProfit,
+			wantAmount: 400,
+		},
+		{
+			name:       "stop loss sells everything",
+			position:   Position{ID: "a", EntryPrice: d("1"), PeakPrice: d("1"), InitialAmount: 1000, Amount: 1000, OpenedAt: opened},
+			price:      "0.7",
+			now:        opened.Add(time.Minute),
+			wantReason: ReasonStopLoss,
+			wantAmount: 1000,
+		},
+		{
+			name:       "trailing stop from peak",
+			position:   Position{ID: "a", EntryPrice: d("1"), PeakPrice: d("1.8"), InitialAmount: 1000, Amount: 1000, OpenedAt: opened},
+			price:      "1.35",
+			now:        opened.Add(time.Minute),
+			wantReason: ReasonTrailingStop,
+			wantAmount: 1000,
+		},
+		{
+			name:       "max hold",
+			position:   Position{ID: "a", EntryPrice: d("1"), PeakPrice: d("1.1"), InitialAmount: 1000, Amount: 600, OpenedAt: opened},
+			price:      "1.1",
+			now:        opened.Add(time.Hour),
+			wantReason: ReasonMaxHold,
+			wantAmount: 600,
+		},
+		{
+			name:     "unknown price is no signal",
+			position: Position{ID: "a", EntryPrice: d("1"), PeakPrice: d("1"), InitialAmount: 1000, Amount: 1000, OpenedAt: opened},
+			price:    "0",
+			now:      opened.Add(time.Minute),
+		},
+		{
+			name:     "empty position",
+			position: Position{ID: "a", EntryPrice: d("1"), PeakPrice: d("1"), InitialAmount: 1000, OpenedAt: opened},
+			price:    "0.1",
+			now:      opened.Add(2 * time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rules.Evaluate(&tt.position, d(tt.price), tt.now)
+			if tt.wantReason == "" {
+				if got != nil {
+					t.Fatalf("Evaluate() = %+v, want hold", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("Evaluate() = nil, want %s", tt.wantReason)
+			}
+			if got.Reason != tt.wantReason || got.Amount != tt.wantAmount {
+				t.Errorf("Evaluate() = %s/%d, want %s/%d", got.Reason, got.Amount, tt.wantReason, tt.wantAmount)
+			}
+		})
+	}
+}
+
+// TestManagerCheck tests that the manager sells through the seller and shrinks positions
+func TestManagerCheck(t *testing.T) {
+	// Pool price starts at 1 lamport per raw unit and is moved by the test
+	base, quote := uint64(1_000_000), uint64(1_000_000)
+	fetch := func(ctx context.Context, p Position) (uint64, uint64, error) {
+		return base, quote, nil
+	}
+
+	var sold []uint64
+	sell := func(ctx context.Context, p Position, exit Exit) (string, error) {
+		sold = append(sold, exit.Amount)
+		return "sig", nil
+	}
+
+	rules := Rules{
+		TakeProfits:  []TakeProfit{{Multiple: d("2"), Fraction: d("0.5")}},
+		StopLoss:     d("0.5"),
+		TrailingStop: d("0.2"),
+	}
+	m := NewManager(rules, time.Second, fetch, sell)
+	m.Open(Position{ID: "pos", EntryPrice: d("1"), InitialAmount: 1000})
+
+	ctx := context.Background()
+	if exits := m.Check(ctx); len(exits) != 0 {
+		t.Fatalf("unexpected exits at entry price: %+v", exits)
+	}
+	// An empty pool gives no price, which must not look like a crash to zero
+	base = 0
+	if exits := m.Check(ctx); len(exits) != 0 {
+		t.Fatalf("unexpected exits without a price: %+v", exits)
+	}
+	base = 1_000_000
+
+	// 2x: take half
+	quote = 2_000_000
+	exits := m.Check(ctx)
+	if len(exits) != 1 || exits[0].Reason != ReasonTakeProfit || exits[0].Amount != 500 {
+		t.Fatalf("Check() at 2x = %+v, want one take profit of 500", exits)
+	}
+	if got := m.Positions(); len(got) != 1 || got[0].Amount != 500 || got[0].TakenProfits != 1 {
+		t.Fatalf("position after take profit = %+v", got)
+	}
+	// Refreshing the position from its fill keeps the highest price seen
+	m.Open(Position{ID: "pos", EntryPrice: d("1"), InitialAmount: 1000, Amount: 500, TakenProfits: 1})
+	if got := m.Positions(); len(got) != 1 || !got[0].PeakPrice.Equal(d("2")) {
+		t.Fatalf("refreshed position = %+v", got)
+	}
+
+	// Run up to 2.5x then fall 20% from the peak: trailing stop sells the rest
+	quote = 2_500_000
+	m.Check(ctx)
+	quote = 2_000_000
+	exits = m.Check(ctx)
+	if len(exits) != 1 || exits[0].Reason != ReasonTrailingStop || exits[0].Amount != 500 {
+		t.Fatalf("Check() after pullback = %+v, want trailing stop of 500", exits)
+	}
+	if got := m.Positions(); len(got) != 0 {
+		t.Fatalf("position should be closed, got %+v", got)
+	}
+	if len(sold) != 2 {
+		t.Errorf("seller called %d times, want 2", len(sold))
+	}
+}
@@ -0,0 +1,218 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"solana-pumpswap-demo/internal/decoder"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// MaxPageSize is the most signatures getSignaturesForAddress returns per call
+const MaxPageSize = 1000
+
+// SignatureLister lists an account's signatures, newest first. *rpc.Client implements it.
+type SignatureLister interface {
+	GetSignaturesForAddressWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error)
+}
+
+// Query selects a range of an account's transactions, walking backwards from the newest
+type Query struct {
+	Account    solana.PublicKey
+	Commitment rpc.CommitmentType
+	Limit      int              // Most signatures to return, 0 for no limit
+	Before     solana.Signature // Start from transactions older than this one, zero for the newest
+	Until      solana.Signature // Stop at this transaction, exclusive, zero for no bound
+	Start      time.Time        // Skip transactions older than this, zero for no bound
+	End        time.Time        // Skip transactions newer than this, zero for no bound
+	PageSize   int              // Signatures per request, MaxPageSize if zero
+}
+
+// Signatures pages backwards through the account's signatures until the query's limit, its
+// Until signature, its Start time or the account's first transaction is reached.
+// Signatures are returned newest first. Transactions without a block time are kept.
+func Signatures(ctx context.Context, client SignatureLister, q Query) ([]*rpc.TransactionSignature, error) {
+	pageSize := q.PageSize
+	if pageSize <= 0 || pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+
+	var out []*rpc.TransactionSignature
+	before := q.Before
+	for {
+		limit := pageSize
+		if q.Limit > 0 && q.Limit-len(out) < limit {
+			limit = q.Limit - len(out)
+		}
+		page, err := client.GetSignaturesForAddressWithOpts(ctx, q.Account, &rpc.GetSignaturesForAddressOpts{
+			Limit:      &limit,
+			Before:     before,
+			Until:      q.Until,
+			Commitment: q.Commitment,
+		})
+		if err != nil {
+			return out, fmt.Errorf("failed to get signatures before %s: %w", before, err)
+		}
+
+		for _, sig := range page {
+			if sig.BlockTime != nil {
+				blockTime := sig.BlockTime.Time()
+				if !q.Start.IsZero() && blockTime.Before(q.Start) {
+					return out, nil
+				}
+				if !q.End.IsZero() && blockTime.After(q.End) {
+					continue
+				}
+			}
+			out = append(out, sig)
+			if q.Limit > 0 && len(out) >= q.Limit {
+				return out, nil
+			}
+		}
+
+		// A short page means the account has no older transactions in range
+		if len(page) < limit {
+			return out, nil
+		}
+		before = page[len(page)-1].Signature
+	}
+}
+
+// FetchFunc fetches one transaction
+type FetchFunc func(ctx context.Context, signature solana.Signature) (*rpc.GetTransactionResult, error)
+
+// Fetch fetches the transactions of sigs with at most workers requests in flight and calls fn with
+// each one in the order of sigs, so the output stays in order while the requests overlap.
+// Fetches run at most 2*workers signatures ahead of the one fn is waiting for, so a slow transaction
+// holds back the rest instead of letting finished results pile up behind it.
+// A fetch error is passed to fn, an error returned by fn stops the remaining fetches.
+func Fetch(ctx context.Context, sigs []*rpc.TransactionSignature, workers int, fetch FetchFunc,
+	fn func(sig *rpc.TransactionSignature, tx *rpc.GetTransactionResult, err error) error) error {
+	if workers <= 0 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+
+	type result struct {
+		tx  *rpc.GetTransactionResult
+		err error
+	}
+	results := make([]chan result, len(sigs))
+	for i := range results {
+		results[i] = make(chan result, 1)
+	}
+
+	// A slot is taken when a signature is handed to a worker and given back once fn has seen it
+	window := make(chan struct{}, 2*workers)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				tx, err := fetch(ctx, sigs[i].Signature)
+				results[i] <- result{tx, err}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for i := range sigs {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	// Stop the workers before returning, whether or not every transaction was handled
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	for i, sig := range sigs {
+		var r result
+		select {
+		case r = <-results[i]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if err := fn(sig, r.tx, r.err); err != nil {
+			return err
+		}
+		<-window
+	}
+	return nil
+}
+
+// Filter selects decoded transactions. The zero Filter matches everything.
+type Filter struct {
+	SuccessOnly bool
+	Side        string           // decoder.SideBuy or decoder.SideSell, empty for any
+	Mint        solana.PublicKey // Base mint of the swap, zero for any
+	MinQuote    uint64           // Smallest SOL amount of the swap in lamports
+}
+
+// swapCriteria reports whether the filter only matches transactions with a swap
+func (f Filter) swapCriteria() bool {
+	return f.Side != "" || !f.Mint.IsZero() || f.MinQuote > 0
+}
+
+// MatchSignature reports whether a listed signature can match, so failed transactions are not fetched
+func (f Filter) MatchSignature(sig *rpc.TransactionSignature) bool {
+	return !f.SuccessOnly || sig.Err == nil
+}
+
+// Match reports whether the transaction matches. With swap criteria it must contain a PumpSwap or bonding-curve
+// swap, direct or routed, that meets all of them. Executed amounts are used when the swap emitted its event.
+func (f Filter) Match(tx *rpc.GetTransactionResult) (bool, error) {
+	if tx == nil {
+		return false, nil
+	}
+	if f.SuccessOnly && (tx.Meta == nil || tx.Meta.Err != nil) {
+		return false, nil
+	}
+	if !f.swapCriteria() {
+		return true, nil
+	}
+
+	swaps, err := decoder.DecodeSwaps(tx)
+	if err != nil {
+		return false, err
+	}
+	for _, swap := range swaps {
+		if f.matchSwap(swap) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f Filter) matchSwap(swap decoder.Swap) bool {
+	if f.Side != "" && swap.Side != f.Side {
+		return false
+	}
+	if !f.Mint.IsZero() && !swap.BaseMint.Equals(f.Mint) {
+		return false
+	}
+	return quoteAmount(swap) >= f.MinQuote
+}
+
+// quoteAmount is the SOL side of the swap, what executed if known and the instruction's limit otherwise
+func quoteAmount(swap decoder.Swap) uint64 {
+	if swap.Trade != nil {
+		return swap.Trade.QuoteAmount
+	}
+	return swap.QuoteLimit
+}
@@ -0,0 +1,204 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"solana-pumpswap-demo/internal/decoder"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// fakeLister serves signatures newest first the way getSignaturesForAddress pages them
+type fakeLister struct {
+	sigs  []*rpc.TransactionSignature
+	calls int
+}
+
+func (f *fakeLister) GetSignaturesForAddressWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error) {
+	f.calls++
+	start := 0
+	if !opts.Before.IsZero() {
+		for i, sig := range f.sigs {
+			if sig.Signature == opts.Before {
+				start = i + 1
+			}
+		}
+	}
+	var page []*rpc.TransactionSignature
+	for _, sig := range f.sigs[start:] {
+		if sig.Signature == opts.Until || len(page) == *opts.Limit {
+			break
+		}
+		page = append(page, sig)
+	}
+	return page, nil
+}
+
+// newFakeLister creates n signatures one minute apart, the newest at base
+func newFakeLister(n int, base time.Time) *fakeLister {
+	f := &fakeLister{}
+	for i := 0; i < n; i++ {
+		var sig solana.Signature
+		sig[0], sig[1] = byte(i), byte(i>>8)
+		sig[63] = 1
+		blockTime := solana.UnixTimeSeconds(base.Add(-time.Duration(i) * time.Minute).Unix())
+		f.sigs = append(f.sigs, &rpc.TransactionSignature{Signature: sig, BlockTime: &blockTime})
+	}
+	return f
+}
+
+// TestSignaturesPages tests walking back across pages up to the limit, the until signature and the start time
+func TestSignaturesPages(t *testing.T) {
+	base := time.Unix(1_750_000_000, 0)
+	ctx := context.Background()
+
+	lister := newFakeLister(25, base)
+	sigs, err := Signatures(ctx, lister, Query{Limit: 12, PageSize: 5})
+	if err != nil {
+		t.Fatalf("Signatures() error = %v", err)
+	}
+	if len(sigs) != 12 || sigs[11] != lister.sigs[11] || lister.calls != 3 {
+		t.Errorf("limit 12: got %d signatures in %d calls", len(sigs), lister.calls)
+	}
+
+	lister = newFakeLister(25, base)
+	sigs, _ = Signatures(ctx, lister, Query{Before: lister.sigs[2].Signature, Until: lister.sigs[20].Signature, PageSize: 5})
+	if len(sigs) != 17 || sigs[0] != lister.sigs[3] || sigs[16] != lister.sigs[19] {
+		t.Errorf("before/until: got %d signatures", len(sigs))
+	}
+
+	lister = newFakeLister(25, base)
+	sigs, _ = Signatures(ctx, lister, Query{
+		Start:    base.Add(-10 * time.Minute),
+		End:      base.Add(-4 * time.Minute),
+		PageSize: 5,
+	})
+	if len(sigs) != 7 || sigs[0] != lister.sigs[4] || sigs[6] != lister.sigs[10] || lister.calls != 3 {
+		t.Errorf("time range: got %d signatures in %d calls", len(sigs), lister.calls)
+	}
+}
+
+// TestFetchKeepsOrder tests that transactions fetched concurrently are handed over in signature order
+func TestFetchKeepsOrder(t *testing.T) {
+	sigs := newFakeLister(30, time.Now()).sigs
+
+	var inFlight, peak int32
+	fetch := func(ctx context.Context, signature solana.Signature) (*rpc.GetTransactionResult, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		// Later signatures finish first
+		time.Sleep(time.Duration(30-int(signature[0])) * 100 * time.Microsecond)
+		return &rpc.GetTransactionResult{Slot: uint64(signature[0])}, nil
+	}
+
+	var got []uint64
+	err := Fetch(context.Background(), sigs, 4, fetch, func(sig *rpc.TransactionSignature, tx *rpc.GetTransactionResult, err error) error {
+		if err != nil {
+			return err
+		}
+		got = append(got, tx.Slot)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	for i, slot := range got {
+		if slot != uint64(i) {
+			t.Fatalf("transaction %d handed over out of order: %v", i, got)
+		}
+	}
+	if len(got) != len(sigs) || peak > 4 {
+		t.Errorf("handled %d transactions with %d in flight, want %d with at most 4", len(got), peak, len(sigs))
+	}
+
+	stop := errors.New("stop")
+	err = Fetch(context.Background(), sigs, 4, fetch, func(*rpc.TransactionSignature, *rpc.GetTransactionResult, error) error {
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("Fetch() error = %v, want the callback's error", err)
+	}
+}
+
+// TestFetchBoundsLookAhead tests that a slow transaction stops the workers from running far ahead of it
+func TestFetchBoundsLookAhead(t *testing.T) {
+	sigs := newFakeLister(30, time.Now()).sigs
+
+	var started int32
+	release := make(chan struct{})
+	fetch := func(ctx context.Context, signature solana.Signature) (*rpc.GetTransactionResult, error) {
+		atomic.AddInt32(&started, 1)
+		if signature[0] == 0 {
+			<-release
+		}
+		return &rpc.GetTransactionResult{Slot: uint64(signature[0])}, nil
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- Fetch(context.Background(), sigs, 2, fetch, func(*rpc.TransactionSignature, *rpc.GetTransactionResult, error) error {
+			return nil
+		})
+	}()
+
+	// The first transaction blocks, so only the window behind it can be fetched
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&started); n != 4 {
+		t.Errorf("started %d fetches while the first was pending, want 4", n)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if n := atomic.LoadInt32(&started); n != int32(len(sigs)) {
+		t.Errorf("started %d fetches, want %d", n, len(sigs))
+	}
+}
+
+// TestFilter tests the success, side, mint and size criteria
+func TestFilter(t *testing.T) {
+	mint := solana.NewWallet().PublicKey()
+	buy := decoder.Swap{Side: decoder.SideBuy, BaseMint: mint, QuoteLimit: 2_000_000_000,
+		Trade: &decoder.Trade{QuoteAmount: 900_000_000}}
+	sell := decoder.Swap{Side: decoder.SideSell, BaseMint: mint, QuoteLimit: 500_000_000}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		swap   decoder.Swap
+		want   bool
+	}{
+		{name: "any", filter: Filter{}, swap: sell, want: true},
+		{name: "side", filter: Filter{Side: decoder.SideBuy}, swap: sell, want: false},
+		{name: "mint", filter: Filter{Mint: solana.NewWallet().PublicKey()}, swap: buy, want: false},
+		{name: "executed size", filter: Filter{MinQuote: 1_000_000_000}, swap: buy, want: false},
+		{name: "limit size", filter: Filter{Side: decoder.SideSell, Mint: mint, MinQuote: 500_000_000}, swap: sell, want: true},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.matchSwap(tt.swap); got != tt.want {
+			t.Errorf("%s: matchSwap() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	failed := &rpc.GetTransactionResult{Meta: &rpc.TransactionMeta{Err: "InstructionError"}}
+	if ok, _ := (Filter{SuccessOnly: true}).Match(failed); ok {
+		t.Error("SuccessOnly matched a failed transaction")
+	}
+	if (Filter{SuccessOnly: true}).MatchSignature(&rpc.TransactionSignature{Err: "InstructionError"}) {
+		t.Error("SuccessOnly matched a failed signature")
+	}
+	if ok, _ := (Filter{Side: decoder.SideBuy}).Match(&rpc.GetTransactionResult{Meta: &rpc.TransactionMeta{}}); ok {
+		t.Error("side filter matched a transaction without swaps")
+	}
+}
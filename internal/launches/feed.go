@@ -0,0 +1,157 @@
+package launches
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"solana-pumpswap-demo/idl/pumpfun/pump/idl/generated/pump"
+	"solana-pumpswap-demo/internal/decoder"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+)
+
+// createLog is the line pump.fun logs when a token is created
+const createLog = "Program log: Instruction: Create"
+
+// fetchAttempts and fetchDelay bound how long a transaction seen at processed is waited for
+const (
+	fetchAttempts = 5
+	fetchDelay    = 400 * time.Millisecond
+)
+
+// Client fetches the launch transactions whose events are not in the logs. *rpc.Client implements it.
+type Client interface {
+	GetTransaction(ctx context.Context, signature solana.Signature, opts *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error)
+}
+
+// Feed turns pump.fun log notifications into filtered launches
+type Feed struct {
+	// Initial is the curve a launch starts with, DefaultCurve until replaced with InitialCurve of the Global account
+	Initial pump.BondingCurve
+	// OnError is called with the transactions Run could not handle, nil ignores them.
+	// Subscription errors have a zero signature.
+	OnError func(signature solana.Signature, err error)
+
+	client     Client
+	commitment rpc.CommitmentType
+	filter     Filter
+}
+
+// NewFeed returns a feed fetching transactions at commitment and passing on the launches filter matches
+func NewFeed(client Client, commitment rpc.CommitmentType, filter Filter) *Feed {
+	return &Feed{
+		Initial:    DefaultCurve,
+		client:     client,
+		commitment: commitment,
+		filter:     filter,
+	}
+}
+
+// Handle checks a transaction's logs and returns the launches in it that pass the filter.
+// CreateEvents pump.fun writes to the logs are read from them directly, a transaction that
+// creates a token without one in its logs, published through emit_cpi, is fetched instead.
+func (f *Feed) Handle(ctx context.Context, slot uint64, signature solana.Signature, logs []string) ([]Launch, error) {
+	creates := false
+	for _, line := range logs {
+		if line == createLog {
+			creates = true
+			break
+		}
+	}
+	if !creates {
+		return nil, nil
+	}
+
+	events := decoder.DecodeLogEvents(logs)
+	launches := FromEvents(signature, events, f.Initial)
+	if len(launches) > 0 {
+		for i := range launches {
+			launches[i].Slot = slot
+		}
+	} else {
+		tx, err := f.fetch(ctx, signature)
+		if err != nil {
+			return nil, err
+		}
+		if launches, err = FromTransaction(signature, tx, f.Initial); err != nil {
+			return nil, fmt.Errorf("failed to decode launches of %s: %w", signature, err)
+		}
+	}
+
+	out := launches[:0]
+	for _, l := range launches {
+		if f.filter.Match(l) {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+// Run subscribes to the pump.fun logs and calls handle with each launch until ctx is cancelled
+func (f *Feed) Run(ctx context.Context, wsClient *ws.Client, handle func(Launch)) error {
+	// Processed gives the earliest notice, a transaction that has to be fetched is fetched at the feed's commitment
+	sub, err := wsClient.LogsSubscribeMentions(pump.ProgramID, rpc.CommitmentProcessed)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s logs: %w", pump.ProgramID, err)
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		result, err := sub.Recv(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			f.report(solana.Signature{}, err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		if result.Value.Err != nil {
+			continue
+		}
+		launches, err := f.Handle(ctx, result.Context.Slot, result.Value.Signature, result.Value.Logs)
+		if err != nil {
+			f.report(result.Value.Signature, err)
+		}
+		for _, l := range launches {
+			handle(l)
+		}
+	}
+}
+
+// fetch gets a transaction, waiting for it to reach the feed's commitment
+func (f *Feed) fetch(ctx context.Context, signature solana.Signature) (*rpc.GetTransactionResult, error) {
+	version := uint64(0)
+	var err error
+	for attempt := 0; attempt < fetchAttempts; attempt++ {
+		var tx *rpc.GetTransactionResult
+		tx, err = f.client.GetTransaction(ctx, signature, &rpc.GetTransactionOpts{
+			Encoding:                       solana.EncodingBase64,
+			Commitment:                     f.commitment,
+			MaxSupportedTransactionVersion: &version,
+		})
+		if err == nil {
+			return tx, nil
+		}
+		if !errors.Is(err, rpc.ErrNotFound) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(fetchDelay):
+		}
+	}
+	return nil, fmt.Errorf("failed to get transaction %s: %w", signature, err)
+}
+
+// report passes an error to OnError if it is set
+func (f *Feed) report(signature solana.Signature, err error) {
+	if f.OnError != nil && !errors.Is(err, context.Canceled) {
+		f.OnError(signature, err)
+	}
+}
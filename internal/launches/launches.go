@@ -0,0 +1,185 @@
+package launches
+
+import (
+	"regexp"
+	"time"
+
+	pumpfun "solana-pumpswap-demo/idl/pumpfun/pump"
+	"solana-pumpswap-demo/idl/pumpfun/pump/idl/generated/pump"
+	"solana-pumpswap-demo/internal/decoder"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// DefaultCurve is the state a new bonding curve starts in on mainnet, used until the Global account is read
+var DefaultCurve = pump.BondingCurve{
+	VirtualTokenReserves: 1_073_000_000_000_000,
+	VirtualSolReserves:   30_000_000_000,
+	RealTokenReserves:    793_100_000_000_000,
+	TokenTotalSupply:     1_000_000_000_000_000,
+}
+
+// InitialCurve returns the state the Global account starts every bonding curve in
+func InitialCurve(global *pump.Global) pump.BondingCurve {
+	return pump.BondingCurve{
+		VirtualTokenReserves: global.InitialVirtualTokenReserves,
+		VirtualSolReserves:   global.InitialVirtualSolReserves,
+		RealTokenReserves:    global.InitialRealTokenReserves,
+		TokenTotalSupply:     global.TokenTotalSupply,
+	}
+}
+
+// Launch is a new pump.fun token
+type Launch struct {
+	Signature    solana.Signature
+	Slot         uint64
+	BlockTime    time.Time // Zero when the transaction was not fetched or the node did not report it
+	Mint         solana.PublicKey
+	BondingCurve solana.PublicKey
+	Creator      solana.PublicKey
+	Name         string
+	Symbol       string
+	URI          string
+	Curve        pump.BondingCurve // As the launch transaction left it, after any buys in the same transaction
+	DevBuySol    uint64            // Lamports the creator spent buying in the launch transaction
+	DevBuyTokens uint64            // Tokens the creator bought in the launch transaction
+}
+
+// Filter selects launches by creator and name. The zero Filter matches every launch.
+type Filter struct {
+	Creators        []solana.PublicKey // Only launches by these creators, any creator if empty
+	ExcludeCreators []solana.PublicKey // Launches by these creators are dropped
+	Names           []*regexp.Regexp   // The name or symbol must match one of these, any name if empty
+	ExcludeNames    []*regexp.Regexp   // Launches whose name or symbol matches one of these are dropped
+}
+
+// Match reports whether the launch passes the filter
+func (f Filter) Match(l Launch) bool {
+	if len(f.Creators) > 0 && !containsKey(f.Creators, l.Creator) {
+		return false
+	}
+	if containsKey(f.ExcludeCreators, l.Creator) {
+		return false
+	}
+	if len(f.Names) > 0 && !matchesName(f.Names, l) {
+		return false
+	}
+	return !matchesName(f.ExcludeNames, l)
+}
+
+func containsKey(keys []solana.PublicKey, key solana.PublicKey) bool {
+	for _, k := range keys {
+		if k.Equals(key) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesName(patterns []*regexp.Regexp, l Launch) bool {
+	for _, re := range patterns {
+		if re.MatchString(l.Name) || re.MatchString(l.Symbol) {
+			return true
+		}
+	}
+	return false
+}
+
+// FromEvents returns a launch for each CreateEvent. The curve is the one the transaction's last
+// TradeEvent of the mint reports, or initial when nothing was bought in the launch transaction.
+func FromEvents(signature solana.Signature, events []decoder.Event, initial pump.BondingCurve) []Launch {
+	var out []Launch
+	for _, event := range events {
+		if created, ok := event.Data.(*pumpfun.CreateEventEventData); ok {
+			out = append(out, Launch{
+				Signature:    signature,
+				Mint:         created.Mint,
+				BondingCurve: created.BondingCurve,
+				Creator:      created.User,
+				Name:         created.Name,
+				Symbol:       created.Symbol,
+				URI:          created.Uri,
+				Curve:        initial,
+			})
+		}
+	}
+	for i := range out {
+		applyTrades(&out[i], events)
+	}
+	return out
+}
+
+// FromTransaction returns the launches of a fetched transaction. Each Create instruction is
+// described by its CreateEvent, or by its own arguments and accounts if the event is missing.
+func FromTransaction(signature solana.Signature, tx *rpc.GetTransactionResult, initial pump.BondingCurve) ([]Launch, error) {
+	events, err := decoder.DecodeEvents(tx)
+	if err != nil {
+		return nil, err
+	}
+	instructions, err := decoder.DecodeInstructions(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := FromEvents(signature, events, initial)
+	for _, ix := range instructions {
+		create, ok := ix.Impl.(*pump.Create)
+		if !ok || hasMint(out, ix.BaseMint()) {
+			continue
+		}
+		l := Launch{
+			Signature:    signature,
+			Mint:         ix.BaseMint(),
+			BondingCurve: ix.Pool(),
+			Creator:      ix.Account("user"),
+			Curve:        initial,
+		}
+		if create.Name != nil {
+			l.Name = *create.Name
+		}
+		if create.Symbol != nil {
+			l.Symbol = *create.Symbol
+		}
+		if create.Uri != nil {
+			l.URI = *create.Uri
+		}
+		applyTrades(&l, events)
+		out = append(out, l)
+	}
+
+	for i := range out {
+		out[i].Slot = tx.Slot
+		if tx.BlockTime != nil {
+			out[i].BlockTime = tx.BlockTime.Time()
+		}
+	}
+	return out, nil
+}
+
+// applyTrades sets the curve from the last trade of the launch's mint and adds up the creator's buys
+func applyTrades(l *Launch, events []decoder.Event) {
+	for _, event := range events {
+		trade, ok := event.Data.(*pumpfun.TradeEventEventData)
+		if !ok || !trade.Mint.Equals(l.Mint) {
+			continue
+		}
+		l.Curve.VirtualTokenReserves = trade.VirtualTokenReserves
+		l.Curve.VirtualSolReserves = trade.VirtualSolReserves
+		l.Curve.RealTokenReserves = trade.RealTokenReserves
+		l.Curve.RealSolReserves = trade.RealSolReserves
+		if trade.IsBuy && trade.User.Equals(l.Creator) {
+			l.DevBuySol += trade.SolAmount
+			l.DevBuyTokens += trade.TokenAmount
+		}
+	}
+}
+
+func hasMint(launches []Launch, mint solana.PublicKey) bool {
+	for _, l := range launches {
+		if l.Mint.Equals(mint) {
+			return true
+		}
+	}
+	return false
+}
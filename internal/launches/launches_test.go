@@ -0,0 +1,164 @@
+package launches
+
+import (
+	"context"
+	"encoding/base64"
+	"regexp"
+	"testing"
+
+	pumpfun "solana-pumpswap-demo/idl/pumpfun/pump"
+	"solana-pumpswap-demo/idl/pumpfun/pump/idl/generated/pump"
+	"solana-pumpswap-demo/internal/decoder"
+	"solana-pumpswap-demo/internal/rpctest"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+func createEvent(creator solana.PublicKey) *pumpfun.CreateEventEventData {
+	return &pumpfun.CreateEventEventData{
+		Name:         "Moon Cat",
+		Symbol:       "MCAT",
+		Uri:          "https://example.com/mcat.json",
+		Mint:         solana.NewWallet().PublicKey(),
+		BondingCurve: solana.NewWallet().PublicKey(),
+		User:         creator,
+	}
+}
+
+// devBuy returns the TradeEvent of the creator buying in the launch transaction
+func devBuy(created *pumpfun.CreateEventEventData) *pumpfun.TradeEventEventData {
+	return &pumpfun.TradeEventEventData{
+		Mint:                 created.Mint,
+		SolAmount:            1_000_000_000,
+		TokenAmount:          34_612_903_225_806,
+		IsBuy:                true,
+		User:                 created.User,
+		VirtualSolReserves:   31_000_000_000,
+		VirtualTokenReserves: 1_038_387_096_774_194,
+		RealSolReserves:      1_000_000_000,
+		RealTokenReserves:    758_487_096_774_194,
+	}
+}
+
+// TestFilter tests the creator lists and name patterns
+func TestFilter(t *testing.T) {
+	creator, other := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	l := Launch{Creator: creator, Name: "Moon Cat", Symbol: "MCAT"}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"zero", Filter{}, true},
+		{"allowed creator", Filter{Creators: []solana.PublicKey{other, creator}}, true},
+		{"other creator", Filter{Creators: []solana.PublicKey{other}}, false},
+		{"excluded creator", Filter{ExcludeCreators: []solana.PublicKey{creator}}, false},
+		{"name", Filter{Names: []*regexp.Regexp{regexp.MustCompile(`(?i)cat`)}}, true},
+		{"symbol", Filter{Names: []*regexp.Regexp{regexp.MustCompile(`^MCAT$`)}}, true},
+		{"no name", Filter{Names: []*regexp.Regexp{regexp.MustCompile(`dog`)}}, false},
+		{"excluded name", Filter{ExcludeNames: []*regexp.Regexp{regexp.MustCompile(`Moon`)}}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Match(l); got != tt.want {
+			t.Errorf("%s: Match() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestFromEvents tests a launch with the creator's buy in the same transaction and one without
+func TestFromEvents(t *testing.T) {
+	bought, quiet := createEvent(solana.NewWallet().PublicKey()), createEvent(solana.NewWallet().PublicKey())
+	events := []decoder.Event{
+		{Name: "CreateEvent", Data: bought},
+		{Name: "TradeEvent", Data: devBuy(bought)},
+		{Name: "CreateEvent", Data: quiet},
+	}
+
+	got := FromEvents(solana.Signature{1}, events, DefaultCurve)
+	if len(got) != 2 {
+		t.Fatalf("got %d launches, want 2", len(got))
+	}
+	l := got[0]
+	if !l.Mint.Equals(bought.Mint) || !l.Creator.Equals(bought.User) || l.Name != bought.Name || l.Symbol != bought.Symbol || l.URI != bought.Uri {
+		t.Errorf("launch = %+v", l)
+	}
+	if l.DevBuySol != 1_000_000_000 || l.DevBuyTokens != 34_612_903_225_806 ||
+		l.Curve.RealSolReserves != 1_000_000_000 || l.Curve.VirtualTokenReserves != 1_038_387_096_774_194 || l.Curve.TokenTotalSupply != DefaultCurve.TokenTotalSupply {
+		t.Errorf("bought launch curve = %+v, dev buy %d/%d", l.Curve, l.DevBuySol, l.DevBuyTokens)
+	}
+	if got[1].Curve != DefaultCurve || got[1].DevBuySol != 0 {
+		t.Errorf("launch without trades = %+v", got[1])
+	}
+}
+
+// createTransaction returns a transaction creating a token with no events in its metadata
+func createTransaction(t *testing.T, created *pumpfun.CreateEventEventData) *rpc.GetTransactionResult {
+	p := solana.NewWallet().PublicKey
+	ix, err := pump.NewCreateInstruction(created.Name, created.Symbol, created.Uri,
+		created.Mint, p(), created.BondingCurve, p(), p(), p(), p(), created.User,
+		solana.SystemProgramID, solana.TokenProgramID, solana.SPLAssociatedTokenAccountProgramID, solana.SysVarRentPubkey, p(), pump.ProgramID,
+	).ValidateAndBuild()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := solana.NewTransaction([]solana.Instruction{ix}, solana.Hash{}, solana.TransactionPayer(created.User))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := rpctest.NewTransactionResult(42, tx, &rpc.TransactionMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blockTime := solana.UnixTimeSeconds(1745000000)
+	result.BlockTime = &blockTime
+	return result
+}
+
+// TestFeedHandle tests a launch read from the logs and one fetched for its Create instruction
+func TestFeedHandle(t *testing.T) {
+	fromLogs, fetched := createEvent(solana.NewWallet().PublicKey()), createEvent(solana.NewWallet().PublicKey())
+	fetchedSig := solana.Signature{2}
+	srv := rpctest.NewServer()
+	defer srv.Close()
+	if err := srv.AddTransaction(fetchedSig, createTransaction(t, fetched)); err != nil {
+		t.Fatal(err)
+	}
+	feed := NewFeed(rpc.New(srv.URL), rpc.CommitmentConfirmed, Filter{})
+	ctx := context.Background()
+
+	program := pump.ProgramID.String()
+	logs := []string{
+		"Program " + program + " invoke [1]",
+		createLog,
+		"Program data: " + base64.StdEncoding.EncodeToString(rpctest.Borsh(t, fromLogs)),
+		"Program " + program + " success",
+	}
+	got, err := feed.Handle(ctx, 7, solana.Signature{1}, logs)
+	if err != nil || len(got) != 1 || srv.Calls("getTransaction") != 0 {
+		t.Fatalf("Handle(logs) = %+v, %v after %d fetches", got, err, srv.Calls("getTransaction"))
+	}
+	if !got[0].Mint.Equals(fromLogs.Mint) || got[0].Slot != 7 || got[0].Curve != DefaultCurve {
+		t.Errorf("Handle(logs) = %+v", got[0])
+	}
+
+	got, err = feed.Handle(ctx, 42, fetchedSig, []string{"Program " + program + " invoke [1]", createLog, "Program " + program + " success"})
+	if err != nil || len(got) != 1 || srv.Calls("getTransaction") != 1 {
+		t.Fatalf("Handle(fetched) = %+v, %v after %d fetches", got, err, srv.Calls("getTransaction"))
+	}
+	l := got[0]
+	if !l.Mint.Equals(fetched.Mint) || !l.BondingCurve.Equals(fetched.BondingCurve) || !l.Creator.Equals(fetched.User) ||
+		l.Name != fetched.Name || l.URI != fetched.Uri || l.Slot != 42 || l.BlockTime.Unix() != 1745000000 {
+		t.Errorf("Handle(fetched) = %+v", l)
+	}
+
+	// Trades are not launches, and filtered launches are dropped
+	if got, err := feed.Handle(ctx, 8, solana.Signature{3}, []string{"Program log: Instruction: Buy"}); err != nil || len(got) != 0 {
+		t.Errorf("Handle(buy) = %+v, %v", got, err)
+	}
+	feed.filter = Filter{ExcludeCreators: []solana.PublicKey{fromLogs.User}}
+	if got, err := feed.Handle(ctx, 9, solana.Signature{4}, logs); err != nil || len(got) != 0 {
+		t.Errorf("Handle(excluded) = %+v, %v", got, err)
+	}
+}
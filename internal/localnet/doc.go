@@ -0,0 +1,20 @@
+// Package localnet runs solana-test-validator with the PumpSwap and pump.fun programs cloned from
+// mainnet, so swaps can be built, sent and decoded end to end without a live cluster.
+//
+// The fixtures live in testdata, in the layout DumpFixtures writes:
+//
+//	testdata/mints.txt                   the mints the fixtures are dumped for, one per line
+//	testdata/programs/<program id>.so    the program binaries, stored with Git LFS
+//	testdata/accounts/<address>.json     the accounts trading those mints needs
+//
+// The programs must match the IDLs the decoders are generated from, idl/pumpfun/pump/idl/idl.json
+// (pump 0.1.0: initialize, setParams, create, buy, sell, withdraw) and idl/pumpfun/amm/idl/idl.json
+// (pump_amm 0.1.0, with create_pool, deposit, withdraw, buy and sell). Refresh them together:
+//
+//	RPC_ENDPOINT=https://api.mainnet-beta.solana.com go run ./cmd/localnet_fixtures
+//	git add internal/localnet/testdata
+//
+// Until the binaries are fetched with git lfs pull, or dumped as above, LoadFixtures returns
+// ErrNoFixtures and the tests using Run are skipped. Set LOCALNET_REQUIRED=1 where the fixtures
+// and the validator must be present, so those tests fail rather than pass by skipping.
+package localnet
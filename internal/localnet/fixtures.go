@@ -0,0 +1,371 @@
+package localnet
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	pumpswap "solana-pumpswap-demo/idl/pumpfun/amm"
+	"solana-pumpswap-demo/idl/pumpfun/amm/idl/generated/amm"
+	pumpfun "solana-pumpswap-demo/idl/pumpfun/pump"
+	"solana-pumpswap-demo/idl/pumpfun/pump/idl/generated/pump"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// lfsPointerPrefix starts the text file Git LFS leaves in place of a binary it has not fetched
+const lfsPointerPrefix = "version https://git-lfs.github.com/spec/"
+
+// programDataOffset is where the ELF starts in an upgradeable program's ProgramData account,
+// after the enum tag, the deploy slot and the optional upgrade authority
+const programDataOffset = 45
+
+// Programs are the programs DumpFixtures clones, PumpSwap and pump.fun
+var Programs = []solana.PublicKey{amm.ProgramID, pump.ProgramID}
+
+// Fixtures are the program binaries and accounts found in a fixture directory
+type Fixtures struct {
+	Dir      string
+	programs []solana.PublicKey
+	accounts []solana.PublicKey
+}
+
+// AccountFixture is an account in the JSON format `solana account --output json` writes
+// and solana-test-validator --account reads
+type AccountFixture struct {
+	Pubkey  string      `json:"pubkey"`
+	Account AccountData `json:"account"`
+}
+
+// AccountData is the account part of an AccountFixture, with the data as ["<base64>", "base64"]
+type AccountData struct {
+	Lamports   uint64    `json:"lamports"`
+	Data       [2]string `json:"data"`
+	Owner      string    `json:"owner"`
+	Executable bool      `json:"executable"`
+	RentEpoch  uint64    `json:"rentEpoch"`
+	Space      int       `json:"space"`
+}
+
+// LoadFixtures lists the programs/<program id>.so and accounts/<address>.json files in dir.
+// It returns ErrNoFixtures when there are no programs, or only the Git LFS pointers to them,
+// as nothing can be traded without them.
+func LoadFixtures(dir string) (*Fixtures, error) {
+	f := &Fixtures{Dir: dir}
+	var err error
+	if f.programs, err = listKeys(filepath.Join(dir, "programs"), ".so"); err != nil {
+		return nil, err
+	}
+	if len(f.programs) == 0 {
+		return nil, fmt.Errorf("%w in %s", ErrNoFixtures, dir)
+	}
+	for _, p := range f.programs {
+		path := filepath.Join(dir, "programs", p.String()+".so")
+		pointer, err := isLFSPointer(path)
+		if err != nil {
+			return nil, err
+		}
+		if pointer {
+			return nil, fmt.Errorf("%w: %s is a Git LFS pointer, run git lfs pull", ErrNoFixtures, path)
+		}
+	}
+	if f.accounts, err = listKeys(filepath.Join(dir, "accounts"), ".json"); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Programs returns the ids of the programs in the fixtures
+func (f *Fixtures) Programs() []solana.PublicKey {
+	return f.programs
+}
+
+// Accounts returns the addresses of the accounts in the fixtures
+func (f *Fixtures) Accounts() []solana.PublicKey {
+	return f.accounts
+}
+
+// PoolMints returns the base mints of the PumpSwap pools in the fixtures, the mints a localnet can trade
+func (f *Fixtures) PoolMints() ([]solana.PublicKey, error) {
+	var mints []solana.PublicKey
+	for _, a := range f.accounts {
+		fixture, data, err := ReadAccountFixture(filepath.Join(f.Dir, "accounts", a.String()+".json"))
+		if err != nil {
+			return nil, err
+		}
+		if fixture.Account.Owner != amm.ProgramID.String() || a.Equals(pumpswap.PumpAmmGlobalConfigAddress) {
+			continue
+		}
+		pool, err := pumpswap.DecodePool(data)
+		if err != nil {
+			return nil, fmt.Errorf("pool fixture %s: %w", a, err)
+		}
+		mints = append(mints, pool.BaseMint)
+	}
+	return mints, nil
+}
+
+// Args returns the solana-test-validator arguments loading the fixtures
+func (f *Fixtures) Args() []string {
+	var args []string
+	for _, p := range f.programs {
+		args = append(args, "--bpf-program", p.String(), filepath.Join(f.Dir, "programs", p.String()+".so"))
+	}
+	for _, a := range f.accounts {
+		args = append(args, "--account", a.String(), filepath.Join(f.Dir, "accounts", a.String()+".json"))
+	}
+	return args
+}
+
+// listKeys returns the public keys files with ext in dir are named after, sorted.
+// A missing directory has none.
+func listKeys(dir, ext string) ([]solana.PublicKey, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	var keys []solana.PublicKey
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ext)
+		if e.IsDir() || !ok {
+			continue
+		}
+		key, err := solana.PublicKeyFromBase58(name)
+		if err != nil {
+			return nil, fmt.Errorf("fixture %s is not named after an address: %w", e.Name(), err)
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	return keys, nil
+}
+
+// isLFSPointer reports whether the file is a Git LFS pointer rather than the content it points to
+func isLFSPointer(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read fixture: %w", err)
+	}
+	defer file.Close()
+	head := make([]byte, len(lfsPointerPrefix))
+	n, _ := io.ReadFull(file, head)
+	return string(head[:n]) == lfsPointerPrefix, nil
+}
+
+// ReadMints reads the mints file in a fixture directory: one mint per line, with blank lines and
+// lines starting with # ignored
+func ReadMints(path string) ([]solana.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mints: %w", err)
+	}
+	var mints []solana.PublicKey
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		mint, err := solana.PublicKeyFromBase58(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid mint %q: %w", path, i+1, line, err)
+		}
+		mints = append(mints, mint)
+	}
+	return mints, nil
+}
+
+// NewAccountFixture describes an account as a fixture
+func NewAccountFixture(address solana.PublicKey, account *rpc.Account) AccountFixture {
+	data := account.Data.GetBinary()
+	return AccountFixture{
+		Pubkey: address.String(),
+		Account: AccountData{
+			Lamports:   account.Lamports,
+			Data:       [2]string{base64.StdEncoding.EncodeToString(data), "base64"},
+			Owner:      account.Owner.String(),
+			Executable: account.Executable,
+			RentEpoch:  0,
+			Space:      len(data),
+		},
+	}
+}
+
+// ReadAccountFixture reads an account fixture and decodes its data
+func ReadAccountFixture(path string) (*AccountFixture, []byte, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read account fixture: %w", err)
+	}
+	fixture := new(AccountFixture)
+	if err := json.Unmarshal(raw, fixture); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode account fixture %s: %w", path, err)
+	}
+	if fixture.Account.Data[1] != "base64" {
+		return nil, nil, fmt.Errorf("account fixture %s has %q data, want base64", path, fixture.Account.Data[1])
+	}
+	data, err := base64.StdEncoding.DecodeString(fixture.Account.Data[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode account fixture %s data: %w", path, err)
+	}
+	return fixture, data, nil
+}
+
+// FixtureClient reads the accounts DumpFixtures clones. *rpc.Client implements it.
+type FixtureClient interface {
+	GetMultipleAccountsWithOpts(ctx context.Context, accounts []solana.PublicKey, opts *rpc.GetMultipleAccountsOpts) (*rpc.GetMultipleAccountsResult, error)
+}
+
+// FixtureAccounts returns the accounts trading the mints needs besides the programs: both programs'
+// global accounts and fee recipients, and each mint with its bonding curve and canonical PumpSwap pool.
+// The pool's token accounts and LP mint are read from the pool, so DumpFixtures adds them.
+func FixtureAccounts(mints ...solana.PublicKey) ([]solana.PublicKey, error) {
+	feeRecipientATA, _, err := solana.FindAssociatedTokenAddress(pumpswap.ProtocolFeeRecipients[0], solana.WrappedSol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find fee recipient token account: %w", err)
+	}
+	keys := []solana.PublicKey{
+		pumpswap.PumpAmmGlobalConfigAddress,
+		pumpswap.ProtocolFeeRecipients[0],
+		feeRecipientATA,
+		pumpfun.GlobalPumpFunAddress,
+		pumpfun.PumpFunFeeRecipient,
+	}
+	for _, mint := range mints {
+		curve, err := pumpfun.GetBondingCurveAndAssociatedBondingCurve(mint)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := pumpswap.FindCanonicalPool(mint)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, mint, curve.BondingCurve, curve.AssociatedBondingCurve, pool)
+	}
+	return keys, nil
+}
+
+// DumpFixtures clones the programs and the accounts trading the mints needs from client into dir,
+// in the layout LoadFixtures reads. Accounts that do not exist, such as the pool of a mint still
+// on its bonding curve, are skipped. It returns the addresses of the accounts written.
+func DumpFixtures(ctx context.Context, client FixtureClient, dir string, mints ...solana.PublicKey) ([]solana.PublicKey, error) {
+	for _, sub := range []string{"programs", "accounts"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create fixture directory: %w", err)
+		}
+	}
+	for _, program := range Programs {
+		if err := dumpProgram(ctx, client, dir, program); err != nil {
+			return nil, err
+		}
+	}
+
+	keys, err := FixtureAccounts(mints...)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := fetch(ctx, client, keys)
+	if err != nil {
+		return nil, err
+	}
+	// Each pool's token accounts and LP mint are only known once the pool is read
+	var extra []solana.PublicKey
+	for i, account := range accounts {
+		if account == nil || !account.Owner.Equals(amm.ProgramID) || keys[i].Equals(pumpswap.PumpAmmGlobalConfigAddress) {
+			continue
+		}
+		pool, err := pumpswap.DecodePool(account.Data.GetBinary())
+		if err != nil {
+			return nil, fmt.Errorf("pool %s: %w", keys[i], err)
+		}
+		extra = append(extra, pool.PoolBaseTokenAccount, pool.PoolQuoteTokenAccount, pool.LpMint)
+	}
+	if len(extra) > 0 {
+		extraAccounts, err := fetch(ctx, client, extra)
+		if err != nil {
+			return nil, err
+		}
+		keys, accounts = append(keys, extra...), append(accounts, extraAccounts...)
+	}
+
+	var written []solana.PublicKey
+	for i, account := range accounts {
+		if account == nil {
+			continue
+		}
+		raw, err := json.MarshalIndent(NewAccountFixture(keys[i], account), "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		path := filepath.Join(dir, "accounts", keys[i].String()+".json")
+		if err := os.WriteFile(path, append(raw, '\n'), 0o644); err != nil {
+			return nil, fmt.Errorf("failed to write account fixture: %w", err)
+		}
+		written = append(written, keys[i])
+	}
+	return written, nil
+}
+
+// dumpProgram writes the ELF of an upgradeable program, read from its ProgramData account
+func dumpProgram(ctx context.Context, client FixtureClient, dir string, program solana.PublicKey) error {
+	accounts, err := fetch(ctx, client, []solana.PublicKey{program})
+	if err != nil {
+		return err
+	}
+	// The program account is the enum tag followed by the ProgramData address
+	data := accountData(accounts[0])
+	if len(data) < 36 {
+		return fmt.Errorf("program %s is not an upgradeable program", program)
+	}
+	programData := solana.PublicKeyFromBytes(data[4:36])
+	accounts, err = fetch(ctx, client, []solana.PublicKey{programData})
+	if err != nil {
+		return err
+	}
+	data = accountData(accounts[0])
+	if len(data) <= programDataOffset {
+		return fmt.Errorf("program data %s of %s is empty", programData, program)
+	}
+	path := filepath.Join(dir, "programs", program.String()+".so")
+	if err := os.WriteFile(path, data[programDataOffset:], 0o644); err != nil {
+		return fmt.Errorf("failed to write program fixture: %w", err)
+	}
+	return nil
+}
+
+// fetch reads the accounts in batches of the most getMultipleAccounts takes, leaving nil for missing ones
+func fetch(ctx context.Context, client FixtureClient, keys []solana.PublicKey) ([]*rpc.Account, error) {
+	const batch = 100
+	accounts := make([]*rpc.Account, 0, len(keys))
+	for start := 0; start < len(keys); start += batch {
+		end := min(start+batch, len(keys))
+		res, err := client.GetMultipleAccountsWithOpts(ctx, keys[start:end], &rpc.GetMultipleAccountsOpts{
+			Encoding:   solana.EncodingBase64,
+			Commitment: rpc.CommitmentConfirmed,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get accounts: %w", err)
+		}
+		if len(res.Value) != end-start {
+			return nil, fmt.Errorf("got %d accounts, want %d", len(res.Value), end-start)
+		}
+		accounts = append(accounts, res.Value...)
+	}
+	return accounts, nil
+}
+
+func accountData(account *rpc.Account) []byte {
+	if account == nil {
+		return nil
+	}
+	return account.Data.GetBinary()
+}
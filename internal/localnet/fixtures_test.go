@@ -0,0 +1,155 @@
+package localnet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pumpswap "solana-pumpswap-demo/idl/pumpfun/amm"
+	"solana-pumpswap-demo/idl/pumpfun/amm/idl/generated/amm"
+	"solana-pumpswap-demo/idl/pumpfun/pump/idl/generated/pump"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// fakeClient serves accounts from memory, owned by owners[key] or the system program
+type fakeClient struct {
+	accounts map[solana.PublicKey][]byte
+	owners   map[solana.PublicKey]solana.PublicKey
+}
+
+func (f *fakeClient) GetMultipleAccountsWithOpts(_ context.Context, accounts []solana.PublicKey, _ *rpc.GetMultipleAccountsOpts) (*rpc.GetMultipleAccountsResult, error) {
+	res := &rpc.GetMultipleAccountsResult{Value: make([]*rpc.Account, len(accounts))}
+	for i, key := range accounts {
+		if data, ok := f.accounts[key]; ok {
+			owner, ok := f.owners[key]
+			if !ok {
+				owner = solana.SystemProgramID
+			}
+			res.Value[i] = &rpc.Account{Lamports: 1_000_000, Owner: owner, Data: rpc.DataBytesOrJSONFromBytes(data)}
+		}
+	}
+	return res, nil
+}
+
+// addProgram stores an upgradeable program whose ProgramData holds elf
+func (f *fakeClient) addProgram(program solana.PublicKey, elf []byte) {
+	programData := solana.NewWallet().PublicKey()
+	f.accounts[program] = append([]byte{2, 0, 0, 0}, programData.Bytes()...)
+	f.accounts[programData] = append(make([]byte, programDataOffset), elf...)
+}
+
+// TestDumpFixtures tests cloning the programs and a mint's pool, then loading them back for the validator
+func TestDumpFixtures(t *testing.T) {
+	mint := solana.NewWallet().PublicKey()
+	poolAddress, err := pumpswap.FindCanonicalPool(mint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := amm.PoolAccount{
+		BaseMint:              mint,
+		QuoteMint:             solana.WrappedSol,
+		LpMint:                solana.NewWallet().PublicKey(),
+		PoolBaseTokenAccount:  solana.NewWallet().PublicKey(),
+		PoolQuoteTokenAccount: solana.NewWallet().PublicKey(),
+	}
+	buf := new(bytes.Buffer)
+	if err := pool.MarshalWithEncoder(bin.NewBorshEncoder(buf)); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &fakeClient{
+		accounts: map[solana.PublicKey][]byte{
+			pumpswap.PumpAmmGlobalConfigAddress: {1, 2, 3},
+			mint:                                {4},
+			poolAddress:                         buf.Bytes(),
+			pool.PoolBaseTokenAccount:           {5},
+			pool.PoolQuoteTokenAccount:          {6},
+		},
+		owners: map[solana.PublicKey]solana.PublicKey{
+			pumpswap.PumpAmmGlobalConfigAddress: amm.ProgramID,
+			poolAddress:                         amm.ProgramID,
+		},
+	}
+	client.addProgram(amm.ProgramID, []byte("\x7fELF amm"))
+	client.addProgram(pump.ProgramID, []byte("\x7fELF pump"))
+
+	dir := t.TempDir()
+	written, err := DumpFixtures(context.Background(), client, dir, mint)
+	if err != nil {
+		t.Fatalf("DumpFixtures() error = %v", err)
+	}
+	// The global config, the mint, the pool and its token accounts; the LP mint and the rest do not exist
+	if len(written) != 5 {
+		t.Errorf("wrote %d accounts: %v", len(written), written)
+	}
+
+	fixtures, err := LoadFixtures(dir)
+	if err != nil {
+		t.Fatalf("LoadFixtures() error = %v", err)
+	}
+	if len(fixtures.Programs()) != 2 || len(fixtures.Accounts()) != 5 {
+		t.Errorf("fixtures = %v programs, %v accounts", fixtures.Programs(), fixtures.Accounts())
+	}
+	if args := fixtures.Args(); len(args) != 3*7 || args[0] != "--bpf-program" || args[6] != "--account" {
+		t.Errorf("Args() = %v", args)
+	}
+	mints, err := fixtures.PoolMints()
+	if err != nil || len(mints) != 1 || !mints[0].Equals(mint) {
+		t.Errorf("PoolMints() = %v, %v", mints, err)
+	}
+
+	fixture, data, err := ReadAccountFixture(filepath.Join(dir, "accounts", poolAddress.String()+".json"))
+	if err != nil {
+		t.Fatalf("ReadAccountFixture() error = %v", err)
+	}
+	if fixture.Account.Owner != amm.ProgramID.String() || fixture.Account.Lamports != 1_000_000 || !bytes.Equal(data, buf.Bytes()) {
+		t.Errorf("pool fixture = %+v", fixture)
+	}
+}
+
+// TestLoadFixturesEmpty tests that a fixture directory without programs is reported as missing
+func TestLoadFixturesEmpty(t *testing.T) {
+	if _, err := LoadFixtures(t.TempDir()); !errors.Is(err, ErrNoFixtures) {
+		t.Errorf("LoadFixtures() error = %v, want ErrNoFixtures", err)
+	}
+}
+
+// TestLoadFixturesLFSPointer tests that programs Git LFS has not fetched are reported as missing
+func TestLoadFixturesLFSPointer(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "programs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	pointer := lfsPointerPrefix + "v1\noid sha256:0\nsize 1\n"
+	if err := os.WriteFile(filepath.Join(dir, "programs", amm.ProgramID.String()+".so"), []byte(pointer), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFixtures(dir); !errors.Is(err, ErrNoFixtures) {
+		t.Errorf("LoadFixtures() error = %v, want ErrNoFixtures", err)
+	}
+}
+
+// TestReadMints tests reading the committed mints file
+func TestReadMints(t *testing.T) {
+	mints, err := ReadMints(filepath.Join("testdata", "mints.txt"))
+	if err != nil {
+		t.Fatalf("ReadMints() error = %v", err)
+	}
+	if len(mints) == 0 {
+		t.Error("no mints in testdata/mints.txt")
+	}
+
+	path := filepath.Join(t.TempDir(), "mints.txt")
+	if err := os.WriteFile(path, []byte("# comment\n\nnot-a-mint\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadMints(path); err == nil {
+		t.Error("ReadMints() accepted an invalid mint")
+	}
+}
@@ -0,0 +1,261 @@
+package localnet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// ValidatorBinary is the validator Start runs, found on PATH
+const ValidatorBinary = "solana-test-validator"
+
+// startTimeout bounds how long a validator has to become healthy
+const startTimeout = 90 * time.Second
+
+// ErrNoFixtures is returned when the fixture directory holds no programs
+var ErrNoFixtures = errors.New("no program fixtures")
+
+// Config selects the fixtures a validator is started with
+type Config struct {
+	// FixtureDir holds programs/<program id>.so and accounts/<address>.json, as DumpFixtures writes them.
+	// DefaultFixtureDir if empty.
+	FixtureDir string
+	// LedgerDir is where the validator keeps its ledger, a temporary directory if empty
+	LedgerDir string
+	// Log receives the validator's output, discarded if nil
+	Log *os.File
+}
+
+// Validator is a running solana-test-validator with the fixtures loaded
+type Validator struct {
+	RPCURL string
+	WSURL  string
+	RPC    *rpc.Client
+
+	Fixtures *Fixtures
+
+	cmd     *exec.Cmd
+	tempDir string
+	exited  chan struct{}
+	exitErr error
+}
+
+// DefaultFixtureDir returns $LOCALNET_FIXTURES, or the testdata directory of this package
+func DefaultFixtureDir() string {
+	if dir := os.Getenv("LOCALNET_FIXTURES"); dir != "" {
+		return dir
+	}
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "testdata")
+}
+
+// Run starts a validator for a test and stops it when the test ends. The test is skipped when
+// the validator is not installed or there are no fixtures, so offline CI runs it wherever both exist.
+// With $LOCALNET_REQUIRED set, as in the CI job that exists to run these tests, it fails instead.
+func Run(t testing.TB) *Validator {
+	t.Helper()
+	unavailable := t.Skipf
+	if os.Getenv("LOCALNET_REQUIRED") != "" {
+		unavailable = t.Fatalf
+	}
+	if _, err := exec.LookPath(ValidatorBinary); err != nil {
+		unavailable("%s not found on PATH", ValidatorBinary)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
+	defer cancel()
+	v, err := Start(ctx, Config{})
+	if errors.Is(err, ErrNoFixtures) {
+		unavailable("%v, run git lfs pull or go run ./cmd/localnet_fixtures against mainnet first", err)
+	}
+	if err != nil {
+		t.Fatalf("failed to start validator: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := v.Close(); err != nil {
+			t.Logf("validator exited with %v", err)
+		}
+	})
+	return v
+}
+
+// Start boots solana-test-validator on free local ports with every program and account fixture
+// loaded, and waits until it is healthy
+func Start(ctx context.Context, cfg Config) (*Validator, error) {
+	if cfg.FixtureDir == "" {
+		cfg.FixtureDir = DefaultFixtureDir()
+	}
+	fixtures, err := LoadFixtures(cfg.FixtureDir)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Validator{Fixtures: fixtures, exited: make(chan struct{})}
+	if cfg.LedgerDir == "" {
+		if v.tempDir, err = os.MkdirTemp("", "localnet-ledger-"); err != nil {
+			return nil, fmt.Errorf("failed to create ledger directory: %w", err)
+		}
+		cfg.LedgerDir = v.tempDir
+	}
+	ports, err := freePorts(3)
+	if err != nil {
+		v.cleanup()
+		return nil, err
+	}
+	// The validator serves WebSocket on the port after the RPC port
+	rpcPort, faucetPort, gossipPort := ports[0], ports[1], ports[2]
+	v.RPCURL = fmt.Sprintf("http://127.0.0.1:%d", rpcPort)
+	v.WSURL = fmt.Sprintf("ws://127.0.0.1:%d", rpcPort+1)
+	v.RPC = rpc.New(v.RPCURL)
+
+	args := append([]string{
+		"--ledger", cfg.LedgerDir,
+		"--reset",
+		"--quiet",
+		"--bind-address", "127.0.0.1",
+		"--rpc-port", strconv.Itoa(rpcPort),
+		"--faucet-port", strconv.Itoa(faucetPort),
+		"--gossip-port", strconv.Itoa(gossipPort),
+	}, fixtures.Args()...)
+	v.cmd = exec.Command(ValidatorBinary, args...)
+	if cfg.Log != nil {
+		v.cmd.Stdout, v.cmd.Stderr = cfg.Log, cfg.Log
+	}
+	if err := v.cmd.Start(); err != nil {
+		v.cleanup()
+		return nil, fmt.Errorf("failed to start %s: %w", ValidatorBinary, err)
+	}
+	go func() {
+		v.exitErr = v.cmd.Wait()
+		close(v.exited)
+	}()
+
+	if err := v.waitHealthy(ctx); err != nil {
+		v.Close()
+		return nil, err
+	}
+	return v, nil
+}
+
+// Close stops the validator and removes its temporary ledger
+func (v *Validator) Close() error {
+	defer v.cleanup()
+	select {
+	case <-v.exited:
+		return v.exitErr
+	default:
+	}
+	if err := v.cmd.Process.Signal(os.Interrupt); err != nil {
+		v.cmd.Process.Kill()
+	}
+	select {
+	case <-v.exited:
+	case <-time.After(10 * time.Second):
+		v.cmd.Process.Kill()
+		<-v.exited
+	}
+	return nil
+}
+
+// Fund airdrops lamports to key and waits for the airdrop to confirm
+func (v *Validator) Fund(ctx context.Context, key solana.PublicKey, lamports uint64) error {
+	sig, err := v.RPC.RequestAirdrop(ctx, key, lamports, rpc.CommitmentConfirmed)
+	if err != nil {
+		return fmt.Errorf("failed to request airdrop: %w", err)
+	}
+	return v.Confirm(ctx, sig)
+}
+
+// Confirm waits until the transaction is confirmed, returning its on-chain error if it failed
+func (v *Validator) Confirm(ctx context.Context, sig solana.Signature) error {
+	for {
+		res, err := v.RPC.GetSignatureStatuses(ctx, false, sig)
+		if err == nil && len(res.Value) == 1 && res.Value[0] != nil {
+			status := res.Value[0]
+			if status.Err != nil {
+				return fmt.Errorf("transaction %s failed: %v", sig, status.Err)
+			}
+			if status.ConfirmationStatus == rpc.ConfirmationStatusConfirmed || status.ConfirmationStatus == rpc.ConfirmationStatusFinalized {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("transaction %s not confirmed: %w", sig, ctx.Err())
+		case <-v.exited:
+			return fmt.Errorf("validator exited: %v", v.exitErr)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+// waitHealthy polls getHealth until the validator answers, it exits or ctx is done
+func (v *Validator) waitHealthy(ctx context.Context) error {
+	for {
+		if health, err := v.RPC.GetHealth(ctx); err == nil && health == rpc.HealthOk {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("validator not healthy: %w", ctx.Err())
+		case <-v.exited:
+			return fmt.Errorf("validator exited during startup: %v", v.exitErr)
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+func (v *Validator) cleanup() {
+	if v.tempDir != "" {
+		os.RemoveAll(v.tempDir)
+		v.tempDir = ""
+	}
+}
+
+// freePorts reserves n distinct local ports and releases them for the validator to bind.
+// Each port and the one after it are checked, as the RPC port is followed by the WebSocket port.
+func freePorts(n int) ([]int, error) {
+	var ports []int
+	var listeners []net.Listener
+	defer func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}()
+	for len(ports) < n {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, fmt.Errorf("failed to find a free port: %w", err)
+		}
+		listeners = append(listeners, l)
+		port := l.Addr().(*net.TCPAddr).Port
+		next, err := net.Listen("tcp", "127.0.0.1:"+strconv.Itoa(port+1))
+		if err != nil {
+			continue
+		}
+		listeners = append(listeners, next)
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+// String describes the validator and its programs, for test logs
+func (v *Validator) String() string {
+	programs := v.Fixtures.Programs()
+	names := make([]string, len(programs))
+	for i, p := range programs {
+		names[i] = p.String()
+	}
+	return fmt.Sprintf("validator at %s with programs %s", v.RPCURL, strings.Join(names, ", "))
+}
@@ -0,0 +1,49 @@
+package localnet
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+// recordingTB notes whether Run skipped or failed, and stops the calling goroutine like testing.T
+type recordingTB struct {
+	testing.TB
+	skipped, failed string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Skipf(format string, args ...interface{}) {
+	r.skipped = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+func (r *recordingTB) Fatalf(format string, args ...interface{}) {
+	r.failed = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+// TestRunUnavailable tests that Run skips without a validator unless LOCALNET_REQUIRED is set
+func TestRunUnavailable(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	run := func() *recordingTB {
+		tb := &recordingTB{TB: t}
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			Run(tb)
+		}()
+		<-done
+		return tb
+	}
+
+	t.Setenv("LOCALNET_REQUIRED", "")
+	if tb := run(); tb.skipped == "" || tb.failed != "" {
+		t.Errorf("Run() without a validator skipped %q and failed %q, want a skip", tb.skipped, tb.failed)
+	}
+	t.Setenv("LOCALNET_REQUIRED", "1")
+	if tb := run(); tb.failed == "" || tb.skipped != "" {
+		t.Errorf("Run() with LOCALNET_REQUIRED skipped %q and failed %q, want a failure", tb.skipped, tb.failed)
+	}
+}
@@ -0,0 +1,190 @@
+package migration
+
+import (
+	"time"
+
+	pumpswap "solana-pumpswap-demo/idl/pumpfun/amm"
+	"solana-pumpswap-demo/idl/pumpfun/amm/idl/generated/amm"
+	pumpfun "solana-pumpswap-demo/idl/pumpfun/pump"
+	"solana-pumpswap-demo/internal/decoder"
+	"solana-pumpswap-demo/internal/router"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// DefaultWindow is how long a completion or a pool creation waits for the other half
+const DefaultWindow = 10 * time.Minute
+
+// Migration is a completed bonding curve matched with the PumpSwap pool its liquidity moved into
+type Migration struct {
+	Mint              solana.PublicKey
+	BondingCurve      solana.PublicKey
+	CompleteSignature solana.Signature // Zero when the completion was read from the curve account instead of an event
+	PoolSignature     solana.Signature
+	CompletedAt       time.Time // Zero when the completion was read from the curve account
+	PoolCreatedAt     time.Time
+	Creator           solana.PublicKey // Pool creator, the curve's pool authority
+	LpMint            solana.PublicKey
+	Pool              router.PoolState // Accounts and reserves at creation, ready for Router.PlanPool
+}
+
+// completion is a CompleteEvent waiting for its pool
+type completion struct {
+	event     *pumpfun.CompleteEventEventData
+	signature solana.Signature
+	seen      time.Time
+}
+
+// creation is a CreatePoolEvent waiting for its curve's completion
+type creation struct {
+	event     *amm.CreatePoolEventEventData
+	signature solana.Signature
+	seen      time.Time
+}
+
+// Correlator matches pump.fun CompleteEvents with the PumpSwap CreatePoolEvents of the same mint.
+// Either may be seen first, each is kept for Window before it is dropped. A mint migrates once,
+// so a matched mint is not reported again while it is remembered.
+type Correlator struct {
+	Window time.Duration
+
+	now         func() time.Time
+	completions map[solana.PublicKey]completion
+	creations   map[solana.PublicKey]creation
+	matched     map[solana.PublicKey]time.Time
+}
+
+// NewCorrelator returns a correlator keeping unmatched events for window, DefaultWindow if zero
+func NewCorrelator(window time.Duration) *Correlator {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Correlator{
+		Window:      window,
+		now:         time.Now,
+		completions: make(map[solana.PublicKey]completion),
+		creations:   make(map[solana.PublicKey]creation),
+		matched:     make(map[solana.PublicKey]time.Time),
+	}
+}
+
+// Observe records the transaction's CompleteEvents and migration pool CreatePoolEvents
+// and returns the migrations they complete
+func (c *Correlator) Observe(signature solana.Signature, events []decoder.Event) []Migration {
+	c.expire()
+	now := c.now()
+
+	var out []Migration
+	for _, event := range events {
+		var mint solana.PublicKey
+		switch e := event.Data.(type) {
+		case *pumpfun.CompleteEventEventData:
+			mint = e.Mint
+			if _, ok := c.completions[mint]; !ok {
+				c.completions[mint] = completion{event: e, signature: signature, seen: now}
+			}
+		case *amm.CreatePoolEventEventData:
+			if !IsMigrationPool(e) {
+				continue
+			}
+			mint = e.BaseMint
+			if _, ok := c.creations[mint]; !ok {
+				c.creations[mint] = creation{event: e, signature: signature, seen: now}
+			}
+		default:
+			continue
+		}
+		if m, ok := c.match(mint); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// Completed reports whether a completion of mint is waiting or was already matched
+func (c *Correlator) Completed(mint solana.PublicKey) bool {
+	_, waiting := c.completions[mint]
+	_, matched := c.matched[mint]
+	return waiting || matched
+}
+
+// match returns the migration of mint once both halves have been seen
+func (c *Correlator) match(mint solana.PublicKey) (Migration, bool) {
+	done, ok := c.completions[mint]
+	if !ok {
+		return Migration{}, false
+	}
+	created, ok := c.creations[mint]
+	if !ok {
+		return Migration{}, false
+	}
+	delete(c.completions, mint)
+	delete(c.creations, mint)
+	if _, ok := c.matched[mint]; ok {
+		return Migration{}, false
+	}
+	c.matched[mint] = c.now()
+
+	e := created.event
+	m := Migration{
+		Mint:              mint,
+		BondingCurve:      done.event.BondingCurve,
+		CompleteSignature: done.signature,
+		PoolSignature:     created.signature,
+		PoolCreatedAt:     time.Unix(e.Timestamp, 0),
+		Creator:           e.Creator,
+		LpMint:            e.LpMint,
+		Pool: router.PoolState{
+			Address:               e.Pool,
+			BaseMint:              e.BaseMint,
+			QuoteMint:             e.QuoteMint,
+			PoolBaseTokenAccount:  poolTokenAccount(e.Pool, e.BaseMint),
+			PoolQuoteTokenAccount: poolTokenAccount(e.Pool, e.QuoteMint),
+			BaseReserve:           e.PoolBaseAmount,
+			QuoteReserve:          e.PoolQuoteAmount,
+		},
+	}
+	if done.event.Timestamp != 0 {
+		m.CompletedAt = time.Unix(done.event.Timestamp, 0)
+	}
+	return m, true
+}
+
+// expire drops what has waited longer than the window
+func (c *Correlator) expire() {
+	cutoff := c.now().Add(-c.Window)
+	for mint, done := range c.completions {
+		if done.seen.Before(cutoff) {
+			delete(c.completions, mint)
+		}
+	}
+	for mint, created := range c.creations {
+		if created.seen.Before(cutoff) {
+			delete(c.creations, mint)
+		}
+	}
+	for mint, at := range c.matched {
+		if at.Before(cutoff) {
+			delete(c.matched, mint)
+		}
+	}
+}
+
+// IsMigrationPool reports whether a pool is the mint/WSOL pool a completed curve migrates into,
+// created by the curve's pool authority. Anyone can create other pools for the same mint.
+func IsMigrationPool(e *amm.CreatePoolEventEventData) bool {
+	if !e.QuoteMint.Equals(solana.WrappedSol) {
+		return false
+	}
+	authority, err := pumpswap.FindPoolAuthority(e.BaseMint)
+	return err == nil && e.Creator.Equals(authority)
+}
+
+// poolTokenAccount returns the pool's associated token account for mint, where PumpSwap keeps its reserves
+func poolTokenAccount(pool, mint solana.PublicKey) solana.PublicKey {
+	account, _, err := solana.FindAssociatedTokenAddress(pool, mint)
+	if err != nil {
+		return solana.PublicKey{}
+	}
+	return account
+}
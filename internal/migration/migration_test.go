@@ -0,0 +1,290 @@
+package migration
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	pumpswap "solana-pumpswap-demo/idl/pumpfun/amm"
+	"solana-pumpswap-demo/idl/pumpfun/amm/idl/generated/amm"
+	pumpfun "solana-pumpswap-demo/idl/pumpfun/pump"
+	"solana-pumpswap-demo/idl/pumpfun/pump/idl/generated/pump"
+	"solana-pumpswap-demo/internal/decoder"
+	"solana-pumpswap-demo/internal/rpctest"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// eventIxTag starts the data of an emit_cpi self-invocation
+var eventIxTag = []byte{0xe4, 0x45, 0xa5, 0x2e, 0x51, 0xcb, 0x9a, 0x1d}
+
+// migrationPool returns the CreatePoolEvent of mint's migration pool
+func migrationPool(t *testing.T, mint solana.PublicKey) *amm.CreatePoolEventEventData {
+	authority, err := pumpswap.FindPoolAuthority(mint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool, err := pumpswap.FindCanonicalPool(mint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &amm.CreatePoolEventEventData{
+		Timestamp:       1745000100,
+		Creator:         authority,
+		BaseMint:        mint,
+		QuoteMint:       solana.WrappedSol,
+		PoolBaseAmount:  206_900_000_000_000,
+		PoolQuoteAmount: 84_990_359_056,
+		Pool:            pool,
+		LpMint:          solana.NewWallet().PublicKey(),
+	}
+}
+
+func completeEvent(mint solana.PublicKey) decoder.Event {
+	return decoder.Event{Name: "CompleteEvent", Data: &pumpfun.CompleteEventEventData{Mint: mint, BondingCurve: solana.NewWallet().PublicKey(), Timestamp: 1745000000}}
+}
+
+func createPoolEvent(e *amm.CreatePoolEventEventData) decoder.Event {
+	return decoder.Event{Name: "CreatePoolEvent", Data: e}
+}
+
+// TestCorrelator tests matching a completion and a pool creation seen in either order
+func TestCorrelator(t *testing.T) {
+	for _, poolFirst := range []bool{false, true} {
+		mint := solana.NewWallet().PublicKey()
+		created := migrationPool(t, mint)
+		complete := completeEvent(mint)
+		completeSig, poolSig := solana.Signature{1}, solana.Signature{2}
+
+		c := NewCorrelator(0)
+		var got []Migration
+		if poolFirst {
+			got = append(got, c.Observe(poolSig, []decoder.Event{createPoolEvent(created)})...)
+			got = append(got, c.Observe(completeSig, []decoder.Event{complete})...)
+		} else {
+			got = append(got, c.Observe(completeSig, []decoder.Event{complete})...)
+			got = append(got, c.Observe(poolSig, []decoder.Event{createPoolEvent(created)})...)
+		}
+		if len(got) != 1 {
+			t.Fatalf("pool first %v: got %d migrations, want 1", poolFirst, len(got))
+		}
+
+		m := got[0]
+		baseAccount, _, _ := solana.FindAssociatedTokenAddress(created.Pool, mint)
+		if !m.Mint.Equals(mint) || m.CompleteSignature != completeSig || m.PoolSignature != poolSig ||
+			!m.BondingCurve.Equals(complete.Data.(*pumpfun.CompleteEventEventData).BondingCurve) || m.CompletedAt.Unix() != 1745000000 {
+			t.Errorf("migration = %+v", m)
+		}
+		if !m.Pool.Address.Equals(created.Pool) || !m.Pool.PoolBaseTokenAccount.Equals(baseAccount) ||
+			m.Pool.BaseReserve != created.PoolBaseAmount || m.Pool.QuoteReserve != created.PoolQuoteAmount || !m.LpMint.Equals(created.LpMint) {
+			t.Errorf("pool = %+v", m.Pool)
+		}
+
+		// The same pair delivered again is not a new migration
+		if again := c.Observe(poolSig, []decoder.Event{complete, createPoolEvent(created)}); len(again) != 0 {
+			t.Errorf("repeated events gave %d migrations", len(again))
+		}
+	}
+}
+
+// TestCorrelatorIgnoresOtherPools tests that pools not created by the curve's pool authority never match
+func TestCorrelatorIgnoresOtherPools(t *testing.T) {
+	mint := solana.NewWallet().PublicKey()
+	c := NewCorrelator(0)
+	c.Observe(solana.Signature{1}, []decoder.Event{completeEvent(mint)})
+
+	userPool := migrationPool(t, mint)
+	userPool.Creator = solana.NewWallet().PublicKey()
+	if got := c.Observe(solana.Signature{2}, []decoder.Event{createPoolEvent(userPool)}); len(got) != 0 {
+		t.Errorf("pool from another creator matched: %+v", got)
+	}
+	usdcPool := migrationPool(t, mint)
+	usdcPool.QuoteMint = solana.NewWallet().PublicKey()
+	if got := c.Observe(solana.Signature{3}, []decoder.Event{createPoolEvent(usdcPool)}); len(got) != 0 {
+		t.Errorf("pool with another quote mint matched: %+v", got)
+	}
+}
+
+// TestCorrelatorExpires tests that a completion no pool followed within the window is dropped
+func TestCorrelatorExpires(t *testing.T) {
+	mint := solana.NewWallet().PublicKey()
+	now := time.Unix(1745000000, 0)
+	c := NewCorrelator(time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Observe(solana.Signature{1}, []decoder.Event{completeEvent(mint)})
+	now = now.Add(2 * time.Minute)
+	if got := c.Observe(solana.Signature{2}, []decoder.Event{createPoolEvent(migrationPool(t, mint))}); len(got) != 0 {
+		t.Errorf("expired completion matched: %+v", got)
+	}
+	if c.Completed(mint) {
+		t.Error("Completed() = true after the window")
+	}
+}
+
+// poolTransaction returns a transaction creating a pool whose event is published through emit_cpi
+func poolTransaction(t *testing.T, created *amm.CreatePoolEventEventData) *rpc.GetTransactionResult {
+	payer := solana.NewWallet().PublicKey()
+	ix := solana.NewInstruction(amm.ProgramID, solana.AccountMetaSlice{solana.Meta(payer).WRITE().SIGNER()}, []byte{1})
+	tx, err := solana.NewTransaction([]solana.Instruction{ix}, solana.Hash{}, solana.TransactionPayer(payer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ammIndex, err := tx.Message.GetAccountIndex(amm.ProgramID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := rpctest.NewTransactionResult(1, tx, &rpc.TransactionMeta{InnerInstructions: []rpc.InnerInstruction{{Index: 0, Instructions: []solana.CompiledInstruction{
+		{ProgramIDIndex: ammIndex, Data: append(append([]byte{}, eventIxTag...), rpctest.Borsh(t, created)...)},
+	}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// TestWatcherHandle tests a completion read from the logs followed by a pool creation fetched for its event
+func TestWatcherHandle(t *testing.T) {
+	mint := solana.NewWallet().PublicKey()
+	created := migrationPool(t, mint)
+	completeSig, poolSig := solana.Signature{1}, solana.Signature{2}
+	srv := rpctest.NewServer()
+	defer srv.Close()
+	if err := srv.AddTransaction(poolSig, poolTransaction(t, created)); err != nil {
+		t.Fatal(err)
+	}
+	w := NewWatcher(rpc.New(srv.URL), rpc.CommitmentConfirmed, 0)
+	ctx := context.Background()
+
+	program := pump.ProgramID.String()
+	buyLogs := []string{
+		"Program " + program + " invoke [1]",
+		"Program log: Instruction: Buy",
+		"Program data: " + base64.StdEncoding.EncodeToString(rpctest.Borsh(t, pumpfun.CompleteEventEventData{Mint: mint, BondingCurve: solana.NewWallet().PublicKey()})),
+		"Program " + program + " success",
+	}
+	if got, err := w.Handle(ctx, completeSig, buyLogs); err != nil || len(got) != 0 || srv.Calls("getTransaction") != 0 {
+		t.Fatalf("Handle(completion) = %+v, %v after %d fetches", got, err, srv.Calls("getTransaction"))
+	}
+
+	poolLogs := []string{"Program " + amm.ProgramID.String() + " invoke [1]", createPoolLog, "Program " + amm.ProgramID.String() + " success"}
+	got, err := w.Handle(ctx, poolSig, poolLogs)
+	if err != nil {
+		t.Fatalf("Handle(pool) error = %v", err)
+	}
+	if len(got) != 1 || got[0].CompleteSignature != completeSig || got[0].PoolSignature != poolSig || !got[0].Pool.Address.Equals(created.Pool) {
+		t.Fatalf("Handle(pool) = %+v", got)
+	}
+
+	// The other subscription delivers the same transaction
+	if got, err := w.Handle(ctx, poolSig, poolLogs); err != nil || len(got) != 0 || srv.Calls("getTransaction") != 1 {
+		t.Errorf("repeated Handle() = %+v, %v after %d fetches", got, err, srv.Calls("getTransaction"))
+	}
+}
+
+// TestWatcherChecksCurve tests a pool creation whose completion was not seen, confirmed from the curve account
+func TestWatcherChecksCurve(t *testing.T) {
+	mint := solana.NewWallet().PublicKey()
+	created := migrationPool(t, mint)
+	poolSig := solana.Signature{2}
+	keys, err := pumpfun.GetBondingCurveAndAssociatedBondingCurve(mint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := rpctest.NewServer()
+	defer srv.Close()
+	if err := srv.AddTransaction(poolSig, poolTransaction(t, created)); err != nil {
+		t.Fatal(err)
+	}
+	srv.SetAccountData(keys.BondingCurve, pump.ProgramID, rpctest.Borsh(t, pump.BondingCurve{Complete: true}))
+
+	got, err := NewWatcher(rpc.New(srv.URL), rpc.CommitmentConfirmed, 0).Handle(context.Background(), poolSig, []string{createPoolLog})
+	if err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if len(got) != 1 || !got[0].BondingCurve.Equals(keys.BondingCurve) || !got[0].CompleteSignature.IsZero() || !got[0].CompletedAt.IsZero() {
+		t.Fatalf("Handle() = %+v", got)
+	}
+}
+
+// TestWatcherRemembersMigrations tests that only pool creations and completions are remembered, for the window
+func TestWatcherRemembersMigrations(t *testing.T) {
+	now := time.Unix(1745000000, 0)
+	w := NewWatcher(rpc.New(""), rpc.CommitmentConfirmed, time.Minute)
+	w.correlator.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	tradeLogs := []string{"Program " + pump.ProgramID.String() + " invoke [1]", "Program log: Instruction: Buy"}
+	for i := byte(0); i < 3; i++ {
+		if _, err := w.Handle(ctx, solana.Signature{10 + i}, tradeLogs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(w.handled) != 0 || len(w.handledOrder) != 0 {
+		t.Fatalf("trades remembered: %d, %d", len(w.handled), len(w.handledOrder))
+	}
+
+	completeLogs := append(tradeLogs,
+		"Program data: "+base64.StdEncoding.EncodeToString(rpctest.Borsh(t, pumpfun.CompleteEventEventData{Mint: solana.NewWallet().PublicKey()})),
+		"Program "+pump.ProgramID.String()+" success",
+	)
+	w.Handle(ctx, solana.Signature{1}, completeLogs)
+	now = now.Add(30 * time.Second)
+	w.Handle(ctx, solana.Signature{2}, completeLogs)
+	if !w.seen(solana.Signature{1}) || len(w.handled) != 2 {
+		t.Fatalf("handled = %v", w.handled)
+	}
+
+	now = now.Add(45 * time.Second)
+	if w.seen(solana.Signature{1}) {
+		t.Error("signature still seen after the window")
+	}
+	if len(w.handledOrder) != 2 || w.handledOrder[0].signature != (solana.Signature{2}) {
+		t.Errorf("handledOrder = %+v", w.handledOrder)
+	}
+}
+
+// TestWatcherRetriesFailedFetch tests that a pool creation whose first fetch failed is handled on its second delivery
+func TestWatcherRetriesFailedFetch(t *testing.T) {
+	mint := solana.NewWallet().PublicKey()
+	created := migrationPool(t, mint)
+	poolSig := solana.Signature{2}
+	raw, err := json.Marshal(poolTransaction(t, created))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := rpctest.NewServer()
+	defer srv.Close()
+	srv.Handle("getTransaction", func(json.RawMessage) (interface{}, error) {
+		if srv.Calls("getTransaction") == 1 {
+			return nil, errors.New("node unavailable")
+		}
+		return json.RawMessage(raw), nil
+	})
+	keys, err := pumpfun.GetBondingCurveAndAssociatedBondingCurve(mint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.SetAccountData(keys.BondingCurve, pump.ProgramID, rpctest.Borsh(t, pump.BondingCurve{Complete: true}))
+	w := NewWatcher(rpc.New(srv.URL), rpc.CommitmentConfirmed, 0)
+	ctx := context.Background()
+
+	logs := []string{createPoolLog}
+	if got, err := w.Handle(ctx, poolSig, logs); err == nil || len(got) != 0 {
+		t.Fatalf("Handle() with a failing node = %+v, %v", got, err)
+	}
+	got, err := w.Handle(ctx, poolSig, logs)
+	if err != nil {
+		t.Fatalf("second Handle() error = %v", err)
+	}
+	if len(got) != 1 || !got[0].Mint.Equals(mint) {
+		t.Fatalf("second Handle() = %+v", got)
+	}
+	if got, err := w.Handle(ctx, poolSig, logs); err != nil || len(got) != 0 || srv.Calls("getTransaction") != 2 {
+		t.Errorf("third Handle() = %+v, %v after %d fetches", got, err, srv.Calls("getTransaction"))
+	}
+}
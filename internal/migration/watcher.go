@@ -0,0 +1,268 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"solana-pumpswap-demo/idl/pumpfun/amm/idl/generated/amm"
+	pumpfun "solana-pumpswap-demo/idl/pumpfun/pump"
+	"solana-pumpswap-demo/idl/pumpfun/pump/idl/generated/pump"
+	"solana-pumpswap-demo/internal/decoder"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+)
+
+// createPoolLog is the line PumpSwap logs when a pool is created
+const createPoolLog = "Program log: Instruction: CreatePool"
+
+// fetchAttempts and fetchDelay bound how long a transaction seen at processed is waited for
+const (
+	fetchAttempts = 5
+	fetchDelay    = 400 * time.Millisecond
+)
+
+// Client fetches the transactions and bonding curves the watcher checks. *rpc.Client implements it.
+type Client interface {
+	GetTransaction(ctx context.Context, signature solana.Signature, opts *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error)
+	GetAccountInfoWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetAccountInfoOpts) (*rpc.GetAccountInfoResult, error)
+}
+
+// Watcher turns pump.fun and PumpSwap log notifications into migrations
+type Watcher struct {
+	// OnError is called with the transactions Run could not handle, nil ignores them.
+	// Subscription errors come from the receiving goroutines, with a zero signature.
+	OnError func(signature solana.Signature, err error)
+
+	client     Client
+	commitment rpc.CommitmentType
+	correlator *Correlator
+	handled    map[solana.Signature]time.Time
+	// handledOrder holds the handled signatures oldest first, so expiring them stops at the first recent one
+	handledOrder []handledSignature
+}
+
+// handledSignature is a signature the watcher handled and when
+type handledSignature struct {
+	signature solana.Signature
+	at        time.Time
+}
+
+// NewWatcher returns a watcher fetching transactions at commitment and correlating within window
+func NewWatcher(client Client, commitment rpc.CommitmentType, window time.Duration) *Watcher {
+	return &Watcher{
+		client:     client,
+		commitment: commitment,
+		correlator: NewCorrelator(window),
+		handled:    make(map[solana.Signature]time.Time),
+	}
+}
+
+// Handle checks a transaction's logs and returns the migrations it completes.
+// CompleteEvents pump.fun writes to the logs are read from them directly. A transaction that
+// creates a pool is fetched for its CreatePoolEvent, and if no completion of that mint was seen,
+// which happens when pump.fun published it through emit_cpi, the curve account is checked instead.
+// Both subscriptions deliver a migration transaction, so each signature that creates a pool or
+// completes a curve is handled once. Every other transaction is dropped without being remembered,
+// as is one that could not be fetched or decoded, so the other subscription's delivery retries it.
+func (w *Watcher) Handle(ctx context.Context, signature solana.Signature, logs []string) ([]Migration, error) {
+	createsPool := false
+	for _, line := range logs {
+		if line == createPoolLog {
+			createsPool = true
+			break
+		}
+	}
+	var completions []decoder.Event
+	if !createsPool {
+		for _, event := range decoder.DecodeLogEvents(logs) {
+			if _, ok := event.Data.(*pumpfun.CompleteEventEventData); ok {
+				completions = append(completions, event)
+			}
+		}
+		if len(completions) == 0 {
+			return nil, nil
+		}
+	}
+	if w.seen(signature) {
+		return nil, nil
+	}
+	if !createsPool {
+		return w.correlator.Observe(signature, completions), nil
+	}
+
+	tx, err := w.fetch(ctx, signature)
+	if err != nil {
+		w.forget(signature)
+		return nil, err
+	}
+	events, err := decoder.DecodeEvents(tx)
+	if err != nil {
+		w.forget(signature)
+		return nil, fmt.Errorf("failed to decode events of %s: %w", signature, err)
+	}
+
+	migrations := w.correlator.Observe(signature, events)
+	for _, event := range events {
+		created, ok := event.Data.(*amm.CreatePoolEventEventData)
+		if !ok || !IsMigrationPool(created) || w.correlator.Completed(created.BaseMint) {
+			continue
+		}
+		complete, err := w.curveCompletion(ctx, created.BaseMint)
+		if err != nil {
+			return migrations, err
+		}
+		if complete != nil {
+			migrations = append(migrations, w.correlator.Observe(solana.Signature{}, []decoder.Event{*complete})...)
+		}
+	}
+	return migrations, nil
+}
+
+// Run subscribes to the pump.fun and PumpSwap logs and calls handle with each migration until ctx is cancelled
+func (w *Watcher) Run(ctx context.Context, wsClient *ws.Client, handle func(Migration)) error {
+	results := make(chan *ws.LogResult, 64)
+	for _, program := range []solana.PublicKey{pump.ProgramID, amm.ProgramID} {
+		// Processed gives the earliest notice, the transaction is fetched at the watcher's commitment
+		sub, err := wsClient.LogsSubscribeMentions(program, rpc.CommitmentProcessed)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to %s logs: %w", program, err)
+		}
+		defer sub.Unsubscribe()
+
+		go func() {
+			for {
+				result, err := sub.Recv(ctx)
+				if err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					w.report(solana.Signature{}, err)
+					time.Sleep(100 * time.Millisecond)
+					continue
+				}
+				select {
+				case results <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case result := <-results:
+			if result.Value.Err != nil {
+				continue
+			}
+			migrations, err := w.Handle(ctx, result.Value.Signature, result.Value.Logs)
+			if err != nil {
+				w.report(result.Value.Signature, err)
+			}
+			for _, m := range migrations {
+				handle(m)
+			}
+		}
+	}
+}
+
+// seen reports whether the signature was already handled and remembers it for the correlation window.
+// Signatures are appended in the order they are handled, so only the expired ones at the front are visited.
+func (w *Watcher) seen(signature solana.Signature) bool {
+	now := w.correlator.now()
+	expired := 0
+	for _, h := range w.handledOrder {
+		if now.Sub(h.at) <= w.correlator.Window {
+			break
+		}
+		// A forgotten signature handled again has a later entry of its own
+		if at, ok := w.handled[h.signature]; ok && at.Equal(h.at) {
+			delete(w.handled, h.signature)
+		}
+		expired++
+	}
+	w.handledOrder = w.handledOrder[expired:]
+
+	if _, ok := w.handled[signature]; ok {
+		return true
+	}
+	w.handled[signature] = now
+	w.handledOrder = append(w.handledOrder, handledSignature{signature: signature, at: now})
+	return false
+}
+
+// forget lets a signature whose handling failed be handled again. Its queue entry expires as usual.
+func (w *Watcher) forget(signature solana.Signature) {
+	delete(w.handled, signature)
+}
+
+// fetch gets a transaction, waiting for it to reach the watcher's commitment
+func (w *Watcher) fetch(ctx context.Context, signature solana.Signature) (*rpc.GetTransactionResult, error) {
+	version := uint64(0)
+	var err error
+	for attempt := 0; attempt < fetchAttempts; attempt++ {
+		var tx *rpc.GetTransactionResult
+		tx, err = w.client.GetTransaction(ctx, signature, &rpc.GetTransactionOpts{
+			Encoding:                       solana.EncodingBase64,
+			Commitment:                     w.commitment,
+			MaxSupportedTransactionVersion: &version,
+		})
+		if err == nil {
+			return tx, nil
+		}
+		if !errors.Is(err, rpc.ErrNotFound) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(fetchDelay):
+		}
+	}
+	return nil, fmt.Errorf("failed to get transaction %s: %w", signature, err)
+}
+
+// curveCompletion reads the mint's bonding curve and returns a CompleteEvent standing in for the one
+// we did not see if the curve is complete, or nil if it is not
+func (w *Watcher) curveCompletion(ctx context.Context, mint solana.PublicKey) (*decoder.Event, error) {
+	keys, err := pumpfun.GetBondingCurveAndAssociatedBondingCurve(mint)
+	if err != nil {
+		return nil, err
+	}
+	info, err := w.client.GetAccountInfoWithOpts(ctx, keys.BondingCurve, &rpc.GetAccountInfoOpts{
+		Encoding:   solana.EncodingBase64,
+		Commitment: rpc.CommitmentProcessed,
+	})
+	if errors.Is(err, rpc.ErrNotFound) || (err == nil && info.Value == nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bonding curve of %s: %w", mint, err)
+	}
+	curve, err := pumpfun.DecodeBondingCurve(info.Value.Data.GetBinary())
+	if err != nil {
+		return nil, err
+	}
+	if !curve.Complete {
+		return nil, nil
+	}
+	return &decoder.Event{
+		Venue: decoder.VenuePumpFun,
+		Name:  "CompleteEvent",
+		Inner: -1,
+		Data:  &pumpfun.CompleteEventEventData{Mint: mint, BondingCurve: keys.BondingCurve},
+	}, nil
+}
+
+// report passes an error to OnError if it is set
+func (w *Watcher) report(signature solana.Signature, err error) {
+	if w.OnError != nil && !errors.Is(err, context.Canceled) {
+		w.OnError(signature, err)
+	}
+}
@@ -0,0 +1,336 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"solana-pumpswap-demo/internal/decoder"
+	"solana-pumpswap-demo/internal/store"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+)
+
+// Status is the lifecycle state of a copy order
+type Status string
+
+const (
+	StatusPending Status = "pending" // Recorded, not yet sent
+	StatusSent    Status = "sent"    // Sent, waiting for confirmation
+	StatusLanded  Status = "landed"  // Confirmed on chain without error
+	StatusFailed  Status = "failed"  // Send failed or the transaction errored on chain
+	StatusExpired Status = "expired" // Sent but never landed
+)
+
+// reasonTakeProfit is the exits package's take-profit reason, a sell placed for it moves the
+// position to its next take-profit level
+const reasonTakeProfit = "take-profit"
+
+// maxSignatureStatuses is the most signatures getSignatureStatuses accepts in one call
+const maxSignatureStatuses = 256
+
+// ErrDuplicate is returned when an order for the same leader transaction and wallet already exists
+var ErrDuplicate = errors.New("orders: duplicate order")
+
+// Intent describes the order we want to place in response to a leader transaction
+type Intent struct {
+	SourceSignature string
+	Wallet          string
+	Mint            string
+	Side            string
+	Reason          string // Exit rule that placed a sell, empty for copies
+	AmountIn        uint64
+	MinAmountOut    uint64
+}
+
+// ID returns the idempotency key of the intent
+func (i Intent) ID() string {
+	return OrderID(i.SourceSignature, i.Wallet)
+}
+
+// OrderID builds the idempotency key from the leader signature and our wallet
+func OrderID(sourceSignature, wallet string) string {
+	return sourceSignature + ":" + wallet
+}
+
+// SendFunc signs and sends the order's transaction and returns its signature
+type SendFunc func(ctx context.Context) (string, error)
+
+// Manager records copy orders in the store and refuses to place the same one twice
+type Manager struct {
+	st *store.Store
+	mu sync.Mutex
+}
+
+// NewManager creates an order manager backed by the store
+func NewManager(st *store.Store) *Manager {
+	return &Manager{st: st}
+}
+
+// Submit records the intent and sends it. An order already pending, sent or landed for the same
+// key is refused with ErrDuplicate; a failed or expired one is retried under the same record.
+func (m *Manager) Submit(ctx context.Context, intent Intent, send SendFunc) (store.Order, error) {
+	order, err := m.claim(intent)
+	if err != nil {
+		return order, err
+	}
+	return m.send(ctx, order, send)
+}
+
+// Retry resends a failed or expired order, reusing its intent record
+func (m *Manager) Retry(ctx context.Context, id string, send SendFunc) (store.Order, error) {
+	order, err := m.st.GetOrder(id)
+	if err != nil {
+		return order, err
+	}
+	return m.Submit(ctx, Intent{
+		SourceSignature: order.SourceSignature,
+		Wallet:          order.Wallet,
+		Mint:            order.Mint,
+		Side:            order.Side,
+		Reason:          order.Reason,
+		AmountIn:        order.AmountIn,
+		MinAmountOut:    order.MinAmountOut,
+	}, send)
+}
+
+// claim atomically checks for an existing order and marks the key pending
+func (m *Manager) claim(intent Intent) (store.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	order, err := m.st.GetOrder(intent.ID())
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		order = store.Order{
+			ID:              intent.ID(),
+			SourceSignature: intent.SourceSignature,
+			Wallet:          intent.Wallet,
+			Mint:            intent.Mint,
+			Side:            intent.Side,
+			Reason:          intent.Reason,
+			AmountIn:        intent.AmountIn,
+			MinAmountOut:    intent.MinAmountOut,
+		}
+	case err != nil:
+		return order, err
+	case Status(order.Status) != StatusFailed && Status(order.Status) != StatusExpired:
+		return order, fmt.Errorf("%w: %s is %s", ErrDuplicate, order.ID, order.Status)
+	}
+
+	order.Status = string(StatusPending)
+	order.Error = ""
+	order.Attempts++
+	if err := m.st.PutOrder(order); err != nil {
+		return order, err
+	}
+	return order, nil
+}
+
+func (m *Manager) send(ctx context.Context, order store.Order, send SendFunc) (store.Order, error) {
+	sig, sendErr := send(ctx)
+	if sendErr != nil {
+		order.Status = string(StatusFailed)
+		order.Error = sendErr.Error()
+	} else {
+		order.Status = string(StatusSent)
+		order.Signature = sig
+	}
+
+	if err := m.st.PutOrder(order); err != nil {
+		return order, err
+	}
+	return order, sendErr
+}
+
+// Reconcile checks every sent order's signature and marks it landed, failed or, once older
+// than maxAge without landing, expired. An order left pending for longer than maxAge was claimed
+// by a process that stopped before it recorded the send, it is expired so Retry can place it again.
+// Signatures are checked in batches of the most getSignatureStatuses accepts.
+// The orders whose status changed are returned.
+func (m *Manager) Reconcile(ctx context.Context, client *rpc.Client, maxAge time.Duration) ([]store.Order, error) {
+	all, err := m.st.Orders()
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []store.Order
+	var sent []store.Order
+	var sigs []solana.Signature
+	for _, o := range all {
+		switch Status(o.Status) {
+		case StatusPending:
+			if time.Since(o.UpdatedAt) < maxAge {
+				continue
+			}
+			o.Status = string(StatusExpired)
+			o.Error = "never sent"
+			if err := m.st.PutOrder(o); err != nil {
+				return changed, err
+			}
+			changed = append(changed, o)
+		case StatusSent:
+			sig, err := solana.SignatureFromBase58(o.Signature)
+			if err != nil {
+				continue
+			}
+			sent = append(sent, o)
+			sigs = append(sigs, sig)
+		}
+	}
+	if len(sigs) == 0 {
+		return changed, nil
+	}
+
+	statuses := make([]*rpc.SignatureStatusesResult, 0, len(sigs))
+	for start := 0; start < len(sigs); start += maxSignatureStatuses {
+		end := min(start+maxSignatureStatuses, len(sigs))
+		res, err := client.GetSignatureStatuses(ctx, true, sigs[start:end]...)
+		if err != nil {
+			return changed, fmt.Errorf("failed to get signature statuses: %w", err)
+		}
+		// A short answer leaves the rest of the batch unknown
+		batch := make([]*rpc.SignatureStatusesResult, end-start)
+		copy(batch, res.Value)
+		statuses = append(statuses, batch...)
+	}
+
+	for i, o := range sent {
+		status := statuses[i]
+
+		switch {
+		case status == nil:
+			if time.Since(o.UpdatedAt) < maxAge {
+				continue
+			}
+			o.Status = string(StatusExpired)
+		case status.Err != nil:
+			o.Status = string(StatusFailed)
+			o.Error = fmt.Sprint(status.Err)
+		case status.ConfirmationStatus == rpc.ConfirmationStatusConfirmed ||
+			status.ConfirmationStatus == rpc.ConfirmationStatusFinalized:
+			o.Status = string(StatusLanded)
+		default:
+			continue
+		}
+
+		if err := m.st.PutOrder(o); err != nil {
+			return changed, err
+		}
+		changed = append(changed, o)
+	}
+	return changed, nil
+}
+
+// Fill is what a landed order executed, from its swap event
+type Fill struct {
+	BaseAmount  uint64 // Tokens bought or sold
+	QuoteAmount uint64 // Lamports paid or received, the bonding curve's fee is not included
+	Pool        string
+	At          time.Time
+}
+
+// FillFromTransaction finds the order's swap in its landed transaction
+func FillFromTransaction(tx *rpc.GetTransactionResult, o store.Order) (Fill, error) {
+	trades, err := decoder.DecodeTrades(tx)
+	if err != nil {
+		return Fill{}, err
+	}
+	for _, trade := range trades {
+		if trade.User.String() != o.Wallet || trade.Side != o.Side ||
+			!trade.BaseMint.IsZero() && trade.BaseMint.String() != o.Mint {
+			continue
+		}
+		fill := Fill{BaseAmount: trade.BaseAmount, QuoteAmount: trade.QuoteAmount, Pool: trade.Pool.String(), At: time.Now()}
+		if tx.BlockTime != nil {
+			fill.At = tx.BlockTime.Time()
+		}
+		return fill, nil
+	}
+	return Fill{}, fmt.Errorf("orders: no %s of %s by %s in %s", o.Side, o.Mint, o.Wallet, o.Signature)
+}
+
+// Unfilled returns the landed orders whose fill has not been applied to a position yet
+func (m *Manager) Unfilled() ([]store.Order, error) {
+	all, err := m.st.Orders()
+	if err != nil {
+		return nil, err
+	}
+	var unfilled []store.Order
+	for _, o := range all {
+		if Status(o.Status) == StatusLanded && o.PositionID == "" {
+			unfilled = append(unfilled, o)
+		}
+	}
+	return unfilled, nil
+}
+
+// ApplyFill applies a landed order to the mint's open position. A buy opens the position or adds
+// to it, a sell reduces it and closes it once nothing is left. Applying the same order twice returns
+// the position it already changed.
+func (m *Manager) ApplyFill(id string, fill Fill) (store.Position, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, err := m.st.GetOrder(id)
+	if err != nil {
+		return store.Position{}, err
+	}
+	if o.PositionID != "" {
+		return m.st.GetPosition(o.PositionID)
+	}
+	if Status(o.Status) != StatusLanded {
+		return store.Position{}, fmt.Errorf("orders: %s is %s, not landed", o.ID, o.Status)
+	}
+
+	positions, err := m.st.Positions()
+	if err != nil {
+		return store.Position{}, err
+	}
+	var p store.Position
+	found := false
+	for _, open := range positions {
+		if open.Mint == o.Mint && open.Open() {
+			p, found = open, true
+			break
+		}
+	}
+
+	switch o.Side {
+	case "buy":
+		if !found {
+			p = store.Position{ID: o.ID, Mint: o.Mint, Pool: fill.Pool, OpenedAt: fill.At}
+		}
+		p.InitialAmount += fill.BaseAmount
+		p.Amount += fill.BaseAmount
+		p.CostLamports += fill.QuoteAmount
+		if p.InitialAmount > 0 {
+			p.EntryPrice = decimal.NewFromUint64(p.CostLamports).Div(decimal.NewFromUint64(p.InitialAmount)).String()
+		}
+	case "sell":
+		if !found {
+			return p, fmt.Errorf("orders: no open %s position for %s", o.Mint, o.ID)
+		}
+		p.Amount -= min(fill.BaseAmount, p.Amount)
+		p.ProceedsLamports += fill.QuoteAmount
+		if o.Reason == reasonTakeProfit {
+			p.TakenProfits++
+		}
+		if p.Amount == 0 {
+			p.ClosedAt = fill.At
+		}
+	default:
+		return p, fmt.Errorf("orders: unknown side %q for %s", o.Side, o.ID)
+	}
+
+	o.AmountOut = fill.BaseAmount
+	if o.Side == "sell" {
+		o.AmountOut = fill.QuoteAmount
+	}
+	o.PositionID = p.ID
+	return p, m.st.PutFill(o, p)
+}
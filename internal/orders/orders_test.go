@@ -0,0 +1,308 @@
+package orders
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"solana-pumpswap-demo/internal/decoder"
+	"solana-pumpswap-demo/internal/rpctest"
+	"solana-pumpswap-demo/internal/store"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+func newTestManager(t *testing.T) (*Manager, *store.Store) {
+	t.Helper()
+	st, err := store.Open(filepath.Join(t.TempDir(), "orders.db"))
+	if err != nil {
+		t.Fatalf("store.Open() error = %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return NewManager(st), st
+}
+
+// TestSubmitRefusesDuplicates tests that the same leader transaction is copied once per wallet
+func TestSubmitRefusesDuplicates(t *testing.T) {
+	m, _ := newTestManager(t)
+	ctx := context.Background()
+
+	sends := 0
+	send := func(ctx context.Context) (string, error) {
+		sends++
+		return "oursig", nil
+	}
+
+	intent := Intent{SourceSignature: "leadersig", Wallet: "wallet1", Mint: "mint", Side: "buy", AmountIn: 1000}
+	order, err := m.Submit(ctx, intent, send)
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if Status(order.Status) != StatusSent || order.Signature != "oursig" || order.Attempts != 1 {
+		t.Errorf("Submit() = %+v", order)
+	}
+
+	// Seen again at a different commitment level or through backfill
+	if _, err := m.Submit(ctx, intent, send); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("second Submit() error = %v, want ErrDuplicate", err)
+	}
+
+	// A different wallet copying the same leader is a separate order
+	other := intent
+	other.Wallet = "wallet2"
+	if _, err := m.Submit(ctx, other, send); err != nil {
+		t.Errorf("Submit() for another wallet error = %v", err)
+	}
+
+	if sends != 2 {
+		t.Errorf("send called %d times, want 2", sends)
+	}
+}
+
+// TestRetryReusesIntent tests that a failed order is retried under the same record
+func TestRetryReusesIntent(t *testing.T) {
+	m, st := newTestManager(t)
+	ctx := context.Background()
+
+	intent := Intent{SourceSignature: "leadersig", Wallet: "wallet1", Mint: "mint", Side: "buy", AmountIn: 1000}
+	_, err := m.Submit(ctx, intent, func(ctx context.Context) (string, error) {
+		return "", errors.New("blockhash not found")
+	})
+	if err == nil {
+		t.Fatal("Submit() with failing send returned no error")
+	}
+
+	failed, err := st.GetOrder(intent.ID())
+	if err != nil || Status(failed.Status) != StatusFailed || failed.Error != "blockhash not found" {
+		t.Fatalf("stored order after failure = %+v, %v", failed, err)
+	}
+
+	retried, err := m.Retry(ctx, intent.ID(), func(ctx context.Context) (string, error) {
+		return "oursig", nil
+	})
+	if err != nil {
+		t.Fatalf("Retry() error = %v", err)
+	}
+	if retried.ID != failed.ID || !retried.CreatedAt.Equal(failed.CreatedAt) {
+		t.Errorf("Retry() created a new record: %+v vs %+v", retried, failed)
+	}
+	if Status(retried.Status) != StatusSent || retried.Attempts != 2 || retried.Error != "" || retried.AmountIn != 1000 {
+		t.Errorf("Retry() = %+v", retried)
+	}
+
+	orders, err := st.Orders()
+	if err != nil || len(orders) != 1 {
+		t.Errorf("store has %d orders, %v; want 1", len(orders), err)
+	}
+}
+
+// TestReconcile tests that sent orders follow their signature's status and that an order a crash
+// left pending is expired once it is too old to still be in flight
+func TestReconcile(t *testing.T) {
+	m, st := newTestManager(t)
+	ctx := context.Background()
+	s := rpctest.NewServer()
+	defer s.Close()
+
+	landed, failed, unknown := solana.Signature{1}, solana.Signature{2}, solana.Signature{3}
+	s.Handle("getSignatureStatuses", func(params json.RawMessage) (interface{}, error) {
+		var sigs []solana.Signature
+		if err := json.Unmarshal(params, &[]interface{}{&sigs}); err != nil {
+			return nil, err
+		}
+		value := make([]interface{}, len(sigs))
+		for i, sig := range sigs {
+			switch sig {
+			case landed:
+				value[i] = map[string]interface{}{"slot": 10, "confirmationStatus": "confirmed"}
+			case failed:
+				value[i] = map[string]interface{}{"slot": 10, "confirmationStatus": "confirmed", "err": map[string]interface{}{"InstructionError": []interface{}{0, "Custom"}}}
+			}
+		}
+		return map[string]interface{}{"context": map[string]uint64{"slot": 10}, "value": value}, nil
+	})
+
+	for i, sig := range []solana.Signature{landed, failed, unknown} {
+		intent := Intent{SourceSignature: string(rune('a' + i)), Wallet: "wallet1", Side: "buy", AmountIn: 1000}
+		if _, err := m.Submit(ctx, intent, func(ctx context.Context) (string, error) { return sig.String(), nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Claimed, then the process stopped before sending
+	crashed := Intent{SourceSignature: "d", Wallet: "wallet1", Side: "buy", AmountIn: 1000}
+	if _, err := m.claim(crashed); err != nil {
+		t.Fatal(err)
+	}
+
+	client := rpc.New(s.URL)
+	changed, err := m.Reconcile(ctx, client, time.Hour)
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if len(changed) != 2 || changed[0].Signature != landed.String() || Status(changed[0].Status) != StatusLanded ||
+		Status(changed[1].Status) != StatusFailed || changed[1].Error == "" {
+		t.Errorf("Reconcile() = %+v", changed)
+	}
+
+	changed, err = m.Reconcile(ctx, client, 0)
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if len(changed) != 2 {
+		t.Fatalf("Reconcile() once stale = %+v", changed)
+	}
+	for _, o := range changed {
+		if Status(o.Status) != StatusExpired || (o.ID != OrderID("c", "wallet1") && o.ID != crashed.ID()) {
+			t.Errorf("stale order = %+v", o)
+		}
+	}
+
+	// The expired order is placed again under the same record
+	retried, err := m.Retry(ctx, crashed.ID(), func(ctx context.Context) (string, error) { return "oursig", nil })
+	if err != nil || Status(retried.Status) != StatusSent || retried.Attempts != 2 {
+		t.Errorf("Retry() = %+v, %v", retried, err)
+	}
+	if order, err := st.GetOrder(OrderID("a", "wallet1")); err != nil || Status(order.Status) != StatusLanded {
+		t.Errorf("landed order = %+v, %v", order, err)
+	}
+}
+
+// TestReconcileBatches tests that a backlog of sent orders is checked in batches the RPC accepts
+func TestReconcileBatches(t *testing.T) {
+	m, _ := newTestManager(t)
+	ctx := context.Background()
+	s := rpctest.NewServer()
+	defer s.Close()
+	s.Handle("getSignatureStatuses", func(params json.RawMessage) (interface{}, error) {
+		var sigs []solana.Signature
+		if err := json.Unmarshal(params, &[]interface{}{&sigs}); err != nil {
+			return nil, err
+		}
+		if len(sigs) > maxSignatureStatuses {
+			return nil, &rpctest.Error{Code: rpctest.CodeInvalidParams, Message: "Too many inputs provided; max 256"}
+		}
+		value := make([]interface{}, len(sigs))
+		for i := range sigs {
+			value[i] = map[string]interface{}{"slot": 10, "confirmationStatus": "finalized"}
+		}
+		return map[string]interface{}{"context": map[string]uint64{"slot": 10}, "value": value}, nil
+	})
+
+	const n = 300
+	for i := 0; i < n; i++ {
+		sig := solana.Signature{byte(i), byte(i >> 8), 1}
+		intent := Intent{SourceSignature: sig.String(), Wallet: "wallet1", Side: "buy", AmountIn: 1000}
+		if _, err := m.Submit(ctx, intent, func(ctx context.Context) (string, error) { return sig.String(), nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	changed, err := m.Reconcile(ctx, rpc.New(s.URL), time.Hour)
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if len(changed) != n || s.Calls("getSignatureStatuses") != 2 {
+		t.Errorf("Reconcile() changed %d orders in %d calls", len(changed), s.Calls("getSignatureStatuses"))
+	}
+	for _, o := range changed {
+		if Status(o.Status) != StatusLanded {
+			t.Fatalf("order = %+v", o)
+		}
+	}
+}
+
+// landOrder submits an order and marks it landed the way Reconcile would
+func landOrder(t *testing.T, m *Manager, st *store.Store, intent Intent) store.Order {
+	t.Helper()
+	order, err := m.Submit(context.Background(), intent, func(ctx context.Context) (string, error) { return "sig-" + intent.SourceSignature, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	order.Status = string(StatusLanded)
+	if err := st.PutOrder(order); err != nil {
+		t.Fatal(err)
+	}
+	return order
+}
+
+// TestApplyFill tests that landed buys open and grow the mint's position and landed sells close it
+func TestApplyFill(t *testing.T) {
+	m, st := newTestManager(t)
+	opened := time.Unix(1750000000, 0)
+
+	first := landOrder(t, m, st, Intent{SourceSignature: "a", Wallet: "wallet1", Mint: "mint", Side: "buy", AmountIn: 1000})
+	p, err := m.ApplyFill(first.ID, Fill{BaseAmount: 500, QuoteAmount: 1000, Pool: "pool", At: opened})
+	if err != nil {
+		t.Fatalf("ApplyFill() error = %v", err)
+	}
+	if p.ID != first.ID || !p.Open() || p.Amount != 500 || p.CostLamports != 1000 || p.EntryPrice != "2" || !p.OpenedAt.Equal(opened) {
+		t.Errorf("opened position = %+v", p)
+	}
+	// Applying the same fill again changes nothing
+	if again, err := m.ApplyFill(first.ID, Fill{BaseAmount: 500, QuoteAmount: 1000}); err != nil || again.Amount != 500 {
+		t.Errorf("second ApplyFill() = %+v, %v", again, err)
+	}
+
+	second := landOrder(t, m, st, Intent{SourceSignature: "b", Wallet: "wallet1", Mint: "mint", Side: "buy", AmountIn: 2000})
+	p, err = m.ApplyFill(second.ID, Fill{BaseAmount: 500, QuoteAmount: 2000, At: opened.Add(time.Minute)})
+	if err != nil || p.ID != first.ID || p.Amount != 1000 || p.InitialAmount != 1000 || p.CostLamports != 3000 || p.EntryPrice != "3" {
+		t.Errorf("added to position = %+v, %v", p, err)
+	}
+	if unfilled, err := m.Unfilled(); err != nil || len(unfilled) != 0 {
+		t.Errorf("Unfilled() = %+v, %v", unfilled, err)
+	}
+
+	for i, reason := range []string{"take-profit", "stop-loss"} {
+		base := uint64(400 + 200*i)
+		sell := landOrder(t, m, st, Intent{SourceSignature: string(rune('c' + i)), Wallet: "wallet1", Mint: "mint", Side: "sell", Reason: reason, AmountIn: base})
+		if unfilled, err := m.Unfilled(); err != nil || len(unfilled) != 1 || unfilled[0].ID != sell.ID {
+			t.Errorf("Unfilled() = %+v, %v", unfilled, err)
+		}
+		if p, err = m.ApplyFill(sell.ID, Fill{BaseAmount: base, QuoteAmount: base * 4, At: opened.Add(time.Hour)}); err != nil {
+			t.Fatalf("ApplyFill(sell) error = %v", err)
+		}
+	}
+	if p.Open() || p.Amount != 0 || p.ProceedsLamports != 4000 || p.RealizedPnL() != 1000 || p.TakenProfits != 1 || !p.ClosedAt.Equal(opened.Add(time.Hour)) {
+		t.Errorf("closed position = %+v", p)
+	}
+
+	orphan := landOrder(t, m, st, Intent{SourceSignature: "e", Wallet: "wallet1", Mint: "mint", Side: "sell", AmountIn: 1})
+	if _, err := m.ApplyFill(orphan.ID, Fill{BaseAmount: 1}); err == nil {
+		t.Error("ApplyFill() of a sell without an open position succeeded")
+	}
+}
+
+// TestFillFromTransaction tests that the order's own swap is found in its transaction
+func TestFillFromTransaction(t *testing.T) {
+	raw, err := os.ReadFile(filepath.Join("..", "decoder", "testdata", "transactions", "pumpswap_buy.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := new(rpc.GetTransactionResult)
+	if err := json.Unmarshal(raw, tx); err != nil {
+		t.Fatal(err)
+	}
+	trades, err := decoder.DecodeTrades(tx)
+	if err != nil || len(trades) != 1 {
+		t.Fatalf("DecodeTrades() = %+v, %v", trades, err)
+	}
+	trade := trades[0]
+
+	order := store.Order{Wallet: trade.User.String(), Mint: trade.BaseMint.String(), Side: "buy"}
+	fill, err := FillFromTransaction(tx, order)
+	if err != nil {
+		t.Fatalf("FillFromTransaction() error = %v", err)
+	}
+	if fill.BaseAmount != trade.BaseAmount || fill.QuoteAmount != trade.QuoteAmount || fill.Pool != trade.Pool.String() {
+		t.Errorf("FillFromTransaction() = %+v, want the amounts of %+v", fill, trade)
+	}
+
+	order.Wallet = solana.PublicKey{1}.String()
+	if _, err := FillFromTransaction(tx, order); err == nil {
+		t.Error("FillFromTransaction() found another wallet's swap")
+	}
+}
@@ -0,0 +1,160 @@
+package risk
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"solana-pumpswap-demo/internal/store"
+)
+
+// Side of a proposed order
+const (
+	SideBuy  = "buy"
+	SideSell = "sell"
+)
+
+// Limits are the pre-trade safety rails. A zero value disables the limit.
+type Limits struct {
+	MaxSolPerTrade    uint64 // Lamports spent by a single buy
+	MaxTokenExposure  uint64 // Lamports held in a single mint, including this buy
+	MaxOpenPositions  int    // Distinct mints held at once, including this buy
+	DailyLossLimit    uint64 // Realized loss in lamports since UTC midnight
+	MaxPriceImpactBps uint64 // Price move caused by the trade
+	MinPoolLiquidity  uint64 // Quote lamports in the pool
+	KillSwitch        bool   // Reject every buy
+	KillSwitchFile    string // Reject every buy while this file exists
+}
+
+// Order is a proposed trade waiting for approval
+type Order struct {
+	Mint             string
+	Side             string
+	AmountIn         uint64 // Lamports for a buy, raw base units for a sell
+	PriceImpactBps   uint64
+	PoolQuoteReserve uint64
+}
+
+// Book is the current exposure the order is checked against
+type Book struct {
+	OpenPositions int
+	Exposure      map[string]uint64 // Lamports per mint
+	DailyPnL      int64             // Realized lamports since UTC midnight
+}
+
+// Rejection is the error returned when an order breaks a limit
+type Rejection struct {
+	Rule   string
+	Reason string
+}
+
+func (r *Rejection) Error() string {
+	return fmt.Sprintf("risk check %s rejected order: %s", r.Rule, r.Reason)
+}
+
+func reject(rule, format string, args ...interface{}) *Rejection {
+	return &Rejection{Rule: rule, Reason: fmt.Sprintf(format, args...)}
+}
+
+// Engine evaluates proposed orders against the limits
+type Engine struct {
+	limits Limits
+}
+
+// NewEngine creates a risk engine with the given limits
+func NewEngine(limits Limits) *Engine {
+	return &Engine{limits: limits}
+}
+
+// Check returns nil if the order may be signed, or a *Rejection explaining which limit it breaks.
+// Sells only reduce exposure and are always allowed, so exits are never blocked.
+func (e *Engine) Check(o Order, b Book) error {
+	if o.Side == SideSell {
+		return nil
+	}
+	l := e.limits
+
+	if l.KillSwitch {
+		return reject("kill_switch", "kill switch is on")
+	}
+	if l.KillSwitchFile != "" {
+		if _, err := os.Stat(l.KillSwitchFile); err == nil {
+			return reject("kill_switch", "kill switch file %s exists", l.KillSwitchFile)
+		}
+	}
+
+	if l.MaxSolPerTrade > 0 && o.AmountIn > l.MaxSolPerTrade {
+		return reject("max_sol_per_trade", "%s SOL exceeds the %s SOL per trade limit", lamportsToSol(o.AmountIn), lamportsToSol(l.MaxSolPerTrade))
+	}
+
+	held := b.Exposure[o.Mint]
+	if l.MaxTokenExposure > 0 && held+o.AmountIn > l.MaxTokenExposure {
+		return reject("max_token_exposure", "%s SOL already in %s plus %s SOL exceeds the %s SOL per token limit",
+			lamportsToSol(held), o.Mint, lamportsToSol(o.AmountIn), lamportsToSol(l.MaxTokenExposure))
+	}
+
+	if l.MaxOpenPositions > 0 && held == 0 && b.OpenPositions >= l.MaxOpenPositions {
+		// Adding to a mint we already hold does not open a new position
+		return reject("max_open_positions", "%d positions already open, limit is %d", b.OpenPositions, l.MaxOpenPositions)
+	}
+
+	if l.DailyLossLimit > 0 && b.DailyPnL < 0 && uint64(-b.DailyPnL) >= l.DailyLossLimit {
+		return reject("daily_loss_limit", "lost %s SOL today, limit is %s SOL", lamportsToSol(uint64(-b.DailyPnL)), lamportsToSol(l.DailyLossLimit))
+	}
+
+	if l.MaxPriceImpactBps > 0 && o.PriceImpactBps > l.MaxPriceImpactBps {
+		return reject("max_price_impact", "price impact %d bps exceeds %d bps", o.PriceImpactBps, l.MaxPriceImpactBps)
+	}
+
+	if l.MinPoolLiquidity > 0 && o.PoolQuoteReserve < l.MinPoolLiquidity {
+		return reject("min_pool_liquidity", "pool holds %s SOL, minimum is %s SOL", lamportsToSol(o.PoolQuoteReserve), lamportsToSol(l.MinPoolLiquidity))
+	}
+
+	return nil
+}
+
+// BookFromStore builds the book from the open positions, the positions closed today and the buys
+// that have not been applied to a position yet: the ones pending or sent, and the ones that landed
+// before their fill was read
+func BookFromStore(st *store.Store, now time.Time) (Book, error) {
+	book := Book{Exposure: make(map[string]uint64)}
+	dayStart := now.UTC().Truncate(24 * time.Hour)
+
+	positions, err := st.Positions()
+	if err != nil {
+		return book, err
+	}
+	open := make(map[string]bool)
+	for _, p := range positions {
+		if p.Open() {
+			open[p.Mint] = true
+			if p.CostLamports > p.ProceedsLamports {
+				book.Exposure[p.Mint] += p.CostLamports - p.ProceedsLamports
+			}
+		} else if !p.ClosedAt.Before(dayStart) {
+			book.DailyPnL += p.RealizedPnL()
+		}
+	}
+
+	orders, err := st.Orders()
+	if err != nil {
+		return book, err
+	}
+	for _, o := range orders {
+		if o.Side != SideBuy || o.PositionID != "" {
+			continue
+		}
+		switch o.Status {
+		case "pending", "sent", "landed":
+			open[o.Mint] = true
+			book.Exposure[o.Mint] += o.AmountIn
+		}
+	}
+
+	book.OpenPositions = len(open)
+	return book, nil
+}
+
+func lamportsToSol(lamports uint64) string {
+	return fmt.Sprintf("%.4f", float64(lamports)/1e9)
+}
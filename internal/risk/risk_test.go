@@ -0,0 +1,185 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"solana-pumpswap-demo/internal/orders"
+	"solana-pumpswap-demo/internal/store"
+)
+
+// TestCheck tests each limit in isolation
+func TestCheck(t *testing.T) {
+	killFile := filepath.Join(t.TempDir(), "KILL")
+	if err := os.WriteFile(killFile, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	book := Book{
+		OpenPositions: 2,
+		Exposure:      map[string]uint64{"held": 400_000_000},
+		DailyPnL:      -100_000_000,
+	}
+	buy := Order{Mint: "new", Side: SideBuy, AmountIn: 100_000_000, PriceImpactBps: 50, PoolQuoteReserve: 50_000_000_000}
+
+	tests := []struct {
+		name     string
+		limits   Limits
+		order    Order
+		wantRule string
+	}{
+		{name: "no limits", limits: Limits{}, order: buy},
+		{name: "within every limit", limits: Limits{
+			MaxSolPerTrade: 500_000_000, MaxTokenExposure: 500_000_000, MaxOpenPositions: 3,
+			DailyLossLimit: 200_000_000, MaxPriceImpactBps: 100, MinPoolLiquidity: 10_000_000_000,
+		}, order: buy},
+		{name: "kill switch", limits: Limits{KillSwitch: true}, order: buy, wantRule: "kill_switch"},
+		{name: "kill switch file", limits: Limits{KillSwitchFile: killFile}, order: buy, wantRule: "kill_switch"},
+		{name: "missing kill switch file", limits: Limits{KillSwitchFile: killFile + ".missing"}, order: buy},
+		{name: "max sol per trade", limits: Limits{MaxSolPerTrade: 50_000_000}, order: buy, wantRule: "max_sol_per_trade"},
+		{name: "max token exposure", limits: Limits{MaxTokenExposure: 450_000_000},
+			order: Order{Mint: "held", Side: SideBuy, AmountIn: 100_000_000}, wantRule: "max_token_exposure"},
+		{name: "max open positions", limits: Limits{MaxOpenPositions: 2}, order: buy, wantRule: "max_open_positions"},
+		{name: "adding to a held mint is not a new position", limits: Limits{MaxOpenPositions: 2},
+			order: Order{Mint: "held", Side: SideBuy, AmountIn: 100_000_000}},
+		{name: "daily loss limit", limits: Limits{DailyLossLimit: 100_000_000}, order: buy, wantRule: "daily_loss_limit"},
+		{name: "max price impact", limits: Limits{MaxPriceImpactBps: 25}, order: buy, wantRule: "max_price_impact"},
+		{name: "min pool liquidity", limits: Limits{MinPoolLiquidity: 80_000_000_000}, order: buy, wantRule: "min_pool_liquidity"},
+		{name: "sells are never blocked", limits: Limits{KillSwitch: true, MaxSolPerTrade: 1},
+			order: Order{Mint: "held", Side: SideSell, AmountIn: 1_000_000_000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewEngine(tt.limits).Check(tt.order, book)
+			if tt.wantRule == "" {
+				if err != nil {
+					t.Errorf("Check() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var rejection *Rejection
+			if !errors.As(err, &rejection) {
+				t.Fatalf("Check() error = %v, want *Rejection", err)
+			}
+			if rejection.Rule != tt.wantRule || rejection.Reason == "" {
+				t.Errorf("Check() rejection = %+v, want rule %s", rejection, tt.wantRule)
+			}
+		})
+	}
+}
+
+// TestBookFromStore tests exposure and daily PnL built from positions and in-flight orders
+func TestBookFromStore(t *testing.T) {
+	st, err := store.Open(filepath.Join(t.TempDir(), "risk.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	now := time.Date(2025, 4, 20, 15, 0, 0, 0, time.UTC)
+	records := []store.Position{
+		{ID: "open", Mint: "a", Amount: 10, CostLamports: 300, ProceedsLamports: 100},
+		{ID: "closed-today", Mint: "b", CostLamports: 500, ProceedsLamports: 200, ClosedAt: now.Add(-time.Hour)},
+		{ID: "closed-yesterday", Mint: "c", CostLamports: 500, ProceedsLamports: 100, ClosedAt: now.Add(-24 * time.Hour)},
+	}
+	for _, p := range records {
+		if err := st.PutPosition(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	orderRecords := []store.Order{
+		{ID: "1", Mint: "d", Side: SideBuy, AmountIn: 1000, Status: "sent"},
+		{ID: "2", Mint: "e", Side: SideBuy, AmountIn: 1000, Status: "failed"},
+		{ID: "3", Mint: "a", Side: SideBuy, AmountIn: 1000, Status: "landed", PositionID: "open"},
+		{ID: "4", Mint: "b", Side: SideBuy, AmountIn: 700, Status: "landed"}, // Fill not read yet
+		{ID: "5", Mint: "c", Side: SideBuy, AmountIn: 500, Status: "landed", PositionID: "closed-yesterday"},
+	}
+	for _, o := range orderRecords {
+		if err := st.PutOrder(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	book, err := BookFromStore(st, now)
+	if err != nil {
+		t.Fatalf("BookFromStore() error = %v", err)
+	}
+	if book.OpenPositions != 3 {
+		t.Errorf("OpenPositions = %d, want 3", book.OpenPositions)
+	}
+	if book.Exposure["a"] != 200 || book.Exposure["b"] != 700 || book.Exposure["c"] != 0 || book.Exposure["d"] != 1000 || book.Exposure["e"] != 0 {
+		t.Errorf("Exposure = %v", book.Exposure)
+	}
+	if book.DailyPnL != -300 {
+		t.Errorf("DailyPnL = %d, want -300", book.DailyPnL)
+	}
+}
+
+// TestBookFollowsPositionCycle tests the book through a buy and a sell placed and filled in the store:
+// the buy counts while in flight and once held, and the round trip's loss counts once it closes
+func TestBookFollowsPositionCycle(t *testing.T) {
+	st, err := store.Open(filepath.Join(t.TempDir(), "risk.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	m := orders.NewManager(st)
+	ctx := context.Background()
+	now := time.Now()
+
+	bookOf := func() Book {
+		t.Helper()
+		book, err := BookFromStore(st, now)
+		if err != nil {
+			t.Fatalf("BookFromStore() error = %v", err)
+		}
+		return book
+	}
+	place := func(source, side string, amountIn uint64) store.Order {
+		t.Helper()
+		order, err := m.Submit(ctx, orders.Intent{SourceSignature: source, Wallet: "wallet", Mint: "mint", Side: side, AmountIn: amountIn},
+			func(ctx context.Context) (string, error) { return "sig-" + source, nil })
+		if err != nil {
+			t.Fatal(err)
+		}
+		return order
+	}
+	fill := func(order store.Order, fill orders.Fill) {
+		t.Helper()
+		order.Status = "landed"
+		if err := st.PutOrder(order); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.ApplyFill(order.ID, fill); err != nil {
+			t.Fatalf("ApplyFill() error = %v", err)
+		}
+	}
+
+	buy := place("leader-buy", SideBuy, 1_000_000)
+	if book := bookOf(); book.OpenPositions != 1 || book.Exposure["mint"] != 1_000_000 {
+		t.Errorf("book with the buy in flight = %+v", book)
+	}
+	fill(buy, orders.Fill{BaseAmount: 5000, QuoteAmount: 990_000, At: now.Add(-time.Minute)})
+	if book := bookOf(); book.OpenPositions != 1 || book.Exposure["mint"] != 990_000 || book.DailyPnL != 0 {
+		t.Errorf("book holding the position = %+v", book)
+	}
+
+	sell := place("exit", SideSell, 5000)
+	fill(sell, orders.Fill{BaseAmount: 5000, QuoteAmount: 700_000, At: now})
+	if book := bookOf(); book.OpenPositions != 0 || book.Exposure["mint"] != 0 || book.DailyPnL != -290_000 {
+		t.Errorf("book after the sell = %+v", book)
+	}
+
+	// The loss counts against the daily limit
+	engine := NewEngine(Limits{DailyLossLimit: 200_000})
+	var rejection *Rejection
+	if err := engine.Check(Order{Mint: "other", Side: SideBuy, AmountIn: 1000}, bookOf()); !errors.As(err, &rejection) || rejection.Rule != "daily_loss_limit" {
+		t.Errorf("Check() after the loss = %v", err)
+	}
+}
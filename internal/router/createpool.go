@@ -0,0 +1,127 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	pumpswap "solana-pumpswap-demo/idl/pumpfun/amm"
+	"solana-pumpswap-demo/idl/pumpfun/amm/idl/generated/amm"
+
+	"github.com/gagliardetto/solana-go"
+	ata "github.com/gagliardetto/solana-go/programs/associated-token-account"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// ErrPoolExists is returned when the pool to create is already there
+var ErrPoolExists = errors.New("pool already exists")
+
+// ErrPoolNotCreated is returned by VerifyPoolCreation while the new pool account cannot be read yet
+var ErrPoolNotCreated = errors.New("pool account not found")
+
+// PoolCreation is a create_pool seeding a PumpSwap pool from the signer's tokens
+type PoolCreation struct {
+	Param        pumpswap.CreatePoolParam
+	Addresses    *pumpswap.PoolAddresses
+	Instructions []solana.Instruction
+}
+
+// PlanCreatePool builds the creation of the pool at index for baseMint and quoteMint, seeded with
+// baseAmountIn and quoteAmountIn from the signer. A WSOL quote is wrapped from SOL and what is left is
+// unwrapped, any other quote must already be in the signer's token account.
+func (r *Router) PlanCreatePool(ctx context.Context, index uint16, baseMint, quoteMint solana.PublicKey, baseAmountIn, quoteAmountIn uint64) (*PoolCreation, error) {
+	user := r.signer.PublicKey()
+	param := pumpswap.CreatePoolParam{
+		Index:         index,
+		BaseAmountIn:  baseAmountIn,
+		QuoteAmountIn: quoteAmountIn,
+		Creator:       user,
+		BaseMint:      baseMint,
+		QuoteMint:     quoteMint,
+	}
+	createIx, addrs, err := pumpswap.NewCreatePoolInstruction(&param)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create create_pool instruction: %w", err)
+	}
+
+	accounts, err := r.accounts(ctx, addrs.Pool, addrs.UserBaseTokenAccount, addrs.UserQuoteTokenAccount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool accounts: %w", err)
+	}
+	if accounts[0] != nil {
+		return nil, fmt.Errorf("%w: %s", ErrPoolExists, addrs.Pool)
+	}
+	wrapSol := quoteMint.Equals(solana.WrappedSol)
+	type source struct {
+		mint    solana.PublicKey
+		account *rpc.Account
+		need    uint64
+	}
+	funding := []source{{baseMint, accounts[1], baseAmountIn}}
+	if !wrapSol {
+		funding = append(funding, source{quoteMint, accounts[2], quoteAmountIn})
+	}
+	for _, f := range funding {
+		var balance uint64
+		if f.account != nil {
+			if balance, err = tokenBalance(f.account); err != nil {
+				return nil, fmt.Errorf("failed to decode token account: %w", err)
+			}
+		}
+		if balance < f.need {
+			return nil, fmt.Errorf("%w: seeding the pool takes %d of %s, have %d", ErrInsufficientBalance, f.need, f.mint, balance)
+		}
+	}
+
+	c := &PoolCreation{Param: param, Addresses: addrs}
+	if c.Instructions, err = computeBudget(); err != nil {
+		return nil, err
+	}
+	if wrapSol {
+		if accounts[2] == nil {
+			ataIx, err := ata.NewCreateInstruction(user, user, quoteMint).ValidateAndBuild()
+			if err != nil {
+				return nil, fmt.Errorf("failed to build create WSOL ATA instruction: %w", err)
+			}
+			c.Instructions = append(c.Instructions, ataIx)
+		}
+		transferIx, err := system.NewTransferInstruction(quoteAmountIn, user, addrs.UserQuoteTokenAccount).ValidateAndBuild()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build SOL transfer instruction: %w", err)
+		}
+		syncIx, err := token.NewSyncNativeInstruction(addrs.UserQuoteTokenAccount).ValidateAndBuild()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build sync native instruction: %w", err)
+		}
+		c.Instructions = append(c.Instructions, transferIx, syncIx)
+	}
+	c.Instructions = append(c.Instructions, createIx)
+	if wrapSol {
+		closeIx, err := token.NewCloseAccountInstruction(addrs.UserQuoteTokenAccount, user, user, []solana.PublicKey{}).ValidateAndBuild()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build close account instruction: %w", err)
+		}
+		c.Instructions = append(c.Instructions, closeIx)
+	}
+	return c, nil
+}
+
+// SendCreatePool signs the creation's instructions and sends them as one transaction
+func (r *Router) SendCreatePool(ctx context.Context, c *PoolCreation) (solana.Signature, error) {
+	return r.send(ctx, c.Instructions)
+}
+
+// VerifyPoolCreation reads the created pool and checks it against the creation.
+// It returns ErrPoolNotCreated until the pool account is visible.
+func (r *Router) VerifyPoolCreation(ctx context.Context, c *PoolCreation) (*amm.PoolAccount, error) {
+	accounts, err := r.accounts(ctx, c.Addresses.Pool)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool: %w", err)
+	}
+	if accounts[0] == nil {
+		return nil, fmt.Errorf("%w: %s", ErrPoolNotCreated, c.Addresses.Pool)
+	}
+	return pumpswap.VerifyPool(accounts[0].Data.GetBinary(), &c.Param, c.Addresses)
+}
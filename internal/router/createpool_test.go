@@ -0,0 +1,85 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pumpswap "solana-pumpswap-demo/idl/pumpfun/amm"
+	"solana-pumpswap-demo/idl/pumpfun/amm/idl/generated/amm"
+	"solana-pumpswap-demo/internal/decoder"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+)
+
+// TestPlanCreatePool tests seeding a WSOL pool and verifying the pool account once it exists
+func TestPlanCreatePool(t *testing.T) {
+	signer := solana.NewWallet().PrivateKey
+	mint := solana.NewWallet().PublicKey()
+	client := &fakeClient{accounts: map[solana.PublicKey][]byte{}}
+	r := New(client, signer, 100)
+	ctx := context.Background()
+
+	if _, err := r.PlanCreatePool(ctx, 1, mint, solana.WrappedSol, 1_000_000_000_000, 2_000_000_000); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("PlanCreatePool() without tokens error = %v", err)
+	}
+	userBase, _, _ := solana.FindAssociatedTokenAddress(signer.PublicKey(), mint)
+	client.accounts[userBase] = encode(t, token.Account{Mint: mint, Owner: signer.PublicKey(), Amount: 1_000_000_000_000}, false)
+
+	c, err := r.PlanCreatePool(ctx, 1, mint, solana.WrappedSol, 1_000_000_000_000, 2_000_000_000)
+	if err != nil {
+		t.Fatalf("PlanCreatePool() error = %v", err)
+	}
+	wantPool, _ := pumpswap.FindPoolAddress(1, signer.PublicKey(), mint, solana.WrappedSol)
+	wantLpMint, _ := pumpswap.FindLpMint(wantPool)
+	if !c.Addresses.Pool.Equals(wantPool) || !c.Addresses.LpMint.Equals(wantLpMint) || !c.Addresses.UserBaseTokenAccount.Equals(userBase) {
+		t.Fatalf("addresses = %+v", c.Addresses)
+	}
+	// Compute budget, create WSOL ATA, wrap, sync, create_pool, close WSOL
+	if len(c.Instructions) != 7 {
+		t.Fatalf("instructions = %d", len(c.Instructions))
+	}
+	createIx := c.Instructions[5]
+	data, err := createIx.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decoder.DecodeInstruction(createIx.Accounts(), data)
+	if err != nil {
+		t.Fatalf("DecodeInstruction() error = %v", err)
+	}
+	create, ok := decoded.Impl.(*amm.CreatePool)
+	if !ok || *create.Index != 1 || *create.BaseAmountIn != 1_000_000_000_000 || *create.QuoteAmountIn != 2_000_000_000 ||
+		!create.GetPoolBaseTokenAccountAccount().PublicKey.Equals(c.Addresses.PoolBaseTokenAccount) {
+		t.Fatalf("create_pool = %+v", decoded.Impl)
+	}
+
+	if _, err := r.VerifyPoolCreation(ctx, c); !errors.Is(err, ErrPoolNotCreated) {
+		t.Errorf("VerifyPoolCreation() before creation error = %v", err)
+	}
+	pool := amm.PoolAccount{
+		Index:                 1,
+		Creator:               signer.PublicKey(),
+		BaseMint:              mint,
+		QuoteMint:             solana.WrappedSol,
+		LpMint:                c.Addresses.LpMint,
+		PoolBaseTokenAccount:  c.Addresses.PoolBaseTokenAccount,
+		PoolQuoteTokenAccount: c.Addresses.PoolQuoteTokenAccount,
+		LpSupply:              44_721_359_549,
+	}
+	client.accounts[c.Addresses.Pool] = encode(t, pool, true)
+	if got, err := r.VerifyPoolCreation(ctx, c); err != nil || got.LpSupply != pool.LpSupply {
+		t.Errorf("VerifyPoolCreation() = %+v, %v", got, err)
+	}
+	pool.LpMint = solana.NewWallet().PublicKey()
+	client.accounts[c.Addresses.Pool] = encode(t, pool, true)
+	if _, err := r.VerifyPoolCreation(ctx, c); err == nil {
+		t.Error("VerifyPoolCreation() accepted a pool with another LP mint")
+	}
+
+	// The pool now exists, so it cannot be created again
+	if _, err := r.PlanCreatePool(ctx, 1, mint, solana.WrappedSol, 1_000_000_000_000, 2_000_000_000); !errors.Is(err, ErrPoolExists) {
+		t.Errorf("PlanCreatePool() of an existing pool error = %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	pumpswap "solana-pumpswap-demo/idl/pumpfun/amm"
+
+	"github.com/gagliardetto/solana-go"
+	ata "github.com/gagliardetto/solana-go/programs/associated-token-account"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/shopspring/decimal"
+)
+
+// Liquidity plan sides
+const (
+	SideDeposit  = "deposit"
+	SideWithdraw = "withdraw"
+)
+
+// ErrInsufficientBalance is returned when the user holds fewer tokens than a liquidity plan needs
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// LiquidityPlan is a deposit into or a withdrawal from a mint's canonical PumpSwap pool
+type LiquidityPlan struct {
+	Side         string // SideDeposit or SideWithdraw
+	Mint         solana.PublicKey
+	Pool         solana.PublicKey
+	LpMint       solana.PublicKey
+	LpSupply     uint64 // Before the plan
+	LpAmount     uint64 // LP tokens a deposit mints or a withdrawal burns
+	BaseAmount   uint64 // Tokens expected in or out, before slippage
+	QuoteAmount  uint64 // Lamports expected in or out, before slippage
+	BaseLimit    uint64 // Most tokens a deposit takes, fewest a withdrawal returns
+	QuoteLimit   uint64 // Most lamports a deposit takes, fewest a withdrawal returns
+	BaseReserve  uint64
+	QuoteReserve uint64
+	Instructions []solana.Instruction
+}
+
+// Share returns the part of the pool the plan's LP tokens stand for, against the supply after the plan
+func (p *LiquidityPlan) Share() decimal.Decimal {
+	supply := p.LpSupply + p.LpAmount
+	if p.Side == SideWithdraw {
+		supply = p.LpSupply
+	}
+	if supply == 0 {
+		return decimal.Zero
+	}
+	return decimal.NewFromUint64(p.LpAmount).Div(decimal.NewFromUint64(supply))
+}
+
+// SendLiquidity signs the plan's instructions and sends them as one transaction
+func (r *Router) SendLiquidity(ctx context.Context, plan *LiquidityPlan) (solana.Signature, error) {
+	return r.send(ctx, plan.Instructions)
+}
+
+// PlanDeposit builds a deposit of at most quoteAmountIn lamports, and the tokens that match them at the
+// pool's ratio, into the mint's canonical pool. The LP tokens minted are sized so that the deposit still
+// fits the lamports if the pool moves by the router's slippage, and the LP token account is created if needed.
+func (r *Router) PlanDeposit(ctx context.Context, mint solana.PublicKey, quoteAmountIn uint64) (*LiquidityPlan, error) {
+	loaded, err := r.loadPool(ctx, mint)
+	if err != nil {
+		return nil, err
+	}
+	pool := loaded.state
+	target := uint64(decimal.NewFromUint64(quoteAmountIn).Mul(decimal.NewFromInt(10000)).
+		Div(decimal.NewFromUint64(10000 + uint64(r.slippageBps))).Floor().IntPart())
+	lpAmount, err := pumpswap.LpTokensForQuote(target, loaded.account.LpSupply, pool.QuoteReserve)
+	if err != nil {
+		return nil, err
+	}
+	if lpAmount == 0 {
+		return nil, fmt.Errorf("%d lamports is too little to mint an LP token", quoteAmountIn)
+	}
+	baseIn, quoteIn, err := pumpswap.DepositAmounts(lpAmount, loaded.account.LpSupply, pool.BaseReserve, pool.QuoteReserve)
+	if err != nil {
+		return nil, err
+	}
+
+	var balance uint64
+	if loaded.userBase != nil {
+		if balance, err = tokenBalance(loaded.userBase); err != nil {
+			return nil, fmt.Errorf("failed to decode token account: %w", err)
+		}
+	}
+	if balance < baseIn {
+		return nil, fmt.Errorf("%w: depositing %d lamports takes %d tokens, have %d", ErrInsufficientBalance, quoteAmountIn, baseIn, balance)
+	}
+	plan := newLiquidityPlan(SideDeposit, loaded, lpAmount, baseIn, quoteIn)
+	plan.BaseLimit = min(withSlippage(baseIn, int64(r.slippageBps)), balance)
+	plan.QuoteLimit = quoteAmountIn
+
+	user := r.signer.PublicKey()
+	userBase, userQuote, err := r.userTokenAccounts(pool)
+	if err != nil {
+		return nil, err
+	}
+	if plan.Instructions, err = computeBudget(); err != nil {
+		return nil, err
+	}
+	if loaded.userQuote == nil {
+		createIx, err := ata.NewCreateInstruction(user, user, pool.QuoteMint).ValidateAndBuild()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build create WSOL ATA instruction: %w", err)
+		}
+		plan.Instructions = append(plan.Instructions, createIx)
+	}
+	transferIx, err := system.NewTransferInstruction(plan.QuoteLimit, user, userQuote).ValidateAndBuild()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build SOL transfer instruction: %w", err)
+	}
+	syncIx, err := token.NewSyncNativeInstruction(userQuote).ValidateAndBuild()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sync native instruction: %w", err)
+	}
+	plan.Instructions = append(plan.Instructions, transferIx, syncIx)
+	if loaded.userLp == nil {
+		createIx, err := pumpswap.NewCreateUserPoolTokenAccountInstruction(user, user, plan.LpMint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build create LP token account instruction: %w", err)
+		}
+		plan.Instructions = append(plan.Instructions, createIx)
+	}
+	return r.finishLiquidity(plan, pool, pumpswap.DepositDirection, userBase, userQuote)
+}
+
+// PlanWithdraw builds a withdrawal burning lpAmountIn LP tokens from the mint's canonical pool, or the
+// whole LP balance if it is zero. The tokens and lamports returned may fall short of the pool's ratio by
+// the router's slippage, and the lamports are unwrapped.
+func (r *Router) PlanWithdraw(ctx context.Context, mint solana.PublicKey, lpAmountIn uint64) (*LiquidityPlan, error) {
+	loaded, err := r.loadPool(ctx, mint)
+	if err != nil {
+		return nil, err
+	}
+	pool := loaded.state
+
+	var balance uint64
+	if loaded.userLp != nil {
+		// Token-2022 accounts start with the legacy layout, extensions follow it
+		if balance, err = tokenBalance(loaded.userLp); err != nil {
+			return nil, fmt.Errorf("failed to decode LP token account: %w", err)
+		}
+	}
+	if lpAmountIn == 0 {
+		lpAmountIn = balance
+	}
+	if lpAmountIn == 0 || balance < lpAmountIn {
+		return nil, fmt.Errorf("%w: withdrawing %d LP tokens, have %d", ErrInsufficientBalance, lpAmountIn, balance)
+	}
+	baseOut, quoteOut, err := pumpswap.WithdrawAmounts(lpAmountIn, loaded.account.LpSupply, pool.BaseReserve, pool.QuoteReserve)
+	if err != nil {
+		return nil, err
+	}
+	plan := newLiquidityPlan(SideWithdraw, loaded, lpAmountIn, baseOut, quoteOut)
+	plan.BaseLimit = withSlippage(baseOut, -int64(r.slippageBps))
+	plan.QuoteLimit = withSlippage(quoteOut, -int64(r.slippageBps))
+
+	user := r.signer.PublicKey()
+	userBase, userQuote, err := r.userTokenAccounts(pool)
+	if err != nil {
+		return nil, err
+	}
+	if plan.Instructions, err = computeBudget(); err != nil {
+		return nil, err
+	}
+	if loaded.userBase == nil {
+		createIx, err := ata.NewCreateInstruction(user, user, pool.BaseMint).ValidateAndBuild()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build create ATA instruction: %w", err)
+		}
+		plan.Instructions = append(plan.Instructions, createIx)
+	}
+	if loaded.userQuote == nil {
+		createIx, err := ata.NewCreateInstruction(user, user, pool.QuoteMint).ValidateAndBuild()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build create WSOL ATA instruction: %w", err)
+		}
+		plan.Instructions = append(plan.Instructions, createIx)
+	}
+	return r.finishLiquidity(plan, pool, pumpswap.WithdrawDirection, userBase, userQuote)
+}
+
+// finishLiquidity appends the deposit or withdraw and the WSOL close that unwraps what is left
+func (r *Router) finishLiquidity(plan *LiquidityPlan, pool PoolState, direction pumpswap.LiquidityDirection, userBase, userQuote solana.PublicKey) (*LiquidityPlan, error) {
+	user := r.signer.PublicKey()
+	userLp, err := pumpswap.FindUserPoolTokenAccount(user, plan.LpMint)
+	if err != nil {
+		return nil, err
+	}
+	liquidityIx, err := pumpswap.NewLiquidityInstruction(&pumpswap.LiquidityParam{
+		Direction:             direction,
+		LpTokenAmount:         plan.LpAmount,
+		BaseAmount:            plan.BaseLimit,
+		QuoteAmount:           plan.QuoteLimit,
+		Pool:                  pool.Address,
+		User:                  user,
+		BaseMint:              pool.BaseMint,
+		QuoteMint:             pool.QuoteMint,
+		LpMint:                plan.LpMint,
+		UserBaseTokenAccount:  userBase,
+		UserQuoteTokenAccount: userQuote,
+		UserPoolTokenAccount:  userLp,
+		PoolBaseTokenAccount:  pool.PoolBaseTokenAccount,
+		PoolQuoteTokenAccount: pool.PoolQuoteTokenAccount,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s instruction: %w", plan.Side, err)
+	}
+	closeIx, err := token.NewCloseAccountInstruction(userQuote, user, user, []solana.PublicKey{}).ValidateAndBuild()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build close account instruction: %w", err)
+	}
+	plan.Instructions = append(plan.Instructions, liquidityIx, closeIx)
+	return plan, nil
+}
+
+func newLiquidityPlan(side string, loaded *loadedPool, lpAmount, baseAmount, quoteAmount uint64) *LiquidityPlan {
+	return &LiquidityPlan{
+		Side:         side,
+		Mint:         loaded.state.BaseMint,
+		Pool:         loaded.state.Address,
+		LpMint:       loaded.account.LpMint,
+		LpSupply:     loaded.account.LpSupply,
+		LpAmount:     lpAmount,
+		BaseAmount:   baseAmount,
+		QuoteAmount:  quoteAmount,
+		BaseReserve:  loaded.state.BaseReserve,
+		QuoteReserve: loaded.state.QuoteReserve,
+	}
+}
+
+// withSlippage moves amount by bps basis points, up for positive bps and down for negative, rounded down
+func withSlippage(amount uint64, bps int64) uint64 {
+	moved := decimal.NewFromUint64(amount).Mul(decimal.NewFromInt(10000 + bps)).Div(decimal.NewFromInt(10000))
+	return uint64(moved.Floor().IntPart())
+}
@@ -0,0 +1,120 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pumpswap "solana-pumpswap-demo/idl/pumpfun/amm"
+	"solana-pumpswap-demo/idl/pumpfun/amm/idl/generated/amm"
+	"solana-pumpswap-demo/internal/decoder"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/shopspring/decimal"
+)
+
+// liquidityInstruction decodes the plan's deposit or withdraw, the one before the WSOL close
+func liquidityInstruction(t *testing.T, plan *LiquidityPlan) interface{} {
+	t.Helper()
+	ix := plan.Instructions[len(plan.Instructions)-2]
+	data, err := ix.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decoder.DecodeInstruction(ix.Accounts(), data)
+	if err != nil {
+		t.Fatalf("DecodeInstruction() error = %v", err)
+	}
+	return decoded.Impl
+}
+
+// TestPlanDeposit tests sizing a deposit from the SOL to add and the checks on the token balance
+func TestPlanDeposit(t *testing.T) {
+	signer := solana.NewWallet().PrivateKey
+	mint := solana.NewWallet().PublicKey()
+	client := curveClient(t, mint, true)
+	addPool(t, client, mint, 200_000_000_000_000, 80_000_000_000)
+	r := New(client, signer, 100)
+
+	// Without tokens to match the SOL there is nothing to deposit
+	if _, err := r.PlanDeposit(context.Background(), mint, 1_010_000_000); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("PlanDeposit() without tokens error = %v", err)
+	}
+
+	userBase, _, _ := solana.FindAssociatedTokenAddress(signer.PublicKey(), mint)
+	client.accounts[userBase] = encode(t, token.Account{Mint: mint, Owner: signer.PublicKey(), Amount: 3_000_000_000_000}, false)
+	plan, err := r.PlanDeposit(context.Background(), mint, 1_010_000_000)
+	if err != nil {
+		t.Fatalf("PlanDeposit() error = %v", err)
+	}
+	// 1 SOL after slippage is 1/80 of the quote reserve, so 1/80 of the LP supply and of the tokens
+	if plan.LpAmount != 50_000_000_000 || plan.QuoteAmount != 1_000_000_000 || plan.BaseAmount != 2_500_000_000_000 {
+		t.Errorf("plan = %+v", plan)
+	}
+	if plan.QuoteLimit != 1_010_000_000 || plan.BaseLimit != 2_525_000_000_000 {
+		t.Errorf("limits = %d tokens, %d lamports", plan.BaseLimit, plan.QuoteLimit)
+	}
+	// Compute budget, create WSOL ATA, wrap, sync, create LP ATA, deposit, close WSOL
+	if len(plan.Instructions) != 8 || !plan.Instructions[6].ProgramID().Equals(amm.ProgramID) {
+		t.Fatalf("instructions = %d", len(plan.Instructions))
+	}
+	deposit, ok := liquidityInstruction(t, plan).(*amm.Deposit)
+	if !ok || *deposit.LpTokenAmountOut != plan.LpAmount || *deposit.MaxBaseAmountIn != plan.BaseLimit || *deposit.MaxQuoteAmountIn != plan.QuoteLimit {
+		t.Fatalf("deposit = %+v", deposit)
+	}
+	userLp, _ := pumpswap.FindUserPoolTokenAccount(signer.PublicKey(), plan.LpMint)
+	if !deposit.GetUserPoolTokenAccountAccount().PublicKey.Equals(userLp) || !deposit.GetToken2022ProgramAccount().PublicKey.Equals(solana.Token2022ProgramID) {
+		t.Errorf("deposit LP accounts = %s, %s", deposit.GetUserPoolTokenAccountAccount().PublicKey, deposit.GetToken2022ProgramAccount().PublicKey)
+	}
+	if !plan.Instructions[5].Accounts()[1].PublicKey.Equals(userLp) {
+		t.Errorf("created LP account = %s, want %s", plan.Instructions[5].Accounts()[1].PublicKey, userLp)
+	}
+
+	// With fewer tokens than the slippage allows for, the limit is what the user holds
+	client.accounts[userBase] = encode(t, token.Account{Mint: mint, Owner: signer.PublicKey(), Amount: 2_510_000_000_000}, false)
+	if plan, err = r.PlanDeposit(context.Background(), mint, 1_010_000_000); err != nil || plan.BaseLimit != 2_510_000_000_000 {
+		t.Errorf("PlanDeposit() limited by balance = %+v, %v", plan, err)
+	}
+}
+
+// TestPlanWithdraw tests withdrawing the whole LP balance with slippage bounds
+func TestPlanWithdraw(t *testing.T) {
+	signer := solana.NewWallet().PrivateKey
+	mint := solana.NewWallet().PublicKey()
+	client := curveClient(t, mint, true)
+	poolAddress := addPool(t, client, mint, 200_000_000_000_000, 80_000_000_000)
+	r := New(client, signer, 100)
+
+	if _, err := r.PlanWithdraw(context.Background(), mint, 0); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("PlanWithdraw() without LP tokens error = %v", err)
+	}
+
+	pool, err := pumpswap.DecodePool(client.accounts[poolAddress])
+	if err != nil {
+		t.Fatal(err)
+	}
+	userLp, _ := pumpswap.FindUserPoolTokenAccount(signer.PublicKey(), pool.LpMint)
+	client.accounts[userLp] = encode(t, token.Account{Mint: pool.LpMint, Owner: signer.PublicKey(), Amount: 400_000_000_000}, false)
+
+	if _, err := r.PlanWithdraw(context.Background(), mint, 400_000_000_001); !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("PlanWithdraw() past the balance error = %v", err)
+	}
+	plan, err := r.PlanWithdraw(context.Background(), mint, 0)
+	if err != nil {
+		t.Fatalf("PlanWithdraw() error = %v", err)
+	}
+	// A tenth of the supply takes a tenth of each reserve
+	if plan.LpAmount != 400_000_000_000 || plan.BaseAmount != 20_000_000_000_000 || plan.QuoteAmount != 8_000_000_000 ||
+		plan.BaseLimit != 19_800_000_000_000 || plan.QuoteLimit != 7_920_000_000 || !plan.Share().Equal(decimal.RequireFromString("0.1")) {
+		t.Errorf("plan = %+v, share %s", plan, plan.Share())
+	}
+	// Compute budget, create ATA, create WSOL ATA, withdraw, close WSOL
+	if len(plan.Instructions) != 6 {
+		t.Fatalf("instructions = %d", len(plan.Instructions))
+	}
+	withdraw, ok := liquidityInstruction(t, plan).(*amm.Withdraw)
+	if !ok || *withdraw.LpTokenAmountIn != plan.LpAmount || *withdraw.MinBaseAmountOut != plan.BaseLimit || *withdraw.MinQuoteAmountOut != plan.QuoteLimit {
+		t.Errorf("withdraw = %+v", withdraw)
+	}
+}
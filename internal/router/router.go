@@ -0,0 +1,446 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	pumpswap "solana-pumpswap-demo/idl/pumpfun/amm"
+	"solana-pumpswap-demo/idl/pumpfun/amm/idl/generated/amm"
+	pumpfun "solana-pumpswap-demo/idl/pumpfun/pump"
+	"solana-pumpswap-demo/internal/decoder"
+	"solana-pumpswap-demo/internal/swapper"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	ata "github.com/gagliardetto/solana-go/programs/associated-token-account"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+)
+
+// computeUnitPrice is the priority fee in micro-lamports per compute unit, as ExecutePumpSwap pays
+const computeUnitPrice = 150000
+
+// ErrNoRoute is returned when a mint has neither an active bonding curve nor a PumpSwap pool
+var ErrNoRoute = errors.New("no bonding curve or pool to trade on")
+
+// Client reads the accounts a route depends on and sends the trade. *rpc.Client implements it.
+type Client interface {
+	GetMultipleAccountsWithOpts(ctx context.Context, accounts []solana.PublicKey, opts *rpc.GetMultipleAccountsOpts) (*rpc.GetMultipleAccountsResult, error)
+	GetLatestBlockhash(ctx context.Context, commitment rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error)
+	SendTransaction(ctx context.Context, transaction *solana.Transaction) (solana.Signature, error)
+}
+
+// Plan is a trade routed to the venue the mint currently trades on
+type Plan struct {
+	Venue        string           // decoder.VenuePumpFun or decoder.VenuePumpSwap
+	Side         string           // decoder.SideBuy or decoder.SideSell
+	Mint         solana.PublicKey // Token traded
+	Pool         solana.PublicKey // Bonding curve or PumpSwap pool
+	Quote        swapper.Quote    // Priced against the curve's virtual reserves or the pool's balances
+	Instructions []solana.Instruction
+}
+
+// Router trades a mint on its bonding curve until the curve completes and on its PumpSwap pool after
+type Router struct {
+	client      Client
+	signer      solana.PrivateKey
+	slippageBps uint32
+}
+
+// New returns a router trading for signer with the given slippage
+func New(client Client, signer solana.PrivateKey, slippageBps uint32) *Router {
+	return &Router{client: client, signer: signer, slippageBps: slippageBps}
+}
+
+// Trade routes the trade with Plan, then signs and sends it.
+// amount is lamports to spend for a buy and raw token units to sell for a sell.
+func (r *Router) Trade(ctx context.Context, mint solana.PublicKey, side string, amount uint64) (solana.Signature, *Plan, error) {
+	plan, err := r.Plan(ctx, mint, side, amount)
+	if err != nil {
+		return solana.Signature{}, nil, err
+	}
+	sig, err := r.Send(ctx, plan)
+	return sig, plan, err
+}
+
+// Send signs the plan's instructions and sends them as one transaction
+func (r *Router) Send(ctx context.Context, plan *Plan) (solana.Signature, error) {
+	return r.send(ctx, plan.Instructions)
+}
+
+func (r *Router) send(ctx context.Context, instructions []solana.Instruction) (solana.Signature, error) {
+	recent, err := r.client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to get latest blockhash: %w", err)
+	}
+	user := r.signer.PublicKey()
+	tx, err := solana.NewTransaction(instructions, recent.Value.Blockhash, solana.TransactionPayer(user))
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	if _, err := tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+		if key.Equals(user) {
+			return &r.signer
+		}
+		return nil
+	}); err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	sig, err := r.client.SendTransaction(ctx, tx)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to send transaction: %w", err)
+	}
+	return sig, nil
+}
+
+// Plan checks the mint's bonding curve and builds the trade on it while it is active,
+// otherwise on the PumpSwap pool the curve migrated into. A curve trade is priced with the fee
+// in pump.fun's Global account, read with the curve.
+func (r *Router) Plan(ctx context.Context, mint solana.PublicKey, side string, amount uint64) (*Plan, error) {
+	if side != decoder.SideBuy && side != decoder.SideSell {
+		return nil, fmt.Errorf("unknown side %q", side)
+	}
+	keys, err := pumpfun.GetBondingCurveAndAssociatedBondingCurve(mint)
+	if err != nil {
+		return nil, err
+	}
+	userATA, _, err := solana.FindAssociatedTokenAddress(r.signer.PublicKey(), mint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find token account: %w", err)
+	}
+	accounts, err := r.accounts(ctx, keys.BondingCurve, userATA, pumpfun.GlobalPumpFunAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bonding curve: %w", err)
+	}
+	if accounts[0] != nil {
+		curve, err := pumpfun.DecodeBondingCurve(accounts[0].Data.GetBinary())
+		if err != nil {
+			return nil, err
+		}
+		if !curve.Complete {
+			fee, err := curveFee(accounts[2])
+			if err != nil {
+				return nil, err
+			}
+			return r.planCurve(mint, side, amount, keys.BondingCurve, curve, fee, userATA, accounts[1] != nil)
+		}
+	}
+	return r.planPool(ctx, mint, side, amount)
+}
+
+// curveFee reads the fee from pump.fun's Global account, or the default fee when the account does not exist
+func curveFee(global *rpc.Account) (uint64, error) {
+	if global == nil {
+		return pumpfun.DefaultFeeBasisPoints, nil
+	}
+	decoded, err := pumpfun.DecodeGlobal(global.Data.GetBinary())
+	if err != nil {
+		return 0, err
+	}
+	return decoded.FeeBasisPoints, nil
+}
+
+// planCurve builds the trade with the pump.fun builders against the fetched curve and fee
+func (r *Router) planCurve(mint solana.PublicKey, side string, amount uint64, curveAddress solana.PublicKey,
+	curve *pumpfun.BondingCurveData, feeBps uint64, userATA solana.PublicKey, haveATA bool) (*Plan, error) {
+	user := r.signer.PublicKey()
+	plan, err := newPlan(decoder.VenuePumpFun, side, mint, curveAddress)
+	if err != nil {
+		return nil, err
+	}
+	base, quote := curve.VirtualTokenReserves.Uint64(), curve.VirtualSolReserves.Uint64()
+
+	var swapIx solana.Instruction
+	var minAmountOut, amountOut uint64
+	if side == decoder.SideBuy {
+		if !haveATA {
+			createIx, err := ata.NewCreateInstruction(user, user, mint).ValidateAndBuild()
+			if err != nil {
+				return nil, fmt.Errorf("failed to build create ATA instruction: %w", err)
+			}
+			plan.Instructions = append(plan.Instructions, createIx)
+		}
+		swapIx, minAmountOut, amountOut, err = pumpfun.BuyFromCurve(user, mint, amount, r.slippageBps, curve, feeBps)
+	} else {
+		swapIx, minAmountOut, amountOut, err = pumpfun.SellFromCurve(userATA, user, mint, amount, r.slippageBps, curve, feeBps)
+	}
+	if err != nil {
+		return nil, err
+	}
+	plan.Instructions = append(plan.Instructions, swapIx)
+	plan.Quote, err = curveQuote(side == decoder.SideBuy, amount, minAmountOut, amountOut, base, quote, feeBps)
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// PoolState is a PumpSwap pool's accounts and reserves, as its account or its CreatePoolEvent gives them
+type PoolState struct {
+	Address               solana.PublicKey
+	BaseMint              solana.PublicKey
+	QuoteMint             solana.PublicKey
+	PoolBaseTokenAccount  solana.PublicKey
+	PoolQuoteTokenAccount solana.PublicKey
+	BaseReserve           uint64
+	QuoteReserve          uint64
+}
+
+// PlanPool builds the trade on a pool the caller already resolved, skipping the curve and pool lookups.
+// Only the user's token accounts are fetched, to know which ones to create.
+func (r *Router) PlanPool(ctx context.Context, pool PoolState, side string, amount uint64) (*Plan, error) {
+	if side != decoder.SideBuy && side != decoder.SideSell {
+		return nil, fmt.Errorf("unknown side %q", side)
+	}
+	userBase, userQuote, err := r.userTokenAccounts(pool)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := r.accounts(ctx, userBase, userQuote)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token accounts: %w", err)
+	}
+	return r.buildPool(pool, side, amount, accounts[0] != nil, accounts[1] != nil)
+}
+
+// planPool resolves the mint's canonical PumpSwap pool and its reserves, then builds the trade on it
+func (r *Router) planPool(ctx context.Context, mint solana.PublicKey, side string, amount uint64) (*Plan, error) {
+	loaded, err := r.loadPool(ctx, mint)
+	if err != nil {
+		return nil, err
+	}
+	return r.buildPool(loaded.state, side, amount, loaded.userBase != nil, loaded.userQuote != nil)
+}
+
+// loadedPool is a pool with its reserves and the user's token accounts for it, nil where they do not exist
+type loadedPool struct {
+	state                       PoolState
+	account                     *amm.PoolAccount
+	userBase, userQuote, userLp *rpc.Account
+}
+
+// loadPool fetches the mint's canonical PumpSwap pool, then its reserves and the user's accounts in one call
+func (r *Router) loadPool(ctx context.Context, mint solana.PublicKey) (*loadedPool, error) {
+	poolAddress, err := pumpswap.FindCanonicalPool(mint)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := r.accounts(ctx, poolAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool: %w", err)
+	}
+	if accounts[0] == nil {
+		return nil, fmt.Errorf("%w for %s", ErrNoRoute, mint)
+	}
+	pool, err := pumpswap.DecodePool(accounts[0].Data.GetBinary())
+	if err != nil {
+		return nil, err
+	}
+	state := PoolState{
+		Address:               poolAddress,
+		BaseMint:              pool.BaseMint,
+		QuoteMint:             pool.QuoteMint,
+		PoolBaseTokenAccount:  pool.PoolBaseTokenAccount,
+		PoolQuoteTokenAccount: pool.PoolQuoteTokenAccount,
+	}
+
+	userBase, userQuote, err := r.userTokenAccounts(state)
+	if err != nil {
+		return nil, err
+	}
+	userLp, err := pumpswap.FindUserPoolTokenAccount(r.signer.PublicKey(), pool.LpMint)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err = r.accounts(ctx, pool.PoolBaseTokenAccount, pool.PoolQuoteTokenAccount, userBase, userQuote, userLp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool reserves: %w", err)
+	}
+	reserves := []*uint64{&state.BaseReserve, &state.QuoteReserve}
+	for i, reserve := range reserves {
+		if accounts[i] == nil {
+			return nil, fmt.Errorf("pool token account %d of %s not found", i, poolAddress)
+		}
+		if *reserve, err = tokenBalance(accounts[i]); err != nil {
+			return nil, fmt.Errorf("failed to decode pool reserve: %w", err)
+		}
+	}
+	return &loadedPool{state: state, account: pool, userBase: accounts[2], userQuote: accounts[3], userLp: accounts[4]}, nil
+}
+
+// tokenBalance decodes a token account's balance
+func tokenBalance(account *rpc.Account) (uint64, error) {
+	var balance token.Account
+	if err := bin.NewBinDecoder(account.Data.GetBinary()).Decode(&balance); err != nil {
+		return 0, err
+	}
+	return balance.Amount, nil
+}
+
+// userTokenAccounts returns the user's token and WSOL accounts for the pool
+func (r *Router) userTokenAccounts(pool PoolState) (userBase, userQuote solana.PublicKey, err error) {
+	user := r.signer.PublicKey()
+	userBase, _, err = solana.FindAssociatedTokenAddress(user, pool.BaseMint)
+	if err != nil {
+		return userBase, userQuote, fmt.Errorf("failed to find token account: %w", err)
+	}
+	userQuote, _, err = solana.FindAssociatedTokenAddress(user, pool.QuoteMint)
+	if err != nil {
+		return userBase, userQuote, fmt.Errorf("failed to find WSOL account: %w", err)
+	}
+	return userBase, userQuote, nil
+}
+
+// buildPool builds the swap with amm.NewSwapInstruction, wrapping SOL in and out the way ExecutePumpSwap does.
+// haveBase and haveQuote tell whether the user's token and WSOL accounts exist.
+func (r *Router) buildPool(pool PoolState, side string, amount uint64, haveBase, haveQuote bool) (*Plan, error) {
+	user := r.signer.PublicKey()
+	userBase, userQuote, err := r.userTokenAccounts(pool)
+	if err != nil {
+		return nil, err
+	}
+
+	isBuy := side == decoder.SideBuy
+	quote, err := swapper.QuoteSwap(r.slippageBps, amount, isBuy, pool.BaseReserve, pool.QuoteReserve, swapper.PumpSwapFeeRate)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := newPlan(decoder.VenuePumpSwap, side, pool.BaseMint, pool.Address)
+	if err != nil {
+		return nil, err
+	}
+	plan.Quote = quote
+
+	if isBuy && !haveBase {
+		createIx, err := ata.NewCreateInstruction(user, user, pool.BaseMint).ValidateAndBuild()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build create ATA instruction: %w", err)
+		}
+		plan.Instructions = append(plan.Instructions, createIx)
+	}
+	if !haveQuote {
+		createIx, err := ata.NewCreateInstruction(user, user, pool.QuoteMint).ValidateAndBuild()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build create WSOL ATA instruction: %w", err)
+		}
+		plan.Instructions = append(plan.Instructions, createIx)
+	}
+	if isBuy {
+		transferIx, err := system.NewTransferInstruction(amount, user, userQuote).ValidateAndBuild()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build SOL transfer instruction: %w", err)
+		}
+		syncIx, err := token.NewSyncNativeInstruction(userQuote).ValidateAndBuild()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build sync native instruction: %w", err)
+		}
+		plan.Instructions = append(plan.Instructions, transferIx, syncIx)
+	}
+
+	feeRecipient := pumpswap.ProtocolFeeRecipients[0]
+	feeRecipientATA, _, err := solana.FindAssociatedTokenAddress(feeRecipient, pool.QuoteMint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find fee recipient token account: %w", err)
+	}
+	// Buy takes (BaseAmountOut, MaxQuoteAmountIn), sell takes (BaseAmountIn, MinQuoteAmountOut)
+	direction, tokenAmount1, tokenAmount2 := pumpswap.BuyDirection, quote.MinAmountOut, amount
+	if !isBuy {
+		direction, tokenAmount1, tokenAmount2 = pumpswap.SellDirection, amount, quote.MinAmountOut
+	}
+	swapIx, err := pumpswap.NewSwapInstruction(&pumpswap.SwapParam{
+		Direction:                        direction,
+		TokenAmount1:                     tokenAmount1,
+		TokenAmount2:                     tokenAmount2,
+		Pool:                             pool.Address,
+		User:                             user,
+		BaseMint:                         pool.BaseMint,
+		QuoteMint:                        pool.QuoteMint,
+		UserBaseTokenAccount:             userBase,
+		UserQuoteTokenAccount:            userQuote,
+		PoolBaseTokenAccount:             pool.PoolBaseTokenAccount,
+		PoolQuoteTokenAccount:            pool.PoolQuoteTokenAccount,
+		ProtocolFeeRecipient:             feeRecipient,
+		ProtocolFeeRecipientTokenAccount: feeRecipientATA,
+		BaseTokenProgram:                 solana.TokenProgramID,
+		QuoteTokenProgram:                solana.TokenProgramID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create swap instruction: %w", err)
+	}
+
+	// Unwrap what is left of the WSOL, the unspent input of a buy or the output of a sell
+	closeIx, err := token.NewCloseAccountInstruction(userQuote, user, user, []solana.PublicKey{}).ValidateAndBuild()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build close account instruction: %w", err)
+	}
+	plan.Instructions = append(plan.Instructions, swapIx, closeIx)
+	return plan, nil
+}
+
+// accounts fetches the accounts in one call, leaving nil for any that do not exist
+func (r *Router) accounts(ctx context.Context, keys ...solana.PublicKey) ([]*rpc.Account, error) {
+	res, err := r.client.GetMultipleAccountsWithOpts(ctx, keys, &rpc.GetMultipleAccountsOpts{
+		Encoding:   solana.EncodingBase64,
+		Commitment: rpc.CommitmentProcessed,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Value) != len(keys) {
+		return nil, fmt.Errorf("got %d accounts, want %d", len(res.Value), len(keys))
+	}
+	return res.Value, nil
+}
+
+// newPlan starts a plan with the compute budget instructions every trade carries
+func newPlan(venue, side string, mint, pool solana.PublicKey) (*Plan, error) {
+	instructions, err := computeBudget()
+	if err != nil {
+		return nil, err
+	}
+	return &Plan{Venue: venue, Side: side, Mint: mint, Pool: pool, Instructions: instructions}, nil
+}
+
+// computeBudget returns the priority fee and compute unit limit instructions
+func computeBudget() ([]solana.Instruction, error) {
+	priceIx, err := computebudget.NewSetComputeUnitPriceInstruction(computeUnitPrice).ValidateAndBuild()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build compute unit price instruction: %w", err)
+	}
+	limitIx, err := computebudget.NewSetComputeUnitLimitInstruction(swapper.PumpFunSwapCU).ValidateAndBuild()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build compute unit limit instruction: %w", err)
+	}
+	return []solana.Instruction{priceIx, limitIx}, nil
+}
+
+// curveQuote describes a bonding curve trade the pump.fun helpers priced as a swapper.Quote, so risk
+// checks read both venues the same way. base and quote are the curve's virtual reserves, feeBps the program's fee.
+func curveQuote(isBuy bool, amountIn, minAmountOut, amountOut, base, quote, feeBps uint64) (swapper.Quote, error) {
+	q := swapper.Quote{IsBuy: isBuy, BaseReserve: base, QuoteReserve: quote, AmountIn: amountIn, AmountOut: amountOut, MinAmountOut: minAmountOut}
+	if base == 0 || quote == 0 || amountOut == 0 {
+		return q, fmt.Errorf("calculated amount out is zero or negative")
+	}
+	in, out := decimal.NewFromUint64(amountIn), decimal.NewFromUint64(amountOut)
+	baseDec, quoteDec := decimal.NewFromUint64(base), decimal.NewFromUint64(quote)
+	q.SpotPriceBefore = quoteDec.Div(baseDec)
+	if isBuy {
+		// Only the SOL after the fee reaches the curve
+		q.Fee = amountIn - uint64(in.Mul(decimal.NewFromInt(10000)).Div(decimal.NewFromUint64(10000+feeBps)).Floor().IntPart())
+		q.SpotPriceAfter = quoteDec.Add(in.Sub(decimal.NewFromUint64(q.Fee))).Div(baseDec.Sub(out))
+		q.EffectivePrice = in.Div(out)
+	} else {
+		// The fee comes out of what the curve pays, amountOut is already net of it
+		gross := out.Mul(decimal.NewFromInt(10000)).Div(decimal.NewFromUint64(10000 - feeBps))
+		q.Fee = uint64(gross.Sub(out).Ceil().IntPart())
+		q.SpotPriceAfter = quoteDec.Sub(gross).Div(baseDec.Add(in))
+		q.EffectivePrice = out.Div(in)
+	}
+	impact := q.SpotPriceAfter.Sub(q.SpotPriceBefore).Abs().Div(q.SpotPriceBefore).Mul(decimal.NewFromInt(10000))
+	q.PriceImpactBps = uint64(impact.Round(0).IntPart())
+	return q, nil
+}
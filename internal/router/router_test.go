@@ -0,0 +1,219 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	pumpswap "solana-pumpswap-demo/idl/pumpfun/amm"
+	"solana-pumpswap-demo/idl/pumpfun/amm/idl/generated/amm"
+	pumpfun "solana-pumpswap-demo/idl/pumpfun/pump"
+	"solana-pumpswap-demo/idl/pumpfun/pump/idl/generated/pump"
+	"solana-pumpswap-demo/internal/decoder"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// fakeClient serves accounts from a map and records what is sent
+type fakeClient struct {
+	accounts map[solana.PublicKey][]byte
+	sent     []*solana.Transaction
+}
+
+func (f *fakeClient) GetMultipleAccountsWithOpts(_ context.Context, accounts []solana.PublicKey, _ *rpc.GetMultipleAccountsOpts) (*rpc.GetMultipleAccountsResult, error) {
+	res := &rpc.GetMultipleAccountsResult{Value: make([]*rpc.Account, len(accounts))}
+	for i, key := range accounts {
+		if data, ok := f.accounts[key]; ok {
+			res.Value[i] = &rpc.Account{Data: rpc.DataBytesOrJSONFromBytes(data)}
+		}
+	}
+	return res, nil
+}
+
+func (f *fakeClient) GetLatestBlockhash(context.Context, rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+	return &rpc.GetLatestBlockhashResult{Value: &rpc.LatestBlockhashResult{Blockhash: solana.Hash{1}}}, nil
+}
+
+func (f *fakeClient) SendTransaction(_ context.Context, tx *solana.Transaction) (solana.Signature, error) {
+	f.sent = append(f.sent, tx)
+	return tx.Signatures[0], nil
+}
+
+// encode serializes an account the way the program stores it
+func encode(t *testing.T, account interface {
+	MarshalWithEncoder(*bin.Encoder) error
+}, borsh bool) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	enc := bin.NewBinEncoder(buf)
+	if borsh {
+		enc = bin.NewBorshEncoder(buf)
+	}
+	if err := account.MarshalWithEncoder(enc); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// curveClient returns a client holding the mint's bonding curve
+func curveClient(t *testing.T, mint solana.PublicKey, complete bool) *fakeClient {
+	keys, err := pumpfun.GetBondingCurveAndAssociatedBondingCurve(mint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	curve := pump.BondingCurve{
+		VirtualTokenReserves: 1_073_000_000_000_000,
+		VirtualSolReserves:   30_000_000_000,
+		RealTokenReserves:    793_100_000_000_000,
+		TokenTotalSupply:     1_000_000_000_000_000,
+		Complete:             complete,
+	}
+	return &fakeClient{accounts: map[solana.PublicKey][]byte{keys.BondingCurve: encode(t, curve, true)}}
+}
+
+// addPool adds the mint's canonical pool with the given reserves to the client
+func addPool(t *testing.T, client *fakeClient, mint solana.PublicKey, baseReserve, quoteReserve uint64) solana.PublicKey {
+	poolAddress, err := pumpswap.FindCanonicalPool(mint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := amm.PoolAccount{
+		BaseMint:              mint,
+		QuoteMint:             solana.WrappedSol,
+		LpMint:                solana.NewWallet().PublicKey(),
+		PoolBaseTokenAccount:  solana.NewWallet().PublicKey(),
+		PoolQuoteTokenAccount: solana.NewWallet().PublicKey(),
+		LpSupply:              4_000_000_000_000,
+	}
+	client.accounts[poolAddress] = encode(t, pool, true)
+	client.accounts[pool.PoolBaseTokenAccount] = encode(t, token.Account{Mint: mint, Owner: poolAddress, Amount: baseReserve}, false)
+	client.accounts[pool.PoolQuoteTokenAccount] = encode(t, token.Account{Mint: solana.WrappedSol, Owner: poolAddress, Amount: quoteReserve}, false)
+	return poolAddress
+}
+
+// TestPlanOnCurve tests that a buy of a token still on its curve goes to pump.fun
+func TestPlanOnCurve(t *testing.T) {
+	signer := solana.NewWallet().PrivateKey
+	mint := solana.NewWallet().PublicKey()
+	client := curveClient(t, mint, false)
+
+	plan, err := New(client, signer, 100).Plan(context.Background(), mint, decoder.SideBuy, 1_010_000_000)
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	keys, _ := pumpfun.GetBondingCurveAndAssociatedBondingCurve(mint)
+	if plan.Venue != decoder.VenuePumpFun || !plan.Pool.Equals(keys.BondingCurve) {
+		t.Fatalf("plan = %s on %s, want pumpfun on %s", plan.Venue, plan.Pool, keys.BondingCurve)
+	}
+	// Compute budget, create ATA, buy
+	if len(plan.Instructions) != 4 || !plan.Instructions[2].ProgramID().Equals(solana.SPLAssociatedTokenAccountProgramID) {
+		t.Fatalf("instructions = %d", len(plan.Instructions))
+	}
+
+	buyIx := plan.Instructions[3]
+	data, err := buyIx.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := pump.DecodeInstruction(buyIx.Accounts(), data)
+	if err != nil {
+		t.Fatalf("DecodeInstruction() error = %v", err)
+	}
+	buy, ok := decoded.Impl.(*pump.Buy)
+	if !ok || *buy.MaxSolCost != 1_010_000_000 || *buy.Amount != plan.Quote.MinAmountOut {
+		t.Errorf("buy = %+v, min out %d", decoded.Impl, plan.Quote.MinAmountOut)
+	}
+	// 1 SOL reaches the curve after the 1% fee
+	if plan.Quote.AmountOut != 34_612_903_225_807 || plan.Quote.Fee != 10_000_000 || plan.Quote.PriceImpactBps == 0 {
+		t.Errorf("quote = %+v", plan.Quote)
+	}
+
+	// The fee in the Global account replaces the default
+	client.accounts[pumpfun.GlobalPumpFunAddress] = encode(t, pump.Global{Initialized: true, FeeBasisPoints: 200}, true)
+	plan, err = New(client, signer, 100).Plan(context.Background(), mint, decoder.SideBuy, 1_010_000_000)
+	if err != nil {
+		t.Fatalf("Plan() with the Global account error = %v", err)
+	}
+	if plan.Quote.Fee != 1_010_000_000-1_010_000_000*10000/10200 || plan.Quote.AmountOut >= 34_612_903_225_807 {
+		t.Errorf("quote with a 2%% fee = %+v", plan.Quote)
+	}
+}
+
+// TestPlanOnPool tests that a sell of a token whose curve completed goes through its PumpSwap pool
+func TestPlanOnPool(t *testing.T) {
+	signer := solana.NewWallet().PrivateKey
+	mint := solana.NewWallet().PublicKey()
+	client := curveClient(t, mint, true)
+	poolAddress := addPool(t, client, mint, 200_000_000_000_000, 85_000_000_000)
+
+	plan, err := New(client, signer, 100).Plan(context.Background(), mint, decoder.SideSell, 1_000_000_000)
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if plan.Venue != decoder.VenuePumpSwap || !plan.Pool.Equals(poolAddress) {
+		t.Fatalf("plan = %s on %s, want pumpswap on %s", plan.Venue, plan.Pool, poolAddress)
+	}
+	if plan.Quote.BaseReserve != 200_000_000_000_000 || plan.Quote.QuoteReserve != 85_000_000_000 || plan.Quote.MinAmountOut == 0 {
+		t.Errorf("quote = %+v", plan.Quote)
+	}
+	// Compute budget, create WSOL ATA, sell, close WSOL
+	if len(plan.Instructions) != 5 {
+		t.Fatalf("instructions = %d", len(plan.Instructions))
+	}
+
+	sellIx := plan.Instructions[3]
+	data, err := sellIx.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decoder.DecodeInstruction(sellIx.Accounts(), data)
+	if err != nil {
+		t.Fatalf("DecodeInstruction() error = %v", err)
+	}
+	sell, ok := decoded.Impl.(*amm.Sell)
+	if !ok || *sell.BaseAmountIn != 1_000_000_000 || *sell.MinQuoteAmountOut != plan.Quote.MinAmountOut {
+		t.Errorf("sell = %+v", decoded.Impl)
+	}
+	if !decoded.Pool().Equals(poolAddress) || !decoded.Account("user").Equals(signer.PublicKey()) {
+		t.Errorf("sell accounts = %v", sellIx.Accounts())
+	}
+	if !plan.Instructions[4].ProgramID().Equals(solana.TokenProgramID) {
+		t.Errorf("last instruction program = %s, want the token program closing WSOL", plan.Instructions[4].ProgramID())
+	}
+}
+
+// TestPlanNoRoute tests a mint with neither a curve nor a pool
+func TestPlanNoRoute(t *testing.T) {
+	client := &fakeClient{accounts: map[solana.PublicKey][]byte{}}
+	_, err := New(client, solana.NewWallet().PrivateKey, 100).Plan(context.Background(), solana.NewWallet().PublicKey(), decoder.SideBuy, 1_000_000)
+	if !errors.Is(err, ErrNoRoute) {
+		t.Errorf("Plan() error = %v, want ErrNoRoute", err)
+	}
+}
+
+// TestTrade tests that a routed trade is signed by the router's signer and sent
+func TestTrade(t *testing.T) {
+	signer := solana.NewWallet().PrivateKey
+	mint := solana.NewWallet().PublicKey()
+	client := curveClient(t, mint, true)
+	addPool(t, client, mint, 200_000_000_000_000, 85_000_000_000)
+
+	sig, plan, err := New(client, signer, 100).Trade(context.Background(), mint, decoder.SideBuy, 100_000_000)
+	if err != nil {
+		t.Fatalf("Trade() error = %v", err)
+	}
+	if plan.Venue != decoder.VenuePumpSwap || len(client.sent) != 1 {
+		t.Fatalf("Trade() sent %d transactions on %s", len(client.sent), plan.Venue)
+	}
+	tx := client.sent[0]
+	if sig != tx.Signatures[0] || !tx.Message.AccountKeys[0].Equals(signer.PublicKey()) {
+		t.Errorf("signature %s, payer %s", sig, tx.Message.AccountKeys[0])
+	}
+	if err := tx.VerifySignatures(); err != nil {
+		t.Errorf("VerifySignatures() error = %v", err)
+	}
+}
@@ -0,0 +1,21 @@
+package rpctest
+
+import (
+	"bytes"
+	"testing"
+
+	bin "github.com/gagliardetto/binary"
+)
+
+// Borsh serializes a generated account, event or instruction with its discriminator, as served in
+// account data and written to logs, and fails the test if it cannot
+func Borsh(t testing.TB, v interface {
+	MarshalWithEncoder(*bin.Encoder) error
+}) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	if err := v.MarshalWithEncoder(bin.NewBorshEncoder(buf)); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
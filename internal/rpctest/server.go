@@ -0,0 +1,372 @@
+// Package rpctest serves scripted Solana JSON-RPC and WebSocket responses over real HTTP, so code
+// that takes *rpc.Client or dials ws.Connect can be tested unchanged against Server.URL and Server.WSURL.
+package rpctest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gorilla/websocket"
+)
+
+// JSON-RPC error codes the server answers with
+const (
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternal       = -32603
+)
+
+// Error is a JSON-RPC error. Handlers return one to choose the code, any other error is CodeInternal.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// HandlerFunc answers a JSON-RPC method with its result, given the request's params array
+type HandlerFunc func(params json.RawMessage) (interface{}, error)
+
+// Server is an in-process Solana RPC node serving getAccountInfo, getMultipleAccounts, getTransaction,
+// sendTransaction and getLatestBlockhash from what the test scripted, and logsSubscribe notifications
+// from PublishLogs. Any method can be added or overridden with Handle.
+type Server struct {
+	URL   string // HTTP JSON-RPC endpoint
+	WSURL string // WebSocket endpoint
+
+	srv      *httptest.Server
+	upgrader websocket.Upgrader
+
+	mu           sync.Mutex
+	slot         uint64
+	blockhash    solana.Hash
+	accounts     map[solana.PublicKey]*rpc.Account
+	transactions map[solana.Signature]json.RawMessage
+	sent         []*solana.Transaction
+	onSend       func(*solana.Transaction) error
+	handlers     map[string]HandlerFunc
+	calls        map[string]int
+	conns        map[*wsConn]bool
+	subs         map[uint64]*subscription
+	nextSub      uint64
+}
+
+// NewServer starts a server at slot 1 with a random blockhash. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		slot:         1,
+		blockhash:    solana.Hash(solana.NewWallet().PublicKey()),
+		accounts:     map[solana.PublicKey]*rpc.Account{},
+		transactions: map[solana.Signature]json.RawMessage{},
+		handlers:     map[string]HandlerFunc{},
+		calls:        map[string]int{},
+		conns:        map[*wsConn]bool{},
+		subs:         map[uint64]*subscription{},
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.srv.URL
+	s.WSURL = "ws" + strings.TrimPrefix(s.srv.URL, "http")
+	return s
+}
+
+// Close drops every WebSocket connection and stops the server
+func (s *Server) Close() {
+	s.mu.Lock()
+	conns := s.conns
+	s.conns = map[*wsConn]bool{}
+	s.subs = map[uint64]*subscription{}
+	s.mu.Unlock()
+	for c := range conns {
+		c.ws.Close()
+	}
+	s.srv.Close()
+}
+
+// SetSlot sets the slot reported in response contexts and log notifications
+func (s *Server) SetSlot(slot uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.slot = slot
+}
+
+// SetBlockhash sets the blockhash getLatestBlockhash returns
+func (s *Server) SetBlockhash(hash solana.Hash) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blockhash = hash
+}
+
+// SetAccount serves the account from getAccountInfo and getMultipleAccounts, or removes it if nil
+func (s *Server) SetAccount(key solana.PublicKey, account *rpc.Account) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if account == nil {
+		delete(s.accounts, key)
+		return
+	}
+	s.accounts[key] = account
+}
+
+// SetAccountData serves a rent-exempt account owned by owner holding data
+func (s *Server) SetAccountData(key, owner solana.PublicKey, data []byte) {
+	s.SetAccount(key, &rpc.Account{
+		Lamports: rentExempt(len(data)),
+		Owner:    owner,
+		Data:     rpc.DataBytesOrJSONFromBytes(data),
+	})
+}
+
+// rentExempt is the minimum balance of an account with size bytes of data
+func rentExempt(size int) uint64 {
+	return uint64(128+size) * 3480 * 2
+}
+
+// AddTransaction serves the transaction from getTransaction
+func (s *Server) AddTransaction(sig solana.Signature, result *rpc.GetTransactionResult) error {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode transaction %s: %w", sig, err)
+	}
+	s.AddTransactionJSON(sig, raw)
+	return nil
+}
+
+// AddTransactionJSON serves the raw getTransaction result, as recorded from a real node
+func (s *Server) AddTransactionJSON(sig solana.Signature, result json.RawMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transactions[sig] = result
+}
+
+// NewTransactionResult builds the getTransaction result of tx landing in slot with meta, base64 encoded
+func NewTransactionResult(slot uint64, tx *solana.Transaction, meta *rpc.TransactionMeta) (*rpc.GetTransactionResult, error) {
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transaction: %w", err)
+	}
+	raw, err := json.Marshal(map[string]interface{}{
+		"slot":        slot,
+		"transaction": []string{base64.StdEncoding.EncodeToString(data), "base64"},
+		"meta":        meta,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := new(rpc.GetTransactionResult)
+	if err := json.Unmarshal(raw, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// OnSend makes sendTransaction call fn with each transaction and fail with its error, if any
+func (s *Server) OnSend(fn func(*solana.Transaction) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onSend = fn
+}
+
+// Sent returns the transactions sendTransaction accepted, in order
+func (s *Server) Sent() []*solana.Transaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*solana.Transaction(nil), s.sent...)
+}
+
+// Handle answers method with h, replacing the built-in handler if there is one
+func (s *Server) Handle(method string, h HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[method] = h
+}
+
+// Calls returns how many times method was called, over HTTP or WebSocket
+func (s *Server) Calls(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		s.serveWS(w, r)
+		return
+	}
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON-RPC request", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.call(req))
+}
+
+// call answers a request with the method's handler
+func (s *Server) call(req request) response {
+	s.mu.Lock()
+	s.calls[req.Method]++
+	h, ok := s.handlers[req.Method]
+	s.mu.Unlock()
+	if !ok {
+		h, ok = s.builtin(req.Method)
+	}
+	resp := response{JSONRPC: "2.0", ID: req.ID}
+	if !ok {
+		resp.Error = &Error{Code: CodeMethodNotFound, Message: "Method not found: " + req.Method}
+		return resp
+	}
+	result, err := h(req.Params)
+	if err != nil {
+		rpcErr, ok := err.(*Error)
+		if !ok {
+			rpcErr = &Error{Code: CodeInternal, Message: err.Error()}
+		}
+		resp.Error = rpcErr
+		return resp
+	}
+	// A nil result is JSON null, which omitempty would drop
+	if result == nil {
+		result = json.RawMessage("null")
+	}
+	resp.Result = result
+	return resp
+}
+
+func (s *Server) builtin(method string) (HandlerFunc, bool) {
+	switch method {
+	case "getAccountInfo":
+		return s.getAccountInfo, true
+	case "getMultipleAccounts":
+		return s.getMultipleAccounts, true
+	case "getTransaction":
+		return s.getTransaction, true
+	case "sendTransaction":
+		return s.sendTransaction, true
+	case "getLatestBlockhash":
+		return s.getLatestBlockhash, true
+	}
+	return nil, false
+}
+
+// contextResult wraps a value in the {context, value} envelope of the account and blockhash methods
+func (s *Server) contextResult(value interface{}) interface{} {
+	return map[string]interface{}{
+		"context": map[string]uint64{"slot": s.slot},
+		"value":   value,
+	}
+}
+
+// decodeParams unmarshals the leading params into out, ignoring the config object that may follow
+func decodeParams(params json.RawMessage, out ...interface{}) error {
+	var list []json.RawMessage
+	if err := json.Unmarshal(params, &list); err != nil || len(list) < len(out) {
+		return &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("want %d params", len(out))}
+	}
+	for i, o := range out {
+		if err := json.Unmarshal(list[i], o); err != nil {
+			return &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("param %d: %v", i, err)}
+		}
+	}
+	return nil
+}
+
+func (s *Server) getAccountInfo(params json.RawMessage) (interface{}, error) {
+	var key solana.PublicKey
+	if err := decodeParams(params, &key); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.contextResult(s.accounts[key]), nil
+}
+
+func (s *Server) getMultipleAccounts(params json.RawMessage) (interface{}, error) {
+	var keys []solana.PublicKey
+	if err := decodeParams(params, &keys); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	accounts := make([]*rpc.Account, len(keys))
+	for i, key := range keys {
+		accounts[i] = s.accounts[key]
+	}
+	return s.contextResult(accounts), nil
+}
+
+func (s *Server) getTransaction(params json.RawMessage) (interface{}, error) {
+	var sig solana.Signature
+	if err := decodeParams(params, &sig); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if raw, ok := s.transactions[sig]; ok {
+		return raw, nil
+	}
+	return nil, nil
+}
+
+func (s *Server) sendTransaction(params json.RawMessage) (interface{}, error) {
+	var encoded string
+	if err := decodeParams(params, &encoded); err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, &Error{Code: CodeInvalidParams, Message: "transaction is not base64: " + err.Error()}
+	}
+	tx, err := solana.TransactionFromDecoder(bin.NewBinDecoder(data))
+	if err != nil {
+		return nil, &Error{Code: CodeInvalidParams, Message: "failed to decode transaction: " + err.Error()}
+	}
+	if len(tx.Signatures) == 0 {
+		return nil, &Error{Code: CodeInvalidParams, Message: "transaction is not signed"}
+	}
+
+	s.mu.Lock()
+	onSend := s.onSend
+	s.mu.Unlock()
+	if onSend != nil {
+		if err := onSend(tx); err != nil {
+			return nil, err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, tx)
+	return tx.Signatures[0], nil
+}
+
+func (s *Server) getLatestBlockhash(json.RawMessage) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.contextResult(map[string]interface{}{
+		"blockhash":            s.blockhash,
+		"lastValidBlockHeight": s.slot + 150,
+	}), nil
+}
@@ -0,0 +1,189 @@
+package rpctest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+	"github.com/gorilla/websocket"
+)
+
+func newTransfer(t *testing.T, from solana.PrivateKey, blockhash solana.Hash) *solana.Transaction {
+	t.Helper()
+	ix := system.NewTransferInstruction(1000, from.PublicKey(), solana.NewWallet().PublicKey()).Build()
+	tx, err := solana.NewTransaction([]solana.Instruction{ix}, blockhash, solana.TransactionPayer(from.PublicKey()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Sign(func(solana.PublicKey) *solana.PrivateKey { return &from }); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+// TestAccounts tests serving scripted accounts to the unmodified rpc.Client
+func TestAccounts(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	client := rpc.New(s.URL)
+	ctx := context.Background()
+
+	key, missing := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	s.SetAccountData(key, solana.TokenProgramID, []byte{1, 2, 3})
+	s.SetSlot(42)
+
+	res, err := client.GetMultipleAccountsWithOpts(ctx, []solana.PublicKey{key, missing}, &rpc.GetMultipleAccountsOpts{Encoding: solana.EncodingBase64})
+	if err != nil {
+		t.Fatalf("GetMultipleAccounts() error = %v", err)
+	}
+	if res.Context.Slot != 42 || len(res.Value) != 2 || res.Value[1] != nil ||
+		!bytes.Equal(res.Value[0].Data.GetBinary(), []byte{1, 2, 3}) || !res.Value[0].Owner.Equals(solana.TokenProgramID) {
+		t.Errorf("GetMultipleAccounts() = %+v", res)
+	}
+
+	info, err := client.GetAccountInfo(ctx, key)
+	if err != nil || !bytes.Equal(info.Value.Data.GetBinary(), []byte{1, 2, 3}) {
+		t.Errorf("GetAccountInfo() = %+v, %v", info, err)
+	}
+	if _, err := client.GetAccountInfo(ctx, missing); !errors.Is(err, rpc.ErrNotFound) {
+		t.Errorf("GetAccountInfo(missing) error = %v, want ErrNotFound", err)
+	}
+	if s.Calls("getAccountInfo") != 2 || s.Calls("getMultipleAccounts") != 1 {
+		t.Errorf("calls = %d getAccountInfo, %d getMultipleAccounts", s.Calls("getAccountInfo"), s.Calls("getMultipleAccounts"))
+	}
+}
+
+// TestSendAndGetTransaction tests sending a signed transaction, scripted send failures and getTransaction
+func TestSendAndGetTransaction(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	client := rpc.New(s.URL)
+	ctx := context.Background()
+	payer := solana.NewWallet().PrivateKey
+
+	latest, err := client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		t.Fatalf("GetLatestBlockhash() error = %v", err)
+	}
+	tx := newTransfer(t, payer, latest.Value.Blockhash)
+	sig, err := client.SendTransaction(ctx, tx)
+	if err != nil {
+		t.Fatalf("SendTransaction() error = %v", err)
+	}
+	if sent := s.Sent(); sig != tx.Signatures[0] || len(sent) != 1 || sent[0].Message.RecentBlockhash != latest.Value.Blockhash {
+		t.Errorf("sent %v as %s", sent, sig)
+	}
+
+	s.OnSend(func(*solana.Transaction) error {
+		return &Error{Code: -32002, Message: "Transaction simulation failed"}
+	})
+	if _, err := client.SendTransaction(ctx, newTransfer(t, payer, latest.Value.Blockhash)); err == nil {
+		t.Error("SendTransaction() succeeded past the scripted failure")
+	}
+	if len(s.Sent()) != 1 {
+		t.Errorf("recorded the failed send")
+	}
+
+	if _, err := client.GetTransaction(ctx, sig, nil); !errors.Is(err, rpc.ErrNotFound) {
+		t.Errorf("GetTransaction() before it landed error = %v, want ErrNotFound", err)
+	}
+	result, err := NewTransactionResult(7, tx, &rpc.TransactionMeta{Fee: 5000, LogMessages: []string{"Program log: hi"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddTransaction(sig, result); err != nil {
+		t.Fatal(err)
+	}
+	got, err := client.GetTransaction(ctx, sig, &rpc.GetTransactionOpts{Encoding: solana.EncodingBase64})
+	if err != nil {
+		t.Fatalf("GetTransaction() error = %v", err)
+	}
+	decoded, err := got.Transaction.GetTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Slot != 7 || got.Meta.Fee != 5000 || decoded.Signatures[0] != sig {
+		t.Errorf("GetTransaction() = %+v", got)
+	}
+}
+
+// TestLogsSubscribe tests that published logs reach the ws client's matching subscriptions only
+func TestLogsSubscribe(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := ws.Connect(ctx, s.WSURL)
+	if err != nil {
+		t.Fatalf("ws.Connect() error = %v", err)
+	}
+	defer client.Close()
+	program := solana.NewWallet().PublicKey()
+	sub, err := client.LogsSubscribeMentions(program, rpc.CommitmentConfirmed)
+	if err != nil {
+		t.Fatalf("LogsSubscribeMentions() error = %v", err)
+	}
+	defer sub.Unsubscribe()
+	if err := s.WaitSubscriptions(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := s.PublishLogs(Logs{Signature: solana.Signature{1}, Logs: []string{"other"}, Mentions: []solana.PublicKey{solana.SystemProgramID}}); n != 0 {
+		t.Errorf("PublishLogs() of an unrelated transaction notified %d", n)
+	}
+	s.SetSlot(99)
+	want := solana.Signature{2}
+	if n := s.PublishLogs(Logs{Signature: want, Logs: []string{"Program log: Instruction: Buy"}, Mentions: []solana.PublicKey{program}}); n != 1 {
+		t.Fatalf("PublishLogs() notified %d", n)
+	}
+	got, err := sub.Recv(ctx)
+	if err != nil {
+		t.Fatalf("Recv() error = %v", err)
+	}
+	if got.Context.Slot != 99 || got.Value.Signature != want || len(got.Value.Logs) != 1 || got.Value.Err != nil {
+		t.Errorf("Recv() = %+v", got)
+	}
+}
+
+// TestSubscribeReplyFirst tests that logs published as soon as a subscription counts reach the client after its reply
+func TestSubscribeReplyFirst(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for i := 0; i < 20; i++ {
+		conn, _, err := websocket.DefaultDialer.DialContext(ctx, s.WSURL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		subscribe := map[string]interface{}{"jsonrpc": "2.0", "id": i, "method": "logsSubscribe", "params": []interface{}{"all"}}
+		if err := conn.WriteJSON(subscribe); err != nil {
+			t.Fatal(err)
+		}
+		for s.PublishLogs(Logs{Signature: solana.Signature{1}}) == 0 {
+			if ctx.Err() != nil {
+				t.Fatal(ctx.Err())
+			}
+		}
+
+		var first struct {
+			ID     *int   `json:"id"`
+			Method string `json:"method"`
+		}
+		if err := conn.ReadJSON(&first); err != nil {
+			t.Fatal(err)
+		}
+		if first.ID == nil || *first.ID != i {
+			t.Fatalf("first message = %+v, want the reply to %d", first, i)
+		}
+		conn.Close()
+	}
+}
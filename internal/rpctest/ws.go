@@ -0,0 +1,221 @@
+package rpctest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gorilla/websocket"
+)
+
+// Logs is a transaction's logs published to logsSubscribe subscribers
+type Logs struct {
+	Signature solana.Signature
+	Err       interface{} // Transaction error, nil if it succeeded
+	Logs      []string
+	Mentions  []solana.PublicKey // Accounts the transaction mentions, matched against mentions filters
+}
+
+// wsConn is a WebSocket connection whose writes are serialized between replies and notifications
+type wsConn struct {
+	ws *websocket.Conn
+	mu sync.Mutex
+}
+
+func (c *wsConn) write(v interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ws.WriteJSON(v)
+}
+
+// subscription is a logsSubscribe subscription with either the "all" filter or a mentions filter
+type subscription struct {
+	conn     *wsConn
+	all      bool
+	mentions []solana.PublicKey
+}
+
+func (sub *subscription) matches(logs Logs) bool {
+	if sub.all {
+		return true
+	}
+	for _, want := range sub.mentions {
+		for _, key := range logs.Mentions {
+			if key.Equals(want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *Server) serveWS(w http.ResponseWriter, r *http.Request) {
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	conn := &wsConn{ws: ws}
+	s.mu.Lock()
+	s.conns[conn] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		for id, sub := range s.subs {
+			if sub.conn == conn {
+				delete(s.subs, id)
+			}
+		}
+		s.mu.Unlock()
+		ws.Close()
+	}()
+
+	for {
+		var req request
+		if err := ws.ReadJSON(&req); err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.calls[req.Method]++
+		s.mu.Unlock()
+		if req.Method == "logsSubscribe" {
+			if err := s.subscribe(conn, req); err != nil {
+				return
+			}
+			continue
+		}
+		resp := response{JSONRPC: "2.0", ID: req.ID}
+		switch req.Method {
+		case "logsUnsubscribe":
+			var id uint64
+			if err := decodeParams(req.Params, &id); err != nil {
+				resp.Error = err.(*Error)
+				break
+			}
+			s.mu.Lock()
+			_, ok := s.subs[id]
+			delete(s.subs, id)
+			s.mu.Unlock()
+			resp.Result = ok
+		default:
+			resp.Error = &Error{Code: CodeMethodNotFound, Message: "Method not found: " + req.Method}
+		}
+		if err := conn.write(resp); err != nil {
+			return
+		}
+	}
+}
+
+// subscribe answers a logsSubscribe with the "all" filter or {"mentions": [address]}. The subscription
+// is registered only once its reply is written, and under the connection's write lock, so no
+// notification reaches the client before it knows the subscription id.
+func (s *Server) subscribe(conn *wsConn, req request) error {
+	resp := response{JSONRPC: "2.0", ID: req.ID}
+	sub, rpcErr := parseSubscription(conn, req.Params)
+	if rpcErr != nil {
+		resp.Error = rpcErr
+		return conn.write(resp)
+	}
+	s.mu.Lock()
+	s.nextSub++
+	id := s.nextSub
+	s.mu.Unlock()
+	resp.Result = id
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	if err := conn.ws.WriteJSON(resp); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.subs[id] = sub
+	s.mu.Unlock()
+	return nil
+}
+
+// parseSubscription reads a logsSubscribe filter
+func parseSubscription(conn *wsConn, params json.RawMessage) (*subscription, *Error) {
+	var filter json.RawMessage
+	if err := decodeParams(params, &filter); err != nil {
+		return nil, err.(*Error)
+	}
+	sub := &subscription{conn: conn}
+	var name string
+	var mentions struct {
+		Mentions []solana.PublicKey `json:"mentions"`
+	}
+	switch {
+	case json.Unmarshal(filter, &name) == nil && (name == "all" || name == "allWithVotes"):
+		sub.all = true
+	case json.Unmarshal(filter, &mentions) == nil && len(mentions.Mentions) == 1:
+		sub.mentions = mentions.Mentions
+	default:
+		return nil, &Error{Code: CodeInvalidParams, Message: "unsupported logsSubscribe filter " + string(filter)}
+	}
+	return sub, nil
+}
+
+// Subscriptions returns the number of active logsSubscribe subscriptions
+func (s *Server) Subscriptions() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subs)
+}
+
+// WaitSubscriptions waits until at least n logsSubscribe subscriptions are active, so logs published
+// after it returns reach them. A subscription counts once its reply is written, so a client always
+// sees it confirmed before the first notification.
+func (s *Server) WaitSubscriptions(ctx context.Context, n int) error {
+	for s.Subscriptions() < n {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	return nil
+}
+
+// PublishLogs notifies every matching logsSubscribe subscriber of the logs at the current slot
+// and returns how many were notified
+func (s *Server) PublishLogs(logs Logs) int {
+	s.mu.Lock()
+	slot := s.slot
+	type target struct {
+		id   uint64
+		conn *wsConn
+	}
+	var targets []target
+	for id, sub := range s.subs {
+		if sub.matches(logs) {
+			targets = append(targets, target{id, sub.conn})
+		}
+	}
+	s.mu.Unlock()
+
+	delivered := 0
+	for _, t := range targets {
+		notification := map[string]interface{}{
+			"jsonrpc": "2.0",
+			"method":  "logsNotification",
+			"params": map[string]interface{}{
+				"subscription": t.id,
+				"result": map[string]interface{}{
+					"context": map[string]uint64{"slot": slot},
+					"value": map[string]interface{}{
+						"signature": logs.Signature,
+						"err":       logs.Err,
+						"logs":      logs.Logs,
+					},
+				},
+			},
+		}
+		if t.conn.write(notification) == nil {
+			delivered++
+		}
+	}
+	return delivered
+}
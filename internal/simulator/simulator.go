@@ -0,0 +1,222 @@
+// Package simulator is an offline PumpSwap pool. It applies buys and sells with the program's
+// arithmetic and rounding, fails them with the program's errors, and reports each swap as the
+// BuyEvent or SellEvent the program would emit, so strategies can be run without a chain.
+package simulator
+
+import (
+	"fmt"
+	"math/big"
+
+	"solana-pumpswap-demo/idl/pumpfun/amm/idl/generated/amm"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+)
+
+// Fees of the PumpSwap global config at the time of writing
+const (
+	DefaultLpFeeBps       = 20
+	DefaultProtocolFeeBps = 5
+)
+
+const bpsDenominator = 10000
+
+// Pool is a PumpSwap pool's reserves and fee config. The LP fee stays in the pool, the protocol
+// fee is paid out of the swap to the fee recipient and counted in ProtocolFees.
+type Pool struct {
+	Address        solana.PublicKey
+	BaseReserve    uint64 // Pool token balance, raw units
+	QuoteReserve   uint64 // Pool quote (WSOL) balance, lamports
+	LpFeeBps       uint64
+	ProtocolFeeBps uint64
+	ProtocolFees   uint64 // Quote paid to the protocol fee recipient so far
+	Timestamp      int64  // Unix time stamped on the events, set by the caller as it replays
+}
+
+// NewPool returns a pool holding the reserves with the default fees
+func NewPool(address solana.PublicKey, baseReserve, quoteReserve uint64) *Pool {
+	return &Pool{
+		Address:        address,
+		BaseReserve:    baseReserve,
+		QuoteReserve:   quoteReserve,
+		LpFeeBps:       DefaultLpFeeBps,
+		ProtocolFeeBps: DefaultProtocolFeeBps,
+	}
+}
+
+// Price is the pool's spot price in quote lamports per raw base unit
+func (p *Pool) Price() decimal.Decimal {
+	if p.BaseReserve == 0 {
+		return decimal.Zero
+	}
+	return decimal.NewFromUint64(p.QuoteReserve).Div(decimal.NewFromUint64(p.BaseReserve))
+}
+
+// QuoteBuy prices buying exactly baseAmountOut without changing the pool. maxQuoteAmountIn is
+// only reported in the event, the slippage check is left to Buy.
+func (p *Pool) QuoteBuy(user solana.PublicKey, baseAmountOut, maxQuoteAmountIn uint64) (*amm.BuyEventEventData, error) {
+	if baseAmountOut == 0 {
+		return nil, amm.ErrZeroBaseAmount
+	}
+	if baseAmountOut >= p.BaseReserve {
+		return nil, amm.ErrBuyMoreBaseAmountThanPoolReserves
+	}
+	quoteAmountIn, err := mulDiv(p.QuoteReserve, baseAmountOut, p.BaseReserve-baseAmountOut, true)
+	if err != nil {
+		return nil, err
+	}
+	lpFee, protocolFee := fee(quoteAmountIn, p.LpFeeBps), fee(quoteAmountIn, p.ProtocolFeeBps)
+	userQuoteAmountIn := quoteAmountIn + lpFee + protocolFee
+	if userQuoteAmountIn < quoteAmountIn {
+		return nil, amm.ErrOverflow
+	}
+	return &amm.BuyEventEventData{
+		Timestamp:              p.Timestamp,
+		BaseAmountOut:          baseAmountOut,
+		MaxQuoteAmountIn:       maxQuoteAmountIn,
+		PoolBaseTokenReserves:  p.BaseReserve,
+		PoolQuoteTokenReserves: p.QuoteReserve,
+		QuoteAmountIn:          quoteAmountIn,
+		LpFeeBasisPoints:       p.LpFeeBps,
+		LpFee:                  lpFee,
+		ProtocolFeeBasisPoints: p.ProtocolFeeBps,
+		ProtocolFee:            protocolFee,
+		QuoteAmountInWithLpFee: quoteAmountIn + lpFee,
+		UserQuoteAmountIn:      userQuoteAmountIn,
+		Pool:                   p.Address,
+		User:                   user,
+	}, nil
+}
+
+// Buy buys exactly baseAmountOut for at most maxQuoteAmountIn, fees included, and returns its event.
+// It fails with amm.ErrExceededSlippage like the program when the pool asks for more.
+func (p *Pool) Buy(user solana.PublicKey, baseAmountOut, maxQuoteAmountIn uint64) (*amm.BuyEventEventData, error) {
+	event, err := p.QuoteBuy(user, baseAmountOut, maxQuoteAmountIn)
+	if err != nil {
+		return nil, err
+	}
+	if event.UserQuoteAmountIn > maxQuoteAmountIn {
+		return nil, amm.ErrExceededSlippage
+	}
+	if p.QuoteReserve+event.QuoteAmountInWithLpFee < p.QuoteReserve {
+		return nil, amm.ErrOverflow
+	}
+	p.BaseReserve -= baseAmountOut
+	p.QuoteReserve += event.QuoteAmountInWithLpFee
+	p.ProtocolFees += event.ProtocolFee
+	return event, nil
+}
+
+// BuyWithQuote spends at most quoteAmountIn, fees included, on the most base it buys, the way a
+// client sizes a buy by SOL before sending the exact-out instruction. The event's UserQuoteAmountIn
+// is what was actually spent.
+func (p *Pool) BuyWithQuote(user solana.PublicKey, quoteAmountIn uint64) (*amm.BuyEventEventData, error) {
+	baseAmountOut, err := p.BaseOutForQuote(quoteAmountIn)
+	if err != nil {
+		return nil, err
+	}
+	return p.Buy(user, baseAmountOut, quoteAmountIn)
+}
+
+// BaseOutForQuote returns the most base quoteAmountIn buys, fees included. Fees round up, so the
+// estimate is stepped down until its exact-out price fits.
+func (p *Pool) BaseOutForQuote(quoteAmountIn uint64) (uint64, error) {
+	if quoteAmountIn == 0 {
+		return 0, amm.ErrZeroQuoteAmount
+	}
+	if p.BaseReserve == 0 || p.QuoteReserve == 0 {
+		return 0, fmt.Errorf("pool has no liquidity")
+	}
+	effective, err := mulDiv(quoteAmountIn, bpsDenominator, bpsDenominator+p.LpFeeBps+p.ProtocolFeeBps, false)
+	if err != nil {
+		return 0, err
+	}
+	baseAmountOut, err := mulDiv(p.BaseReserve, effective, p.QuoteReserve+effective, false)
+	if err != nil {
+		return 0, err
+	}
+	for baseAmountOut > 0 {
+		event, err := p.QuoteBuy(solana.PublicKey{}, baseAmountOut, quoteAmountIn)
+		if err != nil {
+			return 0, err
+		}
+		if event.UserQuoteAmountIn <= quoteAmountIn {
+			return baseAmountOut, nil
+		}
+		baseAmountOut--
+	}
+	return 0, amm.ErrZeroBaseAmount
+}
+
+// QuoteSell prices selling exactly baseAmountIn without changing the pool. minQuoteAmountOut is
+// only reported in the event, the slippage check is left to Sell.
+func (p *Pool) QuoteSell(user solana.PublicKey, baseAmountIn, minQuoteAmountOut uint64) (*amm.SellEventEventData, error) {
+	if baseAmountIn == 0 {
+		return nil, amm.ErrZeroBaseAmount
+	}
+	if p.BaseReserve+baseAmountIn < p.BaseReserve {
+		return nil, amm.ErrOverflow
+	}
+	quoteAmountOut, err := mulDiv(p.QuoteReserve, baseAmountIn, p.BaseReserve+baseAmountIn, false)
+	if err != nil {
+		return nil, err
+	}
+	lpFee, protocolFee := fee(quoteAmountOut, p.LpFeeBps), fee(quoteAmountOut, p.ProtocolFeeBps)
+	if lpFee+protocolFee > quoteAmountOut {
+		return nil, amm.ErrZeroQuoteAmount
+	}
+	return &amm.SellEventEventData{
+		Timestamp:                  p.Timestamp,
+		BaseAmountIn:               baseAmountIn,
+		MinQuoteAmountOut:          minQuoteAmountOut,
+		PoolBaseTokenReserves:      p.BaseReserve,
+		PoolQuoteTokenReserves:     p.QuoteReserve,
+		QuoteAmountOut:             quoteAmountOut,
+		LpFeeBasisPoints:           p.LpFeeBps,
+		LpFee:                      lpFee,
+		ProtocolFeeBasisPoints:     p.ProtocolFeeBps,
+		ProtocolFee:                protocolFee,
+		QuoteAmountOutWithoutLpFee: quoteAmountOut - lpFee,
+		UserQuoteAmountOut:         quoteAmountOut - lpFee - protocolFee,
+		Pool:                       p.Address,
+		User:                       user,
+	}, nil
+}
+
+// Sell sells exactly baseAmountIn for at least minQuoteAmountOut after fees and returns its event.
+// It fails with amm.ErrExceededSlippage like the program when the pool pays less.
+func (p *Pool) Sell(user solana.PublicKey, baseAmountIn, minQuoteAmountOut uint64) (*amm.SellEventEventData, error) {
+	event, err := p.QuoteSell(user, baseAmountIn, minQuoteAmountOut)
+	if err != nil {
+		return nil, err
+	}
+	if event.UserQuoteAmountOut < minQuoteAmountOut {
+		return nil, amm.ErrExceededSlippage
+	}
+	p.BaseReserve += baseAmountIn
+	p.QuoteReserve -= event.QuoteAmountOutWithoutLpFee
+	p.ProtocolFees += event.ProtocolFee
+	return event, nil
+}
+
+// fee is bps of amount, rounded up as the program does
+func fee(amount, bps uint64) uint64 {
+	f, _ := mulDiv(amount, bps, bpsDenominator, true)
+	return f
+}
+
+// mulDiv returns a*b/c rounded up or down, in 128-bit arithmetic like the program
+func mulDiv(a, b, c uint64, roundUp bool) (uint64, error) {
+	if c == 0 {
+		return 0, amm.ErrDivisionByZero
+	}
+	n := new(big.Int).Mul(new(big.Int).SetUint64(a), new(big.Int).SetUint64(b))
+	q, r := new(big.Int).QuoRem(n, new(big.Int).SetUint64(c), new(big.Int))
+	if roundUp && r.Sign() > 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if !q.IsUint64() {
+		return 0, amm.ErrOverflow
+	}
+	return q.Uint64(), nil
+}
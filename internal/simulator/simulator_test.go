@@ -0,0 +1,243 @@
+package simulator
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"solana-pumpswap-demo/idl/pumpfun/amm/idl/generated/amm"
+	"solana-pumpswap-demo/internal/decoder"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Reserves of a pool holding 1M tokens (6 decimals) and 100 SOL
+const (
+	baseReserve  = 1_000_000_000_000
+	quoteReserve = 100_000_000_000
+)
+
+// TestBuy tests an exact-out buy's amounts, rounding and effect on the pool
+func TestBuy(t *testing.T) {
+	p := NewPool(solana.PublicKey{1}, baseReserve, quoteReserve)
+	user := solana.PublicKey{2}
+	p.Timestamp = 1750000000
+
+	event, err := p.Buy(user, 1_000_000_000, 101_000_000)
+	if err != nil {
+		t.Fatalf("Buy() error = %v", err)
+	}
+	want := amm.BuyEventEventData{
+		Timestamp:              1750000000,
+		BaseAmountOut:          1_000_000_000,
+		MaxQuoteAmountIn:       101_000_000,
+		PoolBaseTokenReserves:  baseReserve,
+		PoolQuoteTokenReserves: quoteReserve,
+		QuoteAmountIn:          100_100_101, // 1e20 / 999e9 rounded up
+		LpFeeBasisPoints:       20,
+		LpFee:                  200_201,
+		ProtocolFeeBasisPoints: 5,
+		ProtocolFee:            50_051,
+		QuoteAmountInWithLpFee: 100_300_302,
+		UserQuoteAmountIn:      100_350_353,
+		Pool:                   p.Address,
+		User:                   user,
+	}
+	if *event != want {
+		t.Errorf("Buy() = %+v, want %+v", *event, want)
+	}
+	if p.BaseReserve != 999_000_000_000 || p.QuoteReserve != 100_100_300_302 || p.ProtocolFees != 50_051 {
+		t.Errorf("pool after buy = %+v", p)
+	}
+}
+
+// TestSell tests an exact-in sell's amounts, rounding and effect on the pool
+func TestSell(t *testing.T) {
+	p := NewPool(solana.PublicKey{1}, baseReserve, quoteReserve)
+	event, err := p.Sell(solana.PublicKey{2}, 10_000_000_000, 980_000_000)
+	if err != nil {
+		t.Fatalf("Sell() error = %v", err)
+	}
+	if event.QuoteAmountOut != 990_099_009 || event.LpFee != 1_980_199 || event.ProtocolFee != 495_050 ||
+		event.QuoteAmountOutWithoutLpFee != 988_118_810 || event.UserQuoteAmountOut != 987_623_760 {
+		t.Errorf("Sell() = %+v", *event)
+	}
+	if p.BaseReserve != 1_010_000_000_000 || p.QuoteReserve != 99_011_881_190 || p.ProtocolFees != 495_050 {
+		t.Errorf("pool after sell = %+v", p)
+	}
+}
+
+// TestSwapErrors tests that failing swaps return the program's errors and leave the pool alone
+func TestSwapErrors(t *testing.T) {
+	user := solana.PublicKey{2}
+	tests := []struct {
+		name string
+		swap func(p *Pool) error
+		want error
+	}{
+		{"buy over the limit", func(p *Pool) error { _, err := p.Buy(user, 1_000_000_000, 100_350_352); return err }, amm.ErrExceededSlippage},
+		{"sell under the limit", func(p *Pool) error { _, err := p.Sell(user, 10_000_000_000, 987_623_761); return err }, amm.ErrExceededSlippage},
+		{"buy the whole pool", func(p *Pool) error { _, err := p.Buy(user, baseReserve, 1<<63); return err }, amm.ErrBuyMoreBaseAmountThanPoolReserves},
+		{"zero buy", func(p *Pool) error { _, err := p.Buy(user, 0, 1); return err }, amm.ErrZeroBaseAmount},
+		{"zero sell", func(p *Pool) error { _, err := p.Sell(user, 0, 0); return err }, amm.ErrZeroBaseAmount},
+		{"zero quote", func(p *Pool) error { _, err := p.BuyWithQuote(user, 0); return err }, amm.ErrZeroQuoteAmount},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPool(solana.PublicKey{1}, baseReserve, quoteReserve)
+			if err := tt.swap(p); !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+			if p.BaseReserve != baseReserve || p.QuoteReserve != quoteReserve || p.ProtocolFees != 0 {
+				t.Errorf("failed swap changed the pool: %+v", p)
+			}
+		})
+	}
+}
+
+// TestBuyWithQuote tests that sizing a buy by quote spends as much of it as the rounding allows
+func TestBuyWithQuote(t *testing.T) {
+	for _, quoteIn := range []uint64{1_000, 100_000_000, 5_000_000_000, 60_000_000_000} {
+		p := NewPool(solana.PublicKey{1}, baseReserve, quoteReserve)
+		event, err := p.BuyWithQuote(solana.PublicKey{2}, quoteIn)
+		if err != nil {
+			t.Fatalf("BuyWithQuote(%d) error = %v", quoteIn, err)
+		}
+		if event.UserQuoteAmountIn > quoteIn {
+			t.Errorf("BuyWithQuote(%d) spent %d", quoteIn, event.UserQuoteAmountIn)
+		}
+		// One more base unit would have cost too much
+		next, err := NewPool(solana.PublicKey{1}, baseReserve, quoteReserve).QuoteBuy(solana.PublicKey{}, event.BaseAmountOut+1, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next.UserQuoteAmountIn <= quoteIn {
+			t.Errorf("BuyWithQuote(%d) bought %d, %d more still fits", quoteIn, event.BaseAmountOut, next.UserQuoteAmountIn)
+		}
+	}
+}
+
+// TestRoundTripLosesFees tests that buying and selling back never returns more than was paid and
+// the constant product never shrinks
+func TestRoundTripLosesFees(t *testing.T) {
+	p := NewPool(solana.PublicKey{1}, baseReserve, quoteReserve)
+	user := solana.PublicKey{2}
+	k := func() float64 { return float64(p.BaseReserve) * float64(p.QuoteReserve) }
+	before := k()
+	buy, err := p.BuyWithQuote(user, 3_000_000_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sell, err := p.Sell(user, buy.BaseAmountOut, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sell.UserQuoteAmountOut >= buy.UserQuoteAmountIn {
+		t.Errorf("round trip paid %d and got back %d", buy.UserQuoteAmountIn, sell.UserQuoteAmountOut)
+	}
+	if k() < before || p.BaseReserve != baseReserve {
+		t.Errorf("pool after round trip = %+v", p)
+	}
+}
+
+// corpusAmounts are the swap amounts of the decoder's corpus worked out by hand from the program's
+// published formulas, so the corpus is not only checked against the math that synthesized it:
+//
+//	buy:  quote_in = ceil(quote_reserve * base_out / (base_reserve - base_out))
+//	sell: quote_out = floor(quote_reserve * base_in / (base_reserve + base_in))
+//	fee = ceil(amount * fee_bps / 10000) for the LP (20 bps) and the protocol (5 bps)
+//
+// The user pays quote_in plus both fees on a buy and receives quote_out less both fees on a sell.
+// Work the amounts out again when testdata/capture.sh replaces one of these transactions.
+var corpusAmounts = map[string]struct{ quote, lpFee, protocolFee, user uint64 }{
+	// 84_990_000_000 * 1_200_000_000_000 / 205_700_000_000_000 = 495_809_431.2
+	"pumpswap_buy.json": {495_809_432, 991_619, 247_905, 497_048_956},
+	// 116_700_000_000 * 3_500_000_000_000 / 153_750_000_000_000 = 2_656_585_365.8
+	"pumpswap_sell.json": {2_656_585_365, 5_313_171, 1_328_293, 2_649_943_901},
+	// 178_300_000_000 * 250_000_000_000 / 98_150_000_000_000 = 454_151_808.5
+	"routed_buy.json": {454_151_809, 908_304, 227_076, 455_287_189},
+	// 142_500_000_000 * 800_000_000_000 / 119_200_000_000_000 = 956_375_838.9
+	"v0_lookup_table_buy.json": {956_375_839, 1_912_752, 478_188, 958_766_779},
+}
+
+// TestMatchesCorpus replays the PumpSwap swaps of the decoder's corpus against pools holding the
+// reserves their events report, and checks the simulator emits the same events. Only the swaps
+// captured from mainnet check the simulator against the program; the synthesized ones listed in
+// the corpus' sources.txt were built with the same fee math, so their amounts are also checked
+// against corpusAmounts. internal/decoder/testdata/capture.sh replaces them.
+func TestMatchesCorpus(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "decoder", "testdata", "transactions", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed := 0
+	for _, file := range files {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		txResult := new(rpc.GetTransactionResult)
+		if err := json.Unmarshal(raw, txResult); err != nil {
+			t.Fatalf("failed to parse %s: %v", file, err)
+		}
+		events, err := decoder.DecodeEvents(txResult)
+		if err != nil {
+			t.Fatalf("%s: DecodeEvents() error = %v", file, err)
+		}
+		for _, e := range events {
+			switch recorded := e.Data.(type) {
+			case *amm.BuyEventEventData:
+				p := &Pool{Address: recorded.Pool, BaseReserve: recorded.PoolBaseTokenReserves, QuoteReserve: recorded.PoolQuoteTokenReserves,
+					LpFeeBps: recorded.LpFeeBasisPoints, ProtocolFeeBps: recorded.ProtocolFeeBasisPoints, Timestamp: recorded.Timestamp}
+				got, err := p.Buy(recorded.User, recorded.BaseAmountOut, recorded.MaxQuoteAmountIn)
+				if err != nil {
+					t.Fatalf("%s: Buy() error = %v", file, err)
+				}
+				// The simulator has no user balances or token accounts
+				got.UserBaseTokenReserves, got.UserQuoteTokenReserves = recorded.UserBaseTokenReserves, recorded.UserQuoteTokenReserves
+				got.UserBaseTokenAccount, got.UserQuoteTokenAccount = recorded.UserBaseTokenAccount, recorded.UserQuoteTokenAccount
+				got.ProtocolFeeRecipient, got.ProtocolFeeRecipientTokenAccount = recorded.ProtocolFeeRecipient, recorded.ProtocolFeeRecipientTokenAccount
+				if *got != *recorded {
+					t.Errorf("%s: Buy() = %+v, recorded %+v", file, *got, *recorded)
+				}
+				checkCorpusAmounts(t, file, recorded.QuoteAmountIn, recorded.LpFee, recorded.ProtocolFee, recorded.UserQuoteAmountIn)
+				replayed++
+			case *amm.SellEventEventData:
+				p := &Pool{Address: recorded.Pool, BaseReserve: recorded.PoolBaseTokenReserves, QuoteReserve: recorded.PoolQuoteTokenReserves,
+					LpFeeBps: recorded.LpFeeBasisPoints, ProtocolFeeBps: recorded.ProtocolFeeBasisPoints, Timestamp: recorded.Timestamp}
+				got, err := p.Sell(recorded.User, recorded.BaseAmountIn, recorded.MinQuoteAmountOut)
+				if err != nil {
+					t.Fatalf("%s: Sell() error = %v", file, err)
+				}
+				got.UserBaseTokenReserves, got.UserQuoteTokenReserves = recorded.UserBaseTokenReserves, recorded.UserQuoteTokenReserves
+				got.UserBaseTokenAccount, got.UserQuoteTokenAccount = recorded.UserBaseTokenAccount, recorded.UserQuoteTokenAccount
+				got.ProtocolFeeRecipient, got.ProtocolFeeRecipientTokenAccount = recorded.ProtocolFeeRecipient, recorded.ProtocolFeeRecipientTokenAccount
+				if *got != *recorded {
+					t.Errorf("%s: Sell() = %+v, recorded %+v", file, *got, *recorded)
+				}
+				checkCorpusAmounts(t, file, recorded.QuoteAmountOut, recorded.LpFee, recorded.ProtocolFee, recorded.UserQuoteAmountOut)
+				replayed++
+			}
+		}
+	}
+	if replayed == 0 {
+		t.Fatal("no PumpSwap swaps in the corpus")
+	}
+}
+
+// checkCorpusAmounts compares a corpus swap's amounts with the ones worked out by hand
+func checkCorpusAmounts(t *testing.T, file string, quote, lpFee, protocolFee, user uint64) {
+	t.Helper()
+	want, ok := corpusAmounts[filepath.Base(file)]
+	if !ok {
+		t.Errorf("%s: no hand-computed amounts in corpusAmounts", file)
+		return
+	}
+	if quote != want.quote || lpFee != want.lpFee || protocolFee != want.protocolFee || user != want.user {
+		t.Errorf("%s: quote %d, lp fee %d, protocol fee %d, user %d, want %d, %d, %d, %d",
+			file, quote, lpFee, protocolFee, user, want.quote, want.lpFee, want.protocolFee, want.user)
+	}
+}
@@ -0,0 +1,351 @@
+package store
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Bucket names
+var (
+	metaBucket         = []byte("meta")
+	transactionsBucket = []byte("transactions")
+	ordersBucket       = []byte("orders")
+	positionsBucket    = []byte("positions")
+	cursorsBucket      = []byte("cursors")
+	processedBucket    = []byte("processed")
+
+	schemaVersionKey = []byte("schema_version")
+)
+
+// ErrNotFound is returned when a record does not exist
+var ErrNotFound = errors.New("store: not found")
+
+// migrations are applied in order on Open; the schema version is the number applied.
+// Never edit or reorder an existing entry, append a new one instead.
+var migrations = []func(tx *bolt.Tx) error{
+	// v1: initial buckets
+	func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{transactionsBucket, ordersBucket, positionsBucket, cursorsBucket, processedBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// Transaction is a decoded transaction the bot has seen
+type Transaction struct {
+	Signature   string
+	Slot        uint64
+	BlockTime   int64
+	Success     bool
+	Fee         uint64
+	Operation   string // e.g. "Swap", "CreatePool"
+	Direction   string // "Buy" or "Sell" for swaps
+	Pool        string
+	BaseMint    string
+	User        string
+	BaseAmount  uint64
+	QuoteAmount uint64
+	RecordedAt  time.Time
+
+	// PumpSwap pool reserves after the swap and its fees, zero when unknown or for older records
+	PoolBaseReserve  uint64
+	PoolQuoteReserve uint64
+	LpFeeBps         uint64
+	ProtocolFeeBps   uint64
+}
+
+// Order is a transaction we submitted and its outcome
+type Order struct {
+	ID              string
+	SourceSignature string // Leader transaction that triggered the order
+	Wallet          string
+	Mint            string
+	Side            string
+	Reason          string // Exit rule that placed a sell, empty for copies
+	AmountIn        uint64
+	MinAmountOut    uint64
+	Signature       string // Our transaction, empty until sent
+	Status          string
+	Error           string
+	Attempts        int
+	AmountOut       uint64 // Executed output, set when the fill is applied
+	PositionID      string // Position the landed order opened or changed, empty until its fill is applied
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// Position is a token holding opened by a copy buy and its realized PnL
+type Position struct {
+	ID               string
+	Mint             string
+	Pool             string
+	EntryPrice       string // Decimal quote lamports per raw base unit
+	InitialAmount    uint64
+	Amount           uint64
+	CostLamports     uint64 // SOL spent opening the position
+	ProceedsLamports uint64 // SOL received from sells so far
+	TakenProfits     int    // Take-profit levels already sold
+	OpenedAt         time.Time
+	ClosedAt         time.Time
+}
+
+// Open reports whether the position still holds tokens
+func (p Position) Open() bool {
+	return p.Amount > 0 && p.ClosedAt.IsZero()
+}
+
+// RealizedPnL returns proceeds minus cost in lamports
+func (p Position) RealizedPnL() int64 {
+	return int64(p.ProceedsLamports) - int64(p.CostLamports)
+}
+
+// Store is the bot's embedded on-disk state
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens or creates the database at path and brings its schema up to date
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store %s: %w", path, err)
+	}
+
+	s := &Store{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// SchemaVersion returns the number of migrations applied to the database
+func (s *Store) SchemaVersion() (int, error) {
+	var version int
+	err := s.db.View(func(tx *bolt.Tx) error {
+		version = schemaVersion(tx)
+		return nil
+	})
+	return version, err
+}
+
+func schemaVersion(tx *bolt.Tx) int {
+	b := tx.Bucket(metaBucket)
+	if b == nil {
+		return 0
+	}
+	v := b.Get(schemaVersionKey)
+	if len(v) != 8 {
+		return 0
+	}
+	return int(binary.BigEndian.Uint64(v))
+}
+
+func (s *Store) migrate() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+
+		version := schemaVersion(tx)
+		if version > len(migrations) {
+			return fmt.Errorf("store schema version %d is newer than this binary supports (%d)", version, len(migrations))
+		}
+
+		for i := version; i < len(migrations); i++ {
+			if err := migrations[i](tx); err != nil {
+				return fmt.Errorf("migration to schema version %d failed: %w", i+1, err)
+			}
+		}
+
+		buf := make([]byte, 8)
+		binary.BigEndian.PutUint64(buf, uint64(len(migrations)))
+		return meta.Put(schemaVersionKey, buf)
+	})
+}
+
+// put encodes v under key in bucket
+func put(tx *bolt.Tx, bucket []byte, key string, v interface{}) error {
+	data, err := msgpack.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s record: %w", bucket, err)
+	}
+	return tx.Bucket(bucket).Put([]byte(key), data)
+}
+
+// get decodes the record under key in bucket into v
+func get(tx *bolt.Tx, bucket []byte, key string, v interface{}) error {
+	data := tx.Bucket(bucket).Get([]byte(key))
+	if data == nil {
+		return ErrNotFound
+	}
+	if err := msgpack.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode %s record %s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+// PutTransaction saves a decoded transaction
+func (s *Store) PutTransaction(t Transaction) error {
+	if t.RecordedAt.IsZero() {
+		t.RecordedAt = time.Now()
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return put(tx, transactionsBucket, t.Signature, t)
+	})
+}
+
+// GetTransaction loads a decoded transaction by signature
+func (s *Store) GetTransaction(signature string) (Transaction, error) {
+	var t Transaction
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return get(tx, transactionsBucket, signature, &t)
+	})
+	return t, err
+}
+
+// Transactions returns every stored transaction
+func (s *Store) Transactions() ([]Transaction, error) {
+	var transactions []Transaction
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(transactionsBucket).ForEach(func(k, v []byte) error {
+			var t Transaction
+			if err := msgpack.Unmarshal(v, &t); err != nil {
+				return fmt.Errorf("failed to decode transaction %s: %w", k, err)
+			}
+			transactions = append(transactions, t)
+			return nil
+		})
+	})
+	return transactions, err
+}
+
+// PutOrder saves an order, setting its timestamps
+func (s *Store) PutOrder(o Order) error {
+	now := time.Now()
+	if o.CreatedAt.IsZero() {
+		o.CreatedAt = now
+	}
+	o.UpdatedAt = now
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return put(tx, ordersBucket, o.ID, o)
+	})
+}
+
+// GetOrder loads an order by ID
+func (s *Store) GetOrder(id string) (Order, error) {
+	var o Order
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return get(tx, ordersBucket, id, &o)
+	})
+	return o, err
+}
+
+// Orders returns every stored order
+func (s *Store) Orders() ([]Order, error) {
+	var orders []Order
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(ordersBucket).ForEach(func(k, v []byte) error {
+			var o Order
+			if err := msgpack.Unmarshal(v, &o); err != nil {
+				return fmt.Errorf("failed to decode order %s: %w", k, err)
+			}
+			orders = append(orders, o)
+			return nil
+		})
+	})
+	return orders, err
+}
+
+// PutPosition saves a position
+func (s *Store) PutPosition(p Position) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return put(tx, positionsBucket, p.ID, p)
+	})
+}
+
+// PutFill saves an order together with the position its fill changed, so a fill is never applied twice
+func (s *Store) PutFill(o Order, p Position) error {
+	o.UpdatedAt = time.Now()
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := put(tx, positionsBucket, p.ID, p); err != nil {
+			return err
+		}
+		return put(tx, ordersBucket, o.ID, o)
+	})
+}
+
+// GetPosition loads a position by ID
+func (s *Store) GetPosition(id string) (Position, error) {
+	var p Position
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return get(tx, positionsBucket, id, &p)
+	})
+	return p, err
+}
+
+// Positions returns every stored position, open and closed
+func (s *Store) Positions() ([]Position, error) {
+	var positions []Position
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(positionsBucket).ForEach(func(k, v []byte) error {
+			var p Position
+			if err := msgpack.Unmarshal(v, &p); err != nil {
+				return fmt.Errorf("failed to decode position %s: %w", k, err)
+			}
+			positions = append(positions, p)
+			return nil
+		})
+	})
+	return positions, err
+}
+
+// MarkProcessed records that a signature has been handled
+func (s *Store) MarkProcessed(signature string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		ts := make([]byte, 8)
+		binary.BigEndian.PutUint64(ts, uint64(time.Now().Unix()))
+		return tx.Bucket(processedBucket).Put([]byte(signature), ts)
+	})
+}
+
+// IsProcessed reports whether a signature has already been handled
+func (s *Store) IsProcessed(signature string) (bool, error) {
+	var processed bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		processed = tx.Bucket(processedBucket).Get([]byte(signature)) != nil
+		return nil
+	})
+	return processed, err
+}
+
+// SetCursor saves the last processed signature for a monitored account
+func (s *Store) SetCursor(account, signature string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(cursorsBucket).Put([]byte(account), []byte(signature))
+	})
+}
+
+// Cursor returns the last processed signature for a monitored account, or "" if none
+func (s *Store) Cursor(account string) (string, error) {
+	var signature string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		signature = string(tx.Bucket(cursorsBucket).Get([]byte(account)))
+		return nil
+	})
+	return signature, err
+}
@@ -0,0 +1,121 @@
+package store
+
+import (
+	"encoding/binary"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func openTestStore(t *testing.T, path string) *Store {
+	t.Helper()
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	return s
+}
+
+// TestStoreRoundTrip tests that records survive closing and reopening the database
+func TestStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	s := openTestStore(t, path)
+
+	version, err := s.SchemaVersion()
+	if err != nil || version != len(migrations) {
+		t.Fatalf("SchemaVersion() = %d, %v; want %d", version, err, len(migrations))
+	}
+
+	tx := Transaction{Signature: "sig1", Slot: 42, Success: true, Operation: "Swap", Direction: "Buy", BaseAmount: 1000, QuoteAmount: 5000, PoolBaseReserve: 9000, PoolQuoteReserve: 55000}
+	order := Order{ID: "order1", SourceSignature: "sig1", Wallet: "wallet", Side: "buy", AmountIn: 5000, Status: "pending"}
+	position := Position{ID: "pos1", Mint: "mint", InitialAmount: 1000, Amount: 400, CostLamports: 5000, ProceedsLamports: 7000, OpenedAt: time.Unix(1700000000, 0).UTC()}
+
+	if err := s.PutTransaction(tx); err != nil {
+		t.Fatalf("PutTransaction() error = %v", err)
+	}
+	if err := s.PutOrder(order); err != nil {
+		t.Fatalf("PutOrder() error = %v", err)
+	}
+	if err := s.PutPosition(position); err != nil {
+		t.Fatalf("PutPosition() error = %v", err)
+	}
+	if err := s.MarkProcessed("sig1"); err != nil {
+		t.Fatalf("MarkProcessed() error = %v", err)
+	}
+	if err := s.SetCursor("account", "sig1"); err != nil {
+		t.Fatalf("SetCursor() error = %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	s = openTestStore(t, path)
+	defer s.Close()
+
+	gotTx, err := s.GetTransaction("sig1")
+	if err != nil || gotTx.Slot != 42 || gotTx.Direction != "Buy" || gotTx.RecordedAt.IsZero() {
+		t.Errorf("GetTransaction() = %+v, %v", gotTx, err)
+	}
+	transactions, err := s.Transactions()
+	if err != nil || len(transactions) != 1 || transactions[0].PoolQuoteReserve != 55000 {
+		t.Errorf("Transactions() = %+v, %v", transactions, err)
+	}
+
+	gotOrder, err := s.GetOrder("order1")
+	if err != nil || gotOrder.SourceSignature != "sig1" || gotOrder.CreatedAt.IsZero() {
+		t.Errorf("GetOrder() = %+v, %v", gotOrder, err)
+	}
+	orders, err := s.Orders()
+	if err != nil || len(orders) != 1 {
+		t.Errorf("Orders() = %d orders, %v; want 1", len(orders), err)
+	}
+
+	positions, err := s.Positions()
+	if err != nil || len(positions) != 1 {
+		t.Fatalf("Positions() = %d positions, %v; want 1", len(positions), err)
+	}
+	if !positions[0].Open() || positions[0].RealizedPnL() != 2000 || !positions[0].OpenedAt.Equal(position.OpenedAt) {
+		t.Errorf("Positions()[0] = %+v", positions[0])
+	}
+
+	processed, err := s.IsProcessed("sig1")
+	if err != nil || !processed {
+		t.Errorf("IsProcessed(sig1) = %v, %v; want true", processed, err)
+	}
+	processed, err = s.IsProcessed("sig2")
+	if err != nil || processed {
+		t.Errorf("IsProcessed(sig2) = %v, %v; want false", processed, err)
+	}
+
+	cursor, err := s.Cursor("account")
+	if err != nil || cursor != "sig1" {
+		t.Errorf("Cursor() = %q, %v; want sig1", cursor, err)
+	}
+
+	if _, err := s.GetOrder("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetOrder(missing) error = %v, want ErrNotFound", err)
+	}
+}
+
+// TestStoreRejectsNewerSchema tests that an old binary refuses a database migrated by a newer one
+func TestStoreRejectsNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	s := openTestStore(t, path)
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		buf := make([]byte, 8)
+		binary.BigEndian.PutUint64(buf, uint64(len(migrations)+1))
+		return tx.Bucket(metaBucket).Put(schemaVersionKey, buf)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	if s, err := Open(path); err == nil {
+		s.Close()
+		t.Fatal("Open() succeeded on a newer schema")
+	}
+}
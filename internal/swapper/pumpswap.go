@@ -23,6 +23,7 @@ const (
 	PumpSwapProgramID = "pAMMBay6oceH9fJKBRHGP5D4bD4sWpmSwMn52FMfXEA"
 	WrappedSOL        = "So11111111111111111111111111111111111111112"
 	PumpFunSwapCU     = 300_000
+	PumpSwapFeeRate   = 2500 // 0.25% of feeDenominator
 )
 
 // Valid protocol fee recipients - must use one of these
@@ -134,6 +135,18 @@ func ExecutePumpSwap(
 
 	// 5.3 If input is SOL, add instruction to wrap SOL
 	var closeIx solana.Instruction
+	if !isBuy {
+		// Selling pays out WSOL, unwrap it once the swap has landed
+		closeIx, err = token.NewCloseAccountInstruction(
+			outATA,    // The account to close
+			publicKey, // Rent destination
+			publicKey, // Owner
+			[]solana.PublicKey{},
+		).ValidateAndBuild()
+		if err != nil {
+			return "", fmt.Errorf("failed to build close account instruction: %w", err)
+		}
+	}
 	if isBuy {
 		// Convert amount string to lamports
 		amountDecimal, err := decimal.NewFromString(amountInStr)
@@ -216,9 +229,6 @@ func ExecutePumpSwap(
 		amountInLamports = amountDecimal.Mul(decimal.New(1, 6)).BigInt().Uint64()
 	}
 
-	// Define the standard fee rate for PumpFun AMM
-	feeRate := uint64(2500) // 0.25%
-
 	// Get token balances (reserves) for the pool
 
 	// Replace hardcoded accounts with the ones from poolInfo
@@ -239,24 +249,33 @@ func ExecutePumpSwap(
 	}
 
 	// Calculate minimum amount out based on slippage
-	// The direction parameter should be true for buy, false for sell
-	minAmountOut, _, err := CalculateMinAmountOut(uint32(slippage), amountInLamports, isBuy, reserves[0], reserves[1], feeRate)
+	quote, err := QuoteSwap(uint32(slippage), amountInLamports, isBuy, reserves[0], reserves[1], PumpSwapFeeRate)
 	if err != nil {
 		return "", fmt.Errorf("failed to calculate minimum amount out: %w", err)
 	}
+	minAmountOut := quote.MinAmountOut
+	fmt.Printf("quote: out %d (min %d), fee %d, price %s -> %s, impact %d bps\n",
+		quote.AmountOut, quote.MinAmountOut, quote.Fee, quote.SpotPriceBefore, quote.SpotPriceAfter, quote.PriceImpactBps)
 
 	//
 	fmt.Println("outATA is:", outATA)
 	fmt.Println("inATA is:", inATA)
 
+	// The pool's base side is always the token and the quote side WSOL
+	userBaseTokenAccount, userQuoteTokenAccount := outATA, inATA
+	if !isBuy {
+		userBaseTokenAccount, userQuoteTokenAccount = inATA, outATA
+	}
+
 	// Create the swap instruction
 	swapIx, err := createPumpSwapInstruction(
+		isBuy,
 		solana.MustPublicKeyFromBase58(poolInfo.PoolAddress),
 		publicKey,
 		solana.MustPublicKeyFromBase58(poolInfo.BaseMint),
 		solana.MustPublicKeyFromBase58(poolInfo.QuoteMint),
-		outATA,
-		inATA,
+		userBaseTokenAccount,
+		userQuoteTokenAccount,
 		solana.MustPublicKeyFromBase58(poolInfo.PoolBaseTokenAccount),
 		solana.MustPublicKeyFromBase58(poolInfo.PoolQuoteTokenAccount),
 		solana.MustPublicKeyFromBase58(poolInfo.ProtocolFeeRecipient),
@@ -271,8 +290,8 @@ func ExecutePumpSwap(
 
 	instructions = append(instructions, swapIx)
 
-	// Add the close instruction for wrapped SOL if this is a buy
-	if isBuy && closeIx != nil {
+	// Add the close instruction for wrapped SOL
+	if closeIx != nil {
 		instructions = append(instructions, closeIx)
 	}
 
@@ -363,53 +382,19 @@ func GetMultipleTokenBalances(ctx context.Context, client *rpc.Client, accounts
 // 	return amounts, nil
 // }
 
-// CalculateMinAmountOut calculates minimum amount out based on slippage
+// CalculateMinAmountOut calculates minimum amount out based on slippage.
+// tokenAmount is the pool's token reserve and baseAmount its SOL reserve.
 func CalculateMinAmountOut(slippageBP uint32, amountIn uint64, isBuy bool, tokenAmount, baseAmount, feeRate uint64) (uint64, uint64, error) {
-	// Convert everything to decimal for precision
-	amountInDec := decimal.NewFromUint64(amountIn)
-	tokenAmountDec := decimal.NewFromUint64(tokenAmount)
-	baseAmountDec := decimal.NewFromUint64(baseAmount)
-	feeRateDec := decimal.NewFromUint64(feeRate).Div(decimal.NewFromUint64(1000000)) // Assuming fee rate denominator is 1000000
-
-	// Calculate fee
-	feeDec := amountInDec.Mul(feeRateDec)
-	amountInAfterFee := amountInDec.Sub(feeDec)
-
-	var inReserve, outReserve decimal.Decimal
-	if isBuy {
-		// Buying token with SOL
-		inReserve = baseAmountDec
-		outReserve = tokenAmountDec
-	} else {
-		// Selling token for SOL
-		inReserve = tokenAmountDec
-		outReserve = baseAmountDec
-	}
-
-	// Calculate constant product
-	k := inReserve.Mul(outReserve)
-
-	// Calculate new in reserve after swap
-	newInReserve := inReserve.Add(amountInAfterFee)
-
-	// Calculate new out reserve using constant product formula
-	newOutReserve := k.Div(newInReserve)
-
-	// Calculate amount out
-	amountOut := outReserve.Sub(newOutReserve)
-
-	// Apply slippage to get minimum amount out
-	minAmountOut := amountOut.Mul(decimal.NewFromUint64(10000).Sub(decimal.NewFromUint64(uint64(slippageBP)))).Div(decimal.NewFromUint64(10000))
-
-	if minAmountOut.LessThanOrEqual(decimal.Zero) || amountOut.LessThanOrEqual(decimal.Zero) {
-		return 0, 0, fmt.Errorf("calculated amount out is zero or negative")
+	q, err := QuoteSwap(slippageBP, amountIn, isBuy, tokenAmount, baseAmount, feeRate)
+	if err != nil {
+		return 0, 0, err
 	}
-
-	return uint64(minAmountOut.IntPart()), uint64(amountOut.IntPart()), nil
+	return q.MinAmountOut, q.AmountOut, nil
 }
 
-// createPumpSwapInstruction creates a PumpSwap instruction
+// createPumpSwapInstruction creates a PumpSwap buy or sell instruction
 func createPumpSwapInstruction(
+	isBuy bool,
 	pool solana.PublicKey,
 	user solana.PublicKey,
 	baseMint solana.PublicKey,
@@ -427,10 +412,15 @@ func createPumpSwapInstruction(
 	baseTokenProgram := "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"
 	quoteTokenProgram := "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"
 	// pumpswap的base是meme quote是sol
+	// Buy takes (BaseAmountOut, MaxQuoteAmountIn), sell takes (BaseAmountIn, MinQuoteAmountOut)
+	direction, tokenAmount1, tokenAmount2 := amm.BuyDirection, minAmountOut, amountIn
+	if !isBuy {
+		direction, tokenAmount1, tokenAmount2 = amm.SellDirection, amountIn, minAmountOut
+	}
 	swapParam := &amm.SwapParam{
-		TokenAmount1:                     minAmountOut,
-		TokenAmount2:                     amountIn,
-		Direction:                        amm.BuyDirection,
+		TokenAmount1:                     tokenAmount1,
+		TokenAmount2:                     tokenAmount2,
+		Direction:                        direction,
 		Pool:                             pool,
 		User:                             user, // Use the actual public key directly
 		BaseMint:                         baseMint,
@@ -445,8 +435,7 @@ func createPumpSwapInstruction(
 		QuoteTokenProgram:                ag_solanago.MustPublicKeyFromBase58(quoteTokenProgram),
 	}
 
-	swapInstruction, _ := amm.NewSwapInstruction(swapParam)
-	return swapInstruction, nil
+	return amm.NewSwapInstruction(swapParam)
 }
 
 // encodeU64 encodes a uint64 into a little-endian byte array
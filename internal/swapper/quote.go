@@ -0,0 +1,122 @@
+package swapper
+
+import (
+	"context"
+	"fmt"
+
+	"solana-pumpswap-demo/internal/simulator"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+)
+
+// feeDenominator is the scale of the pool fee rate, 2500 is 0.25%
+const feeDenominator = 1000000
+
+// Quote is the expected result of a swap against the pool's reserves.
+// Prices are quote units per base unit in raw amounts (lamports per token unit).
+type Quote struct {
+	IsBuy           bool
+	BaseReserve     uint64 // Pool token balance the quote was priced against
+	QuoteReserve    uint64 // Pool WSOL balance the quote was priced against
+	AmountIn        uint64 // Lamports for a buy, raw token units for a sell
+	AmountOut       uint64 // Expected output before slippage
+	MinAmountOut    uint64 // Output after slippage, used as the instruction limit
+	Fee             uint64 // LP and protocol fees in lamports, charged on the input of a buy and the output of a sell
+	SpotPriceBefore decimal.Decimal
+	SpotPriceAfter  decimal.Decimal
+	EffectivePrice  decimal.Decimal // Quote paid or received per base unit, fee included
+	PriceImpactBps  uint64          // How far the trade moves the spot price
+}
+
+// PriceImpactError is returned when a quote moves the price more than allowed
+type PriceImpactError struct {
+	ImpactBps uint64
+	MaxBps    uint64
+}
+
+func (e *PriceImpactError) Error() string {
+	return fmt.Sprintf("price impact %d bps exceeds maximum %d bps", e.ImpactBps, e.MaxBps)
+}
+
+// CheckImpact returns a *PriceImpactError if the trade moves the price more than maxBps.
+// A zero maxBps disables the check.
+func (q Quote) CheckImpact(maxBps uint64) error {
+	if maxBps > 0 && q.PriceImpactBps > maxBps {
+		return &PriceImpactError{ImpactBps: q.PriceImpactBps, MaxBps: maxBps}
+	}
+	return nil
+}
+
+// QuoteSwap prices a swap against the constant product reserves with the program's arithmetic,
+// through simulator.Pool: a buy spends amountIn lamports including fees, a sell receives the quote
+// out of amountIn tokens less the fees. baseReserve is the pool's token balance and quoteReserve
+// its WSOL balance.
+func QuoteSwap(slippageBP uint32, amountIn uint64, isBuy bool, baseReserve, quoteReserve, feeRate uint64) (Quote, error) {
+	q := Quote{IsBuy: isBuy, AmountIn: amountIn, BaseReserve: baseReserve, QuoteReserve: quoteReserve}
+	if slippageBP >= 10000 {
+		return q, fmt.Errorf("slippage %d bps must be below 10000", slippageBP)
+	}
+	if baseReserve == 0 || quoteReserve == 0 {
+		return q, fmt.Errorf("pool has no liquidity")
+	}
+
+	pool := simulator.NewPool(solana.PublicKey{}, baseReserve, quoteReserve)
+	pool.LpFeeBps, pool.ProtocolFeeBps = feeSplit(feeRate)
+	q.SpotPriceBefore = pool.Price()
+	if isBuy {
+		baseOut, err := pool.BaseOutForQuote(amountIn)
+		if err != nil || baseOut == 0 {
+			return q, fmt.Errorf("calculated amount out is zero or negative")
+		}
+		event, err := pool.Buy(solana.PublicKey{}, baseOut, amountIn)
+		if err != nil {
+			return q, err
+		}
+		q.AmountOut = baseOut
+		q.Fee = event.LpFee + event.ProtocolFee
+		q.EffectivePrice = decimal.NewFromUint64(event.UserQuoteAmountIn).Div(decimal.NewFromUint64(baseOut))
+	} else {
+		event, err := pool.Sell(solana.PublicKey{}, amountIn, 0)
+		if err != nil || event.UserQuoteAmountOut == 0 {
+			return q, fmt.Errorf("calculated amount out is zero or negative")
+		}
+		q.AmountOut = event.UserQuoteAmountOut
+		q.Fee = event.LpFee + event.ProtocolFee
+		q.EffectivePrice = decimal.NewFromUint64(event.UserQuoteAmountOut).Div(decimal.NewFromUint64(amountIn))
+	}
+	q.SpotPriceAfter = pool.Price()
+
+	// Apply slippage to get minimum amount out
+	q.MinAmountOut = uint64(decimal.NewFromUint64(q.AmountOut).Mul(decimal.NewFromUint64(10000 - uint64(slippageBP))).Div(decimal.NewFromUint64(10000)).IntPart())
+	if q.MinAmountOut == 0 {
+		return q, fmt.Errorf("calculated amount out is zero or negative")
+	}
+
+	impact := q.SpotPriceAfter.Sub(q.SpotPriceBefore).Abs().Div(q.SpotPriceBefore).Mul(decimal.NewFromInt(10000))
+	q.PriceImpactBps = uint64(impact.Round(0).IntPart())
+	return q, nil
+}
+
+// feeSplit divides a fee rate of feeDenominator into LP and protocol basis points, in the
+// proportion of the default PumpSwap fees: PumpSwapFeeRate is 20 bps to LPs and 5 to the protocol
+func feeSplit(feeRate uint64) (lpBps, protocolBps uint64) {
+	total := feeRate * 10000 / feeDenominator
+	protocolBps = total * simulator.DefaultProtocolFeeBps / (simulator.DefaultLpFeeBps + simulator.DefaultProtocolFeeBps)
+	return total - protocolBps, protocolBps
+}
+
+// QuotePumpSwap fetches the pool's live reserves and prices the swap against them
+func QuotePumpSwap(ctx context.Context, client *rpc.Client, poolInfo PumpSwapPoolInfo, slippageBP uint32, amountIn uint64, isBuy bool) (Quote, error) {
+	reserves, err := GetMultipleTokenBalances(ctx, client,
+		solana.MustPublicKeyFromBase58(poolInfo.PoolBaseTokenAccount),
+		solana.MustPublicKeyFromBase58(poolInfo.PoolQuoteTokenAccount))
+	if err != nil {
+		return Quote{}, fmt.Errorf("failed to get pool reserves: %w", err)
+	}
+	if len(reserves) < 2 {
+		return Quote{}, fmt.Errorf("failed to get both pool reserves")
+	}
+	return QuoteSwap(slippageBP, amountIn, isBuy, reserves[0], reserves[1], PumpSwapFeeRate)
+}
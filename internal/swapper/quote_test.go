@@ -0,0 +1,150 @@
+package swapper
+
+import (
+	"errors"
+	"testing"
+
+	"solana-pumpswap-demo/internal/simulator"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// TestQuoteSwap tests quotes against a pool holding 1M tokens (6 decimals) and 100 SOL
+func TestQuoteSwap(t *testing.T) {
+	const (
+		baseReserve  = 1_000_000_000_000
+		quoteReserve = 100_000_000_000
+	)
+
+	tests := []struct {
+		name          string
+		amountIn      uint64
+		isBuy         bool
+		wantOut       uint64
+		wantMinOut    uint64
+		wantFee       uint64
+		wantImpactBps uint64
+	}{
+		{
+			name:          "small buy",
+			amountIn:      100_000_000, // 0.1 SOL
+			isBuy:         true,
+			wantOut:       996_512_192,
+			wantMinOut:    986_547_070,
+			wantFee:       249_378,
+			wantImpactBps: 20,
+		},
+		{
+			name:          "buy on a thin pool",
+			amountIn:      20_000_000_000, // 20 SOL
+			isBuy:         true,
+			wantOut:       166_320_166_311,
+			wantMinOut:    164_656_964_647,
+			wantFee:       49_875_313,
+			wantImpactBps: 4393,
+		},
+		{
+			name:     "sell",
+			amountIn: 10_000_000_000, // 10k tokens
+			isBuy:    false,
+			// 990_099_009 lamports gross, less 1_980_199 LP and 495_050 protocol fees, both rounded up
+			wantOut:       987_623_760,
+			wantMinOut:    977_747_522,
+			wantFee:       2_475_249,
+			wantImpactBps: 197,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := QuoteSwap(100, tt.amountIn, tt.isBuy, baseReserve, quoteReserve, PumpSwapFeeRate)
+			if err != nil {
+				t.Fatalf("QuoteSwap() error = %v", err)
+			}
+			if q.AmountOut != tt.wantOut || q.MinAmountOut != tt.wantMinOut || q.Fee != tt.wantFee {
+				t.Errorf("QuoteSwap() out = %d, min = %d, fee = %d; want %d, %d, %d",
+					q.AmountOut, q.MinAmountOut, q.Fee, tt.wantOut, tt.wantMinOut, tt.wantFee)
+			}
+			if q.PriceImpactBps != tt.wantImpactBps {
+				t.Errorf("QuoteSwap() impact = %d bps, want %d", q.PriceImpactBps, tt.wantImpactBps)
+			}
+			if q.SpotPriceBefore.String() != "0.1" {
+				t.Errorf("QuoteSwap() spot before = %s, want 0.1", q.SpotPriceBefore)
+			}
+			// Buys pay above spot and push it up, sells receive below spot and push it down
+			if tt.isBuy && (!q.EffectivePrice.GreaterThan(q.SpotPriceBefore) || !q.SpotPriceAfter.GreaterThan(q.SpotPriceBefore)) {
+				t.Errorf("buy prices: effective %s, after %s, before %s", q.EffectivePrice, q.SpotPriceAfter, q.SpotPriceBefore)
+			}
+			if !tt.isBuy && (!q.EffectivePrice.LessThan(q.SpotPriceBefore) || !q.SpotPriceAfter.LessThan(q.SpotPriceBefore)) {
+				t.Errorf("sell prices: effective %s, after %s, before %s", q.EffectivePrice, q.SpotPriceAfter, q.SpotPriceBefore)
+			}
+		})
+	}
+}
+
+// TestQuoteCheckImpact tests rejecting quotes above the max-impact threshold
+func TestQuoteCheckImpact(t *testing.T) {
+	q := Quote{PriceImpactBps: 2000}
+
+	var impactErr *PriceImpactError
+	if err := q.CheckImpact(500); !errors.As(err, &impactErr) || impactErr.ImpactBps != 2000 || impactErr.MaxBps != 500 {
+		t.Errorf("CheckImpact(500) = %v, want *PriceImpactError", err)
+	}
+	if err := q.CheckImpact(2000); err != nil {
+		t.Errorf("CheckImpact(2000) = %v, want nil", err)
+	}
+	if err := q.CheckImpact(0); err != nil {
+		t.Errorf("CheckImpact(0) = %v, want nil", err)
+	}
+}
+
+// TestQuoteSwapErrors tests quotes that cannot be filled
+func TestQuoteSwapErrors(t *testing.T) {
+	if _, err := QuoteSwap(100, 1000, true, 0, 1000, PumpSwapFeeRate); err == nil {
+		t.Error("QuoteSwap() on an empty pool returned no error")
+	}
+	if _, err := QuoteSwap(10000, 1000, true, 1000, 1000, PumpSwapFeeRate); err == nil {
+		t.Error("QuoteSwap() with 100% slippage returned no error")
+	}
+	if _, err := QuoteSwap(100, 1, true, 1_000_000, 1_000_000_000, PumpSwapFeeRate); err == nil {
+		t.Error("QuoteSwap() with a dust amount returned no error")
+	}
+}
+
+// TestQuoteSwapMatchesSimulator tests that the quotes are what the program would execute: fees are
+// added to the quote paid for a buy and taken from the quote received for a sell.
+func TestQuoteSwapMatchesSimulator(t *testing.T) {
+	const (
+		baseReserve  = 1_000_000_000_000
+		quoteReserve = 100_000_000_000
+	)
+	for _, tt := range []struct {
+		amountIn uint64
+		isBuy    bool
+	}{
+		{100_000_000, true},
+		{10_000_000_000, true},
+		{1_000_000_000, false},
+		{100_000_000_000, false},
+	} {
+		q, err := QuoteSwap(0, tt.amountIn, tt.isBuy, baseReserve, quoteReserve, PumpSwapFeeRate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pool := simulator.NewPool(solana.PublicKey{}, baseReserve, quoteReserve)
+		var want uint64
+		if tt.isBuy {
+			want, err = pool.BaseOutForQuote(tt.amountIn)
+		} else if event, sellErr := pool.Sell(solana.PublicKey{}, tt.amountIn, 0); sellErr == nil {
+			want = event.UserQuoteAmountOut
+		} else {
+			err = sellErr
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q.AmountOut != want {
+			t.Errorf("QuoteSwap(%d, buy=%t) = %d, the program gives %d", tt.amountIn, tt.isBuy, q.AmountOut, want)
+		}
+	}
+}